@@ -9,8 +9,12 @@ require (
 )
 
 require (
+	github.com/alecthomas/chroma/v2 v2.27.0
 	github.com/gosimple/slug v1.15.0
 	golang.org/x/text v0.29.0
 )
 
-require github.com/gosimple/unidecode v1.0.1 // indirect
+require (
+	github.com/dlclark/regexp2/v2 v2.2.1 // indirect
+	github.com/gosimple/unidecode v1.0.1 // indirect
+)
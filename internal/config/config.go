@@ -45,6 +45,9 @@ type Config struct {
 		SSL      bool   `yaml:"ssl"`
 		SSLCert  string `yaml:"ssl_cert"`
 		SSLKey   string `yaml:"ssl_key"`
+		// Path the wiki is served under behind a reverse proxy, e.g. /wiki.
+		// Rendered links, images and file URLs are put below it.
+		BasePath string `yaml:"base_path"`
 	} `yaml:"server"`
 	Wiki struct {
 		RootDir                   string `yaml:"root_dir"`
@@ -85,6 +88,7 @@ func LoadConfig(path string) (*Config, error) {
 	config.Server.SSL = false
 	config.Server.SSLCert = ""
 	config.Server.SSLKey = ""
+	config.Server.BasePath = ""
 	config.Wiki.RootDir = "data"
 	config.Wiki.DocumentsDir = "documents"
 	config.Wiki.Title = "📚 Wiki-Go"
@@ -150,6 +154,7 @@ func LoadConfig(path string) (*Config, error) {
 				config.Server.SSL,
 				config.Server.SSLCert,
 				config.Server.SSLKey,
+				config.Server.BasePath,
 				config.Wiki.RootDir,
 				config.Wiki.DocumentsDir,
 				config.Wiki.Title,
@@ -218,6 +223,9 @@ func GetConfigTemplate() string {
     ssl: %t
     ssl_cert: "%s"
     ssl_key: "%s"
+    # Path the wiki is served under behind a reverse proxy, e.g. /wiki.
+    # Leave empty when it is served from the root of its host.
+    base_path: "%s"
 wiki:
     root_dir: "%s"
     documents_dir: "%s"
@@ -278,6 +286,7 @@ func SaveConfig(cfg *Config, w io.Writer) error {
 		cfg.Server.SSL,
 		cfg.Server.SSLCert,
 		cfg.Server.SSLKey,
+		cfg.Server.BasePath,
 		cfg.Wiki.RootDir,
 		cfg.Wiki.DocumentsDir,
 		cfg.Wiki.Title,
@@ -2,7 +2,12 @@ package frontmatter
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -10,68 +15,366 @@ import (
 // Metadata represents the frontmatter data structure
 // This can be expanded with additional fields in the future
 type Metadata struct {
-	Layout string `yaml:"layout,omitempty"`
+	Layout           string            `yaml:"layout,omitempty" json:"layout,omitempty"`
+	Author           string            `yaml:"author,omitempty" json:"author,omitempty"`                       // Original author of the document
+	LastEditor       string            `yaml:"last_editor,omitempty" json:"last_editor,omitempty"`             // Person who last edited the document
+	Changelog        []ChangelogEntry  `yaml:"changelog,omitempty" json:"changelog,omitempty"`                 // Per-document change history
+	Vars             map[string]string `yaml:"vars,omitempty" json:"vars,omitempty"`                           // Document variables referenced as {{name}}
+	Tags             TagList           `yaml:"tags,omitempty" json:"tags,omitempty"`                           // Comma separated or a list
+	PrimaryTag       string            `yaml:"primary_tag,omitempty" json:"primary_tag,omitempty"`             // Tag used for prev/next navigation
+	Date             string            `yaml:"date,omitempty" json:"date,omitempty"`                           // Publication date as written, see ParseDate for the formats
+	ParsedDate       *time.Time        `yaml:"-" json:"parsed_date,omitempty"`                                 // Date parsed by Parse, nil when unset or invalid
+	Weight           int               `yaml:"weight,omitempty" json:"weight,omitempty"`                       // Ordering weight, lower first
+	Draft            Flag              `yaml:"draft,omitempty" json:"draft,omitempty"`                         // Unfinished document, rendered with a draft banner
+	Modified         string            `yaml:"modified,omitempty" json:"modified,omitempty"`                   // Last modification date (YYYY-MM-DD), defaults to the file time
+	Title            string            `yaml:"title,omitempty" json:"title,omitempty"`                         // Title for social cards, defaults to the first heading
+	Description      string            `yaml:"description,omitempty" json:"description,omitempty"`             // Summary for social cards, defaults to the first paragraph
+	Image            string            `yaml:"image,omitempty" json:"image,omitempty"`                         // Social card image
+	ThemeColor       string            `yaml:"theme_color,omitempty" json:"theme_color,omitempty"`             // Accent color of generated social cards
+	Anchors          *bool             `yaml:"anchors,omitempty" json:"anchors,omitempty"`                     // Heading ¶ anchor links, shown unless set to false
+	Aliases          StringList        `yaml:"aliases,omitempty" json:"aliases,omitempty"`                     // Old paths that redirect to this document
+	ColumnCount      int               `yaml:"column_count,omitempty" json:"column_count,omitempty"`           // Number of columns of the columns layout
+	Trusted          *bool             `yaml:"trusted,omitempty" json:"trusted,omitempty"`                     // Raw HTML is sanitized when set to false
+	Classes          StringList        `yaml:"classes,omitempty" json:"classes,omitempty"`                     // CSS classes of the element wrapping the rendered document
+	NumberedHeadings Flag              `yaml:"numbered_headings,omitempty" json:"numbered_headings,omitempty"` // Prefixes headings with section numbers
+	TOC              Flag              `yaml:"toc,omitempty" json:"toc,omitempty"`                             // Inserts a table of contents after the first heading
+	TOCMaxLevel      int               `yaml:"toc_max_level,omitempty" json:"toc_max_level,omitempty"`         // Deepest heading level listed in the table of contents
+	HardWraps        *bool             `yaml:"hard_wraps,omitempty" json:"hard_wraps,omitempty"`               // Single newlines break lines, see utils.HardWraps for the default
+	Abbreviations    string            `yaml:"abbreviations,omitempty" json:"abbreviations,omitempty"`         // Abbreviation occurrences wrapped, all or first, see goldext.AbbreviationFirstOnly for the default
 	// Add additional fields here as needed
 }
 
-// Parse extracts and parses frontmatter from markdown content
-// Returns the parsed metadata and the content without frontmatter
-func Parse(content string) (Metadata, string, bool) {
-	var metadata Metadata
+// ChangelogEntry is a single dated entry of a document's changelog
+type ChangelogEntry struct {
+	Date        string `yaml:"date" json:"date"`
+	Description string `yaml:"description" json:"description"`
+}
 
-	// Check if the content starts with frontmatter delimiter
-	if !strings.HasPrefix(content, "---\n") {
-		return metadata, content, false
+// UnmarshalYAML decodes a changelog entry without failing the whole frontmatter
+// Malformed entries are left empty so the renderer can skip them
+func (e *ChangelogEntry) UnmarshalYAML(value *yaml.Node) error {
+	var raw struct {
+		Date        string `yaml:"date"`
+		Description string `yaml:"description"`
+	}
+	if value.Kind != yaml.MappingNode || value.Decode(&raw) != nil {
+		*e = ChangelogEntry{}
+		return nil
 	}
+	e.Date, e.Description = raw.Date, raw.Description
+	return nil
+}
 
-	// Find the closing delimiter
-	endDelimIndex := strings.Index(content[4:], "\n---")
-	if endDelimIndex == -1 {
-		return metadata, content, false
+// Flag is a boolean that may also be written as yes/no, on/off or 1/0
+type Flag bool
+
+// UnmarshalYAML accepts true/false, yes/no, on/off and 1/0 in any case
+func (f *Flag) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: expected a boolean", value.Line)
+	}
+	switch strings.ToLower(strings.TrimSpace(value.Value)) {
+	case "true", "yes", "on", "1":
+		*f = true
+	case "false", "no", "off", "0", "", "~", "null":
+		*f = false
+	default:
+		return fmt.Errorf("line %d: expected a boolean, got %q", value.Line, value.Value)
 	}
+	return nil
+}
+
+// StringList is a list of strings that may also be written as a single string
+type StringList []string
 
-	// Extract the frontmatter content
-	fmContent := content[4 : 4+endDelimIndex]
+// UnmarshalYAML accepts both "value" and a list of values
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if value.Tag == "!!null" || strings.TrimSpace(value.Value) == "" {
+			*l = nil
+			return nil
+		}
+		*l = StringList{value.Value}
+		return nil
+	}
 
-	// Parse the frontmatter as YAML
-	err := yaml.Unmarshal([]byte(fmContent), &metadata)
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// TagList is a list of tags that may also be written as a comma separated string
+type TagList []string
+
+// UnmarshalYAML accepts both "one, two" and a list of tags
+func (l *TagList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = nil
+		if value.Tag == "!!null" {
+			return nil
+		}
+		for _, tag := range strings.Split(value.Value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				*l = append(*l, tag)
+			}
+		}
+		return nil
+	}
+
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// Column count limits of the columns layout
+const (
+	DefaultColumnCount = 2
+	MaxColumnCount     = 4
+)
+
+// ColumnCount returns the number of columns of a columns layout document,
+// DefaultColumnCount when unset and clamped to 1..MaxColumnCount otherwise
+func ColumnCount(metadata Metadata) int {
+	switch {
+	case metadata.ColumnCount == 0:
+		return DefaultColumnCount
+	case metadata.ColumnCount < 1:
+		return 1
+	case metadata.ColumnCount > MaxColumnCount:
+		return MaxColumnCount
+	}
+	return metadata.ColumnCount
+}
+
+// Aliases returns the normalized alias paths of a document: without leading or trailing
+// slashes, without duplicates and without paths that would leave the documents root
+func Aliases(metadata Metadata) []string {
+	var aliases []string
+	seen := make(map[string]bool)
+	for _, alias := range metadata.Aliases {
+		alias = strings.Trim(strings.TrimSpace(alias), "/")
+		if alias == "" || strings.Contains(alias, "..") || seen[alias] {
+			continue
+		}
+		seen[alias] = true
+		aliases = append(aliases, alias)
+	}
+	return aliases
+}
+
+// classNameRegex matches the class names Classes keeps
+var classNameRegex = regexp.MustCompile(`^-?[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// Classes returns the CSS classes of a document without duplicates
+// Entries may hold several space separated names; names other than letters, digits,
+// hyphens and underscores are dropped so they can't break out of the class attribute.
+func Classes(metadata Metadata) []string {
+	var classes []string
+	seen := make(map[string]bool)
+	for _, entry := range metadata.Classes {
+		for _, class := range strings.Fields(entry) {
+			if !classNameRegex.MatchString(class) || seen[class] {
+				continue
+			}
+			seen[class] = true
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+// ParseError reports frontmatter that is present but isn't valid YAML, TOML or JSON for Metadata
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return "invalid frontmatter: " + strings.TrimPrefix(e.Err.Error(), "yaml: ")
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Parse extracts and parses frontmatter from markdown content
+// Returns the parsed metadata and the content without frontmatter
+// Invalid frontmatter is reported as no frontmatter; use ParseStrict to tell them apart.
+func Parse(content string) (Metadata, string, bool) {
+	metadata, remainingContent, found, err := ParseStrict(content)
 	if err != nil {
 		return metadata, content, false
 	}
+	return metadata, remainingContent, found
+}
+
+// ParseStrict extracts and parses frontmatter from markdown content
+// Returns the parsed metadata, the content without frontmatter and whether frontmatter
+// delimiters were found. When the frontmatter is invalid, the content is still returned
+// without it along with a *ParseError.
+func ParseStrict(content string) (Metadata, string, bool, error) {
+	var metadata Metadata
+
+	format, fmContent, remainingContent, found := split(content)
+	if !found {
+		return metadata, content, false, nil
+	}
 
-	// Remove frontmatter from content
-	remainingContent := content[4+endDelimIndex+4:]
 	// Remove any leading newlines
 	remainingContent = strings.TrimLeft(remainingContent, "\n")
 
-	return metadata, remainingContent, true
+	if err := decode(format, fmContent, &metadata); err != nil {
+		return Metadata{}, remainingContent, true, &ParseError{Err: err}
+	}
+	metadata.ParsedDate = parsedDate(metadata.Date)
+
+	return metadata, remainingContent, true, nil
 }
 
-// HasFrontmatter checks if content has frontmatter
-func HasFrontmatter(content string) bool {
-	if !strings.HasPrefix(content, "---\n") {
-		return false
+// Frontmatter formats, told apart by how the frontmatter is delimited
+const (
+	formatYAML = "yaml" // Between --- lines
+	formatTOML = "toml" // Between +++ lines
+	formatJSON = "json" // A JSON object from a { line to a } line
+)
+
+// split finds the frontmatter at the start of content
+// Returns its format, the frontmatter without YAML and TOML delimiters, the content after
+// it and whether frontmatter was found.
+func split(content string) (string, string, string, bool) {
+	for _, fence := range []struct{ format, delimiter string }{{formatYAML, "---"}, {formatTOML, "+++"}} {
+		if !strings.HasPrefix(content, fence.delimiter+"\n") {
+			continue
+		}
+		endDelimIndex := strings.Index(content[4:], "\n"+fence.delimiter)
+		if endDelimIndex == -1 {
+			return "", "", content, false
+		}
+		return fence.format, content[4 : 4+endDelimIndex], content[4+endDelimIndex+4:], true
+	}
+
+	// JSON frontmatter keeps its braces, which are part of the object
+	if strings.HasPrefix(content, "{\n") {
+		endIndex := strings.Index(content[1:], "\n}")
+		if endIndex == -1 {
+			return "", "", content, false
+		}
+		return formatJSON, content[:endIndex+3], content[endIndex+3:], true
 	}
 
-	endDelimIndex := strings.Index(content[4:], "\n---")
-	return endDelimIndex != -1
+	return "", "", content, false
 }
 
-// Extract returns just the frontmatter as a string
-func Extract(content string) string {
-	if !strings.HasPrefix(content, "---\n") {
-		return ""
+// decode parses frontmatter of a format into metadata
+// TOML and JSON are converted to YAML first, so every format decodes through the same
+// YAML tags and unmarshalers and unknown keys are ignored alike.
+func decode(format, fmContent string, metadata *Metadata) error {
+	source, err := yamlSource(format, fmContent)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(source, metadata)
+}
+
+// yamlSource returns frontmatter of a format as YAML
+func yamlSource(format, fmContent string) ([]byte, error) {
+	var values map[string]interface{}
+	switch format {
+	case formatTOML:
+		parsed, err := parseTOML(fmContent)
+		if err != nil {
+			return nil, err
+		}
+		values = parsed
+	case formatJSON:
+		if err := json.Unmarshal([]byte(fmContent), &values); err != nil {
+			return nil, err
+		}
+	default:
+		return []byte(fmContent), nil
+	}
+
+	return yaml.Marshal(values)
+}
+
+// dateLayouts are the date formats ParseDate accepts, tried in order
+var dateLayouts = []string{
+	"2006-01-02",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05Z0700",
+}
+
+// ParseDate parses a frontmatter date: YYYY-MM-DD, an RFC 3339 timestamp or an ISO 8601
+// date and time with or without seconds and offset, separated by T or a space.
+// Dates without an offset are in UTC.
+func ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q: expected YYYY-MM-DD or an ISO 8601 date and time", value)
+}
+
+// parsedDate returns the parsed date of a frontmatter date, nil when unset or invalid
+func parsedDate(value string) *time.Time {
+	if strings.TrimSpace(value) == "" {
+		return nil
 	}
+	date, err := ParseDate(value)
+	if err != nil {
+		return nil
+	}
+	return &date
+}
 
-	endDelimIndex := strings.Index(content[4:], "\n---")
-	if endDelimIndex == -1 {
-		return ""
+// Warnings returns problems with the metadata that don't prevent rendering the document,
+// such as a date that can't be parsed
+func (m Metadata) Warnings() []string {
+	var warnings []string
+	if strings.TrimSpace(m.Date) != "" {
+		if _, err := ParseDate(m.Date); err != nil {
+			warnings = append(warnings, "date: "+err.Error())
+		}
 	}
+	return warnings
+}
+
+// HasFrontmatter checks if content has YAML, TOML or JSON frontmatter
+func HasFrontmatter(content string) bool {
+	_, _, _, found := split(content)
+	return found
+}
+
+// Extract returns just the frontmatter as a string, in the format it is written in
+func Extract(content string) string {
+	_, fmContent, _, _ := split(content)
+	return fmContent
+}
 
-	return content[4 : 4+endDelimIndex]
+// Block returns the frontmatter at the start of content with its delimiters, as written,
+// and the content after it; content without frontmatter is returned as the rest
+func Block(content string) (string, string) {
+	_, _, rest, found := split(content)
+	if !found {
+		return "", content
+	}
+	return content[:len(content)-len(rest)], rest
 }
 
 // Add adds or updates frontmatter in content
+// The frontmatter is written as YAML, replacing TOML or JSON frontmatter.
 func Add(content string, metadata Metadata) (string, error) {
 	// Remove existing frontmatter if present
 	_, contentWithoutFM, hasFM := Parse(content)
@@ -89,4 +392,95 @@ func Add(content string, metadata Metadata) (string, error) {
 
 	// Construct new content with frontmatter
 	return "---\n" + buf.String() + "---\n\n" + contentWithoutFM, nil
-}
\ No newline at end of file
+}
+
+// MergeMetadata parses the frontmatter of a document's content like Parse, with the keys it
+// doesn't set taken from directory defaults: YAML mappings of frontmatter keys, farthest
+// directory first. Precedence, highest first:
+//  1. the document's own frontmatter
+//  2. directory defaults, nearer directories first
+//
+// Keys are merged as written, so an explicit draft: false, toc: false or weight: 0 in the
+// document overrides a default. Frontmatter of included documents only applies to the
+// included content and never leaks into the host, so it is not an input here. Aliases
+// name a single document and are never inherited. ParsedDate follows the merged Date.
+// The returned bool also holds for a document without frontmatter that got defaults.
+// Invalid document frontmatter is reported as none, without defaults, and invalid
+// defaults are skipped.
+func MergeMetadata(content string, directoryDefaults ...string) (Metadata, string, bool) {
+	if len(directoryDefaults) == 0 {
+		return Parse(content)
+	}
+
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, defaults := range directoryDefaults {
+		var check Metadata
+		node, err := mappingNode(formatYAML, defaults)
+		if err != nil || node.Decode(&check) != nil {
+			continue
+		}
+		mergeMapping(merged, node, "aliases")
+	}
+
+	format, fmContent, remainingContent, found := split(content)
+	if found {
+		node, err := mappingNode(format, fmContent)
+		if err != nil {
+			return Metadata{}, content, false
+		}
+		mergeMapping(merged, node)
+		remainingContent = strings.TrimLeft(remainingContent, "\n")
+	}
+
+	var metadata Metadata
+	if err := merged.Decode(&metadata); err != nil {
+		return Metadata{}, content, false
+	}
+	metadata.ParsedDate = parsedDate(metadata.Date)
+
+	return metadata, remainingContent, found || len(merged.Content) > 0
+}
+
+// mappingNode parses frontmatter of a format into a YAML mapping node
+// Empty frontmatter is an empty mapping.
+func mappingNode(format, fmContent string) (*yaml.Node, error) {
+	source, err := yamlSource(format, fmContent)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(source, &document); err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 || document.Content[0].Tag == "!!null" {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	if node := document.Content[0]; node.Kind == yaml.MappingNode {
+		return node, nil
+	}
+	return nil, fmt.Errorf("line %d: expected a mapping of keys", document.Content[0].Line)
+}
+
+// mergeMapping sets the keys of the src mapping in dst, replacing the values dst has,
+// except for the skipped keys
+func mergeMapping(dst, src *yaml.Node, skip ...string) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		if slices.Contains(skip, key.Value) {
+			continue
+		}
+
+		replaced := false
+		for j := 0; j+1 < len(dst.Content); j += 2 {
+			if dst.Content[j].Value == key.Value {
+				dst.Content[j+1] = value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			dst.Content = append(dst.Content, key, value)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package frontmatter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergeMetadataPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		defaults []string
+		expected Metadata
+	}{
+		{
+			name:     "Document frontmatter wins",
+			document: "---\nlayout: kanban\nauthor: Alice\n---\n",
+			defaults: []string{"layout: links\nauthor: Team\n"},
+			expected: Metadata{Layout: "kanban", Author: "Alice"},
+		},
+		{
+			name:     "Directory defaults fill unset fields",
+			document: "---\nauthor: Alice\n---\n",
+			defaults: []string{"layout: links\nlast_editor: Bob\n"},
+			expected: Metadata{Layout: "links", Author: "Alice", LastEditor: "Bob"},
+		},
+		{
+			name:     "Explicit false and zero override defaults",
+			document: "---\ndraft: false\ntoc: no\nnumbered_headings: off\nweight: 0\ntags: []\n---\n",
+			defaults: []string{"draft: true\ntoc: true\nnumbered_headings: true\nweight: 5\ntags: [guide]\n"},
+			expected: Metadata{Tags: TagList{}},
+		},
+		{
+			name:     "Nearer directories win",
+			document: "+++\nauthor = \"Alice\"\n+++\n",
+			defaults: []string{"layout: links\ntags: [root]\ndraft: true\n", "tags: [guides]\n", "draft: false\n"},
+			expected: Metadata{Layout: "links", Author: "Alice", Tags: TagList{"guides"}},
+		},
+		{
+			name:     "Aliases are not inherited, invalid defaults are skipped",
+			document: "No frontmatter",
+			defaults: []string{"aliases: /old\nlayout: links\n", "draft: maybe\nlayout: kanban\n", "- not a mapping\n", ""},
+			expected: Metadata{Layout: "links"},
+		},
+		{
+			name:     "No sources",
+			document: "---\n---\n",
+			expected: Metadata{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _, _ := MergeMetadata(tt.document, tt.defaults...)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected: %+v, got: %+v", tt.expected, result)
+			}
+		})
+	}
+
+	// Content and presence are reported like Parse
+	metadata, body, found := MergeMetadata("---\nauthor: Alice\n---\n\n# Body", "layout: links")
+	if body != "# Body" || !found || metadata.Layout != "links" {
+		t.Errorf("Expected the body without frontmatter, got %q %v %+v", body, found, metadata)
+	}
+	if _, body, found := MergeMetadata("# Body", "layout: links"); body != "# Body" || !found {
+		t.Errorf("Expected defaults for a document without frontmatter, got %q %v", body, found)
+	}
+	if _, _, found := MergeMetadata("# Body", "", "# comment\n"); found {
+		t.Errorf("Expected empty defaults to add no frontmatter")
+	}
+	if metadata, body, found := MergeMetadata("---\ndraft: maybe\n---\n# Body", "layout: links"); found || body != "---\ndraft: maybe\n---\n# Body" || metadata.Layout != "" {
+		t.Errorf("Expected invalid frontmatter to be reported as none, got %q %v %+v", body, found, metadata)
+	}
+}
+
+func TestAliases(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"Single string", "---\naliases: /old/page/\n---\n", []string{"old/page"}},
+		{"List with duplicates", "---\naliases:\n  - old\n  - /old\n  - ../escape\n  - legacy/name\n---\n", []string{"old", "legacy/name"}},
+		{"Empty", "---\naliases:\n---\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, _, ok := Parse(tt.input)
+			if !ok {
+				t.Fatalf("Expected the frontmatter to parse")
+			}
+			if result := Aliases(metadata); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestClasses(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"Single string", "---\nclasses: policy\n---\n", []string{"policy"}},
+		{"Space separated string", "---\nclasses: policy  wide-layout\n---\n", []string{"policy", "wide-layout"}},
+		{"List with unsafe names", "---\nclasses:\n  - tutorial\n  - '\"><script>'\n  - a<b\n  - tutorial\n  - step_2\n---\n", []string{"tutorial", "step_2"}},
+		{"Empty", "---\nclasses:\n---\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, _, ok := Parse(tt.input)
+			if !ok {
+				t.Fatalf("Expected the frontmatter to parse")
+			}
+			if result := Classes(metadata); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFlag(t *testing.T) {
+	for input, expected := range map[string]Flag{
+		"true": true, "Yes": true, "on": true, "1": true,
+		"false": false, "no": false, "OFF": false, "0": false, "": false,
+	} {
+		metadata, _, ok := Parse("---\ndraft: " + input + "\n---\n")
+		if !ok || metadata.Draft != expected {
+			t.Errorf("Expected draft: %q to be %v, got %v", input, expected, metadata.Draft)
+		}
+	}
+
+	var metadata Metadata
+	if err := yaml.Unmarshal([]byte("draft: maybe\n"), &metadata); err == nil {
+		t.Errorf("Expected an error for an unknown boolean")
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	// Valid frontmatter and documents without it parse exactly like Parse
+	for _, input := range []string{"---\ntitle: Setup\n---\nBody\n", "Body\n", "---\nno closing delimiter\n"} {
+		metadata, body, found, err := ParseStrict(input)
+		expectedMetadata, expectedBody, expectedFound := Parse(input)
+		if err != nil || found != expectedFound || body != expectedBody || !reflect.DeepEqual(metadata, expectedMetadata) {
+			t.Errorf("Expected ParseStrict(%q) to match Parse, got %v %q %v %v", input, metadata, body, found, err)
+		}
+	}
+
+	// Invalid frontmatter is found and stripped, and reported with a typed error
+	input := "---\ntitle: Setup\ndraft: maybe\n---\nBody\n"
+	_, body, found, err := ParseStrict(input)
+	var parseError *ParseError
+	if !found || body != "Body\n" || !errors.As(err, &parseError) {
+		t.Fatalf("Expected a ParseError and the body, got %q %v %v", body, found, err)
+	}
+	if err.Error() != `invalid frontmatter: line 2: expected a boolean, got "maybe"` {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+
+	// Parse still reports invalid frontmatter as none
+	if _, content, ok := Parse(input); ok || content != input {
+		t.Errorf("Expected Parse to ignore invalid frontmatter, got %q %v", content, ok)
+	}
+}
+
+func TestDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected time.Time
+	}{
+		{"Date", "date: 2024-03-05", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"Quoted date", `date: "2024-03-05"`, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"RFC 3339", "date: 2024-03-05T10:20:30+02:00", time.Date(2024, 3, 5, 8, 20, 30, 0, time.UTC)},
+		{"RFC 3339 fraction", "date: 2024-03-05T10:20:30.5Z", time.Date(2024, 3, 5, 10, 20, 30, 500000000, time.UTC)},
+		{"ISO 8601 local", "date: 2024-03-05T10:20", time.Date(2024, 3, 5, 10, 20, 0, 0, time.UTC)},
+		{"Space separated", "date: 2024-03-05 10:20:30", time.Date(2024, 3, 5, 10, 20, 30, 0, time.UTC)},
+		{"Basic offset", "date: 2024-03-05T10:20:30+0100", time.Date(2024, 3, 5, 9, 20, 30, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, _, ok := Parse("---\n" + tt.input + "\n---\n")
+			if !ok {
+				t.Fatalf("Expected the frontmatter to parse")
+			}
+			if metadata.ParsedDate == nil || !metadata.ParsedDate.Equal(tt.expected) {
+				t.Errorf("Expected %v from %q, got %v", tt.expected, metadata.Date, metadata.ParsedDate)
+			}
+			if warnings := metadata.Warnings(); len(warnings) != 0 {
+				t.Errorf("Expected no warnings, got %v", warnings)
+			}
+		})
+	}
+
+	// An invalid date keeps the raw value and the rest of the frontmatter
+	metadata, _, ok := Parse("---\ndate: last week\nauthor: jane\n---\n")
+	if !ok || metadata.Author != "jane" || metadata.Date != "last week" || metadata.ParsedDate != nil {
+		t.Fatalf("Expected an unparsed date alongside the other fields, got %+v %v", metadata, ok)
+	}
+	if warnings := metadata.Warnings(); len(warnings) != 1 {
+		t.Errorf("Expected a date warning, got %v", warnings)
+	}
+
+	// The parsed date follows the merged raw date
+	if merged, _, _ := MergeMetadata("---\ndate: last week\n---\n", "date: 2020-01-01\n"); merged.ParsedDate != nil {
+		t.Errorf("Expected the document's invalid date to win, got %v", merged.ParsedDate)
+	}
+	if merged, _, _ := MergeMetadata("", "date: 2020-01-01\n"); merged.ParsedDate == nil || merged.ParsedDate.Year() != 2020 {
+		t.Errorf("Expected the default date, got %v", merged.ParsedDate)
+	}
+}
+
+func TestTagList(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected TagList
+	}{
+		{"Comma list", "---\ntags: Setup, docs ,, howto\n---\n", TagList{"Setup", "docs", "howto"}},
+		{"Sequence", "---\ntags: [setup, docs]\n---\n", TagList{"setup", "docs"}},
+		{"Empty", "---\ntags:\n---\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, _, ok := Parse(tt.input)
+			if !ok {
+				t.Fatalf("Expected the frontmatter to parse")
+			}
+			if !reflect.DeepEqual(metadata.Tags, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, metadata.Tags)
+			}
+		})
+	}
+}
+
+func TestLinkGroups(t *testing.T) {
+	content := "# Bookmarks\n\n- [Loose](https://loose.example) - Before any heading\n\n## Tools\n- [Go](https://go.dev) - The Go site\n\n## Reading\n- [Blog](https://blog.example)\n\n## Tools\n- [Git](https://git-scm.com)\n"
+
+	data, err := ParseLinksContent(content)
+	if err != nil {
+		t.Fatalf("Expected the links to parse, got %v", err)
+	}
+
+	var names []string
+	for _, group := range data.Groups() {
+		names = append(names, group.Name)
+	}
+	if expected := []string{DefaultLinkCategory, "Tools", "Reading"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected groups in heading order %v, got %v", expected, names)
+	}
+	if tools := data.Categories["Tools"]; len(tools) != 2 || tools[1].Title != "Git" {
+		t.Errorf("Expected both Tools sections in one group, got %+v", tools)
+	}
+
+	html, err := RenderLinks(content)
+	if err != nil {
+		t.Fatalf("Expected the links to render, got %v", err)
+	}
+	uncategorized := strings.Index(html, `<div class="links-category" data-category="Uncategorized">`)
+	tools := strings.Index(html, `<div class="links-category" data-category="Tools">`)
+	reading := strings.Index(html, `<div class="links-category" data-category="Reading">`)
+	if !strings.Contains(html, `<div class="links-groups">`) || uncategorized < 0 || !(uncategorized < tools && tools < reading) {
+		t.Errorf("Expected the groups in heading order inside the groups wrapper, got %s", html)
+	}
+}
+
+func TestParseFormats(t *testing.T) {
+	yamlDoc := "---\nlayout: kanban\ntitle: \"Board: Q1\"\ndate: 2024-03-05\ntags: [planning, q1]\nweight: 3\ndraft: yes\nvars:\n  team: core\nchangelog:\n  - date: 2024-03-01\n    description: Created\nunknown: kept out\n---\n\n# Body\n"
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"TOML", "+++\n# Generated\nlayout = \"kanban\"\ntitle = 'Board: Q1'\ndate = 2024-03-05\ntags = [\n  \"planning\",\n  \"q1\", # Trailing comma\n]\nweight = 3\ndraft = true\nvars = { team = \"core\" }\nunknown.nested = 1\n\n[[changelog]]\ndate = 2024-03-01\ndescription = \"\"\"\nCreated\"\"\"\n+++\n\n# Body\n"},
+		{"TOML tables", "+++\nlayout = \"kanban\"\ntitle = \"Board: \\u0051\\U00000031\"\ndate = \"2024-03-05\"\ntags = \"planning, q1\"\nweight = 0x3\ndraft = \"yes\"\n\n[vars]\nteam = \"core\"\n\n[[changelog]]\ndate = \"2024-03-01\"\ndescription = \"Created\"\n+++\n\n# Body\n"},
+		{"JSON", "{\n  \"layout\": \"kanban\",\n  \"title\": \"Board: Q1\",\n  \"date\": \"2024-03-05\",\n  \"tags\": [\"planning\", \"q1\"],\n  \"weight\": 3,\n  \"draft\": true,\n  \"vars\": {\"team\": \"core\"},\n  \"changelog\": [{\"date\": \"2024-03-01\", \"description\": \"Created\"}],\n  \"unknown\": {\"nested\": [1, 2]}\n}\n\n# Body\n"},
+	}
+
+	expected, expectedBody, ok := Parse(yamlDoc)
+	if !ok {
+		t.Fatalf("Expected the YAML frontmatter to parse")
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, body, found, err := ParseStrict(tt.input)
+			if err != nil || !found {
+				t.Fatalf("Expected the frontmatter to parse, got %v %v", found, err)
+			}
+			if !reflect.DeepEqual(metadata, expected) {
+				t.Errorf("Expected %+v, got %+v", expected, metadata)
+			}
+			if body != expectedBody {
+				t.Errorf("Expected body %q, got %q", expectedBody, body)
+			}
+			if !HasFrontmatter(tt.input) {
+				t.Errorf("Expected HasFrontmatter to find the frontmatter")
+			}
+			if block, rest := Block(tt.input); block+rest != tt.input || !strings.HasSuffix(rest, "# Body\n") {
+				t.Errorf("Expected Block to split off the frontmatter, got %q and %q", block, rest)
+			}
+		})
+	}
+
+	// Invalid TOML and JSON are reported like invalid YAML
+	for _, input := range []string{"+++\ntitle = \n+++\nBody\n", "+++\ntitle = \"\\x31\"\n+++\nBody\n", "{\n  \"title\": \n}\nBody\n"} {
+		_, body, found, err := ParseStrict(input)
+		var parseErr *ParseError
+		if !found || !errors.As(err, &parseErr) || body != "Body\n" {
+			t.Errorf("Expected a ParseError for %q, got %v %v %q", input, found, err, body)
+		}
+	}
+
+	// Without a closing delimiter there is no frontmatter
+	for _, input := range []string{"+++\ntitle = \"x\"\n", "{\n  \"title\": \"x\"\n"} {
+		if _, body, found := Parse(input); found || body != input {
+			t.Errorf("Expected no frontmatter in %q", input)
+		}
+	}
+}
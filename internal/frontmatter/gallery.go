@@ -0,0 +1,155 @@
+package frontmatter
+
+import (
+	"fmt"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+// GalleryImage represents a single image of a gallery document
+type GalleryImage struct {
+	URL      string `json:"url"`
+	Alt      string `json:"alt"`
+	Title    string `json:"title"`
+	Resolved bool   `json:"resolved"` // Whether URL points at a file that can be displayed
+}
+
+// GallerySection is a group of images below a ## heading
+type GallerySection struct {
+	Title  string         `json:"title"`
+	Images []GalleryImage `json:"images"`
+}
+
+// GalleryData represents the images of a gallery document
+type GalleryData struct {
+	Title       string           `json:"title"` // Document title (H1)
+	Sections    []GallerySection `json:"sections"`
+	TotalImages int              `json:"total_images"`
+}
+
+// galleryImageRegex matches markdown images: ![alt](url "title")
+var galleryImageRegex = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?([^)\s>]*)>?(?:\s+"([^"]*)")?\s*\)`)
+
+// ParseGalleryContent parses the images of a gallery document
+// Images are grouped by ## headings; images before the first heading form an untitled section.
+// Image paths are expected to be resolved already, e.g. to /api/files/ URLs.
+func ParseGalleryContent(content string) (*GalleryData, error) {
+	data := &GalleryData{}
+	current := GallerySection{}
+	inCodeBlock := false
+
+	h1Regex := regexp.MustCompile(`^#\s+(.+)$`)
+	h2Regex := regexp.MustCompile(`^##\s+(.+)$`)
+
+	flush := func() {
+		if len(current.Images) > 0 {
+			data.Sections = append(data.Sections, current)
+		}
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock || line == "" {
+			continue
+		}
+
+		// Check for document title (# heading)
+		if h1Match := h1Regex.FindStringSubmatch(line); h1Match != nil {
+			data.Title = strings.TrimSpace(h1Match[1])
+			continue
+		}
+
+		// Check for section headers (## heading)
+		if h2Match := h2Regex.FindStringSubmatch(line); h2Match != nil {
+			flush()
+			current = GallerySection{Title: strings.TrimSpace(h2Match[1])}
+			continue
+		}
+
+		for _, match := range galleryImageRegex.FindAllStringSubmatch(line, -1) {
+			url := strings.TrimSpace(match[2])
+			current.Images = append(current.Images, GalleryImage{
+				URL:      url,
+				Alt:      strings.TrimSpace(match[1]),
+				Title:    strings.TrimSpace(match[3]),
+				Resolved: isGalleryImageURL(url),
+			})
+			data.TotalImages++
+		}
+	}
+	flush()
+
+	if data.TotalImages == 0 {
+		return nil, fmt.Errorf("no images found")
+	}
+
+	return data, nil
+}
+
+// isGalleryImageURL reports whether an image URL can be displayed
+// Relative paths that weren't resolved to a wiki path and non-http schemes can't.
+func isGalleryImageURL(url string) bool {
+	lower := strings.ToLower(url)
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+		return true
+	}
+	return strings.HasPrefix(url, "/") && !strings.HasPrefix(url, "//") && !strings.Contains(url, "..")
+}
+
+// RenderGallery renders the images of a gallery document as a thumbnail grid
+// Every image links to its full-size file and carries data-gallery attributes for a lightbox.
+func RenderGallery(content string) (string, error) {
+	galleryData, err := ParseGalleryContent(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse gallery content: %v", err)
+	}
+
+	tmpl := `<div class="gallery-container" data-total-images="{{.TotalImages}}">
+    {{if .Title}}
+    <h1 class="gallery-document-title">{{.Title}}</h1>
+    {{end}}
+    {{range $section := .Sections}}
+    <section class="gallery-section">
+        {{if $section.Title}}
+        <h2 class="gallery-section-title">{{$section.Title}}</h2>
+        {{end}}
+        <div class="gallery-grid">
+            {{range $section.Images}}
+            {{if .Resolved}}
+            <figure class="gallery-item">
+                <a href="{{.URL}}" class="gallery-link" data-gallery="{{$section.Title}}" data-caption="{{.Alt}}" target="_blank">
+                    <img src="{{.URL}}" alt="{{.Alt}}"{{if .Title}} title="{{.Title}}"{{end}} loading="lazy">
+                </a>
+                {{if .Alt}}<figcaption>{{.Alt}}</figcaption>{{end}}
+            </figure>
+            {{else}}
+            <figure class="gallery-item gallery-item-missing">
+                <div class="gallery-placeholder" role="img" aria-label="{{.Alt}}">{{if .Alt}}{{.Alt}}{{else}}Image not found{{end}}</div>
+                {{if .Alt}}<figcaption>{{.Alt}}</figcaption>{{end}}
+            </figure>
+            {{end}}
+            {{end}}
+        </div>
+    </section>
+    {{end}}
+</div>`
+
+	t, err := template.New("gallery").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %v", err)
+	}
+
+	// Execute template
+	var buf strings.Builder
+	if err := t.Execute(&buf, galleryData); err != nil {
+		return "", fmt.Errorf("failed to execute template: %v", err)
+	}
+
+	return buf.String(), nil
+}
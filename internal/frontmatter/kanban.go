@@ -3,10 +3,10 @@ package frontmatter
 import (
 	"bytes"
 	"fmt"
+	"html/template"
 	"regexp"
 	"strconv"
 	"strings"
-	"sync"
 
 	"wiki-go/internal/i18n"
 
@@ -34,15 +34,10 @@ type KanbanTask struct {
 	Checked     bool
 	HTMLText    string // Rendered HTML text of the task
 	IndentLevel int    // Indentation level for nested tasks
+	Body        string // Markdown of the card body, the lines indented below the task
+	HTMLBody    string // Rendered HTML of the card body
 }
 
-// Store kanban boards until after goldext processing
-var (
-	kanbanBoards     = make(map[string]KanbanBoard)
-	kanbanBoardCount = 0
-	kanbanMutex      sync.Mutex
-)
-
 // KanbanSection represents a kanban board section (H5 column + tasks) - kept for backward compatibility
 type KanbanSection struct {
 	Title string
@@ -66,7 +61,9 @@ func RenderKanban(content string) string {
 // This function accepts preprocessor and postprocessor functions to avoid circular dependencies
 func RenderKanbanWithProcessors(content string, preprocessors []PreprocessorFunc, postProcessors []PostProcessorFunc) string {
 	// Apply kanban-aware preprocessing to protect kanban structure while allowing goldext processing
-	processedContent := kanbanAwarePreprocess(content)
+	// The boards are kept per call until after goldext processing, so concurrent renders don't share them
+	boards := make(map[string]KanbanBoard)
+	processedContent := kanbanAwarePreprocess(content, boards)
 
 	// Apply all provided preprocessors to the content
 	for _, preprocessor := range preprocessors {
@@ -86,7 +83,7 @@ func RenderKanbanWithProcessors(content string, preprocessors []PreprocessorFunc
 	}
 
 	// Restore kanban boards and build final kanban HTML
-	return restoreKanbanBoards(renderedHTML, preprocessors)
+	return restoreKanbanBoards(renderedHTML, boards, preprocessors, postProcessors)
 }
 
 // RenderKanbanBasic provides basic kanban rendering without full goldext support (fallback)
@@ -176,14 +173,7 @@ func RenderKanbanBasic(content string) string {
 }
 
 // kanbanAwarePreprocess protects kanban structure while allowing goldext processing of other content
-func kanbanAwarePreprocess(content string) string {
-	kanbanMutex.Lock()
-	defer kanbanMutex.Unlock()
-
-	// Reset storage
-	kanbanBoards = make(map[string]KanbanBoard)
-	kanbanBoardCount = 0
-
+func kanbanAwarePreprocess(content string, boards map[string]KanbanBoard) string {
 	lines := strings.Split(content, "\n")
 	var result []string
 
@@ -198,6 +188,8 @@ func kanbanAwarePreprocess(content string) string {
 	currentBoard := KanbanBoard{}
 	currentColumn := KanbanColumn{}
 	nonKanbanLines := []string{}
+	cardIndent := 0  // Indentation of the last task line
+	bodyIndent := -1 // Indentation of the last task's body, -1 while it has none
 
 	for _, line := range lines {
 		// Check for H4 heading (kanban board title)
@@ -214,7 +206,7 @@ func kanbanAwarePreprocess(content string) string {
 				inKanbanColumn = false
 			}
 			if inKanbanBoard {
-				saveKanbanBoard(currentBoard, &result)
+				saveKanbanBoard(currentBoard, boards, &result)
 			}
 
 			// Start new kanban board
@@ -236,6 +228,7 @@ func kanbanAwarePreprocess(content string) string {
 
 				// Start new kanban column
 				inKanbanColumn = true
+				bodyIndent = -1
 				currentColumn = KanbanColumn{
 					Title: h5Match[1],
 					Tasks: []KanbanTask{},
@@ -255,8 +248,29 @@ func kanbanAwarePreprocess(content string) string {
 				indentLevel = 1 // Handle tab indentation
 			}
 
+			// Lines indented below a task form its card body: a body starts at the first
+			// indented line that isn't a task and takes every line indented as far as it,
+			// tasks and blank lines included
+			if len(currentColumn.Tasks) > 0 {
+				card := &currentColumn.Tasks[len(currentColumn.Tasks)-1]
+				if trimmedLine == "" && bodyIndent >= 0 {
+					card.Body += "\n"
+					continue
+				}
+				if trimmedLine != "" && ((bodyIndent >= 0 && len(indent) >= bodyIndent) ||
+					(bodyIndent < 0 && len(indent) > cardIndent && !taskRegex.MatchString(trimmedLine))) {
+					if bodyIndent < 0 {
+						bodyIndent = len(indent)
+					}
+					card.Body += line[bodyIndent:] + "\n"
+					continue
+				}
+			}
+			bodyIndent = -1
+
 			if taskMatch := taskRegex.FindStringSubmatch(trimmedLine); taskMatch != nil {
 				// This is a task line - add to current column
+				cardIndent = len(indent)
 				isChecked := taskMatch[1] == "x" || taskMatch[1] == "X"
 				taskText := taskMatch[2]
 
@@ -277,7 +291,7 @@ func kanbanAwarePreprocess(content string) string {
 					inKanbanColumn = false
 				}
 				if inKanbanBoard {
-					saveKanbanBoard(currentBoard, &result)
+					saveKanbanBoard(currentBoard, boards, &result)
 					inKanbanBoard = false
 				}
 				nonKanbanLines = append(nonKanbanLines, line)
@@ -288,7 +302,7 @@ func kanbanAwarePreprocess(content string) string {
 			if trimmedLine != "" && !h5Regex.MatchString(line) {
 				// Non-H5 line in kanban board - end the board and treat as regular content
 				if inKanbanBoard {
-					saveKanbanBoard(currentBoard, &result)
+					saveKanbanBoard(currentBoard, boards, &result)
 					inKanbanBoard = false
 				}
 				nonKanbanLines = append(nonKanbanLines, line)
@@ -307,7 +321,7 @@ func kanbanAwarePreprocess(content string) string {
 		currentBoard.Columns = append(currentBoard.Columns, currentColumn)
 	}
 	if inKanbanBoard {
-		saveKanbanBoard(currentBoard, &result)
+		saveKanbanBoard(currentBoard, boards, &result)
 	}
 	if len(nonKanbanLines) > 0 {
 		result = append(result, nonKanbanLines...)
@@ -317,10 +331,9 @@ func kanbanAwarePreprocess(content string) string {
 }
 
 // saveKanbanBoard saves a kanban board and adds a placeholder to the result
-func saveKanbanBoard(board KanbanBoard, result *[]string) {
-	kanbanBoardCount++
-	id := fmt.Sprintf("KANBAN_BOARD_%d", kanbanBoardCount)
-	kanbanBoards[id] = board
+func saveKanbanBoard(board KanbanBoard, boards map[string]KanbanBoard, result *[]string) {
+	id := fmt.Sprintf("KANBAN_BOARD_%d", len(boards)+1)
+	boards[id] = board
 
 	// Add placeholder that won't be processed by goldext
 	placeholder := fmt.Sprintf("<!-- %s -->", id)
@@ -358,10 +371,7 @@ func renderWithGoldmark(content string) string {
 }
 
 // restoreKanbanBoards replaces placeholders with kanban HTML and builds the final result
-func restoreKanbanBoards(htmlContent string, preprocessors []PreprocessorFunc) string {
-	kanbanMutex.Lock()
-	defer kanbanMutex.Unlock()
-
+func restoreKanbanBoards(htmlContent string, boards map[string]KanbanBoard, preprocessors []PreprocessorFunc, postProcessors []PostProcessorFunc) string {
 	// Process the HTML content to find placeholders and build kanban structure
 	lines := strings.Split(htmlContent, "\n")
 	var finalHTML strings.Builder
@@ -375,7 +385,7 @@ func restoreKanbanBoards(htmlContent string, preprocessors []PreprocessorFunc) s
 			matches := re.FindStringSubmatch(line)
 			if len(matches) > 1 {
 				id := matches[1]
-				if board, exists := kanbanBoards[id]; exists {
+				if board, exists := boards[id]; exists {
 					// Generate a unique board ID
 					boardId := fmt.Sprintf("board-%d", boardIndex)
 					if board.Title != "" {
@@ -394,6 +404,8 @@ func restoreKanbanBoards(htmlContent string, preprocessors []PreprocessorFunc) s
 								Checked:     task.Checked,
 								HTMLText:    processedHTML,
 								IndentLevel: task.IndentLevel,
+								Body:        task.Body,
+								HTMLBody:    renderKanbanCardBody(task.Body, preprocessors, postProcessors),
 							})
 						}
 						processedColumns = append(processedColumns, KanbanColumn{
@@ -436,13 +448,22 @@ func restoreKanbanBoards(htmlContent string, preprocessors []PreprocessorFunc) s
 								indentAttr = ` data-indent-level="` + strconv.Itoa(task.IndentLevel) + `"`
 							}
 
-							finalHTML.WriteString(fmt.Sprintf(`<li class="task-list-item-container" style="list-style-type: none;"%s>
+							// Cards with a body keep its markdown so saving the board doesn't drop it
+							containerClass := "task-list-item-container"
+							bodyHTML := ""
+							if task.HTMLBody != "" {
+								containerClass += " has-body"
+								indentAttr += ` data-body-markdown="` + template.HTMLEscapeString(task.Body) + `"`
+								bodyHTML = `<div class="kanban-card-body">` + task.HTMLBody + `</div>`
+							}
+
+							finalHTML.WriteString(fmt.Sprintf(`<li class="%s" style="list-style-type: none;"%s>
 								<span class="task-list-item">
 									<input type="checkbox" class="task-checkbox" %s disabled>
 									<span class="task-text">%s</span>
 									<span class="save-state"></span>
-								</span>
-							</li>`, indentAttr, checkedAttr, task.HTMLText))
+								</span>%s
+							</li>`, containerClass, indentAttr, checkedAttr, task.HTMLText, bodyHTML))
 						}
 
 						finalHTML.WriteString(`</ul></div></div>`)
@@ -506,6 +527,30 @@ func applyProcessorsToTaskText(taskText string, preprocessors []PreprocessorFunc
 	return result
 }
 
+// renderKanbanCardBody renders the markdown body of a card like a document of its own,
+// through the preprocessors, Goldmark and the post-processors
+func renderKanbanCardBody(body string, preprocessors []PreprocessorFunc, postProcessors []PostProcessorFunc) string {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return ""
+	}
+
+	for _, preprocessor := range preprocessors {
+		if preprocessor != nil {
+			body = preprocessor(body, "")
+		}
+	}
+
+	rendered := renderWithGoldmark(body)
+	for _, postProcessor := range postProcessors {
+		if postProcessor != nil {
+			rendered = postProcessor(rendered)
+		}
+	}
+
+	return rendered
+}
+
 // parseKanbanContentBasic extracts header content and parses kanban boards (basic implementation)
 func parseKanbanContentBasic(content string) (string, []KanbanBoard) {
 	// Split content by lines
@@ -6,12 +6,16 @@ import (
 	"net/url"
 	urlPkg "net/url"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
 	"wiki-go/internal/i18n"
 )
 
+// DefaultLinkCategory is the category of links that come before any ## heading
+const DefaultLinkCategory = "Uncategorized"
+
 // Link represents a single link in a links document
 type Link struct {
 	Title       string    `json:"title"`
@@ -25,6 +29,7 @@ type Link struct {
 type LinksData struct {
 	Title      string             `json:"title"`       // Document title (H1)
 	Categories map[string][]Link  `json:"categories"`  // Links organized by category
+	Order      []string           `json:"order"`       // Categories in the order of their headings
 	TotalLinks int                `json:"total_links"` // Total number of links
 	Stats      LinksStats         `json:"stats"`       // Statistics for the links collection
 }
@@ -37,6 +42,46 @@ type LinksStats struct {
 	LatestAdded     time.Time `json:"latest_added"` // Most recent addition date
 }
 
+// LinkGroup is a category of links with its links
+type LinkGroup struct {
+	Name  string
+	Links []Link
+}
+
+// Groups returns the categories with their links in the order of their headings
+// Categories added without a heading come last, sorted by name.
+func (ld *LinksData) Groups() []LinkGroup {
+	var groups []LinkGroup
+	seen := make(map[string]bool, len(ld.Categories))
+	for _, name := range ld.Order {
+		if links, ok := ld.Categories[name]; ok && !seen[name] {
+			seen[name] = true
+			groups = append(groups, LinkGroup{Name: name, Links: links})
+		}
+	}
+
+	var rest []string
+	for name := range ld.Categories {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	for _, name := range rest {
+		groups = append(groups, LinkGroup{Name: name, Links: ld.Categories[name]})
+	}
+
+	return groups
+}
+
+// addCategory starts a category, remembering the order categories appear in
+func (ld *LinksData) addCategory(name string) {
+	if _, exists := ld.Categories[name]; !exists {
+		ld.Categories[name] = []Link{}
+		ld.Order = append(ld.Order, name)
+	}
+}
+
 // NewLinksData creates a new LinksData instance with initialized maps
 func NewLinksData() *LinksData {
 	return &LinksData{
@@ -51,6 +96,7 @@ func (ld *LinksData) AddLink(link Link) {
 		ld.Categories = make(map[string][]Link)
 	}
 	
+	ld.addCategory(link.Category)
 	ld.Categories[link.Category] = append(ld.Categories[link.Category], link)
 	ld.updateStats()
 }
@@ -189,7 +235,7 @@ func SanitizeCategory(category string) string {
 	
 	// If empty after sanitization, return default
 	if category == "" {
-		return "General"
+		return DefaultLinkCategory
 	}
 	
 	return category
@@ -203,7 +249,7 @@ func ParseLinksContent(content string) (*LinksData, error) {
 	}
 	
 	lines := strings.Split(content, "\n")
-	currentCategory := "General"
+	currentCategory := DefaultLinkCategory
 	
 	// Regular expressions for parsing
 	h1Regex := regexp.MustCompile(`^#\s+(.+)$`)
@@ -231,14 +277,12 @@ func ParseLinksContent(content string) (*LinksData, error) {
 		if h2Match := h2Regex.FindStringSubmatch(line); h2Match != nil {
 			categoryName := strings.TrimSpace(h2Match[1])
 			if categoryName == "" {
-				currentCategory = "General"
+				currentCategory = DefaultLinkCategory
 			} else {
 				currentCategory = SanitizeCategory(categoryName)
 			}
 			// Initialize category if it doesn't exist
-			if _, exists := data.Categories[currentCategory]; !exists {
-				data.Categories[currentCategory] = []Link{}
-			}
+			data.addCategory(currentCategory)
 			continue
 		}
 		
@@ -278,9 +322,7 @@ func ParseLinksContent(content string) (*LinksData, error) {
 			}
 			
 			// Initialize category if it doesn't exist
-			if _, exists := data.Categories[currentCategory]; !exists {
-				data.Categories[currentCategory] = []Link{}
-			}
+			data.addCategory(currentCategory)
 			
 			// Add link to category
 			data.Categories[currentCategory] = append(data.Categories[currentCategory], link)
@@ -367,8 +409,8 @@ func RenderLinks(content string) (string, error) {
                 <div class="language-selector-wrapper">
                     <select id="categoryFilter" class="language-selector">
                         <option value="">All Categories</option>
-                        {{range $category, $links := .Categories}}
-                        <option value="{{$category}}">{{$category}} ({{len $links}})</option>
+                        {{range .Groups}}
+                        <option value="{{.Name}}">{{.Name}} ({{len .Links}})</option>
                         {{end}}
                     </select>
                 </div>
@@ -415,16 +457,17 @@ func RenderLinks(content string) (string, error) {
     <!-- Links sections -->
     <div class="links-content" id="linksContent">
         <!-- Hidden data for all categories (including empty ones) -->
-        <div id="allCategories" style="display: none;" data-categories="{{range $category, $links := .Categories}}{{$category}},{{end}}"></div>
+        <div id="allCategories" style="display: none;" data-categories="{{range .Groups}}{{.Name}},{{end}}"></div>
         
-        {{range $category, $links := .Categories}}
-        {{if gt (len $links) 0}}
-        <div class="links-category" data-category="{{$category}}">
+        <div class="links-groups">
+        {{range .Groups}}
+        {{if gt (len .Links) 0}}
+        <div class="links-category" data-category="{{.Name}}">
             <h2 class="links-category-header">
-                {{$category}} <span class="section-count">({{len $links}})</span>
+                {{.Name}} <span class="section-count">({{len .Links}})</span>
             </h2>
             
-            {{range $links}}
+            {{range .Links}}
             <div class="link-item" data-category="{{.Category}}" data-title="{{.Title}}" data-description="{{.Description}}" data-url="{{.URL}}" data-date="{{.AddedAt.Unix}}">
                 <div class="link-content">
                     <div class="link-title-row">
@@ -446,6 +489,7 @@ func RenderLinks(content string) (string, error) {
         </div>
         {{end}}
         {{end}}
+        </div>
     </div>
 
     <!-- No results message -->
@@ -0,0 +1,482 @@
+package frontmatter
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TOML dates, times and date-times, kept as written since Metadata stores dates as text
+var (
+	tomlDateTimeRegex = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(?:[Tt ]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:[Zz]|[+-]\d{2}:\d{2})?)?$`)
+	tomlTimeRegex     = regexp.MustCompile(`^\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?$`)
+	tomlIntegerRegex  = regexp.MustCompile(`^[+-]?(?:0|[1-9](?:_?\d)*)$`)
+	tomlFloatRegex    = regexp.MustCompile(`^[+-]?(?:0|[1-9](?:_?\d)*)(?:\.\d(?:_?\d)*)?(?:[eE][+-]?\d(?:_?\d)*)?$`)
+)
+
+// tomlParser reads TOML into maps: tables, arrays of tables, dotted and quoted keys,
+// strings, numbers, booleans, dates, arrays and inline tables
+type tomlParser struct {
+	src string
+	pos int
+}
+
+// parseTOML parses a TOML document into a map of its keys
+func parseTOML(src string) (map[string]interface{}, error) {
+	p := &tomlParser{src: src}
+	root := make(map[string]interface{})
+	current := root
+
+	for {
+		p.skipBlank(true)
+		if p.eof() {
+			return root, nil
+		}
+
+		if p.peek() == '[' {
+			table, err := p.parseHeader(root)
+			if err != nil {
+				return nil, err
+			}
+			current = table
+		} else {
+			key, err := p.parseKey()
+			if err != nil {
+				return nil, err
+			}
+			p.skipBlank(false)
+			if !p.consume("=") {
+				return nil, p.errorf("expected = after key %q", strings.Join(key, "."))
+			}
+			p.skipBlank(false)
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.set(current, key, value); err != nil {
+				return nil, err
+			}
+		}
+
+		// Only a comment may follow on the line
+		p.skipBlank(false)
+		if !p.eof() && p.peek() != '\n' && p.peek() != '\r' {
+			return nil, p.errorf("unexpected %q", p.peek())
+		}
+	}
+}
+
+// parseHeader reads a [table] or [[array of tables]] header and returns the table it opens
+func (p *tomlParser) parseHeader(root map[string]interface{}) (map[string]interface{}, error) {
+	array := p.consume("[[")
+	if !array {
+		p.pos++
+	}
+	p.skipBlank(false)
+	key, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank(false)
+	if (array && !p.consume("]]")) || (!array && !p.consume("]")) {
+		return nil, p.errorf("unterminated table header %q", strings.Join(key, "."))
+	}
+
+	parent, err := p.table(root, key[:len(key)-1])
+	if err != nil {
+		return nil, err
+	}
+	name := key[len(key)-1]
+
+	if array {
+		table := make(map[string]interface{})
+		switch existing := parent[name].(type) {
+		case nil:
+			parent[name] = []interface{}{table}
+		case []interface{}:
+			parent[name] = append(existing, table)
+		default:
+			return nil, p.errorf("key %q is not an array of tables", strings.Join(key, "."))
+		}
+		return table, nil
+	}
+
+	return p.table(parent, []string{name})
+}
+
+// table returns the table at a key path below parent, creating missing tables
+// A path through an array of tables continues in its last table.
+func (p *tomlParser) table(parent map[string]interface{}, key []string) (map[string]interface{}, error) {
+	for i, name := range key {
+		switch existing := parent[name].(type) {
+		case nil:
+			table := make(map[string]interface{})
+			parent[name] = table
+			parent = table
+		case map[string]interface{}:
+			parent = existing
+		case []interface{}:
+			var last map[string]interface{}
+			if len(existing) > 0 {
+				last, _ = existing[len(existing)-1].(map[string]interface{})
+			}
+			if last == nil {
+				return nil, p.errorf("key %q is not a table", strings.Join(key[:i+1], "."))
+			}
+			parent = last
+		default:
+			return nil, p.errorf("key %q is not a table", strings.Join(key[:i+1], "."))
+		}
+	}
+	return parent, nil
+}
+
+// set assigns a value to a dotted key below table, refusing to redefine a key
+func (p *tomlParser) set(table map[string]interface{}, key []string, value interface{}) error {
+	parent, err := p.table(table, key[:len(key)-1])
+	if err != nil {
+		return err
+	}
+	name := key[len(key)-1]
+	if _, exists := parent[name]; exists {
+		return p.errorf("key %q is defined twice", strings.Join(key, "."))
+	}
+	parent[name] = value
+	return nil
+}
+
+// parseKey reads a bare, quoted or dotted key
+func (p *tomlParser) parseKey() ([]string, error) {
+	var key []string
+	for {
+		var part string
+		switch {
+		case p.eof():
+			return nil, p.errorf("expected a key")
+		case p.peek() == '"':
+			s, err := p.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		case p.peek() == '\'':
+			s, err := p.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		default:
+			start := p.pos
+			for !p.eof() && isTOMLBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected a key, found %q", p.peek())
+			}
+			part = p.src[start:p.pos]
+		}
+		key = append(key, part)
+
+		p.skipBlank(false)
+		if !p.consume(".") {
+			return key, nil
+		}
+		p.skipBlank(false)
+	}
+}
+
+// parseValue reads a value
+func (p *tomlParser) parseValue() (interface{}, error) {
+	if p.eof() {
+		return nil, p.errorf("expected a value")
+	}
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], `"""`):
+		return p.parseMultilineString(`"""`)
+	case strings.HasPrefix(p.src[p.pos:], `'''`):
+		return p.parseMultilineString(`'''`)
+	case p.peek() == '"':
+		return p.parseBasicString()
+	case p.peek() == '\'':
+		return p.parseLiteralString()
+	case p.peek() == '[':
+		return p.parseArray()
+	case p.peek() == '{':
+		return p.parseInlineTable()
+	}
+	return p.parseScalar()
+}
+
+// parseArray reads an array, which may span lines and end with a comma
+func (p *tomlParser) parseArray() (interface{}, error) {
+	p.pos++
+	values := []interface{}{}
+	for {
+		p.skipBlank(true)
+		if p.consume("]") {
+			return values, nil
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		p.skipBlank(true)
+		if p.consume("]") {
+			return values, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected , or ] in array")
+		}
+	}
+}
+
+// parseInlineTable reads an inline table
+func (p *tomlParser) parseInlineTable() (interface{}, error) {
+	p.pos++
+	table := make(map[string]interface{})
+	p.skipBlank(false)
+	if p.consume("}") {
+		return table, nil
+	}
+	for {
+		p.skipBlank(false)
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		p.skipBlank(false)
+		if !p.consume("=") {
+			return nil, p.errorf("expected = after key %q", strings.Join(key, "."))
+		}
+		p.skipBlank(false)
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.set(table, key, value); err != nil {
+			return nil, err
+		}
+		p.skipBlank(false)
+		if p.consume("}") {
+			return table, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected , or } in inline table")
+		}
+	}
+}
+
+// parseBasicString reads a "double-quoted" string
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.peek()
+		switch c {
+		case '"':
+			p.pos++
+			return sb.String(), nil
+		case '\\':
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// parseLiteralString reads a 'single-quoted' string, which has no escapes
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.pos++
+	end := strings.IndexAny(p.src[p.pos:], "'\n")
+	if end == -1 || p.src[p.pos+end] != '\'' {
+		return "", p.errorf("unterminated string")
+	}
+	s := p.src[p.pos : p.pos+end]
+	p.pos += end + 1
+	return s, nil
+}
+
+// parseMultilineString reads a triple-quoted string, dropping a newline right after the
+// opening quotes; in basic strings a backslash at the end of a line joins the next line
+func (p *tomlParser) parseMultilineString(quotes string) (string, error) {
+	p.pos += len(quotes)
+	_ = p.consume("\r\n") || p.consume("\n")
+
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		if strings.HasPrefix(p.src[p.pos:], quotes) {
+			// Up to two quotes right before the closing ones belong to the string
+			for i := 0; i < 2 && strings.HasPrefix(p.src[p.pos+1:], quotes); i++ {
+				sb.WriteByte(p.peek())
+				p.pos++
+			}
+			p.pos += len(quotes)
+			return sb.String(), nil
+		}
+
+		c := p.peek()
+		if c == '\\' && quotes == `"""` {
+			rest := strings.TrimLeft(p.src[p.pos+1:], " \t")
+			if strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n") {
+				p.pos = len(p.src) - len(strings.TrimLeft(rest, " \t\r\n"))
+				continue
+			}
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+			continue
+		}
+		sb.WriteByte(c)
+		p.pos++
+	}
+}
+
+// parseEscape reads a backslash escape, writing the character it stands for
+func (p *tomlParser) parseEscape(sb *strings.Builder) error {
+	p.pos++
+	if p.eof() {
+		return p.errorf("unterminated string")
+	}
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'b':
+		sb.WriteByte('\b')
+	case 't':
+		sb.WriteByte('\t')
+	case 'n':
+		sb.WriteByte('\n')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'r':
+		sb.WriteByte('\r')
+	case 'e':
+		sb.WriteByte('\x1b')
+	case '"', '\\':
+		sb.WriteByte(c)
+	case 'u', 'U':
+		digits := 4
+		if c == 'U' {
+			digits = 8
+		}
+		if p.pos+digits > len(p.src) {
+			return p.errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+digits], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorf("invalid unicode escape")
+		}
+		sb.WriteRune(rune(code))
+		p.pos += digits
+	default:
+		return p.errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
+
+// parseScalar reads a boolean, number, date or time
+func (p *tomlParser) parseScalar() (interface{}, error) {
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.peek())) {
+		p.pos++
+	}
+	token := p.src[start:p.pos]
+
+	// A date and time may be separated by a space
+	if len(token) == 10 && tomlDateTimeRegex.MatchString(token) && strings.HasPrefix(p.src[p.pos:], " ") {
+		end := p.pos + 1
+		for end < len(p.src) && !strings.ContainsRune(" \t\r\n,]}#", rune(p.src[end])) {
+			end++
+		}
+		if candidate := token + p.src[p.pos:end]; tomlDateTimeRegex.MatchString(candidate) {
+			token = candidate
+			p.pos = end
+		}
+	}
+
+	switch {
+	case token == "":
+		return nil, p.errorf("expected a value, found %q", p.peek())
+	case token == "true":
+		return true, nil
+	case token == "false":
+		return false, nil
+	case tomlDateTimeRegex.MatchString(token), tomlTimeRegex.MatchString(token):
+		return token, nil
+	case tomlIntegerRegex.MatchString(token):
+		return strconv.ParseInt(strings.ReplaceAll(token, "_", ""), 10, 64)
+	case strings.HasPrefix(token, "0x"), strings.HasPrefix(token, "0o"), strings.HasPrefix(token, "0b"):
+		if n, err := strconv.ParseInt(strings.ReplaceAll(token, "_", ""), 0, 64); err == nil {
+			return n, nil
+		}
+	case strings.TrimLeft(token, "+-") == "inf":
+		if strings.HasPrefix(token, "-") {
+			return math.Inf(-1), nil
+		}
+		return math.Inf(1), nil
+	case strings.TrimLeft(token, "+-") == "nan":
+		return math.NaN(), nil
+	case tomlFloatRegex.MatchString(token):
+		return strconv.ParseFloat(strings.ReplaceAll(token, "_", ""), 64)
+	}
+	return nil, p.errorf("invalid value %q", token)
+}
+
+// skipBlank skips spaces, tabs and comments, and newlines when newlines is set
+func (p *tomlParser) skipBlank(newlines bool) {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t':
+			p.pos++
+		case newlines && (c == '\n' || c == '\r'):
+			p.pos++
+		case c == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) peek() byte {
+	return p.src[p.pos]
+}
+
+// consume skips s when the input continues with it
+func (p *tomlParser) consume(s string) bool {
+	if strings.HasPrefix(p.src[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+// errorf reports an error at the current line, worded like the YAML decoder's errors
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(p.src[:p.pos], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// isTOMLBareKeyChar reports whether c may appear in a bare key
+func isTOMLBareKeyChar(c byte) bool {
+	return c == '_' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,339 @@
+package goldext
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// AbbreviationListShortcode is replaced by an appendix of the abbreviations used in the document
+const AbbreviationListShortcode = "{{abbr-list}}"
+
+// AbbreviationElements wraps the defined terms in <abbr title="expansion"> wherever they
+// occur in the text. Code, link targets, HTML tags, URLs and headings are left alone.
+// Enabled by default.
+var AbbreviationElements = true
+
+// AbbreviationIgnoreCase matches defined terms regardless of case. Disabled by default.
+var AbbreviationIgnoreCase = false
+
+// AbbreviationFirstOnly wraps only the first occurrence of each term per document, so
+// repeated terms don't all show a tooltip. A document's {{abbr:all}} or {{abbr:first}}
+// marker, which the abbreviations frontmatter key sets, overrides it. Disabled by default.
+var AbbreviationFirstOnly = false
+
+// abbrModeRegex matches the {{abbr:all}} and {{abbr:first}} markers
+var abbrModeRegex = regexp.MustCompile(`^\{\{\s*abbr:\s*(all|first)\s*\}\}$`)
+
+var abbrDefinitionRegex = regexp.MustCompile(`^\s*\*\[([^\]]+)\]:\s*(.*?)\s*$`)
+
+// Spans abbreviations are never wrapped in: wikilinks, images, link targets, HTML tags,
+// attribute lists and bare URLs
+var abbrProtectedRegex = regexp.MustCompile(`\[\[[^\]]*\]\]|!\[[^\]]*\]\([^)]*\)|\]\([^)]*\)|<[^>]+>|\{[^{}]*\}|[A-Za-z][A-Za-z0-9+.-]*://\S*`)
+
+// setextUnderlineRegex matches the line underlining a setext heading
+var setextUnderlineRegex = regexp.MustCompile(`^(?:=+|-+)$`)
+
+// Abbreviation is a term defined with *[TERM]: expansion
+type Abbreviation struct {
+	Term      string
+	Expansion string
+}
+
+// ExtractAbbreviations collects the *[TERM]: expansion definitions of a document
+// It returns the definitions in document order and the markdown without them.
+// A term defined twice keeps its last expansion.
+func ExtractAbbreviations(markdown string) ([]Abbreviation, string) {
+	if !strings.Contains(markdown, "*[") {
+		return nil, markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+	var result []string
+	var abbreviations []Abbreviation
+	index := make(map[string]int)
+	inCodeBlock := false
+
+	for _, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			result = append(result, line)
+			continue
+		}
+
+		if !inCodeBlock {
+			if m := abbrDefinitionRegex.FindStringSubmatch(line); m != nil && strings.TrimSpace(m[1]) != "" {
+				term := strings.TrimSpace(m[1])
+				if i, ok := index[term]; ok {
+					abbreviations[i].Expansion = m[2]
+				} else {
+					index[term] = len(abbreviations)
+					abbreviations = append(abbreviations, Abbreviation{Term: term, Expansion: m[2]})
+				}
+				continue
+			}
+		}
+
+		result = append(result, line)
+	}
+
+	return abbreviations, strings.Join(result, "\n")
+}
+
+// abbreviationRegex matches a term as a whole word, case-sensitively unless
+// AbbreviationIgnoreCase is set
+func abbreviationRegex(term string) *regexp.Regexp {
+	flags := ""
+	if AbbreviationIgnoreCase {
+		flags = "(?i)"
+	}
+	return regexp.MustCompile(flags + `(^|[^\p{L}\p{N}_])(` + regexp.QuoteMeta(term) + `)($|[^\p{L}\p{N}_])`)
+}
+
+// usedAbbreviations returns the abbreviations that occur in the markdown outside code
+func usedAbbreviations(abbreviations []Abbreviation, markdown string) []Abbreviation {
+	var text strings.Builder
+	inCodeBlock := false
+	for _, line := range strings.Split(markdown, "\n") {
+		trimmedLine := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock || trimmedLine == AbbreviationListShortcode {
+			continue
+		}
+
+		// Only the segments outside inline code count
+		segments := strings.Split(line, "`")
+		for i := 0; i < len(segments); i += 2 {
+			text.WriteString(segments[i])
+			text.WriteString("\n")
+		}
+	}
+
+	var used []Abbreviation
+	for _, abbreviation := range abbreviations {
+		if abbreviationRegex(abbreviation.Term).MatchString(text.String()) {
+			used = append(used, abbreviation)
+		}
+	}
+	return used
+}
+
+// AbbreviationPreprocessor strips *[TERM]: expansion definitions, wraps the terms in
+// <abbr> elements and replaces {{abbr-list}} with a definition list of the abbreviations
+// actually used, sorted alphabetically
+func AbbreviationPreprocessor(markdown string, _ string) string {
+	firstOnly, markdown := abbreviationMode(markdown)
+	abbreviations, body := ExtractAbbreviations(markdown)
+	if !strings.Contains(body, AbbreviationListShortcode) {
+		return wrapAbbreviations(abbreviations, body, firstOnly)
+	}
+
+	used := usedAbbreviations(abbreviations, body)
+	body = wrapAbbreviations(abbreviations, body, firstOnly)
+	sort.SliceStable(used, func(i, j int) bool {
+		a, b := strings.ToLower(used[i].Term), strings.ToLower(used[j].Term)
+		if a != b {
+			return a < b
+		}
+		return used[i].Term < used[j].Term
+	})
+
+	lines := strings.Split(body, "\n")
+	var result []string
+	inCodeBlock := false
+
+	for _, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+		}
+
+		if !inCodeBlock && trimmedLine == AbbreviationListShortcode {
+			result = append(result, renderAbbreviationList(used)...)
+			continue
+		}
+		result = append(result, line)
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// FrontmatterAbbreviations applies the abbreviations setting of a document's frontmatter,
+// "all" or "first", by putting its marker at the top of the markdown
+func FrontmatterAbbreviations(markdown string, mode string) string {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode != "all" && mode != "first" {
+		return markdown
+	}
+	return "{{abbr:" + mode + "}}\n\n" + markdown
+}
+
+// abbreviationMode reports whether only first occurrences are wrapped and strips the
+// {{abbr:all}} and {{abbr:first}} markers outside code; the last marker wins
+func abbreviationMode(markdown string) (bool, string) {
+	firstOnly := AbbreviationFirstOnly
+	if !strings.Contains(markdown, "{{") {
+		return firstOnly, markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+	var result []string
+	inCodeBlock := false
+
+	for _, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+		}
+
+		if !inCodeBlock {
+			if m := abbrModeRegex.FindStringSubmatch(trimmedLine); m != nil {
+				firstOnly = m[1] == "first"
+				continue
+			}
+		}
+		result = append(result, line)
+	}
+
+	return firstOnly, strings.Join(result, "\n")
+}
+
+// wrapAbbreviations wraps the terms of the abbreviations in <abbr> elements outside code
+// Headings are skipped so their anchors and table of contents entries stay plain text.
+// With firstOnly, a term is wrapped only the first time it occurs in this markdown.
+func wrapAbbreviations(abbreviations []Abbreviation, markdown string, firstOnly bool) string {
+	if !AbbreviationElements || len(abbreviations) == 0 {
+		return markdown
+	}
+
+	// Longer terms first, so HTML5 wins over HTML; a term without expansion isn't wrapped
+	expansions := make(map[string]string, len(abbreviations))
+	var terms []string
+	for _, abbreviation := range abbreviations {
+		if abbreviation.Expansion == "" {
+			continue
+		}
+		key := abbreviation.Term
+		if AbbreviationIgnoreCase {
+			key = strings.ToLower(key)
+		}
+		if _, ok := expansions[key]; !ok {
+			terms = append(terms, regexp.QuoteMeta(abbreviation.Term))
+		}
+		expansions[key] = abbreviation.Expansion
+	}
+	if len(terms) == 0 {
+		return markdown
+	}
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+	flags := ""
+	if AbbreviationIgnoreCase {
+		flags = "(?i)"
+	}
+	termsRegex := regexp.MustCompile(flags + `(?:` + strings.Join(terms, "|") + `)`)
+
+	// The terms wrapped so far, kept per call so concurrent renderings don't share them
+	var seen map[string]bool
+	if firstOnly {
+		seen = make(map[string]bool, len(expansions))
+	}
+
+	lines := strings.Split(markdown, "\n")
+	inCodeBlock := false
+
+	for i, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+
+		// If we're in a code block or a heading, don't process
+		if inCodeBlock || strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") || headingLineRegex.MatchString(trimmedLine) {
+			continue
+		}
+		if trimmedLine != "" && i+1 < len(lines) && setextUnderlineRegex.MatchString(strings.TrimSpace(lines[i+1])) {
+			continue
+		}
+
+		// Even segments are outside inline code
+		segments := strings.Split(line, "`")
+		for j := 0; j < len(segments); j += 2 {
+			segments[j] = replaceOutside(segments[j], abbrProtectedRegex, func(text string) string {
+				return wrapAbbreviationTerms(text, termsRegex, expansions, seen)
+			})
+		}
+		lines[i] = strings.Join(segments, "`")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// wrapAbbreviationTerms wraps the terms of unprotected text that stand as whole words
+// Terms in seen are left alone and wrapped terms are added to it, unless seen is nil.
+func wrapAbbreviationTerms(text string, termsRegex *regexp.Regexp, expansions map[string]string, seen map[string]bool) string {
+	var sb strings.Builder
+	last := 0
+	for _, match := range termsRegex.FindAllStringIndex(text, -1) {
+		start, end := match[0], match[1]
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (start > 0 && isAbbreviationWordRune(before)) || (end < len(text) && isAbbreviationWordRune(after)) {
+			continue
+		}
+
+		term := text[start:end]
+		key := term
+		if AbbreviationIgnoreCase {
+			key = strings.ToLower(key)
+		}
+		if seen != nil {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(`<abbr title="` + html.EscapeString(expansions[key]) + `">` + term + `</abbr>`)
+		last = end
+	}
+	if last == 0 {
+		return text
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// isAbbreviationWordRune reports whether r continues a word, so a term next to it isn't whole
+func isAbbreviationWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// renderAbbreviationList renders the appendix as a markdown definition list
+func renderAbbreviationList(abbreviations []Abbreviation) []string {
+	if len(abbreviations) == 0 {
+		return nil
+	}
+
+	result := []string{`<div class="abbr-list">`, ""}
+	for _, abbreviation := range abbreviations {
+		result = append(result, abbreviation.Term, ": "+abbreviation.Expansion, "")
+	}
+	result = append(result, `</div>`)
+	return result
+}
@@ -0,0 +1,118 @@
+package goldext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractAbbreviations(t *testing.T) {
+	input := "*[HTML]: Hyper Text Markup Language\nText\n*[CSS]: Cascading Style Sheets\n*[HTML]: HyperText Markup Language\n```\n*[IN]: Code\n```"
+	abbreviations, body := ExtractAbbreviations(input)
+
+	expected := []Abbreviation{
+		{Term: "HTML", Expansion: "HyperText Markup Language"},
+		{Term: "CSS", Expansion: "Cascading Style Sheets"},
+	}
+	if !reflect.DeepEqual(abbreviations, expected) {
+		t.Errorf("Expected %v, got %v", expected, abbreviations)
+	}
+	if expectedBody := "Text\n```\n*[IN]: Code\n```"; body != expectedBody {
+		t.Errorf("Expected body %q, got %q", expectedBody, body)
+	}
+}
+
+func TestAbbreviationList(t *testing.T) {
+	input := "*[W3C]: World Wide Web Consortium\n*[HTML]: Hyper Text Markup Language\n*[css]: Lowercase sheets\n*[API]: Application Programming Interface\n*[XML]: Extensible Markup Language\n*[JSON]: JavaScript Object Notation\n\n" +
+		"The HTML spec by the W3C, styled with CSS. An APIs page.\nSee `XML`.\n\n```\nJSON\n```\n\n{{abbr-list}}"
+
+	result := AbbreviationPreprocessor(input, "")
+
+	// css is case-sensitive and unused, APIs is not a whole word, XML and JSON only appear in code
+	expected := "\nThe <abbr title=\"Hyper Text Markup Language\">HTML</abbr> spec by the <abbr title=\"World Wide Web Consortium\">W3C</abbr>, styled with CSS. An APIs page.\nSee `XML`.\n\n```\nJSON\n```\n\n" +
+		"<div class=\"abbr-list\">\n\nHTML\n: Hyper Text Markup Language\n\nW3C\n: World Wide Web Consortium\n\n</div>"
+	if result != expected {
+		t.Errorf("Expected: %q, got: %q", expected, result)
+	}
+}
+
+func TestAbbreviationListWithoutMarker(t *testing.T) {
+	result := AbbreviationPreprocessor("*[HTML]: Hyper Text Markup Language\nHTML text", "")
+	if result != `<abbr title="Hyper Text Markup Language">HTML</abbr> text` {
+		t.Errorf("Expected definitions to be stripped, got: %q", result)
+	}
+}
+
+func TestAbbreviationElements(t *testing.T) {
+	definitions := "*[HTML]: Hyper Text Markup Language\n*[HTML5]: HTML \"version\" 5\n*[API]: Application Programming Interface\n*[TODO]:\n"
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Whole words", "HTML and HTML5, not HTMLX or XHTML.", `<abbr title="Hyper Text Markup Language">HTML</abbr> and <abbr title="HTML &#34;version&#34; 5">HTML5</abbr>, not HTMLX or XHTML.`},
+		{"Case-sensitive", "html and Api", "html and Api"},
+		{"Inline code", "An `API` call to the API", "An `API` call to the <abbr title=\"Application Programming Interface\">API</abbr>"},
+		{"Fenced code", "```\nAPI\n```\n~~~\nAPI\n~~~", "```\nAPI\n```\n~~~\nAPI\n~~~"},
+		{"Indented code", "    API call", "    API call"},
+		{"Links", "[API docs](https://example.com/API) and ![API](api.png) and [[API]]", `[<abbr title="Application Programming Interface">API</abbr> docs](https://example.com/API) and ![API](api.png) and [[API]]`},
+		{"HTML and URLs", `<span title="API">x</span> https://example.com/API`, `<span title="API">x</span> https://example.com/API`},
+		{"Headings", "## API reference\n\nAPI guide\n===", "## API reference\n\nAPI guide\n==="},
+		{"No expansion", "TODO", "TODO"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := AbbreviationPreprocessor(definitions+tt.input, ""); result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+
+	AbbreviationIgnoreCase = true
+	result := AbbreviationPreprocessor(definitions+"html and Api", "")
+	AbbreviationIgnoreCase = false
+	if expected := `<abbr title="Hyper Text Markup Language">html</abbr> and <abbr title="Application Programming Interface">Api</abbr>`; result != expected {
+		t.Errorf("Expected: %q, got: %q", expected, result)
+	}
+
+	AbbreviationElements = false
+	defer func() { AbbreviationElements = true }()
+	if result := AbbreviationPreprocessor(definitions+"HTML", ""); result != "HTML" {
+		t.Errorf("Expected no <abbr> elements when disabled, got: %q", result)
+	}
+}
+
+func TestAbbreviationFirstOnly(t *testing.T) {
+	definitions := "*[API]: Application Programming Interface\n*[HTML]: Hyper Text Markup Language\n"
+	input := "## API\n\nAn `API` and the API.\n\nHTML, API and HTML again."
+	first := "## API\n\nAn `API` and the <abbr title=\"Application Programming Interface\">API</abbr>.\n\n" +
+		"<abbr title=\"Hyper Text Markup Language\">HTML</abbr>, API and HTML again."
+	all := "## API\n\nAn `API` and the <abbr title=\"Application Programming Interface\">API</abbr>.\n\n" +
+		"<abbr title=\"Hyper Text Markup Language\">HTML</abbr>, <abbr title=\"Application Programming Interface\">API</abbr> and <abbr title=\"Hyper Text Markup Language\">HTML</abbr> again."
+
+	AbbreviationFirstOnly = true
+	defer func() { AbbreviationFirstOnly = false }()
+	if result := AbbreviationPreprocessor(definitions+input, ""); result != first {
+		t.Errorf("Expected only first occurrences wrapped: %q, got: %q", first, result)
+	}
+	if result := AbbreviationPreprocessor(FrontmatterAbbreviations(definitions+input, "all"), ""); result != "\n"+all {
+		t.Errorf("Expected the all marker to wrap every occurrence: %q, got: %q", all, result)
+	}
+
+	// Every rendering tracks its own terms
+	if result := AbbreviationPreprocessor(definitions+input, ""); result != first {
+		t.Errorf("Expected a new rendering to wrap first occurrences again, got: %q", result)
+	}
+
+	AbbreviationFirstOnly = false
+	if result := AbbreviationPreprocessor(FrontmatterAbbreviations(definitions+input, "First"), ""); result != "\n"+first {
+		t.Errorf("Expected the first marker to wrap first occurrences: %q, got: %q", first, result)
+	}
+	if result := AbbreviationPreprocessor(definitions+"```\n{{abbr:first}}\n```\nAPI API", ""); result != "```\n{{abbr:first}}\n```\n<abbr title=\"Application Programming Interface\">API</abbr> <abbr title=\"Application Programming Interface\">API</abbr>" {
+		t.Errorf("Expected markers in code to be left alone, got: %q", result)
+	}
+	if result := FrontmatterAbbreviations("API", "some"); result != "API" {
+		t.Errorf("Expected unknown settings to be ignored, got: %q", result)
+	}
+}
@@ -0,0 +1,71 @@
+package goldext
+
+import (
+	"html"
+	"strings"
+)
+
+// BackToTopLinks inserts "back to top" links before section headings on long pages.
+// Disabled by default.
+var BackToTopLinks = false
+
+// BackToTopLevel is the heading level that starts a section, like FootnoteSectionLevel
+var BackToTopLevel = 2
+
+// BackToTopEvery inserts a link before every Nth section heading after the first
+var BackToTopEvery = 1
+
+// BackToTopLabel is the text of the inserted links
+var BackToTopLabel = "Back to top"
+
+// BackToTopAnchor is the ID of the page-top anchor the links point to
+const BackToTopAnchor = "page-top"
+
+// BackToTopPreprocessor inserts a link to the top of the page before section headings.
+// The first section heading never gets one, so documents with a single section get none.
+func BackToTopPreprocessor(markdown string, _ string) string {
+	if !BackToTopLinks || !strings.Contains(markdown, "#") {
+		return markdown
+	}
+
+	every := BackToTopEvery
+	if every < 1 {
+		every = 1
+	}
+
+	lines := strings.Split(markdown, "\n")
+	var result []string
+	inCodeBlock := false
+	sections := 0
+	inserted := false
+
+	for _, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			result = append(result, line)
+			continue
+		}
+
+		if !inCodeBlock {
+			if level := sectionHeadingLevel(line); level > 0 && level <= BackToTopLevel {
+				if sections > 0 && sections%every == 0 {
+					result = append(result, "", `<p class="back-to-top"><a href="#`+BackToTopAnchor+`">`+html.EscapeString(BackToTopLabel)+`</a></p>`, "")
+					inserted = true
+				}
+				sections++
+			}
+		}
+
+		result = append(result, line)
+	}
+
+	if !inserted {
+		return markdown
+	}
+
+	// The anchor goes first so the links have a target
+	return `<div id="` + BackToTopAnchor + `"></div>` + "\n\n" + strings.Join(result, "\n")
+}
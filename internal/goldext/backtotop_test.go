@@ -0,0 +1,72 @@
+package goldext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBackToTopPreprocessor(t *testing.T) {
+	BackToTopLinks = true
+	defer func() {
+		BackToTopLinks = false
+		BackToTopEvery = 1
+	}()
+
+	link := `<p class="back-to-top"><a href="#page-top">Back to top</a></p>`
+	input := "# Title\n\nIntro\n\n## One\n\n### Detail\n\n## Two\n\n```\n## Not a heading\n```\n\n## Three\n\n## Four"
+
+	tests := []struct {
+		name  string
+		every int
+		count int
+		after []string // Headings preceded by a link; # Title is the first section
+	}{
+		{name: "Every section", every: 1, count: 4, after: []string{"## One", "## Two", "## Three", "## Four"}},
+		{name: "Every second section", every: 2, count: 2, after: []string{"## Two", "## Four"}},
+		{name: "Every third section", every: 3, count: 1, after: []string{"## Three"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			BackToTopEvery = tt.every
+			result := BackToTopPreprocessor(input, "")
+
+			if !strings.HasPrefix(result, "<div id=\"page-top\"></div>\n\n# Title") {
+				t.Errorf("Expected the page-top anchor first, got: %q", result)
+			}
+			if got := strings.Count(result, link); got != tt.count {
+				t.Errorf("Expected %d links, got %d in: %q", tt.count, got, result)
+			}
+			for _, heading := range tt.after {
+				if !strings.Contains(result, link+"\n\n"+heading+"\n") && !strings.HasSuffix(result, link+"\n\n"+heading) {
+					t.Errorf("Expected a link before %q, got: %q", heading, result)
+				}
+			}
+			if strings.Contains(result, link+"\n\n### Detail") || strings.Contains(result, link+"\n\n# Title") {
+				t.Errorf("Expected no link before subsections or the first heading, got: %q", result)
+			}
+		})
+	}
+}
+
+func TestBackToTopShortDocuments(t *testing.T) {
+	BackToTopLinks = true
+	defer func() { BackToTopLinks = false }()
+
+	for _, input := range []string{
+		"Just a paragraph",
+		"# Only heading\n\nText\n\n### Subsection\n\nMore",
+		"## Section\n\n```\n## In code\n```",
+	} {
+		if result := BackToTopPreprocessor(input, ""); result != input {
+			t.Errorf("Expected short document to be unchanged, got: %q", result)
+		}
+	}
+}
+
+func TestBackToTopDisabledByDefault(t *testing.T) {
+	input := "## One\n\n## Two"
+	if result := BackToTopPreprocessor(input, ""); result != input {
+		t.Errorf("Expected unchanged output, got: %q", result)
+	}
+}
@@ -0,0 +1,50 @@
+package goldext
+
+import "strings"
+
+// BasePath is the path the wiki is served under, e.g. /wiki behind a reverse proxy
+// Preprocessors put it in front of the root-relative URLs they emit as raw HTML;
+// markdown links and images are prefixed by the renderer. Set it with utils.SetBasePath.
+// Empty, for a wiki served at the root, by default.
+var BasePath = ""
+
+// CleanBasePath normalizes a base path to a leading slash and no trailing slash
+// The root, "/", becomes "".
+func CleanBasePath(basePath string) string {
+	basePath = strings.Trim(strings.TrimSpace(basePath), "/")
+	if basePath == "" {
+		return ""
+	}
+	return "/" + basePath
+}
+
+// JoinBasePath puts basePath in front of a root-relative URL
+// External and relative URLs, fragments and URLs already below basePath are returned unchanged.
+func JoinBasePath(basePath, u string) string {
+	if basePath == "" || !strings.HasPrefix(u, "/") || strings.HasPrefix(u, "//") || hasBasePath(basePath, u) {
+		return u
+	}
+	return basePath + u
+}
+
+// TrimBasePath removes basePath from the front of a URL, returning a root-relative URL
+// URLs not below basePath are returned unchanged.
+func TrimBasePath(basePath, u string) string {
+	if basePath == "" || !hasBasePath(basePath, u) {
+		return u
+	}
+	trimmed := u[len(basePath):]
+	if !strings.HasPrefix(trimmed, "/") {
+		trimmed = "/" + trimmed
+	}
+	return trimmed
+}
+
+// hasBasePath reports whether a URL is basePath itself or below it
+func hasBasePath(basePath, u string) bool {
+	if !strings.HasPrefix(u, basePath) {
+		return false
+	}
+	rest := u[len(basePath):]
+	return rest == "" || strings.ContainsRune("/?#", rune(rest[0]))
+}
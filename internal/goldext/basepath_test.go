@@ -0,0 +1,70 @@
+package goldext
+
+import "testing"
+
+func TestJoinBasePath(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"/api/files/docs/a.png", "/wiki/api/files/docs/a.png"},
+		{"/", "/wiki/"},
+		{"/wiki", "/wiki"},
+		{"/wiki/guides", "/wiki/guides"},
+		{"/wiki?q=1", "/wiki?q=1"},
+		{"/wikipedia", "/wiki/wikipedia"},
+		{"https://example.com/x", "https://example.com/x"},
+		{"//cdn.example.com/x", "//cdn.example.com/x"},
+		{"#top", "#top"},
+		{"docs/a.png", "docs/a.png"},
+	}
+
+	for _, tt := range tests {
+		if result := JoinBasePath("/wiki", tt.url); result != tt.expected {
+			t.Errorf("JoinBasePath(%q): expected %q, got %q", tt.url, tt.expected, result)
+		}
+	}
+	if result := JoinBasePath("", "/guides"); result != "/guides" {
+		t.Errorf("Expected no prefix without a base path, got %q", result)
+	}
+}
+
+func TestTrimBasePath(t *testing.T) {
+	for url, expected := range map[string]string{
+		"/wiki/guides": "/guides",
+		"/wiki":        "/",
+		"/wiki#top":    "/#top",
+		"/wikipedia":   "/wikipedia",
+		"/guides":      "/guides",
+	} {
+		if result := TrimBasePath("/wiki", url); result != expected {
+			t.Errorf("TrimBasePath(%q): expected %q, got %q", url, expected, result)
+		}
+	}
+
+	for input, expected := range map[string]string{"wiki/": "/wiki", " /a/b/ ": "/a/b", "/": "", "": ""} {
+		if result := CleanBasePath(input); result != expected {
+			t.Errorf("CleanBasePath(%q): expected %q, got %q", input, expected, result)
+		}
+	}
+}
+
+func TestBasePathPreprocessors(t *testing.T) {
+	BasePath, HashtagLinks = "/wiki", true
+	defer func() { BasePath, HashtagLinks = "", false }()
+
+	if result := HashtagPreprocessor("Tagged #release", ""); result != `Tagged <a href="/wiki/tags/release" class="hashtag">#release</a>` {
+		t.Errorf("Expected the hashtag link below the base path, got %q", result)
+	}
+	if result := TransformMP4Path("demo.mp4", "docs"); result != "/wiki/api/files/docs/demo.mp4" {
+		t.Errorf("Expected the video below the base path, got %q", result)
+	}
+	if result := TransformMP4Path("https://example.com/demo.mp4", "docs"); result != "https://example.com/demo.mp4" {
+		t.Errorf("Expected external videos unchanged, got %q", result)
+	}
+
+	// Markdown links are prefixed by the renderer, not by the link preprocessor
+	if result := LinkPreprocessor("![a](a.png)", "docs"); result != "![a](/api/files/docs/a.png)" {
+		t.Errorf("Expected a root-relative destination, got %q", result)
+	}
+}
@@ -0,0 +1,100 @@
+package goldext
+
+import (
+	"regexp"
+	"strings"
+)
+
+// QuoteAttributionPrefixes are the prefixes that mark the last line of a blockquote as its attribution
+var QuoteAttributionPrefixes = []string{"—", "--"}
+
+// BlockquoteAttributionPreprocessor turns a trailing "— Author" line inside a blockquote
+// into a <footer><cite> element within the blockquote
+//
+//	> Simplicity is prerequisite for reliability.
+//	> — Edsger W. Dijkstra
+func BlockquoteAttributionPreprocessor(markdown string, _ string) string {
+	lines := strings.Split(markdown, "\n")
+	var result []string
+
+	// Only top-level quote lines; nested quotes (> >) are left alone
+	quoteRegex := regexp.MustCompile(`^( {0,3}>) ?(.*)$`)
+
+	inCodeBlock := false
+	var quote []string // Lines of the blockquote being collected
+
+	flushQuote := func() {
+		result = append(result, renderQuoteAttribution(quote, quoteRegex)...)
+		quote = nil
+	}
+
+	for _, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			flushQuote()
+			inCodeBlock = !inCodeBlock
+			result = append(result, line)
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock {
+			result = append(result, line)
+			continue
+		}
+
+		if quoteRegex.MatchString(line) {
+			quote = append(quote, line)
+			continue
+		}
+
+		flushQuote()
+		result = append(result, line)
+	}
+	flushQuote()
+
+	return strings.Join(result, "\n")
+}
+
+// renderQuoteAttribution rewrites the last line of a blockquote if it is an attribution
+func renderQuoteAttribution(quote []string, quoteRegex *regexp.Regexp) []string {
+	// An attribution needs quoted text before it
+	if len(quote) < 2 {
+		return quote
+	}
+
+	last := quoteRegex.FindStringSubmatch(quote[len(quote)-1])
+	marker, content := last[1], strings.TrimSpace(last[2])
+
+	// Nested quotes are not attributions
+	if strings.HasPrefix(content, ">") {
+		return quote
+	}
+
+	for _, prefix := range QuoteAttributionPrefixes {
+		if !strings.HasPrefix(content, prefix) {
+			continue
+		}
+
+		author := strings.TrimSpace(strings.TrimPrefix(content, prefix))
+		if author == "" {
+			return quote
+		}
+
+		// Blank quote lines keep the footer out of the quoted paragraph and
+		// let the author text itself be rendered as markdown
+		result := append([]string{}, quote[:len(quote)-1]...)
+		return append(result,
+			marker,
+			marker+` <footer class="blockquote-attribution">`,
+			marker,
+			marker+` — <cite>`+author+`</cite>`,
+			marker,
+			marker+` </footer>`,
+		)
+	}
+
+	return quote
+}
@@ -0,0 +1,204 @@
+package goldext
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// CalloutTypes are the container names rendered as callouts, e.g. ::: warning
+var CalloutTypes = []string{"note", "tip", "info", "important", "warning", "caution", "danger"}
+
+// CalloutMaxDepth limits how deeply callouts can be nested inside each other.
+// Callouts nested deeper are left as plain text.
+var CalloutMaxDepth = 3
+
+var calloutOpenRegex = regexp.MustCompile(`^:::\s*([A-Za-z][\w-]*)(?:\s+(.*?))?\s*$`)
+
+// alertOpenRegex matches the first line of a GitHub-style alert, e.g. > [!WARNING] Optional title
+var alertOpenRegex = regexp.MustCompile(`^ {0,3}> ?\s*\[!([A-Za-z][\w-]*)\]\s*(.*?)\s*$`)
+
+// alertLineRegex matches the lines of a top-level blockquote
+var alertLineRegex = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+
+// CalloutPreprocessor adds support for callout containers with markdown bodies:
+//
+//	::: note Before you start
+//	Read the *setup* guide.
+//
+//	::: warning
+//	Back up your data first.
+//	:::
+//	:::
+//
+// Every ::: line that names a container opens one and every bare ::: closes the
+// innermost open container, so callouts nest inside each other and inside other
+// ::: containers such as tabs. A callout whose closing ::: is missing is left as text.
+func CalloutPreprocessor(markdown string, _ string) string {
+	if !strings.Contains(markdown, ":::") {
+		return markdown
+	}
+
+	return strings.Join(processCalloutLines(strings.Split(markdown, "\n"), 1), "\n")
+}
+
+// processCalloutLines replaces every balanced callout in lines with its HTML
+// depth is the nesting level a callout opened in these lines would have
+func processCalloutLines(lines []string, depth int) []string {
+	var result []string
+	inCodeBlock := false
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			result = append(result, line)
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock {
+			result = append(result, line)
+			continue
+		}
+
+		if m := calloutOpenRegex.FindStringSubmatch(trimmedLine); m != nil && isCalloutType(m[1]) && depth <= CalloutMaxDepth {
+			if end, ok := findContainerEnd(lines, i); ok {
+				result = append(result, renderCallout(strings.ToLower(m[1]), m[2], lines[i+1:end], depth)...)
+				i = end
+				continue
+			}
+			// Unbalanced callout: leave the markers as plain text
+		}
+
+		result = append(result, line)
+	}
+
+	return result
+}
+
+// findContainerEnd returns the index of the ::: line closing the container opened at lines[start]
+func findContainerEnd(lines []string, start int) (int, bool) {
+	depth := 1
+	inCodeBlock := false
+
+	for i := start + 1; i < len(lines); i++ {
+		trimmedLine := strings.TrimSpace(lines[i])
+
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock {
+			continue
+		}
+
+		if trimmedLine == ":::" {
+			depth--
+			if depth == 0 {
+				return i, true
+			}
+		} else if containerOpenRegex.MatchString(trimmedLine) && !strings.HasSuffix(trimmedLine[3:], ":::") {
+			depth++
+		}
+	}
+
+	return start, false
+}
+
+// AlertPreprocessor renders GitHub-style alerts as callouts:
+//
+//	> [!WARNING]
+//	> Back up your data *first*.
+//
+// The quoted lines become the markdown body of the callout. Types other than CalloutTypes
+// render as a plain callout titled with the type name; text after the marker replaces the title.
+func AlertPreprocessor(markdown string, _ string) string {
+	if !strings.Contains(markdown, "[!") {
+		return markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+	var result []string
+	inCodeBlock := false
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			result = append(result, line)
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock {
+			result = append(result, line)
+			continue
+		}
+
+		m := alertOpenRegex.FindStringSubmatch(line)
+		if m == nil || (i > 0 && alertLineRegex.MatchString(lines[i-1])) {
+			result = append(result, line)
+			continue
+		}
+
+		// Collect the rest of the blockquote without its > markers
+		var body []string
+		for i+1 < len(lines) {
+			quoted := alertLineRegex.FindStringSubmatch(lines[i+1])
+			if quoted == nil {
+				break
+			}
+			body = append(body, quoted[1])
+			i++
+		}
+
+		result = append(result, "")
+		result = append(result, renderCallout(strings.ToLower(m[1]), m[2], body, 1)...)
+		result = append(result, "")
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// isCalloutType reports whether name is one of the configured callout types
+func isCalloutType(name string) bool {
+	for _, calloutType := range CalloutTypes {
+		if strings.EqualFold(calloutType, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderCallout renders a callout with a title line and a markdown body
+func renderCallout(calloutType, title string, body []string, depth int) []string {
+	if title == "" {
+		title = strings.ToUpper(calloutType[:1]) + calloutType[1:]
+	}
+
+	// Unknown alert types keep the generic styling
+	class := "callout callout-" + calloutType
+	if !isCalloutType(calloutType) {
+		class = "callout"
+	}
+
+	var result []string
+	result = append(result, `<div class="`+class+`"`+BlockAttributes("callout", "callout-type", calloutType)+`>`)
+	result = append(result, `<p class="callout-title">`+html.EscapeString(title)+`</p>`)
+	result = append(result, `<div class="callout-content">`)
+
+	// Blank lines around the body let Goldmark render it as markdown
+	result = append(result, "")
+	result = append(result, processCalloutLines(body, depth+1)...)
+	result = append(result, "")
+	result = append(result, `</div>`)
+	result = append(result, `</div>`)
+	return result
+}
@@ -0,0 +1,29 @@
+package goldext
+
+import (
+	"html"
+	"strings"
+)
+
+// BlockDataAttributes adds semantic data-block attributes to rendered block markup
+// so themes can style blocks without depending on class names. Disabled by default.
+var BlockDataAttributes = false
+
+// BlockAttributes returns the data attributes for a rendered block, or an empty string
+// when BlockDataAttributes is disabled. Extra attributes are given as name/value pairs
+// without the data- prefix, e.g. BlockAttributes("direction", "direction", "rtl").
+func BlockAttributes(block string, pairs ...string) string {
+	if !BlockDataAttributes {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(` data-block="` + html.EscapeString(block) + `"`)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] == "" {
+			continue
+		}
+		sb.WriteString(` data-` + pairs[i] + `="` + html.EscapeString(pairs[i+1]) + `"`)
+	}
+	return sb.String()
+}
@@ -0,0 +1,141 @@
+package goldext
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DateElements wraps ISO dates (YYYY-MM-DD) in prose with <time datetime="...">.
+// Disabled by default.
+var DateElements = false
+
+// DateDisplayFormat optionally reformats the visible date text using a Go time layout
+// (e.g. "January 2, 2006"). When empty the date is shown as written.
+var DateDisplayFormat = ""
+
+// DatePreprocessor wraps ISO-like dates in <time> elements for machine-readable dates
+// It skips code blocks, inline code, links, URLs and HTML tags
+func DatePreprocessor(markdown string, _ string) string {
+	if !DateElements {
+		return markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+	var result []string
+
+	inCodeBlock := false
+
+	dateRegex := regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+
+	// Spans that must never be touched: links, HTML tags and bare URLs
+	protectedRegex := regexp.MustCompile(`!?\[[^\]]*\]\([^)]*\)|<[^>]+>|https?://\S+`)
+
+	for _, line := range lines {
+		// Check if this line starts or ends a code block
+		trimmedLine := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			result = append(result, line)
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock {
+			result = append(result, line)
+			continue
+		}
+
+		// Process each segment of the line, preserving inline code
+		var processedLine string
+		segments := strings.Split(line, "`")
+
+		for i, segment := range segments {
+			// Even segments (0, 2, 4...) are outside inline code
+			if i%2 == 0 {
+				processedLine += replaceOutside(segment, protectedRegex, func(text string) string {
+					return replaceDates(text, dateRegex)
+				})
+			} else {
+				// Odd segments (1, 3, 5...) are inside inline code - preserve them
+				processedLine += "`" + segment + "`"
+			}
+		}
+
+		result = append(result, processedLine)
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// replaceDates wraps every valid calendar date found in text
+func replaceDates(text string, dateRegex *regexp.Regexp) string {
+	var builder strings.Builder
+	lastEnd := 0
+
+	for _, match := range dateRegex.FindAllStringIndex(text, -1) {
+		start, end := match[0], match[1]
+		date := text[start:end]
+
+		// Dates glued to other numbers, words, paths or versions
+		// (2024-01-15-rc, v2024-01-15, 12024-01-15) are not dates
+		if start > 0 && strings.ContainsRune(dateBoundaryBefore, rune(text[start-1])) || isWordByte(text, start-1) {
+			continue
+		}
+		if end < len(text) && strings.ContainsRune(dateBoundaryAfter, rune(text[end])) || isWordByte(text, end) {
+			continue
+		}
+
+		// Reject strings that look like dates but aren't (e.g. 2024-13-45)
+		t, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			continue
+		}
+
+		display := date
+		if DateDisplayFormat != "" {
+			display = t.Format(DateDisplayFormat)
+		}
+
+		builder.WriteString(text[lastEnd:start])
+		builder.WriteString(`<time datetime="` + date + `">` + display + `</time>`)
+		lastEnd = end
+	}
+
+	builder.WriteString(text[lastEnd:])
+	return builder.String()
+}
+
+// Characters that may not directly surround a date
+const (
+	dateBoundaryBefore = "-/.:"
+	dateBoundaryAfter  = "-/"
+)
+
+// isWordByte reports whether text[i] exists and is a letter, digit or underscore
+func isWordByte(text string, i int) bool {
+	if i < 0 || i >= len(text) {
+		return false
+	}
+	c := text[i]
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// replaceOutside applies fn to the parts of text not matched by protected
+func replaceOutside(text string, protected *regexp.Regexp, fn func(string) string) string {
+	matches := protected.FindAllStringIndex(text, -1)
+	if len(matches) == 0 {
+		return fn(text)
+	}
+
+	var builder strings.Builder
+	lastEnd := 0
+	for _, match := range matches {
+		builder.WriteString(fn(text[lastEnd:match[0]]))
+		builder.WriteString(text[match[0]:match[1]])
+		lastEnd = match[1]
+	}
+	builder.WriteString(fn(text[lastEnd:]))
+
+	return builder.String()
+}
@@ -0,0 +1,77 @@
+package goldext
+
+import (
+	"testing"
+)
+
+func TestDatePreprocessor(t *testing.T) {
+	DateElements = true
+	defer func() { DateElements = false }()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Valid date in prose",
+			input:    "Released on 2024-03-15.",
+			expected: `Released on <time datetime="2024-03-15">2024-03-15</time>.`,
+		},
+		{
+			name:     "Multiple dates",
+			input:    "From 2024-01-01 to 2024-12-31",
+			expected: `From <time datetime="2024-01-01">2024-01-01</time> to <time datetime="2024-12-31">2024-12-31</time>`,
+		},
+		{
+			name:     "Invalid calendar date",
+			input:    "Reference 2024-13-45 is not a date.",
+			expected: "Reference 2024-13-45 is not a date.",
+		},
+		{
+			name:     "Numeric strings that are not dates",
+			input:    "Call 555-123-4567 or order 12024-01-155 or build 2024-01-15-rc1.",
+			expected: "Call 555-123-4567 or order 12024-01-155 or build 2024-01-15-rc1.",
+		},
+		{
+			name:     "Dates in code and links are skipped",
+			input:    "`2024-03-15` and [2024-03-15](/notes/2024-03-15) and https://example.com/2024-03-15",
+			expected: "`2024-03-15` and [2024-03-15](/notes/2024-03-15) and https://example.com/2024-03-15",
+		},
+		{
+			name:     "Dates in fenced code are skipped",
+			input:    "```\n2024-03-15\n```",
+			expected: "```\n2024-03-15\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := DatePreprocessor(tt.input, "")
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestDatePreprocessorDisplayFormat(t *testing.T) {
+	DateElements = true
+	DateDisplayFormat = "January 2, 2006"
+	defer func() {
+		DateElements = false
+		DateDisplayFormat = ""
+	}()
+
+	expected := `Due <time datetime="2024-03-15">March 15, 2024</time>`
+	if result := DatePreprocessor("Due 2024-03-15", ""); result != expected {
+		t.Errorf("Expected: %q, got: %q", expected, result)
+	}
+}
+
+func TestDatePreprocessorDisabled(t *testing.T) {
+	input := "Released on 2024-03-15."
+	if result := DatePreprocessor(input, ""); result != input {
+		t.Errorf("Expected unchanged input, got: %q", result)
+	}
+}
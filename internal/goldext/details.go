@@ -1,9 +1,13 @@
 package goldext
 
 import (
+	"html"
+	"regexp"
 	"strings"
 )
 
+var detailsContainerRegex = regexp.MustCompile(`^:::\s*details(?:\s+(.*?))?\s*$`)
+
 // DetailsPreprocessor adds support for ```details and ~~~details blocks
 func DetailsPreprocessor(markdown string, _ string) string {
 	lines := strings.Split(markdown, "\n")
@@ -68,7 +72,7 @@ func DetailsPreprocessor(markdown string, _ string) string {
 			}
 			
 			// Generate the details HTML with proper markdown content
-			detailsHTML := "<details class=\"markdown-details\">"
+			detailsHTML := "<details class=\"markdown-details\"" + BlockAttributes("details") + ">"
 			if detailsTitle != "" {
 				detailsHTML += "<summary>" + detailsTitle + "</summary>"
 			} else {
@@ -99,5 +103,74 @@ func DetailsPreprocessor(markdown string, _ string) string {
 	return strings.Join(result, "\n")
 }
 
+// DetailsContainerPreprocessor adds support for collapsible ::: details containers:
+//
+//	::: details How do I reset my password?
+//	Open *Settings* and choose **Reset**.
+//
+//	::: details Still stuck?
+//	Ask an admin.
+//	:::
+//	:::
+//
+// The body is rendered as markdown and containers nest like callouts. A container
+// whose closing ::: is missing is left as text.
+func DetailsContainerPreprocessor(markdown string, _ string) string {
+	if !strings.Contains(markdown, ":::") {
+		return markdown
+	}
+
+	return strings.Join(processDetailsLines(strings.Split(markdown, "\n")), "\n")
+}
+
+// processDetailsLines replaces every balanced details container in lines with its HTML
+func processDetailsLines(lines []string) []string {
+	var result []string
+	inCodeBlock := false
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			result = append(result, line)
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock {
+			result = append(result, line)
+			continue
+		}
+
+		if m := detailsContainerRegex.FindStringSubmatch(trimmedLine); m != nil {
+			if end, ok := findContainerEnd(lines, i); ok {
+				summary := m[1]
+				if summary == "" {
+					summary = "Details"
+				}
+
+				// Blank lines around the body let Goldmark render it as markdown
+				result = append(result,
+					`<details class="markdown-details"`+BlockAttributes("details")+`>`,
+					`<summary>`+html.EscapeString(summary)+`</summary>`,
+					`<div class="details-content">`,
+					"")
+				result = append(result, processDetailsLines(lines[i+1:end])...)
+				result = append(result, "", `</div>`, `</details>`)
+				i = end
+				continue
+			}
+			// Unbalanced container: leave the markers as plain text
+		}
+
+		result = append(result, line)
+	}
+
+	return result
+}
+
 // Register Details preprocessor in the list of known processors
 var _ = DetailsPreprocessor
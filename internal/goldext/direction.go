@@ -3,32 +3,22 @@ package goldext
 import (
 	"bytes"
 	"fmt"
+	"regexp"
 	"strings"
-	"sync"
 
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
 	html "github.com/yuin/goldmark/renderer/html"
+	"golang.org/x/text/language"
 )
 
 // Store extracted direction blocks until restored after Goldmark processing
-var (
-	directionBlocks     = make(map[string]string)
-	directionBlockCount = 0
-	directionMutex      sync.Mutex
-)
+var directionBlocks placeholderStore
 
 // DirectionPreprocessor extracts rtl/ltr blocks and replaces them with placeholders
 // The actual HTML generation will happen after Goldmark processes everything else
 func DirectionPreprocessor(markdown string, _ string) string {
-	directionMutex.Lock()
-	defer directionMutex.Unlock()
-
-	// Reset the storage on each new document
-	directionBlocks = make(map[string]string)
-	directionBlockCount = 0
-
 	// Process line by line to safely extract RTL/LTR blocks
 	lines := strings.Split(markdown, "\n")
 	var result []string
@@ -36,7 +26,7 @@ func DirectionPreprocessor(markdown string, _ string) string {
 	// State tracking
 	inCodeBlock := false  // Are we inside a non-RTL/LTR code block?
 	inRtlLtrBlock := false // Are we inside an RTL/LTR block?
-	blockType := "" // rtl or ltr
+	blockType := "" // rtl or ltr, followed by the language when given
 	blockContent := []string{}
 	codeBlockDepth := 0
 
@@ -46,11 +36,11 @@ func DirectionPreprocessor(markdown string, _ string) string {
 
 		// Handle code block markers
 		if strings.HasPrefix(trimmed, "```") {
-			if trimmed == "```rtl" || trimmed == "```ltr" {
+			if direction, ok := directionFence(strings.TrimPrefix(trimmed, "```")); ok {
 				// Only process as RTL/LTR block if we're not already in a code block
 				if !inCodeBlock && !inRtlLtrBlock {
 					inRtlLtrBlock = true
-					blockType = strings.TrimPrefix(trimmed, "```")
+					blockType = direction
 					blockContent = []string{}
 					continue
 				}
@@ -68,12 +58,8 @@ func DirectionPreprocessor(markdown string, _ string) string {
 
 			// If this is the closing marker for an RTL/LTR block
 			if inRtlLtrBlock && trimmed == "```" && !inCodeBlock {
-				// Create a placeholder for this block
-				blockID := fmt.Sprintf("DIRECTION_BLOCK_%d", directionBlockCount)
-				directionBlockCount++
-
-				// Store the direction type and content for later processing
-				directionBlocks[blockID] = blockType + "|" + strings.Join(blockContent, "\n")
+				// Store the direction type and content for later processing behind a placeholder
+				blockID := "DIRECTION_BLOCK_" + directionBlocks.put(blockType+"|"+strings.Join(blockContent, "\n"))
 
 				// Add the placeholder to the output
 				result = append(result, "<!-- "+blockID+" -->")
@@ -85,11 +71,11 @@ func DirectionPreprocessor(markdown string, _ string) string {
 				continue
 			}
 		} else if strings.HasPrefix(trimmed, "~~~") {
-			if trimmed == "~~~rtl" || trimmed == "~~~ltr" {
+			if direction, ok := directionFence(strings.TrimPrefix(trimmed, "~~~")); ok {
 				// Only process as RTL/LTR block if we're not already in a code block
 				if !inCodeBlock && !inRtlLtrBlock {
 					inRtlLtrBlock = true
-					blockType = strings.TrimPrefix(trimmed, "~~~")
+					blockType = direction
 					blockContent = []string{}
 					continue
 				}
@@ -107,12 +93,8 @@ func DirectionPreprocessor(markdown string, _ string) string {
 
 			// If this is the closing marker for an RTL/LTR block
 			if inRtlLtrBlock && trimmed == "~~~" && !inCodeBlock {
-				// Create a placeholder for this block
-				blockID := fmt.Sprintf("DIRECTION_BLOCK_%d", directionBlockCount)
-				directionBlockCount++
-
-				// Store the direction type and content for later processing
-				directionBlocks[blockID] = blockType + "|" + strings.Join(blockContent, "\n")
+				// Store the direction type and content for later processing behind a placeholder
+				blockID := "DIRECTION_BLOCK_" + directionBlocks.put(blockType+"|"+strings.Join(blockContent, "\n"))
 
 				// Add the placeholder to the output
 				result = append(result, "<!-- "+blockID+" -->")
@@ -135,24 +117,89 @@ func DirectionPreprocessor(markdown string, _ string) string {
 
 	// Handle any unclosed blocks at EOF (rare case)
 	if inRtlLtrBlock && !inCodeBlock && blockType != "" {
-		blockID := fmt.Sprintf("DIRECTION_BLOCK_%d", directionBlockCount)
-		directionBlockCount++
-		directionBlocks[blockID] = blockType + "|" + strings.Join(blockContent, "\n")
+		blockID := "DIRECTION_BLOCK_" + directionBlocks.put(blockType+"|"+strings.Join(blockContent, "\n"))
 		result = append(result, "<!-- "+blockID+" -->")
 	}
 
 	return strings.Join(result, "\n")
 }
 
+// directionFenceRegex matches the info string of a direction block: rtl or ltr,
+// optionally followed by the language of the block, as in ```rtl ar
+var directionFenceRegex = regexp.MustCompile(`^(rtl|ltr)(?:[ \t]+(\S+))?$`)
+
+// directionFence returns the direction of a block from the info string of its opening
+// fence, with the language appended after a space when the block names a valid one
+// Invalid language tags are dropped.
+func directionFence(info string) (string, bool) {
+	m := directionFenceRegex.FindStringSubmatch(info)
+	if m == nil {
+		return "", false
+	}
+	if m[2] != "" {
+		if tag, err := language.Parse(m[2]); err == nil {
+			return m[1] + " " + tag.String(), true
+		}
+	}
+	return m[1], true
+}
+
+// directionPlaceholderRegex matches the placeholders left by DirectionPreprocessor
+var directionPlaceholderRegex = regexp.MustCompile(`<!-- DIRECTION_BLOCK_([0-9a-f]+) -->`)
+
 // RestoreDirectionBlocks replaces direction block placeholders with HTML
-// This must be called after Goldmark rendering
+// This must be called after Goldmark rendering. Like RestoreMermaidBlocks it removes
+// the restored blocks.
 func RestoreDirectionBlocks(htmlContent string) string {
-	directionMutex.Lock()
-	defer directionMutex.Unlock()
+	return restoreDirectionBlocks(htmlContent, restoredBlocks{})
+}
+
+// restoreDirectionBlocks replaces direction block placeholders in part of an output
+func restoreDirectionBlocks(htmlContent string, restored restoredBlocks) string {
+	if !strings.Contains(htmlContent, "<!-- DIRECTION_BLOCK_") {
+		return htmlContent
+	}
+
+	var md goldmark.Markdown
+	return directionPlaceholderRegex.ReplaceAllStringFunc(htmlContent, func(placeholder string) string {
+		id := directionPlaceholderRegex.FindStringSubmatch(placeholder)[1]
+		block, _ := directionBlocks.restore(placeholder, id, restored)
+
+		// Split the stored data into type and content
+		parts := strings.SplitN(block, "|", 2)
+		if len(parts) != 2 {
+			return placeholder
+		}
+
+		dirType, lang, _ := strings.Cut(parts[0], " ")
+		content := parts[1]
+
+		// Blocks with a language also carry it and their direction as attributes
+		attributes := BlockAttributes("direction", "direction", dirType)
+		if lang != "" {
+			attributes = fmt.Sprintf(` dir="%s" lang="%s"`, dirType, lang) + attributes
+		}
+
+		// Create our own Goldmark instance for RTL/LTR content processing
+		// This won't be recursive because we're only processing the content inside the blocks
+		if md == nil {
+			md = newDirectionMarkdown()
+		}
 
-	// Create our own Goldmark instance for RTL/LTR content processing
-	// This won't be recursive because we're only processing the content inside the blocks
-	md := goldmark.New(
+		// Render the content with Goldmark
+		var buf bytes.Buffer
+		if err := md.Convert([]byte(content), &buf); err != nil {
+			// If error, just use unprocessed content
+			return fmt.Sprintf("<div class=\"%s\"%s>%s</div>", dirType, attributes, content)
+		}
+		// Use the rendered HTML inside the direction div
+		return fmt.Sprintf("<div class=\"%s\"%s>%s</div>", dirType, attributes, buf.String())
+	})
+}
+
+// newDirectionMarkdown creates the Goldmark instance direction block content is rendered with
+func newDirectionMarkdown() goldmark.Markdown {
+	return goldmark.New(
 		goldmark.WithExtensions(
 			extension.Table,
 			extension.Strikethrough,
@@ -170,32 +217,4 @@ func RestoreDirectionBlocks(htmlContent string) string {
 			html.WithHardWraps(),
 		),
 	)
-
-	result := htmlContent
-
-	// Replace each placeholder with processed HTML
-	for id, block := range directionBlocks {
-		placeholder := fmt.Sprintf("<!-- %s -->", id)
-
-		// Split the stored data into type and content
-		parts := strings.SplitN(block, "|", 2)
-		if len(parts) != 2 {
-			continue
-		}
-
-		dirType := parts[0]
-		content := parts[1]
-
-		// Render the content with Goldmark
-		var buf bytes.Buffer
-		if err := md.Convert([]byte(content), &buf); err != nil {
-			// If error, just use unprocessed content
-			result = strings.Replace(result, placeholder, fmt.Sprintf("<div class=\"%s\">%s</div>", dirType, content), 1)
-		} else {
-			// Use the rendered HTML inside the direction div
-			result = strings.Replace(result, placeholder, fmt.Sprintf("<div class=\"%s\">%s</div>", dirType, buf.String()), 1)
-		}
-	}
-
-	return result
 }
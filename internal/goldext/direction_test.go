@@ -0,0 +1,24 @@
+package goldext
+
+import "testing"
+
+func TestDirectionBlockLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Without a language", "```rtl\nשלום\n```", `<div class="rtl"><p>שלום</p>` + "\n</div>"},
+		{"With a language", "```rtl ar\nمرحبا\n```", `<div class="rtl" dir="rtl" lang="ar"><p>مرحبا</p>` + "\n</div>"},
+		{"Canonical tag", "~~~ltr EN-us\nHello\n~~~", `<div class="ltr" dir="ltr" lang="en-US"><p>Hello</p>` + "\n</div>"},
+		{"Invalid tag dropped", "```rtl \"><script>\nשלום\n```", `<div class="rtl"><p>שלום</p>` + "\n</div>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := RestoreDirectionBlocks(DirectionPreprocessor(tt.input, "")); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io/fs"
 	"log"
+	"regexp"
 	"strings"
 
 	"wiki-go/internal/resources"
@@ -15,14 +16,13 @@ type EmojiData struct {
 	Shortcodes []string `json:"shortcodes"`
 }
 
-// Global emoji map
-var emojis map[string]string
+// Emojis maps shortcode names, without the colons, to emoji, e.g. "rocket" to "🚀"
+// It is loaded from emojis.json; entries may be added or overridden at startup,
+// before rendering starts.
+var Emojis = make(map[string]string)
 
 // init loads emoji data from the JSON file
 func init() {
-	// Initialize the map
-	emojis = make(map[string]string)
-
 	// Get the data filesystem
 	dataFS := resources.GetDataFS()
 
@@ -42,67 +42,96 @@ func init() {
 
 	// Convert to map for faster lookups
 	for _, emoji := range emojiList {
-		// Add all shortcodes in the array
+		// Add all shortcodes in the array, with or without colons
 		for _, code := range emoji.Shortcodes {
-			// Ensure shortcode has colon prefix/suffix if not already present
-			formattedCode := code
-			if !strings.HasPrefix(formattedCode, ":") {
-				formattedCode = ":" + formattedCode
-			}
-			if !strings.HasSuffix(formattedCode, ":") {
-				formattedCode = formattedCode + ":"
-			}
-			emojis[formattedCode] = emoji.Emoji
+			Emojis[strings.Trim(code, ":")] = emoji.Emoji
 		}
 	}
 
-	log.Printf("Loaded %d emojis from emojis.json", len(emojis))
+	log.Printf("Loaded %d emojis from emojis.json", len(Emojis))
 }
 
+// emojiProtectedRegex matches spans never containing shortcodes: link destinations,
+// HTML tags (placeholders included) and bare URLs
+var emojiProtectedRegex = regexp.MustCompile(`\]\([^)]*\)|<[^>]+>|[A-Za-z][A-Za-z0-9+.-]*://\S*`)
+
 // EmojiPreprocessor replaces emoji shortcodes with Unicode emoji characters
-// but avoids processing text inside code blocks
+// but avoids processing text inside code blocks. Unknown shortcodes are left as they are.
 func EmojiPreprocessor(markdown string, _ string) string {
+	if !strings.Contains(markdown, ":") {
+		return markdown
+	}
+
 	// Process line by line instead of relying on regex which might fail on large documents
 	lines := strings.Split(markdown, "\n")
-	var result []string
-
 	inCodeBlock := false
 
-	for _, line := range lines {
+	for i, line := range lines {
 		// Check if this line starts or ends a code block
 		trimmedLine := strings.TrimSpace(line)
 		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
 			inCodeBlock = !inCodeBlock
-			result = append(result, line)
 			continue
 		}
 
 		// If we're in a code block, don't process
-		if inCodeBlock {
-			result = append(result, line)
+		if inCodeBlock || !strings.Contains(line, ":") {
 			continue
 		}
 
-		// Process each segment of the line, preserving inline code
-		var processedLine string
+		// Even segments are outside inline code
 		segments := strings.Split(line, "`")
-
-		for i, segment := range segments {
-			// Even segments (0, 2, 4...) are outside inline code
-			if i%2 == 0 {
-				// Apply all replacements to non-code segments
-				for shortcode, emoji := range emojis {
-					segment = strings.ReplaceAll(segment, shortcode, emoji)
-				}
-				processedLine += segment
-			} else {
-				// Odd segments (1, 3, 5...) are inside inline code - preserve them
-				processedLine += "`" + segment + "`"
-			}
+		for j := 0; j < len(segments); j += 2 {
+			segments[j] = replaceEmojiShortcodes(segments[j])
 		}
+		lines[i] = strings.Join(segments, "`")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// replaceEmojiShortcodes replaces the known shortcodes of a piece of text outside code
+func replaceEmojiShortcodes(text string) string {
+	var sb strings.Builder
+	last := 0
+	for _, span := range emojiProtectedRegex.FindAllStringIndex(text, -1) {
+		sb.WriteString(replaceEmojiShortcodesIn(text[last:span[0]]))
+		sb.WriteString(text[span[0]:span[1]])
+		last = span[1]
+	}
+	sb.WriteString(replaceEmojiShortcodesIn(text[last:]))
+	return sb.String()
+}
 
-		result = append(result, processedLine)
+// replaceEmojiShortcodesIn replaces the known shortcodes of unprotected text
+// The closing colon of an unknown shortcode may still open the next one.
+func replaceEmojiShortcodesIn(text string) string {
+	if !strings.Contains(text, ":") {
+		return text
 	}
 
-	return strings.Join(result, "\n")
-}
\ No newline at end of file
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(text, ':')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(text[start+1:], ':')
+		if end < 0 {
+			break
+		}
+		end += start + 1
+
+		if emoji, ok := Emojis[text[start+1:end]]; ok {
+			sb.WriteString(text[:start])
+			sb.WriteString(emoji)
+			text = text[end+1:]
+			continue
+		}
+
+		sb.WriteString(text[:end])
+		text = text[end:]
+	}
+	sb.WriteString(text)
+	return sb.String()
+}
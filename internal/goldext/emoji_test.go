@@ -0,0 +1,43 @@
+package goldext
+
+import "testing"
+
+func TestEmojiPreprocessor(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Shortcode", "Launch :rocket: now", "Launch 🚀 now"},
+		{"Adjacent shortcodes", ":smile::rocket:", "😄🚀"},
+		{"Unknown shortcode", "Keep :not_an_emoji: and :smile:", "Keep :not_an_emoji: and 😄"},
+		{"Colons in text", "At 12:30:smile:", "At 12:30😄"},
+		{"Inline code", "`:rocket:` :rocket:", "`:rocket:` 🚀"},
+		{"Code block", "```\n:rocket:\n```", "```\n:rocket:\n```"},
+		{"URL", "See https://example.com/:rocket:/x and <https://example.com/:smile:>", "See https://example.com/:rocket:/x and <https://example.com/:smile:>"},
+		{"Link destination", "[:rocket:](/docs/:rocket:)", "[🚀](/docs/:rocket:)"},
+		{"HTML attribute", `<span title=":smile:">:smile:</span>`, `<span title=":smile:">😄</span>`},
+		{"Placeholder", "<!-- MERMAID_BLOCK_0 -->", "<!-- MERMAID_BLOCK_0 -->"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := EmojiPreprocessor(tt.input, ""); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestEmojiOverride(t *testing.T) {
+	saved := Emojis["rocket"]
+	Emojis["wiki"], Emojis["rocket"] = "📚", "🛰"
+	defer func() {
+		delete(Emojis, "wiki")
+		Emojis["rocket"] = saved
+	}()
+
+	if result := EmojiPreprocessor(":wiki: :rocket:", ""); result != "📚 🛰" {
+		t.Errorf("Expected the added and overridden emoji, got %q", result)
+	}
+}
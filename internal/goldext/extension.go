@@ -0,0 +1,32 @@
+package goldext
+
+import (
+	"sync"
+
+	"github.com/yuin/goldmark"
+)
+
+// Goldmark extensions added with RegisterExtension, in registration order
+var (
+	extensionsMutex      sync.RWMutex
+	registeredExtensions []goldmark.Extender
+)
+
+// RegisterExtension adds a Goldmark extension to the renderer of documents, after the
+// built-in ones. Unlike preprocessors, extensions work on the parsed document: they can
+// add parsers, AST transformers and node renderers, ordered against the built-in ones by
+// their util.Prioritized priorities (transformers at 500, node renderers at 100).
+// Preprocessors still run on the markdown before it is parsed. Kanban cards and
+// direction blocks are rendered without registered extensions.
+func RegisterExtension(extension goldmark.Extender) {
+	extensionsMutex.Lock()
+	defer extensionsMutex.Unlock()
+	registeredExtensions = append(registeredExtensions, extension)
+}
+
+// Extensions returns the Goldmark extensions added with RegisterExtension
+func Extensions() []goldmark.Extender {
+	extensionsMutex.RLock()
+	defer extensionsMutex.RUnlock()
+	return append([]goldmark.Extender(nil), registeredExtensions...)
+}
@@ -0,0 +1,222 @@
+package goldext
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// NumberedFigures numbers labelled figures and tables and resolves {{ref:label}}
+// cross-references to them. Disabled by default.
+var NumberedFigures = false
+
+// FigureLabel and TableLabel prefix the numbers of figures and tables
+var (
+	FigureLabel = "Figure"
+	TableLabel  = "Table"
+)
+
+// Shortcodes replaced by the list of figures and the list of tables
+const (
+	ListOfFiguresShortcode = "{{list-of-figures}}"
+	ListOfTablesShortcode  = "{{list-of-tables}}"
+)
+
+var (
+	figureLineRegex   = regexp.MustCompile(`^\s*(!\[([^\]]*)\]\([^)]*\))\s*\{#([A-Za-z][\w-]*)\}\s*$`)
+	tableCaptionRegex = regexp.MustCompile(`^\s*Table:\s*(.*?)\s*\{#([A-Za-z][\w-]*)\}\s*$`)
+	figureRefRegex    = regexp.MustCompile(`\{\{ref:([A-Za-z][\w-]*)\}\}`)
+)
+
+// numberedItem is a labelled figure or table
+type numberedItem struct {
+	Label   string
+	Caption string
+	Kind    string // FigureLabel or TableLabel
+	Number  int
+}
+
+// name returns the display name of the item, e.g. "Figure 2"
+func (n numberedItem) name() string {
+	return fmt.Sprintf("%s %d", n.Kind, n.Number)
+}
+
+// FigurePreprocessor numbers labelled figures and tables and resolves references to them:
+//
+//	![Request flow](flow.png){#fig-flow}
+//
+//	Table: Results {#tbl-results}
+//	| Run | Time |
+//	|-----|------|
+//
+//	See {{ref:fig-flow}} and {{ref:tbl-results}}.
+//
+// References to unknown labels are kept visible as ??label??.
+func FigurePreprocessor(markdown string, _ string) string {
+	if !NumberedFigures {
+		return markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+	items := collectNumberedItems(lines)
+	if len(items) == 0 && !strings.Contains(markdown, "{{ref:") && !strings.Contains(markdown, "{{list-of-") {
+		return markdown
+	}
+
+	byLabel := make(map[string]numberedItem)
+	for _, item := range items {
+		if _, exists := byLabel[item.Label]; !exists {
+			byLabel[item.Label] = item
+		}
+	}
+
+	var result []string
+	inCodeBlock := false
+	next := 0 // Index of the next item in document order
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			result = append(result, line)
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock {
+			result = append(result, line)
+			continue
+		}
+
+		if m := figureLineRegex.FindStringSubmatch(line); m != nil {
+			item := items[next]
+			next++
+			result = append(result,
+				`<figure id="`+html.EscapeString(item.Label)+`" class="numbered-figure">`,
+				"",
+				m[1],
+				"",
+				`<figcaption>`+figureCaption(item)+`</figcaption>`,
+				`</figure>`,
+			)
+			continue
+		}
+
+		if m := tableCaptionRegex.FindStringSubmatch(line); m != nil && isTableStart(lines, i+1) {
+			item := items[next]
+			next++
+			result = append(result,
+				`<figure id="`+html.EscapeString(item.Label)+`" class="numbered-table">`,
+				`<figcaption>`+figureCaption(item)+`</figcaption>`,
+				"",
+			)
+
+			// Copy the table up to the blank line that ends it
+			j := i + 1
+			for ; j < len(lines) && strings.TrimSpace(lines[j]) != ""; j++ {
+				result = append(result, lines[j])
+			}
+			result = append(result, "", `</figure>`)
+			i = j - 1
+			continue
+		}
+
+		switch trimmedLine {
+		case ListOfFiguresShortcode:
+			result = append(result, renderNumberedList(items, FigureLabel, "list-of-figures"))
+			continue
+		case ListOfTablesShortcode:
+			result = append(result, renderNumberedList(items, TableLabel, "list-of-tables"))
+			continue
+		}
+
+		if strings.Contains(line, "{{ref:") {
+			line = resolveFigureRefs(line, byLabel)
+		}
+		result = append(result, line)
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// collectNumberedItems numbers the labelled figures and tables in document order
+func collectNumberedItems(lines []string) []numberedItem {
+	var items []numberedItem
+	figures, tables := 0, 0
+	inCodeBlock := false
+
+	// This walks the lines exactly like FigurePreprocessor so both agree on the items
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmedLine := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock {
+			continue
+		}
+
+		if m := figureLineRegex.FindStringSubmatch(line); m != nil {
+			figures++
+			items = append(items, numberedItem{Label: m[3], Caption: strings.TrimSpace(m[2]), Kind: FigureLabel, Number: figures})
+		} else if m := tableCaptionRegex.FindStringSubmatch(line); m != nil && isTableStart(lines, i+1) {
+			tables++
+			items = append(items, numberedItem{Label: m[2], Caption: m[1], Kind: TableLabel, Number: tables})
+			for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+				i++
+			}
+		}
+	}
+
+	return items
+}
+
+// isTableStart reports whether a table header row and delimiter row start at lines[i]
+func isTableStart(lines []string, i int) bool {
+	return i+1 < len(lines) && strings.Contains(lines[i], "|") &&
+		strings.Contains(lines[i+1], "|") && tableDelimiterRegex.MatchString(lines[i+1])
+}
+
+// figureCaption renders the numbered caption of a figure or table
+func figureCaption(item numberedItem) string {
+	caption := `<span class="figure-number">` + html.EscapeString(item.name()) + `:</span>`
+	if item.Caption != "" {
+		caption += " " + html.EscapeString(item.Caption)
+	}
+	return caption
+}
+
+// renderNumberedList renders the list of figures or tables of the given kind
+func renderNumberedList(items []numberedItem, kind, class string) string {
+	var sb strings.Builder
+	sb.WriteString(`<ol class="` + class + `">`)
+	for _, item := range items {
+		if item.Kind != kind {
+			continue
+		}
+		sb.WriteString(`<li><a href="#` + html.EscapeString(item.Label) + `">` + figureCaption(item) + `</a></li>`)
+	}
+	sb.WriteString(`</ol>`)
+	return sb.String()
+}
+
+// resolveFigureRefs replaces {{ref:label}} outside inline code with links to the figure or table
+func resolveFigureRefs(line string, byLabel map[string]numberedItem) string {
+	segments := strings.Split(line, "`")
+	for i := 0; i < len(segments); i += 2 {
+		segments[i] = figureRefRegex.ReplaceAllStringFunc(segments[i], func(match string) string {
+			label := figureRefRegex.FindStringSubmatch(match)[1]
+			item, ok := byLabel[label]
+			if !ok {
+				return `<span class="figure-ref figure-ref-missing" title="Unresolved reference">??` + html.EscapeString(label) + `??</span>`
+			}
+			return `<a href="#` + html.EscapeString(label) + `" class="figure-ref">` + html.EscapeString(item.name()) + `</a>`
+		})
+	}
+	return strings.Join(segments, "`")
+}
@@ -0,0 +1,79 @@
+package goldext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFigurePreprocessor(t *testing.T) {
+	NumberedFigures = true
+	defer func() { NumberedFigures = false }()
+
+	input := strings.Join([]string{
+		"{{list-of-figures}}",
+		"",
+		"See {{ref:fig-flow}}, {{ref:fig-arch}} and {{ref:tbl-results}}.",
+		"",
+		"![Request flow](flow.png){#fig-flow}",
+		"",
+		"Table: Benchmark results {#tbl-results}",
+		"| Run | Time |",
+		"|-----|------|",
+		"| 1   | 3ms  |",
+		"",
+		"![Architecture](arch.png){#fig-arch}",
+		"",
+		"Missing: {{ref:fig-none}}, literal: `{{ref:fig-flow}}`",
+		"",
+		"```",
+		"![Code](code.png){#fig-code}",
+		"{{ref:fig-flow}}",
+		"```",
+	}, "\n")
+
+	result := FigurePreprocessor(input, "")
+
+	expected := []string{
+		`<ol class="list-of-figures"><li><a href="#fig-flow"><span class="figure-number">Figure 1:</span> Request flow</a></li><li><a href="#fig-arch"><span class="figure-number">Figure 2:</span> Architecture</a></li></ol>`,
+		`See <a href="#fig-flow" class="figure-ref">Figure 1</a>, <a href="#fig-arch" class="figure-ref">Figure 2</a> and <a href="#tbl-results" class="figure-ref">Table 1</a>.`,
+		"<figure id=\"fig-flow\" class=\"numbered-figure\">\n\n![Request flow](flow.png)\n\n<figcaption><span class=\"figure-number\">Figure 1:</span> Request flow</figcaption>\n</figure>",
+		"<figure id=\"tbl-results\" class=\"numbered-table\">\n<figcaption><span class=\"figure-number\">Table 1:</span> Benchmark results</figcaption>\n\n| Run | Time |\n|-----|------|\n| 1   | 3ms  |\n\n</figure>",
+		`<figcaption><span class="figure-number">Figure 2:</span> Architecture</figcaption>`,
+		`Missing: <span class="figure-ref figure-ref-missing" title="Unresolved reference">??fig-none??</span>, literal: ` + "`{{ref:fig-flow}}`",
+		"```\n![Code](code.png){#fig-code}\n{{ref:fig-flow}}\n```",
+	}
+	for _, want := range expected {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, result)
+		}
+	}
+}
+
+func TestFigurePreprocessorListOfTables(t *testing.T) {
+	NumberedFigures = true
+	defer func() { NumberedFigures = false }()
+
+	input := "Table: First {#tbl-a}\n| A |\n|---|\n\nTable: Second {#tbl-b}\n| B |\n|---|\n\n{{list-of-tables}}\n\nSee {{ref:tbl-b}}."
+	result := FigurePreprocessor(input, "")
+
+	for _, want := range []string{
+		`<ol class="list-of-tables"><li><a href="#tbl-a"><span class="figure-number">Table 1:</span> First</a></li><li><a href="#tbl-b"><span class="figure-number">Table 2:</span> Second</a></li></ol>`,
+		`See <a href="#tbl-b" class="figure-ref">Table 2</a>.`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, result)
+		}
+	}
+
+	// A caption line that isn't followed by a table is left alone
+	if got := FigurePreprocessor("Table: Orphan {#tbl-x}\n\ntext", ""); got != "Table: Orphan {#tbl-x}\n\ntext" {
+		t.Errorf("Expected orphan caption to be unchanged, got: %q", got)
+	}
+}
+
+func TestFigurePreprocessorDisabledByDefault(t *testing.T) {
+	input := "![Flow](flow.png){#fig-flow}\n\nSee {{ref:fig-flow}}."
+	if result := FigurePreprocessor(input, ""); result != input {
+		t.Errorf("Expected unchanged output, got: %q", result)
+	}
+}
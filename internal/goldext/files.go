@@ -0,0 +1,41 @@
+package goldext
+
+import (
+	"io/fs"
+	"os"
+)
+
+// FileSystem is the file access of the preprocessors reading other documents: included
+// documents and the targets and titles of wikilinks. It matches utils.FileProvider, so
+// documents rendered from an in-memory or embedded filesystem resolve them there too.
+type FileSystem interface {
+	ReadFile(name string) ([]byte, error)
+	Stat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+}
+
+// osFiles implements FileSystem on top of the os package
+type osFiles struct{}
+
+// ReadFile implements FileSystem.ReadFile
+func (osFiles) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+// Stat implements FileSystem.Stat
+func (osFiles) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// ReadDir implements FileSystem.ReadDir
+func (osFiles) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+// filesOrOS returns files, or the operating system's filesystem when files is nil
+func filesOrOS(files FileSystem) FileSystem {
+	if files == nil {
+		return osFiles{}
+	}
+	return files
+}
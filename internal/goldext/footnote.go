@@ -0,0 +1,259 @@
+package goldext
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// FootnoteARIA adds ARIA labels and keyboard focus targets to rendered footnotes
+var FootnoteARIA = true
+
+// Footnote labels used for screen readers. Override them at startup for other languages.
+// %s in FootnoteRefLabel and FootnoteBacklinkLabel is replaced with the footnote number.
+var (
+	FootnoteSectionLabel  = "Footnotes"
+	FootnoteRefLabel      = "Footnote %s"
+	FootnoteBacklinkLabel = "Back to reference %s"
+)
+
+var (
+	footnoteRefRegex      = regexp.MustCompile(`<a href="#([\w-]*)fn:([^"]+)" class="footnote-ref" role="doc-noteref">([^<]*)</a>`)
+	footnoteBacklinkRegex = regexp.MustCompile(`<a href="#([\w-]*)fnref:([^"]+)" class="footnote-backref" role="doc-backlink">`)
+	footnoteItemRegex     = regexp.MustCompile(`<li id="([\w-]*)fn:([^"]+)">`)
+)
+
+// AddFootnoteARIA augments Goldmark's footnote markup for accessibility
+// References point at a visually hidden section heading via aria-describedby,
+// links get descriptive labels and footnote items become focus targets.
+// This must be called after Goldmark rendering
+func AddFootnoteARIA(htmlContent string) string {
+	if !FootnoteARIA || !strings.Contains(htmlContent, `class="footnotes"`) {
+		return htmlContent
+	}
+	return addFootnoteARIA(htmlContent, footnotesLabelID)
+}
+
+// footnotesLabelID is the ID of the hidden footnotes heading
+const footnotesLabelID = "footnotes-label"
+
+// footnotesOpening is the opening tag of a rendered footnote list
+const footnotesOpening = `<div class="footnotes" role="doc-endnotes">`
+
+// addFootnoteARIA rewrites the footnote markup found in part of the rendered HTML
+// Each footnote list gets its own hidden heading, whose ID carries the ID prefix of the
+// list's footnotes, so the lists of footnote sections don't share one ID. labelID is
+// used for a list whose footnotes aren't part of htmlContent.
+func addFootnoteARIA(htmlContent string, labelID string) string {
+	result := footnoteRefRegex.ReplaceAllStringFunc(htmlContent, func(match string) string {
+		parts := footnoteRefRegex.FindStringSubmatch(match)
+		prefix, id, number := parts[1], parts[2], parts[3]
+		label := html.EscapeString(strings.ReplaceAll(FootnoteRefLabel, "%s", number))
+		return `<a href="#` + prefix + `fn:` + id + `" class="footnote-ref" role="doc-noteref" aria-describedby="` + prefix + footnotesLabelID + `" aria-label="` + label + `">` + number + `</a>`
+	})
+
+	result = footnoteBacklinkRegex.ReplaceAllStringFunc(result, func(match string) string {
+		parts := footnoteBacklinkRegex.FindStringSubmatch(match)
+		prefix, id := parts[1], parts[2]
+		label := html.EscapeString(strings.ReplaceAll(FootnoteBacklinkLabel, "%s", id))
+		return `<a href="#` + prefix + `fnref:` + id + `" class="footnote-backref" role="doc-backlink" aria-label="` + label + `">`
+	})
+
+	result = addFootnotesHeadings(result, labelID)
+
+	result = footnoteItemRegex.ReplaceAllString(result, `<li id="${1}fn:$2" tabindex="-1">`)
+
+	return result
+}
+
+// addFootnotesHeadings inserts the hidden heading of every footnote list
+// The heading ID is taken from the first footnote following the list's opening tag.
+func addFootnotesHeadings(htmlContent string, labelID string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(htmlContent, footnotesOpening)
+		if i < 0 {
+			b.WriteString(htmlContent)
+			return b.String()
+		}
+		end := i + len(footnotesOpening)
+		id := labelID
+		if m := footnoteItemRegex.FindStringSubmatch(htmlContent[end:]); m != nil {
+			id = m[1] + footnotesLabelID
+		}
+		b.WriteString(htmlContent[:end])
+		b.WriteString("\n" + `<h2 id="` + id + `" class="sr-only">` + html.EscapeString(FootnoteSectionLabel) + `</h2>`)
+		htmlContent = htmlContent[end:]
+	}
+}
+
+// FootnotesPerSection collects footnotes at the end of each section instead of the document,
+// with numbering restarting per section. Disabled by default.
+var FootnotesPerSection = false
+
+// FootnoteSectionLevel is the heading level that starts a footnote section
+// Headings of this level or higher (e.g. # and ## for 2) start a new section
+var FootnoteSectionLevel = 2
+
+var (
+	footnoteLabelRegex      = regexp.MustCompile(`\[\^([^\]\s]+)\]`)
+	footnoteDefinitionRegex = regexp.MustCompile(`^ {0,3}\[\^([^\]\s]+)\]:`)
+	sectionHeadingRegex     = regexp.MustCompile(`^ {0,3}(#{1,6})(\s|$)`)
+)
+
+// sectionHeadingLevel returns the level of an ATX heading line, or 0 for other lines
+func sectionHeadingLevel(line string) int {
+	if m := sectionHeadingRegex.FindStringSubmatch(line); m != nil {
+		return len(m[1])
+	}
+	return 0
+}
+
+// FootnoteSectionPrefix returns the footnote ID prefix used for a section
+func FootnoteSectionPrefix(section int) string {
+	return fmt.Sprintf("s%d-", section)
+}
+
+// FootnoteSectionPreprocessor scopes footnote labels to their section so each
+// section can reuse labels like [^1]. Labels are prefixed with the section number,
+// which never shows up in the output since footnotes are displayed by number.
+// A definition belongs to its own section when that section references it; otherwise,
+// as with definitions collected at the end of the document, it is copied for every
+// section referencing the label without defining it.
+func FootnoteSectionPreprocessor(markdown string, _ string) string {
+	if !FootnotesPerSection || !strings.Contains(markdown, "[^") {
+		return markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+
+	// Find the section of every line, which sections reference each label and which define it
+	sections := make([]int, len(lines))
+	isCode := make([]bool, len(lines))
+	referencing := make(map[string][]int)
+	defining := make(map[string]map[int]bool)
+	inCodeBlock := false
+	section := 0
+
+	for i, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			isCode[i] = true
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock {
+			isCode[i] = true
+			continue
+		}
+
+		if level := sectionHeadingLevel(line); level > 0 && level <= FootnoteSectionLevel {
+			section++
+		}
+		sections[i] = section
+
+		if !strings.Contains(line, "[^") {
+			continue
+		}
+
+		rest := line
+		if m := footnoteDefinitionRegex.FindStringSubmatch(line); m != nil {
+			if defining[m[1]] == nil {
+				defining[m[1]] = make(map[int]bool)
+			}
+			defining[m[1]][section] = true
+			rest = line[len(m[0]):]
+		}
+
+		// Skip inline code
+		parts := strings.Split(rest, "`")
+		for j := 0; j < len(parts); j += 2 {
+			for _, m := range footnoteLabelRegex.FindAllStringSubmatch(parts[j], -1) {
+				if !slices.Contains(referencing[m[1]], section) {
+					referencing[m[1]] = append(referencing[m[1]], section)
+				}
+			}
+		}
+	}
+
+	var result []string
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if isCode[i] || !strings.Contains(line, "[^") {
+			result = append(result, line)
+			continue
+		}
+
+		m := footnoteDefinitionRegex.FindStringSubmatch(line)
+		if m == nil || slices.Contains(referencing[m[1]], sections[i]) {
+			result = append(result, prefixFootnoteLabels(line, FootnoteSectionPrefix(sections[i])))
+			continue
+		}
+
+		// Copy a definition its section doesn't reference for the sections that do
+		var targets []int
+		for _, referencingSection := range referencing[m[1]] {
+			if !defining[m[1]][referencingSection] {
+				targets = append(targets, referencingSection)
+			}
+		}
+		if len(targets) == 0 {
+			result = append(result, prefixFootnoteLabels(line, FootnoteSectionPrefix(sections[i])))
+			continue
+		}
+
+		end := footnoteDefinitionEnd(lines, isCode, i)
+		for n, target := range targets {
+			if n > 0 {
+				result = append(result, "")
+			}
+			for _, definitionLine := range lines[i:end] {
+				result = append(result, prefixFootnoteLabels(definitionLine, FootnoteSectionPrefix(target)))
+			}
+		}
+		i = end - 1
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// footnoteDefinitionEnd returns the index of the line after the footnote definition
+// starting at lines[start]: its lazy continuation lines and indented paragraphs
+func footnoteDefinitionEnd(lines []string, isCode []bool, start int) int {
+	end := start + 1
+	for end < len(lines) && !isCode[end] {
+		line := lines[end]
+		if strings.TrimSpace(line) == "" {
+			// A blank line only continues the definition before an indented line
+			next := end + 1
+			for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+				next++
+			}
+			if next < len(lines) && !isCode[next] && (strings.HasPrefix(lines[next], "    ") || strings.HasPrefix(lines[next], "\t")) {
+				end = next
+				continue
+			}
+			break
+		}
+		if footnoteDefinitionRegex.MatchString(line) || sectionHeadingLevel(line) > 0 {
+			break
+		}
+		end++
+	}
+	return end
+}
+
+// prefixFootnoteLabels prefixes the footnote labels of a line outside inline code
+func prefixFootnoteLabels(line string, prefix string) string {
+	parts := strings.Split(line, "`")
+	for j := 0; j < len(parts); j += 2 {
+		parts[j] = footnoteLabelRegex.ReplaceAllString(parts[j], "[^"+prefix+"$1]")
+	}
+	return strings.Join(parts, "`")
+}
@@ -11,7 +11,7 @@ func FrontmatterPreprocessor(markdown string, _ string) string {
 		return markdown
 	}
 
-	// Parse frontmatter and get content without it
-	_, contentWithoutFrontmatter, _ := frontmatter.Parse(markdown)
+	// Parse frontmatter and get content without it, even when it is invalid
+	_, contentWithoutFrontmatter, _, _ := frontmatter.ParseStrict(markdown)
 	return contentWithoutFrontmatter
 }
\ No newline at end of file
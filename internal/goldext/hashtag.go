@@ -0,0 +1,119 @@
+package goldext
+
+import (
+	"regexp"
+	"strings"
+)
+
+// HashtagLinks turns #hashtags in prose into links to their tag page. Disabled by default.
+var HashtagLinks = false
+
+// HashtagPattern matches a hashtag including its leading #
+// A hashtag must also start a word, so URL fragments and entities never match.
+var HashtagPattern = regexp.MustCompile(`#[A-Za-z][\w-]*[A-Za-z0-9_]|#[A-Za-z]`)
+
+// HashtagBaseURL is the URL prefix of tag pages; the lowercased tag is appended
+var HashtagBaseURL = "/tags/"
+
+// Spans never containing hashtags: links, raw anchors, HTML tags and bare URLs
+var hashtagProtectedRegex = regexp.MustCompile(`!?\[[^\]]*\]\([^)]*\)|<a\b[^>]*>.*?</a>|<[^>]+>|https?://\S+`)
+
+// HashtagPreprocessor links #hashtags in prose to HashtagBaseURL + tag, below BasePath
+// Heading markers, code, links and URLs are left alone
+func HashtagPreprocessor(markdown string, _ string) string {
+	if !HashtagLinks || !strings.Contains(markdown, "#") {
+		return markdown
+	}
+
+	return forEachHashtagLine(markdown, func(text string) string {
+		return replaceHashtags(text, func(tag string) string {
+			return `<a href="` + JoinBasePath(BasePath, HashtagBaseURL+normalizeHashtag(tag)) + `" class="hashtag">#` + tag + `</a>`
+		})
+	})
+}
+
+// ExtractHashtags returns the unique lowercased hashtags of a document in order of appearance
+// It applies the same rules as HashtagPreprocessor, so tags can be registered for the document
+func ExtractHashtags(markdown string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+
+	forEachHashtagLine(markdown, func(text string) string {
+		return replaceHashtags(text, func(tag string) string {
+			tag = normalizeHashtag(tag)
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+			return "#" + tag
+		})
+	})
+
+	return tags
+}
+
+// forEachHashtagLine applies fn to the prose of each line that may contain hashtags
+// skipping code blocks, inline code, headings and protected spans
+func forEachHashtagLine(markdown string, fn func(string) string) string {
+	lines := strings.Split(markdown, "\n")
+	var result []string
+
+	inCodeBlock := false
+	headingRegex := regexp.MustCompile(`^ {0,3}#{1,6}(\s|$)`)
+
+	for _, line := range lines {
+		// Check if this line starts or ends a code block
+		trimmedLine := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			result = append(result, line)
+			continue
+		}
+
+		// Code blocks and headings are never processed
+		if inCodeBlock || headingRegex.MatchString(line) || !strings.Contains(line, "#") {
+			result = append(result, line)
+			continue
+		}
+
+		// Process each segment of the line, preserving inline code
+		segments := strings.Split(line, "`")
+		for i := 0; i < len(segments); i += 2 {
+			segments[i] = replaceOutside(segments[i], hashtagProtectedRegex, fn)
+		}
+		result = append(result, strings.Join(segments, "`"))
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// replaceHashtags replaces every hashtag that starts a word with fn(tag), tag without the #
+func replaceHashtags(text string, fn func(string) string) string {
+	var builder strings.Builder
+	lastEnd := 0
+
+	for _, match := range HashtagPattern.FindAllStringIndex(text, -1) {
+		start, end := match[0], match[1]
+
+		// Only count hashtags at the start of a word, e.g. not in "C#" or "&#39;"
+		if start > 0 && !strings.ContainsRune(" \t([", rune(text[start-1])) {
+			continue
+		}
+		// Not a hashtag when glued to more punctuation-free text, e.g. "#tag#other"
+		if end < len(text) && (isWordByte(text, end) || text[end] == '#') {
+			continue
+		}
+
+		builder.WriteString(text[lastEnd:start])
+		builder.WriteString(fn(text[start+1 : end]))
+		lastEnd = end
+	}
+
+	builder.WriteString(text[lastEnd:])
+	return builder.String()
+}
+
+// normalizeHashtag returns the canonical form of a tag used in tag page URLs
+func normalizeHashtag(tag string) string {
+	return strings.ToLower(tag)
+}
@@ -0,0 +1,76 @@
+package goldext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashtagPreprocessor(t *testing.T) {
+	HashtagLinks = true
+	defer func() { HashtagLinks = false }()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Hashtag in prose",
+			input:    "See #Release-Notes for details.",
+			expected: `See <a href="/tags/release-notes" class="hashtag">#Release-Notes</a> for details.`,
+		},
+		{
+			name:     "Hashtag at line start is not a heading",
+			input:    "#draft needs review",
+			expected: `<a href="/tags/draft" class="hashtag">#draft</a> needs review`,
+		},
+		{
+			name:     "Heading markers are left alone",
+			input:    "# Title\n## Section #tag",
+			expected: "# Title\n## Section #tag",
+		},
+		{
+			name:     "Code is left alone",
+			input:    "Use `#tag` here\n```\n#tag\n```",
+			expected: "Use `#tag` here\n```\n#tag\n```",
+		},
+		{
+			name:     "Links, anchors and URLs are left alone",
+			input:    "[#tag](/x) <a href=\"#top\">#top</a> https://example.com/#frag",
+			expected: "[#tag](/x) <a href=\"#top\">#top</a> https://example.com/#frag",
+		},
+		{
+			name:     "Not at a word start",
+			input:    "C# and a#b and &#39; and #123 and #tag#other",
+			expected: "C# and a#b and &#39; and #123 and #tag#other",
+		},
+		{
+			name:     "Trailing punctuation is not part of the tag",
+			input:    "(#go-lang-).",
+			expected: `(<a href="/tags/go-lang" class="hashtag">#go-lang</a>-).`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := HashtagPreprocessor(tt.input, "")
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestHashtagPreprocessorDisabledByDefault(t *testing.T) {
+	if result := HashtagPreprocessor("See #tag", ""); result != "See #tag" {
+		t.Errorf("Expected hashtags to be left alone by default, got: %q", result)
+	}
+}
+
+func TestExtractHashtags(t *testing.T) {
+	tags := ExtractHashtags("# Heading\n#Go and #go and #docs\n`#code`\n")
+	expected := []string{"go", "docs"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("Expected %v, got %v", expected, tags)
+	}
+}
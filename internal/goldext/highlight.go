@@ -5,7 +5,23 @@ import (
 	"strings"
 )
 
+// HighlightColors lists the colors accepted by ==text=={.color} and ==text==[color]
+// Each color maps to a "highlight-<color>" class on the rendered <mark> element
+var HighlightColors = []string{"yellow", "green", "blue", "pink", "orange", "red", "purple"}
+
+// highlightClass returns the class attribute for an allowlisted color, or "" for unknown colors
+func highlightClass(color string) string {
+	color = strings.ToLower(color)
+	for _, allowed := range HighlightColors {
+		if color == allowed {
+			return ` class="highlight-` + color + `"`
+		}
+	}
+	return ""
+}
+
 // HighlightPreprocessor adds support for ==highlighted text==
+// An optional ==text=={.color} or ==text==[color] suffix selects a highlight color
 // It correctly handles code blocks, math blocks, and other special sections
 func HighlightPreprocessor(markdown string, _ string) string {
 	// Process line by line instead of relying on regex which might fail on large documents
@@ -13,7 +29,7 @@ func HighlightPreprocessor(markdown string, _ string) string {
 	var result []string
 
 	inCodeBlock := false
-	highlightRegex := regexp.MustCompile(`([^=]|^)==([^=\n]+?)==([^=]|$)`)
+	highlightRegex := regexp.MustCompile(`([^=]|^)==([^=\n]+?)==(?:\{\.([A-Za-z][\w-]*)\}|\[([A-Za-z][\w-]*)\])?([^=]|$)`)
 
 	for _, line := range lines {
 		// Check if this line starts or ends a code block
@@ -39,7 +55,21 @@ func HighlightPreprocessor(markdown string, _ string) string {
 			if i%2 == 0 {
 				// Apply highlight replacement to non-code segments
 				for {
-					replaced := highlightRegex.ReplaceAllString(segment, "$1<mark>$2</mark>$3")
+					replaced := highlightRegex.ReplaceAllStringFunc(segment, func(match string) string {
+						parts := highlightRegex.FindStringSubmatch(match)
+						before, text, attrColor, bracketColor, after := parts[1], parts[2], parts[3], parts[4], parts[5]
+
+						// A [word] followed by ( is a markdown link, not a color
+						if bracketColor != "" && after == "(" {
+							return before + "<mark>" + text + "</mark>[" + bracketColor + "]" + after
+						}
+
+						color := attrColor
+						if color == "" {
+							color = bracketColor
+						}
+						return before + "<mark" + highlightClass(color) + ">" + text + "</mark>" + after
+					})
 					if replaced == segment {
 						break // No more matches
 					}
@@ -0,0 +1,62 @@
+package goldext
+
+import (
+	"testing"
+)
+
+func TestHighlightPreprocessor(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Default highlight",
+			input:    "This is ==important== text.",
+			expected: "This is <mark>important</mark> text.",
+		},
+		{
+			name:     "Attribute color",
+			input:    "This is ==important=={.yellow} text.",
+			expected: `This is <mark class="highlight-yellow">important</mark> text.`,
+		},
+		{
+			name:     "Bracket color",
+			input:    "This is ==important==[green] text.",
+			expected: `This is <mark class="highlight-green">important</mark> text.`,
+		},
+		{
+			name:     "Unknown color falls back to default",
+			input:    "This is ==important=={.chartreuse} text.",
+			expected: "This is <mark>important</mark> text.",
+		},
+		{
+			name:     "Link after highlight is preserved",
+			input:    "==see==[docs](https://example.com)",
+			expected: "<mark>see</mark>[docs](https://example.com)",
+		},
+		{
+			name:     "Inline code is untouched",
+			input:    "`==code==[red]` and ==text==[red]",
+			expected: "`==code==[red]` and <mark class=\"highlight-red\">text</mark>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := HighlightPreprocessor(tt.input, "")
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+
+	for _, color := range HighlightColors {
+		t.Run("Color "+color, func(t *testing.T) {
+			expected := `<mark class="highlight-` + color + `">x</mark>`
+			if result := HighlightPreprocessor("==x=={."+color+"}", ""); result != expected {
+				t.Errorf("Expected: %q, got: %q", expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package goldext
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// SanitizeAllowedTags are the raw HTML elements kept by SanitizeRawHTML
+var SanitizeAllowedTags = map[string]bool{
+	"a": true, "abbr": true, "b": true, "blockquote": true, "br": true, "caption": true,
+	"cite": true, "code": true, "dd": true, "del": true, "details": true, "dfn": true,
+	"div": true, "dl": true, "dt": true, "em": true, "figcaption": true, "figure": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "hr": true,
+	"i": true, "img": true, "ins": true, "kbd": true, "li": true, "mark": true, "ol": true,
+	"p": true, "pre": true, "q": true, "s": true, "samp": true, "small": true, "span": true,
+	"strong": true, "sub": true, "summary": true, "sup": true, "table": true, "tbody": true,
+	"td": true, "tfoot": true, "th": true, "thead": true, "time": true, "tr": true, "u": true,
+	"ul": true, "var": true,
+}
+
+// SanitizeAllowedAttributes are the attributes kept on allowed elements
+// href and src are only kept with a relative URL or an http, https, mailto or tel scheme.
+var SanitizeAllowedAttributes = map[string]bool{
+	"align": true, "alt": true, "class": true, "colspan": true, "datetime": true, "dir": true,
+	"height": true, "href": true, "id": true, "lang": true, "open": true, "rowspan": true,
+	"src": true, "start": true, "title": true, "type": true, "width": true,
+}
+
+var (
+	sanitizeTagRegex      = regexp.MustCompile(`^<(/?)([A-Za-z][A-Za-z0-9-]*)((?:\s+[^\s"'>/=]+(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?)*)\s*(/?)>`)
+	sanitizeAttrRegex     = regexp.MustCompile(`([^\s"'>/=]+)(?:\s*=\s*("[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?`)
+	sanitizeAutolinkRegex = regexp.MustCompile(`^<(?:(?i:https?|mailto):[^\s<>]*|[A-Za-z0-9.!#$%&'*+/=?^_{|}~-]+@[A-Za-z0-9](?:[A-Za-z0-9-.]*[A-Za-z0-9])?)>`)
+	sanitizeSchemeRegex   = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]*):`)
+)
+
+// SanitizeRawHTML makes the raw HTML of untrusted markdown safe to render
+// Elements and attributes outside the allowlists are escaped or dropped, so formatting
+// like <kbd> or <details> survives while scripts, styles, event handlers and javascript:
+// URLs don't. Code blocks and inline code are left alone, except for rtl and ltr blocks,
+// whose content is markdown; markdown autolinks are kept.
+// It must run before preprocessors add their own HTML.
+func SanitizeRawHTML(markdown string) string {
+	if !strings.Contains(markdown, "<") {
+		return markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+	inCodeBlock := false
+	inDirectionBlock := false
+
+	for i, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// rtl and ltr blocks are rendered as markdown, so their HTML is sanitized too
+		if !inCodeBlock && strings.HasPrefix(trimmedLine, "```") {
+			if _, ok := directionFence(strings.TrimPrefix(trimmedLine, "```")); ok && !inDirectionBlock {
+				inDirectionBlock = true
+				continue
+			}
+			if trimmedLine == "```" && inDirectionBlock {
+				inDirectionBlock = false
+				continue
+			}
+		}
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock || !strings.Contains(line, "<") {
+			continue
+		}
+
+		// Skip inline code
+		parts := strings.Split(line, "`")
+		for j := 0; j < len(parts); j += 2 {
+			parts[j] = sanitizeLineHTML(parts[j])
+		}
+		lines[i] = strings.Join(parts, "`")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// SanitizeUntrustedMarkdown protects the math of untrusted markdown and sanitizes its raw
+// HTML. Math is escaped when it is restored, so SanitizeRawHTML must not escape it first.
+func SanitizeUntrustedMarkdown(markdown string, docPath string) string {
+	return SanitizeRawHTML(MathPreprocessor(markdown, docPath))
+}
+
+// sanitizeLineHTML sanitizes every tag of a piece of a line
+// Any < that could start markup without being an allowed tag is escaped, which
+// also covers tags and comments continuing on the next line.
+func sanitizeLineHTML(text string) string {
+	var sb strings.Builder
+	for {
+		i := strings.IndexByte(text, '<')
+		if i < 0 {
+			sb.WriteString(text)
+			return sb.String()
+		}
+		sb.WriteString(text[:i])
+		text = text[i:]
+
+		if m := sanitizeAutolinkRegex.FindString(text); m != "" {
+			sb.WriteString(m)
+			text = text[len(m):]
+			continue
+		}
+
+		if m := sanitizeTagRegex.FindStringSubmatch(text); m != nil && SanitizeAllowedTags[strings.ToLower(m[2])] {
+			sb.WriteString(sanitizeTag(m[1] == "/", strings.ToLower(m[2]), m[3], m[4] == "/"))
+			text = text[len(m[0]):]
+			continue
+		}
+
+		if len(text) > 1 && strings.ContainsAny(text[1:2], "/!?abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+			sb.WriteString("&lt;")
+		} else {
+			sb.WriteByte('<')
+		}
+		text = text[1:]
+	}
+}
+
+// sanitizeTag rebuilds an allowed tag with only its allowed attributes
+func sanitizeTag(closing bool, name string, attributes string, selfClosing bool) string {
+	if closing {
+		return "</" + name + ">"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<" + name)
+	for _, attr := range sanitizeAttrRegex.FindAllStringSubmatch(attributes, -1) {
+		attrName := strings.ToLower(attr[1])
+		if !SanitizeAllowedAttributes[attrName] {
+			continue
+		}
+
+		value := attr[2]
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+			value = value[1 : len(value)-1]
+		}
+		value = html.UnescapeString(value)
+
+		if (attrName == "href" || attrName == "src") && !isSafeURL(value) {
+			continue
+		}
+		if attr[2] == "" {
+			sb.WriteString(" " + attrName)
+			continue
+		}
+		sb.WriteString(" " + attrName + `="` + html.EscapeString(value) + `"`)
+	}
+	if selfClosing {
+		sb.WriteString(" /")
+	}
+	sb.WriteString(">")
+	return sb.String()
+}
+
+// isSafeURL reports whether a URL is relative or uses a harmless scheme
+func isSafeURL(value string) bool {
+	// Browsers ignore whitespace and control characters inside schemes
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, value)
+
+	m := sanitizeSchemeRegex.FindStringSubmatch(cleaned)
+	if m == nil {
+		return true
+	}
+	switch strings.ToLower(m[1]) {
+	case "http", "https", "mailto", "tel":
+		return true
+	}
+	return false
+}
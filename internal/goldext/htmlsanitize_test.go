@@ -0,0 +1,30 @@
+package goldext
+
+import "testing"
+
+func TestSanitizeRawHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Formatting survives", `Press <kbd>Ctrl</kbd> <span class="x" style="color:red">now</span>`, `Press <kbd>Ctrl</kbd> <span class="x">now</span>`},
+		{"Scripts are escaped", `<script>alert(1)</script>`, `&lt;script>alert(1)&lt;/script>`},
+		{"Event handlers are dropped", `<img src="a.png" onerror="alert(1)" alt='A'>`, `<img src="a.png" alt="A">`},
+		{"Unsafe URLs are dropped", `<a href="jav&#x09;ascript:alert(1)" title="t">x</a> <a href="https://example.com/?a=1&amp;b=2">y</a>`, `<a title="t">x</a> <a href="https://example.com/?a=1&amp;b=2">y</a>`},
+		{"Tags spanning lines", "<div\nonclick=\"alert(1)\">", "&lt;div\nonclick=\"alert(1)\">"},
+		{"Comments and iframes", `<!-- x --><iframe src="https://evil.example"></iframe>`, `&lt;!-- x -->&lt;iframe src="https://evil.example">&lt;/iframe>`},
+		{"Autolinks and comparisons are kept", `<https://example.com> <me@example.com> a < b`, `<https://example.com> <me@example.com> a < b`},
+		{"Self-closing and boolean attributes", `<br/><details open><summary>More</summary></details>`, `<br /><details open><summary>More</summary></details>`},
+		{"Code is left alone", "`<script>`\n\n```html\n<script>x()</script>\n```", "`<script>`\n\n```html\n<script>x()</script>\n```"},
+		{"Direction blocks are markdown", "```rtl\n<img src=x onerror=alert(1)>\n```html\n<b onclick=x()>\n```\n```", "```rtl\n<img src=\"x\">\n```html\n<b onclick=x()>\n```\n```"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := SanitizeRawHTML(tt.input); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package goldext
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IncludeRoot is the documents directory {{include: ...}} paths are resolved against
+var IncludeRoot = filepath.Join("data", "documents")
+
+// IncludeMaxDepth limits how deeply included documents may include further documents
+var IncludeMaxDepth = 5
+
+var includeRegex = regexp.MustCompile(`^\s*\{\{\s*include:\s*([^}]+?)\s*\}\}\s*$`)
+
+// IncludePreprocessor replaces {{include: path/to/doc}} lines with the markdown of that document
+// Relative paths are resolved against the including document, /paths against the documents root.
+// It runs before the other preprocessors, so the included markdown goes through the whole chain
+// with the rest of the document; its relative links and wikilinks are resolved against its own path.
+// Missing documents, include cycles and too deep nesting render an error block instead.
+func IncludePreprocessor(markdown string, docPath string) string {
+	return IncludePreprocessorWithFiles(nil)(markdown, docPath)
+}
+
+// IncludePreprocessorWithFiles returns an IncludePreprocessor reading the included documents
+// from files, or from the operating system's filesystem when files is nil
+func IncludePreprocessorWithFiles(files FileSystem) Preprocessor {
+	return func(markdown string, docPath string) string {
+		if !strings.Contains(markdown, "{{") {
+			return markdown
+		}
+		docPath = strings.Trim(docPath, "/")
+		return expandIncludes(markdown, docPath, []string{docPath}, false, filesOrOS(files))
+	}
+}
+
+// UntrustedIncludePreprocessor expands includes like IncludePreprocessor, reducing the raw
+// HTML of every included document with SanitizeUntrustedMarkdown. Untrusted renderings run it
+// right after sanitizing the document itself, since the included markdown never went through that.
+func UntrustedIncludePreprocessor(markdown string, docPath string) string {
+	return UntrustedIncludePreprocessorWithFiles(nil)(markdown, docPath)
+}
+
+// UntrustedIncludePreprocessorWithFiles returns an UntrustedIncludePreprocessor reading the
+// included documents from files, or from the operating system's filesystem when files is nil
+func UntrustedIncludePreprocessorWithFiles(files FileSystem) Preprocessor {
+	return func(markdown string, docPath string) string {
+		if !strings.Contains(markdown, "{{") {
+			return markdown
+		}
+		docPath = strings.Trim(docPath, "/")
+		return expandIncludes(markdown, docPath, []string{docPath}, true, filesOrOS(files))
+	}
+}
+
+// expandIncludes inlines the includes of a document; stack holds the documents being included
+// and sanitize tells whether their raw HTML is sanitized
+func expandIncludes(markdown string, docPath string, stack []string, sanitize bool, files FileSystem) string {
+	if !strings.Contains(markdown, "include:") {
+		return markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+	inCodeBlock := false
+
+	for i, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock {
+			continue
+		}
+
+		if m := includeRegex.FindStringSubmatch(line); m != nil {
+			lines[i] = includeDocument(m[1], docPath, stack, sanitize, files)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// includeDocument returns the prepared markdown of an included document or an error block
+func includeDocument(target string, docPath string, stack []string, sanitize bool, files FileSystem) string {
+	includePath, ok := resolveIncludePath(target, docPath)
+	if !ok {
+		return renderIncludeError("Invalid include path: " + target)
+	}
+
+	for _, including := range stack {
+		if including == includePath {
+			return renderIncludeError("Include cycle: " + strings.Join(append(stack, includePath), " → "))
+		}
+	}
+	if len(stack) > IncludeMaxDepth {
+		return renderIncludeError(fmt.Sprintf("Includes nested deeper than %d levels: %s", IncludeMaxDepth, includePath))
+	}
+
+	content, err := files.ReadFile(filepath.Join(IncludeRoot, filepath.FromSlash(includePath), "document.md"))
+	if err != nil {
+		return renderIncludeError("Document not found: " + includePath)
+	}
+
+	snippet := FrontmatterPreprocessor(string(content), includePath)
+	if sanitize {
+		snippet = SanitizeUntrustedMarkdown(snippet, includePath)
+	}
+	snippet = expandIncludes(snippet, includePath, append(stack[:len(stack):len(stack)], includePath), sanitize, files)
+
+	// Resolve the snippet's own relative references before it joins the including document
+	snippet = WikilinkPreprocessorWithFiles(files)(snippet, includePath)
+	snippet = LinkPreprocessor(snippet, includePath)
+
+	// Keep the snippet in its own blocks
+	return "\n" + strings.TrimSpace(snippet) + "\n"
+}
+
+// resolveIncludePath returns the document path an include target points to
+// Paths may use .. but not leave the documents root.
+func resolveIncludePath(target string, docPath string) (string, bool) {
+	target = strings.TrimSpace(target)
+
+	var segments []string
+	if !strings.HasPrefix(target, "/") && docPath != "" {
+		segments = strings.Split(docPath, "/")
+	}
+
+	for _, segment := range strings.Split(strings.Trim(target, "/"), "/") {
+		switch segment {
+		case "", ".":
+		case "..":
+			if len(segments) == 0 {
+				return "", false
+			}
+			segments = segments[:len(segments)-1]
+		default:
+			segments = append(segments, segment)
+		}
+	}
+
+	if len(segments) == 0 {
+		return "", false
+	}
+	return strings.Join(segments, "/"), true
+}
+
+// renderIncludeError renders a visible error in place of an include
+func renderIncludeError(message string) string {
+	return "\n" + `<div class="include-error" role="alert">` + html.EscapeString(message) + "</div>\n"
+}
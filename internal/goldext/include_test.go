@@ -0,0 +1,131 @@
+package goldext
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIncludePreprocessor(t *testing.T) {
+	root := t.TempDir()
+	documents := map[string]string{
+		"shared/install": "---\nauthor: me\n---\n# Install\n\n![Step](step.png)",
+		"shared/outer":   "Before\n{{include: ../install}}\nAfter",
+		"loop/a":         "{{include: /loop/b}}",
+		"loop/b":         "{{include: ../a}}",
+	}
+	for docPath, content := range documents {
+		dir := filepath.Join(root, filepath.FromSlash(docPath))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "document.md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	IncludeRoot = root
+	defer func() { IncludeRoot = filepath.Join("data", "documents") }()
+
+	tests := []struct {
+		name     string
+		input    string
+		docPath  string
+		contains []string
+	}{
+		{
+			name:     "Relative to the document",
+			input:    "Intro\n{{include: install}}\nOutro",
+			docPath:  "shared",
+			contains: []string{"Intro\n\n# Install\n\n![Step](/api/files/shared/install/step.png)\n\nOutro"},
+		},
+		{
+			name:     "From the root with nested include",
+			input:    "{{ include: /shared/outer }}",
+			docPath:  "guides/page",
+			contains: []string{"Before\n\n# Install", "After"},
+		},
+		{
+			name:     "Cycle",
+			input:    "{{include: /loop/a}}",
+			docPath:  "guides/page",
+			contains: []string{`<div class="include-error" role="alert">Include cycle: guides/page → loop/a → loop/b → loop/a</div>`},
+		},
+		{
+			name:     "Missing document",
+			input:    "{{include: /nowhere}}",
+			contains: []string{`<div class="include-error" role="alert">Document not found: nowhere</div>`},
+		},
+		{
+			name:     "Outside the documents root",
+			input:    "{{include: ../../etc}}",
+			docPath:  "guides",
+			contains: []string{`<div class="include-error" role="alert">Invalid include path: ../../etc</div>`},
+		},
+		{
+			name:     "Inside a code block",
+			input:    "```\n{{include: /shared/install}}\n```",
+			contains: []string{"```\n{{include: /shared/install}}\n```"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := IncludePreprocessor(tt.input, tt.docPath)
+			for _, want := range tt.contains {
+				if !strings.Contains(result, want) {
+					t.Errorf("Expected %q in result, got: %q", want, result)
+				}
+			}
+		})
+	}
+}
+
+func TestIncludeMaxDepth(t *testing.T) {
+	root := t.TempDir()
+	documents := map[string]string{
+		"a":     "{{include: b}}",
+		"a/b":   "{{include: c}}",
+		"a/b/c": "Deepest",
+	}
+	for docPath, content := range documents {
+		dir := filepath.Join(root, filepath.FromSlash(docPath))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "document.md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	IncludeRoot = root
+	defer func() { IncludeRoot = filepath.Join("data", "documents") }()
+
+	IncludeMaxDepth = 2
+	defer func() { IncludeMaxDepth = 5 }()
+
+	result := IncludePreprocessor("{{include: /a}}", "")
+	if !strings.Contains(result, "Includes nested deeper than 2 levels: a/b/c") || strings.Contains(result, "Deepest") {
+		t.Errorf("Expected the depth limit to stop the third include, got: %q", result)
+	}
+}
+
+func TestProcessMarkdownWithFiles(t *testing.T) {
+	files := fstest.MapFS{
+		"data/documents/shared/notice/document.md": {Data: []byte("Read from memory, see [[/guides/setup]].")},
+		"data/documents/guides/setup/document.md":  {Data: []byte("# Setting up")},
+	}
+
+	result := ProcessMarkdownWithFiles("{{include: /shared/notice}}", "guides", files)
+	if !strings.Contains(result, "Read from memory") {
+		t.Errorf("Expected the included document from files, got: %q", result)
+	}
+	if !strings.Contains(result, `<a href="/guides/setup" class="wikilink">Setting up</a>`) {
+		t.Errorf("Expected the wikilink resolved in files, got: %q", result)
+	}
+
+	// Without files the documents are looked up on disk, where they don't exist
+	if result := ProcessMarkdown("{{include: /shared/notice}}", "guides"); !strings.Contains(result, "Document not found") {
+		t.Errorf("Expected the include to be missing on disk, got: %q", result)
+	}
+}
@@ -3,19 +3,63 @@ package goldext
 import (
 	"fmt"
 	"net/url"
+	"path"
 	"regexp"
+	"sort"
 	"strings"
 )
 
 // Preprocessor defines a function that transforms markdown before rendering
 type Preprocessor func(markdown string, docPath string) string
 
-// RegisteredPreprocessors holds all registered preprocessors
+// RegisteredPreprocessors holds all registered preprocessors in the order they run
+// Use RegisterPreprocessor to add to it.
 var RegisteredPreprocessors []Preprocessor
 
-// RegisterPreprocessor adds a preprocessor to the list
-func RegisterPreprocessor(pp Preprocessor) {
-	RegisteredPreprocessors = append(RegisteredPreprocessors, pp)
+// registeredPreprocessor is a preprocessor with its registration name and priority
+// withFiles, when set, returns the preprocessor reading other documents from a FileSystem.
+type registeredPreprocessor struct {
+	name      string
+	priority  int
+	fn        Preprocessor
+	withFiles func(FileSystem) Preprocessor
+}
+
+// preprocessorRegistry holds the registrations RegisteredPreprocessors is built from
+var preprocessorRegistry []registeredPreprocessor
+
+// RegisterPreprocessor adds a preprocessor to the chain
+// Preprocessors run in ascending priority, like Goldmark's util.Prioritized; equal
+// priorities run in registration order. The built-in preprocessors are registered in
+// load.go in steps of 100, so others can be run between them. Registering a name
+// again replaces the earlier registration.
+func RegisterPreprocessor(name string, priority int, fn Preprocessor) {
+	register(registeredPreprocessor{name: name, priority: priority, fn: fn})
+}
+
+// registerFilePreprocessor registers a built-in preprocessor reading other documents,
+// which ProcessMarkdownWithFiles can point at another FileSystem
+func registerFilePreprocessor(name string, priority int, withFiles func(FileSystem) Preprocessor) {
+	register(registeredPreprocessor{name: name, priority: priority, fn: withFiles(nil), withFiles: withFiles})
+}
+
+// register adds a registration to the chain, replacing one of the same name
+func register(preprocessor registeredPreprocessor) {
+	for i, registered := range preprocessorRegistry {
+		if registered.name == preprocessor.name {
+			preprocessorRegistry = append(preprocessorRegistry[:i], preprocessorRegistry[i+1:]...)
+			break
+		}
+	}
+	preprocessorRegistry = append(preprocessorRegistry, preprocessor)
+	sort.SliceStable(preprocessorRegistry, func(i, j int) bool {
+		return preprocessorRegistry[i].priority < preprocessorRegistry[j].priority
+	})
+
+	RegisteredPreprocessors = make([]Preprocessor, len(preprocessorRegistry))
+	for i, registered := range preprocessorRegistry {
+		RegisteredPreprocessors[i] = registered.fn
+	}
 }
 
 // ProcessMarkdown applies all registered preprocessors to the markdown
@@ -27,6 +71,44 @@ func ProcessMarkdown(markdown string, docPath string) string {
 	return result
 }
 
+// ProcessMarkdownWithFiles applies all registered preprocessors like ProcessMarkdown, with
+// included documents and wikilink targets read from files instead of the operating
+// system's filesystem. A nil files is the same as ProcessMarkdown.
+func ProcessMarkdownWithFiles(markdown string, docPath string, files FileSystem) string {
+	if files == nil {
+		return ProcessMarkdown(markdown, docPath)
+	}
+
+	result := markdown
+	for _, registered := range preprocessorRegistry {
+		preprocessor := registered.fn
+		if registered.withFiles != nil {
+			preprocessor = registered.withFiles(files)
+		}
+		result = preprocessor(result, docPath)
+	}
+	return result
+}
+
+// Stage is the markdown after one preprocessor of ProcessMarkdownTraced ran
+type Stage struct {
+	Name   string // Registration name of the preprocessor, e.g. mermaid
+	Output string
+}
+
+// ProcessMarkdownTraced applies all registered preprocessors like ProcessMarkdown and
+// returns the markdown after each of them, so a mangled block can be traced to the
+// preprocessor changing it. This is meant for debugging; rendering never uses it.
+func ProcessMarkdownTraced(markdown string, docPath string) []Stage {
+	stages := make([]Stage, 0, len(preprocessorRegistry))
+	result := markdown
+	for _, registered := range preprocessorRegistry {
+		result = registered.fn(result, docPath)
+		stages = append(stages, Stage{Name: registered.name, Output: result})
+	}
+	return stages
+}
+
 // Section represents a piece of markdown content that should or shouldn't be processed
 type Section struct {
 	content string
@@ -105,6 +187,8 @@ func joinSections(sections []Section) string {
 }
 
 // LinkPreprocessor resolves local file references
+// Relative image, link and link reference destinations become /api/files/ URLs below the
+// document's directory, so the renderer treats them like uploaded files linked directly; absolute paths, #fragments and URLs with a scheme are left untouched.
 func LinkPreprocessor(markdown string, docPath string) string {
 	// This is a simplified implementation
 	// A more robust version would use a proper Markdown parser
@@ -124,13 +208,13 @@ func LinkPreprocessor(markdown string, docPath string) string {
 				}
 
 				alt := parts[1]
-				path := parts[2]
+				destination, title := splitLinkDestination(parts[2])
 
-				if isLocalPath(path) {
-					path = resolveLocalPath(path, docPath)
+				if !isLocalPath(destination) {
+					return match
 				}
 
-				return "![" + alt + "](" + path + ")"
+				return "![" + alt + "](" + resolveLocalPath(destination, docPath) + title + ")"
 			})
 
 			// Process regular links: [text](local-path)
@@ -142,13 +226,25 @@ func LinkPreprocessor(markdown string, docPath string) string {
 				}
 
 				text := parts[1]
-				path := parts[2]
+				destination, title := splitLinkDestination(parts[2])
+
+				if !isLocalPath(destination) {
+					return match
+				}
 
-				if isLocalPath(path) {
-					path = resolveLocalPath(path, docPath)
+				return "[" + text + "](" + resolveLocalPath(destination, docPath) + title + ")"
+			})
+
+			// Process reference definitions: [label]: local-path
+			sections[i].content = linkReferenceDefinitionRegex.ReplaceAllStringFunc(sections[i].content, func(match string) string {
+				parts := linkReferenceDefinitionRegex.FindStringSubmatch(match)
+				destination := strings.TrimSuffix(strings.TrimPrefix(parts[2], "<"), ">")
+
+				if !isLocalPath(destination) {
+					return match
 				}
 
-				return "[" + text + "](" + path + ")"
+				return parts[1] + resolveLocalPath(destination, docPath) + parts[3]
 			})
 		}
 	}
@@ -157,10 +253,40 @@ func LinkPreprocessor(markdown string, docPath string) string {
 	return joinSections(sections)
 }
 
+// linkReferenceDefinitionRegex matches a link reference definition, e.g. [spec]: spec.pdf
+// Footnote definitions, [^1]: ..., aren't matched.
+var linkReferenceDefinitionRegex = regexp.MustCompile(`(?m)^( {0,3}\[[^\]^][^\]]*\]:[ \t]*)(<[^>]*>|\S+)(.*)$`)
+
+// linkSchemeRegex matches the scheme of a URL, e.g. https: or tel:
+var linkSchemeRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*:`)
+
+// linkTitleRegex matches a destination followed by a quoted link title
+var linkTitleRegex = regexp.MustCompile(`^(.*?)(\s+(?:"[^"]*"|'[^']*'))\s*$`)
+
+// splitLinkDestination splits the inside of a markdown link's (...) into the destination
+// and the optional title that follows it, e.g. `diagram.png "A diagram"`
+// Destinations in <angle brackets> lose the brackets; other destinations may contain spaces.
+func splitLinkDestination(inside string) (string, string) {
+	inside = strings.TrimSpace(inside)
+	title := ""
+	if m := linkTitleRegex.FindStringSubmatch(inside); m != nil {
+		inside, title = m[1], m[2]
+	}
+	if strings.HasPrefix(inside, "<") && strings.HasSuffix(inside, ">") {
+		inside = inside[1 : len(inside)-1]
+	}
+	return inside, title
+}
+
 // isLocalPath returns true if the path is a local file reference
 func isLocalPath(path string) bool {
+	// Skip empty destinations
+	if path == "" {
+		return false
+	}
+
 	// Skip URLs with schemes (http://, https://, ftp://, etc)
-	if strings.Contains(path, "://") {
+	if strings.Contains(path, "://") || linkSchemeRegex.MatchString(path) {
 		return false
 	}
 
@@ -189,23 +315,27 @@ func isLocalPath(path string) bool {
 }
 
 // resolveLocalPath resolves a local path relative to the document path
-func resolveLocalPath(path, docPath string) string {
-	// Remove any leading slashes from docPath
-	docPath = strings.TrimLeft(docPath, "/")
+// A ?query or #fragment is kept; .. segments are resolved but can't leave the documents root.
+func resolveLocalPath(localPath, docPath string) string {
+	// Remove any leading or trailing slashes from docPath
+	docPath = strings.Trim(docPath, "/")
+
+	// Homepage files are stored in "pages/home"
+	if docPath == "" {
+		docPath = "pages/home"
+	}
 
-	// URL encode the filename to handle spaces and special characters
-	escapedPath := url.PathEscape(path)
+	suffix := ""
+	if i := strings.IndexAny(localPath, "?#"); i >= 0 {
+		localPath, suffix = localPath[:i], localPath[i:]
+	}
 
-	// Handle the homepage special case
-	if docPath == "" || docPath == "/" {
-		// Homepage files are stored in "pages/home"
-		result := "/api/files/pages/home/" + escapedPath
-		// fmt.Printf("[resolveLocalPath] docPath: '%s', path: '%s', result: '%s' (homepage)\n", docPath, path, result)
-		return result
+	// Already escaped paths are escaped again below
+	if unescaped, err := url.PathUnescape(localPath); err == nil {
+		localPath = unescaped
 	}
 
-	// Regular document files
-	result := "/api/files/" + docPath + "/" + escapedPath
-	// fmt.Printf("[resolveLocalPath] docPath: '%s', path: '%s', result: '%s'\n", docPath, path, result)
-	return result
+	// URL encode the path segments to handle spaces and special characters
+	resolved := path.Join("/", docPath, localPath)
+	return "/api/files" + (&url.URL{Path: resolved}).EscapedPath() + suffix
 }
@@ -0,0 +1,89 @@
+package goldext
+
+import "testing"
+
+func TestLinkPreprocessor(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		docPath  string
+		expected string
+	}{
+		{"Relative image", "![diagram](diagram.png)", "guides/setup", "![diagram](/api/files/guides/setup/diagram.png)"},
+		{"Subdirectory and title", `![shot](img/my shot.png "Screen")`, "guides", `![shot](/api/files/guides/img/my%20shot.png "Screen")`},
+		{"Parent directory", "[Spec](../shared/spec.pdf)", "guides/setup", "[Spec](/api/files/guides/shared/spec.pdf)"},
+		{"Cannot leave the root", "[x](../../../etc/passwd)", "guides", "[x](/api/files/etc/passwd)"},
+		{"Query and fragment kept", "[Manual](manual.pdf#page=2)", "docs", "[Manual](/api/files/docs/manual.pdf#page=2)"},
+		{"Already escaped", "![a](flow%20chart.png)", "my docs", "![a](/api/files/my%20docs/flow%20chart.png)"},
+		{"Angle brackets", "![a](<my file.png>)", "docs", "![a](/api/files/docs/my%20file.png)"},
+		{"Homepage", "![logo](logo.png)", "", "![logo](/api/files/pages/home/logo.png)"},
+		{"Absolute path", "[Home](/guides/start) ![x](/api/files/a/b.png)", "docs", "[Home](/guides/start) ![x](/api/files/a/b.png)"},
+		{"Fragment", "[Top](#top)", "docs", "[Top](#top)"},
+		{"External URLs", "[a](https://example.com/x) [b](mailto:me@example.com) [c](tel:+123) ![d](//cdn.example.com/d.png)", "docs", "[a](https://example.com/x) [b](mailto:me@example.com) [c](tel:+123) ![d](//cdn.example.com/d.png)"},
+		{"Reference definitions", "[Spec][s]\n\n[s]: spec.pdf \"Spec\"\n  [logo]: <img/my logo.png>\n[home]: /home\n[^1]: note.pdf", "docs", "[Spec][s]\n\n[s]: /api/files/docs/spec.pdf \"Spec\"\n  [logo]: /api/files/docs/img/my%20logo.png\n[home]: /home\n[^1]: note.pdf"},
+		{"Code is skipped", "`![a](a.png)`\n\n```\n[b](b.md)\n```", "docs", "`![a](a.png)`\n\n```\n[b](b.md)\n```"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := LinkPreprocessor(tt.input, tt.docPath); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestProcessMarkdownTraced(t *testing.T) {
+	md := "# Title\n\n![diagram](diagram.png) ||spoiler||\n\n```mermaid\ngraph TD\n```\n"
+
+	stages := ProcessMarkdownTraced(md, "docs")
+	if len(stages) != len(RegisteredPreprocessors) {
+		t.Fatalf("Expected %d stages, got %d", len(RegisteredPreprocessors), len(stages))
+	}
+	// Every rendering stores its blocks under new placeholder IDs, so compare restored output
+	last := RestoreMermaidBlocks(stages[len(stages)-1].Output)
+	if expected := RestoreMermaidBlocks(ProcessMarkdown(md, "docs")); last != expected {
+		t.Errorf("Expected the last stage to be %q, got %q", expected, last)
+	}
+
+	names := make(map[string]bool)
+	for _, stage := range stages {
+		names[stage.Name] = true
+	}
+	for _, name := range []string{"mermaid", "link", "spoiler"} {
+		if !names[name] {
+			t.Errorf("Expected a %s stage, got %v", name, names)
+		}
+	}
+}
+
+func TestRegisterPreprocessorPriority(t *testing.T) {
+	savedRegistry := append([]registeredPreprocessor(nil), preprocessorRegistry...)
+	savedPreprocessors := RegisteredPreprocessors
+	defer func() {
+		preprocessorRegistry = savedRegistry
+		RegisteredPreprocessors = savedPreprocessors
+	}()
+
+	preprocessorRegistry, RegisteredPreprocessors = nil, nil
+	appender := func(suffix string) Preprocessor {
+		return func(markdown string, _ string) string { return markdown + suffix }
+	}
+	RegisterPreprocessor("late", 300, appender("c"))
+	RegisterPreprocessor("early", 100, appender("a"))
+	RegisterPreprocessor("middle", 200, appender("b"))
+	RegisterPreprocessor("also-middle", 200, appender("B"))
+
+	if result := ProcessMarkdown("", ""); result != "abBc" {
+		t.Errorf("Expected ascending priorities in registration order, got %q", result)
+	}
+
+	// Registering a name again replaces it
+	RegisterPreprocessor("early", 400, appender("d"))
+	if result := ProcessMarkdown("", ""); result != "bBcd" {
+		t.Errorf("Expected the replaced preprocessor to run last, got %q", result)
+	}
+	if stages := ProcessMarkdownTraced("", ""); len(stages) != 4 || stages[3].Name != "early" || stages[3].Output != "bBcd" {
+		t.Errorf("Unexpected stages: %+v", stages)
+	}
+}
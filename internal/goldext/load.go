@@ -2,24 +2,42 @@ package goldext
 
 // This file controls the loading order of all preprocessors
 // The order is important as some preprocessors may interfere with others if not run in the correct sequence
+// The built-in priorities are spaced by 100 so custom preprocessors can be registered between them
 
 // These variables ensure the preprocessors are available for registration
 // We don't actually use them directly, but they're needed for the compiler to include the preprocessors
 var (
 	_ = LinkPreprocessor
+	_ = IncludePreprocessor
+	_ = AbbreviationPreprocessor
+	_ = WikilinkPreprocessor
 	_ = MermaidPreprocessor
+	_ = MathPreprocessor
 	_ = DirectionPreprocessor
 	_ = MP4Preprocessor
 	_ = YouTubePreprocessor
 	_ = VimeoPreprocessor
+	_ = VideoEmbedPreprocessor
 	_ = StatsPreprocessor
 	_ = HighlightPreprocessor
+	_ = SpoilerPreprocessor
 	_ = TypographyPreprocessor
 	_ = EmojiPreprocessor
+	_ = DatePreprocessor
+	_ = HashtagPreprocessor
 	_ = DetailsPreprocessor
+	_ = DetailsContainerPreprocessor
+	_ = TabsPreprocessor
+	_ = CalloutPreprocessor
+	_ = AlertPreprocessor
+	_ = BlockquoteAttributionPreprocessor
+	_ = FootnoteSectionPreprocessor
+	_ = OrderedListContinuePreprocessor
+	_ = TaskExternalIDPreprocessor
+	_ = FigurePreprocessor
 	// _ = TaskListPreprocessor
 	_ = TocPreprocessor
-	_ = HeadingAnchorPreprocessor
+	_ = BackToTopPreprocessor
 	_ = SuperscriptPreprocessor
 	_ = SubscriptPreprocessor
 	_ = ScriptSanitizePreprocessor
@@ -29,35 +47,59 @@ var (
 func init() {
 	// Clear any previously registered preprocessors to ensure consistent ordering
 	RegisteredPreprocessors = nil
+	preprocessorRegistry = nil
 
 	// Step 0: Process frontmatter FIRST, before any other processors
-	RegisterPreprocessor(FrontmatterPreprocessor) // Process frontmatter
+	RegisterPreprocessor("frontmatter", 100, FrontmatterPreprocessor) // Process frontmatter
+
+	// Step 0.5: Inline {{include: ...}} documents so they go through every other preprocessor
+	registerFilePreprocessor("include", 200, IncludePreprocessorWithFiles)
+
+	// Step 0.75: Unquote > [!NOTE] alerts so mermaid and rtl/ltr blocks inside them are extracted as usual
+	RegisterPreprocessor("alert", 300, AlertPreprocessor)
 
 	// Step 1: Process Mermaid FIRST, before any other processors can touch the content
-	RegisterPreprocessor(MermaidPreprocessor) // Process mermaid diagrams first
+	RegisterPreprocessor("mermaid", 400, MermaidPreprocessor) // Process mermaid diagrams first
+
+	// Step 1.5: Protect $math$ from emphasis and the other inline preprocessors
+	RegisterPreprocessor("math", 450, MathPreprocessor)
 
 	// Step 2: Security-related preprocessing (add this early to sanitize content before other processors)
-	RegisterPreprocessor(ScriptSanitizePreprocessor) // Sanitize script tags
+	RegisterPreprocessor("script-sanitize", 500, ScriptSanitizePreprocessor) // Sanitize script tags
 
 	// Step 3: Register preprocessors that handle code blocks
-	RegisterPreprocessor(LinkPreprocessor)      // Process links and images
-	RegisterPreprocessor(DirectionPreprocessor) // Process RTL/LTR blocks
-	RegisterPreprocessor(MP4Preprocessor)       // Process MP4 video blocks
-	RegisterPreprocessor(YouTubePreprocessor)   // Process YouTube video blocks
-	RegisterPreprocessor(VimeoPreprocessor)     // Process Vimeo video blocks
-	RegisterPreprocessor(StatsPreprocessor)     // Process stats shortcodes
-	RegisterPreprocessor(DetailsPreprocessor)   // Process details blocks
-	// RegisterPreprocessor(TaskListPreprocessor)  // Process task lists before rendering
-	RegisterPreprocessor(TocPreprocessor)           // Process table of contents markers
-	RegisterPreprocessor(HeadingAnchorPreprocessor) // Add ¶ anchors to headings
+	RegisterPreprocessor("abbreviation", 600, AbbreviationPreprocessor)                     // Collect *[ABBR]: definitions, render {{abbr-list}}
+	registerFilePreprocessor("wikilink", 700, WikilinkPreprocessorWithFiles)                // Resolve [[Page Name]] wikilinks against the documents tree
+	RegisterPreprocessor("link", 800, LinkPreprocessor)                                     // Process links and images
+	RegisterPreprocessor("direction", 900, DirectionPreprocessor)                           // Process RTL/LTR blocks
+	RegisterPreprocessor("mp4", 1000, MP4Preprocessor)                                      // Process MP4 video blocks
+	RegisterPreprocessor("youtube", 1100, YouTubePreprocessor)                              // Process YouTube video blocks
+	RegisterPreprocessor("vimeo", 1200, VimeoPreprocessor)                                  // Process Vimeo video blocks
+	RegisterPreprocessor("video-embed", 1250, VideoEmbedPreprocessor)                       // Embed YouTube and Vimeo URLs on their own line
+	RegisterPreprocessor("stats", 1300, StatsPreprocessor)                                  // Process stats shortcodes
+	RegisterPreprocessor("details", 1400, DetailsPreprocessor)                              // Process details blocks
+	RegisterPreprocessor("details-container", 1500, DetailsContainerPreprocessor)           // Process ::: details containers
+	RegisterPreprocessor("tabs", 1600, TabsPreprocessor)                                    // Process ::: tabs groups
+	RegisterPreprocessor("callout", 1700, CalloutPreprocessor)                              // Process ::: note/warning/... callouts
+	RegisterPreprocessor("blockquote-attribution", 1800, BlockquoteAttributionPreprocessor) // Turn "— Author" quote lines into citations
+	RegisterPreprocessor("footnote-section", 1900, FootnoteSectionPreprocessor)             // Scope footnote labels to their section
+	RegisterPreprocessor("ordered-list-continue", 2000, OrderedListContinuePreprocessor)    // Continue ordered list numbering after {continue}
+	RegisterPreprocessor("task-external-id", 2100, TaskExternalIDPreprocessor)              // Link {#ID} on task items to the external tracker
+	RegisterPreprocessor("figure", 2200, FigurePreprocessor)                                // Number labelled figures and tables, resolve {{ref:...}} (opt-in)
+	// RegisterPreprocessor("task-list", ..., TaskListPreprocessor)  // Process task lists before rendering
+	RegisterPreprocessor("toc", 2300, TocPreprocessor)               // Process table of contents markers
+	RegisterPreprocessor("back-to-top", 2400, BackToTopPreprocessor) // Insert back-to-top links between sections (opt-in)
 
 	// Step 4: Register text formatting preprocessors
-	RegisterPreprocessor(HighlightPreprocessor)  // Process highlighting
-	RegisterPreprocessor(TypographyPreprocessor) // Process typography replacements
-	RegisterPreprocessor(EmojiPreprocessor)      // Process emoji shortcodes
+	RegisterPreprocessor("highlight", 2500, HighlightPreprocessor)   // Process highlighting
+	RegisterPreprocessor("spoiler", 2600, SpoilerPreprocessor)       // Process ||spoilers||
+	RegisterPreprocessor("typography", 2700, TypographyPreprocessor) // Process typography replacements
+	RegisterPreprocessor("emoji", 2800, EmojiPreprocessor)           // Process emoji shortcodes
+	RegisterPreprocessor("date", 2900, DatePreprocessor)             // Wrap ISO dates in <time> elements (opt-in)
+	RegisterPreprocessor("hashtag", 3000, HashtagPreprocessor)       // Link #hashtags to tag pages (opt-in)
 
 	// Step 5: Register these last to avoid interference with other syntax
 	// These preprocessors will skip content inside MathJax blocks ($ and $$)
-	RegisterPreprocessor(SuperscriptPreprocessor) // Process superscript (avoids MathJax content)
-	RegisterPreprocessor(SubscriptPreprocessor)   // Process subscript (avoids MathJax content)
+	RegisterPreprocessor("superscript", 3100, SuperscriptPreprocessor) // Process superscript (avoids MathJax content)
+	RegisterPreprocessor("subscript", 3200, SubscriptPreprocessor)     // Process subscript (avoids MathJax content)
 }
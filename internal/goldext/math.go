@@ -0,0 +1,260 @@
+package goldext
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// MathSpans protects math from markdown processing: $...$, $$...$$, \(...\) and \[...\]
+// are replaced with placeholders before rendering and restored untouched afterwards,
+// normalized to the $ and $$ delimiters MathJax is configured for. Dollar signs that
+// don't delimit math, as in "costs $5 and $10", are shielded from MathJax.
+var MathSpans = true
+
+// Math placeholders are made of private use characters, so they pass through the
+// other preprocessors and Goldmark untouched and never end up in heading IDs
+const (
+	mathPlaceholderStart = "\ue000"
+	mathPlaceholderEnd   = "\ue001"
+	mathLooseDollar      = "\ue002"
+	mathDigitBase        = '\ue010' // Hex digits of the math ID are \ue010 to \ue01f
+)
+
+// mathLooseDollarHTML is a literal dollar sign MathJax doesn't pair with another one
+const mathLooseDollarHTML = `<span class="tex2jax_ignore">$</span>`
+
+// Store extracted math until after Goldmark processing
+var mathSpans placeholderStore
+
+var mathPlaceholderRegex = regexp.MustCompile(mathPlaceholderStart + `([\x{e010}-\x{e01f}]+)` + mathPlaceholderEnd)
+
+// MathPreprocessor replaces math with placeholders restored by RestoreMathSpans
+// Inline $ math follows the usual rules: the opening $ is followed by a non-space and
+// the closing $ is preceded by a non-space and not followed by a digit. Display math
+// may span lines when $$ or \[ opens a line. Code blocks and inline code are left alone.
+func MathPreprocessor(markdown string, _ string) string {
+	if !MathSpans || !strings.ContainsAny(markdown, `$\`) {
+		return markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+	var result []string
+	inCodeBlock := false
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			result = append(result, line)
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock || !strings.ContainsAny(line, `$\`) {
+			result = append(result, line)
+			continue
+		}
+
+		// Display math opening a line may continue on the following lines
+		if open, close, ok := displayMathDelimiters(trimmedLine); ok && !strings.Contains(trimmedLine[len(open):], close) {
+			if end := findDisplayMathEnd(lines, i+1, close); end >= 0 {
+				content := []string{trimmedLine[len(open):]}
+				content = append(content, lines[i+1:end]...)
+				last := strings.TrimSpace(lines[end])
+				content = append(content, strings.TrimSuffix(last, close))
+
+				indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+				result = append(result, indent+storeMath("$$"+strings.Join(content, "\n")+"$$"))
+				i = end
+				continue
+			}
+		}
+
+		// Skip inline code
+		parts := strings.Split(line, "`")
+		for j := 0; j < len(parts); j += 2 {
+			parts[j] = extractInlineMath(parts[j])
+		}
+		result = append(result, strings.Join(parts, "`"))
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// displayMathDelimiters returns the delimiters of display math opening a line
+func displayMathDelimiters(trimmedLine string) (string, string, bool) {
+	switch {
+	case strings.HasPrefix(trimmedLine, "$$"):
+		return "$$", "$$", true
+	case strings.HasPrefix(trimmedLine, `\[`):
+		return `\[`, `\]`, true
+	}
+	return "", "", false
+}
+
+// findDisplayMathEnd returns the index of the line closing multi-line display math, or -1
+// Math doesn't continue past a blank line.
+func findDisplayMathEnd(lines []string, start int, close string) int {
+	for i := start; i < len(lines); i++ {
+		trimmedLine := strings.TrimSpace(lines[i])
+		if trimmedLine == "" {
+			return -1
+		}
+		if strings.HasSuffix(trimmedLine, close) {
+			return i
+		}
+	}
+	return -1
+}
+
+// extractInlineMath replaces the math of a piece of a line with placeholders
+// Raw HTML tags and link destinations are copied unchanged.
+func extractInlineMath(text string) string {
+	var sb strings.Builder
+	for i := 0; i < len(text); {
+		c := text[i]
+		rest := text[i:]
+
+		switch {
+		case c == '\\' && strings.HasPrefix(rest, `\$`):
+			sb.WriteString(mathLooseDollar)
+			i += 2
+			continue
+
+		case c == '\\' && (strings.HasPrefix(rest, `\(`) || strings.HasPrefix(rest, `\[`)):
+			open, close, display := `\(`, `\)`, false
+			if rest[1] == '[' {
+				open, close, display = `\[`, `\]`, true
+			}
+			if end := strings.Index(rest[len(open):], close); end > 0 {
+				content := rest[len(open) : len(open)+end]
+				if display {
+					sb.WriteString(storeMath("$$" + content + "$$"))
+				} else {
+					sb.WriteString(storeMath("$" + content + "$"))
+				}
+				i += len(open) + end + len(close)
+				continue
+			}
+			sb.WriteString(rest[:2])
+			i += 2
+			continue
+
+		case c == '\\' && len(rest) > 1:
+			// Keep other escapes, like \\, together
+			sb.WriteString(rest[:2])
+			i += 2
+			continue
+
+		case c == '<' && len(rest) > 1 && (rest[1] == '/' || isASCIILetter(rest[1])):
+			if end := strings.IndexByte(rest, '>'); end > 0 {
+				sb.WriteString(rest[:end+1])
+				i += end + 1
+				continue
+			}
+
+		case c == ']' && strings.HasPrefix(rest, "]("):
+			if end := strings.IndexByte(rest, ')'); end > 0 {
+				sb.WriteString(rest[:end+1])
+				i += end + 1
+				continue
+			}
+
+		case c == '$' && strings.HasPrefix(rest, "$$"):
+			if end := strings.Index(rest[2:], "$$"); end > 0 {
+				sb.WriteString(storeMath(rest[:end+4]))
+				i += end + 4
+				continue
+			}
+			sb.WriteString(mathLooseDollar + mathLooseDollar)
+			i += 2
+			continue
+
+		case c == '$':
+			if end := inlineMathEnd(rest); end > 0 {
+				sb.WriteString(storeMath(rest[:end+1]))
+				i += end + 1
+				continue
+			}
+			sb.WriteString(mathLooseDollar)
+			i++
+			continue
+		}
+
+		sb.WriteByte(c)
+		i++
+	}
+	return sb.String()
+}
+
+// inlineMathEnd returns the index of the $ closing inline math opened at text[0], or -1
+func inlineMathEnd(text string) int {
+	if len(text) < 3 || text[1] == ' ' || text[1] == '\t' {
+		return -1
+	}
+	for i := 2; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '$':
+			if text[i-1] == ' ' || text[i-1] == '\t' {
+				return -1
+			}
+			if i+1 < len(text) && text[i+1] >= '0' && text[i+1] <= '9' {
+				return -1
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// isASCIILetter reports whether c is an ASCII letter
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// storeMath stores math with its delimiters and returns its placeholder
+func storeMath(math string) string {
+	var sb strings.Builder
+	sb.WriteString(mathPlaceholderStart)
+	for _, digit := range []byte(mathSpans.put(math)) {
+		sb.WriteRune(mathDigitBase + rune(strings.IndexByte(hexDigits, digit)))
+	}
+	sb.WriteString(mathPlaceholderEnd)
+	return sb.String()
+}
+
+// hexDigits are the digits of placeholder IDs
+const hexDigits = "0123456789abcdef"
+
+// RestoreMathSpans replaces math placeholders with the escaped math and shields
+// the remaining dollar signs. This must be called after Goldmark processing; like
+// RestoreMermaidBlocks it removes the restored math.
+func RestoreMathSpans(htmlText string) string {
+	return restoreMathSpans(htmlText, restoredBlocks{})
+}
+
+// restoreMathSpans replaces math placeholders in part of an output
+func restoreMathSpans(htmlText string, restored restoredBlocks) string {
+	if !strings.Contains(htmlText, mathPlaceholderStart) && !strings.Contains(htmlText, mathLooseDollar) {
+		return htmlText
+	}
+
+	htmlText = mathPlaceholderRegex.ReplaceAllStringFunc(htmlText, func(placeholder string) string {
+		var id strings.Builder
+		for _, digit := range mathPlaceholderRegex.FindStringSubmatch(placeholder)[1] {
+			id.WriteByte(hexDigits[digit-mathDigitBase])
+		}
+		math, ok := mathSpans.restore(placeholder, id.String(), restored)
+		if !ok {
+			return placeholder
+		}
+		return html.EscapeString(math)
+	})
+	return strings.ReplaceAll(htmlText, mathLooseDollar, mathLooseDollarHTML)
+}
@@ -0,0 +1,47 @@
+package goldext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMathPreprocessor(t *testing.T) {
+	dollar := `<span class="tex2jax_ignore">$</span>`
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Inline math", "Euler: $e^{i\\pi} + 1 = 0$.", "Euler: $e^{i\\pi} + 1 = 0$."},
+		{"Prices are not math", "The cost is $5 and $10 per seat.", "The cost is " + dollar + "5 and " + dollar + "10 per seat."},
+		{"Closing dollar before a digit", "From $x to $2", "From " + dollar + "x to " + dollar + "2"},
+		{"Escaped dollar", `Just \$5`, "Just " + dollar + "5"},
+		{"Emphasis characters are kept", "$a_1 * b_2 * c$", "$a_1 * b_2 * c$"},
+		{"Normalized inline delimiters", `Area \(\pi r^2\)`, "Area $\\pi r^2$"},
+		{"Normalized display delimiters", `\[x^2\]`, "$$x^2$$"},
+		{"Display math", "$$\n\\sum_{i=1}^n i\n$$", "$$\n\\sum_{i=1}^n i\n$$"},
+		{"Inline display math", "So $$a<b$$ holds", "So $$a&lt;b$$ holds"},
+		{"Inline code is preserved", "Use `$HOME` and $x$", "Use `$HOME` and $x$"},
+		{"Code blocks are preserved", "```\necho $5 $x$\n```", "```\necho $5 $x$\n```"},
+		{"Link destinations are preserved", "[Pay $5](/shop?price=$5)", "[Pay " + dollar + "5](/shop?price=$5)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processed := MathPreprocessor(tt.input, "")
+			if strings.ContainsAny(processed, "$") && !strings.Contains(tt.input, "`") && !strings.Contains(tt.input, "](") {
+				t.Errorf("Expected all math to be replaced, got %q", processed)
+			}
+			if result := RestoreMathSpans(processed); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	MathSpans = false
+	defer func() { MathSpans = true }()
+	if result := MathPreprocessor("$5 and $x$", ""); result != "$5 and $x$" {
+		t.Errorf("Expected math to be left alone when disabled, got %q", result)
+	}
+}
@@ -2,27 +2,34 @@ package goldext
 
 import (
 	"fmt"
+	"html"
+	"regexp"
 	"strings"
-	"sync"
 )
 
+// MermaidValidation checks that mermaid blocks aren't empty and start with a known diagram
+// type, rendering a visible error with the block's source instead of a blank diagram
+// The check only looks at the header; it doesn't parse the diagram.
+var MermaidValidation = true
+
+// mermaidDiagramTypes are the diagram type keywords mermaid recognizes
+var mermaidDiagramTypes = map[string]bool{
+	"graph": true, "flowchart": true, "flowchart-elk": true, "sequenceDiagram": true,
+	"classDiagram": true, "classDiagram-v2": true, "stateDiagram": true, "stateDiagram-v2": true,
+	"erDiagram": true, "journey": true, "gantt": true, "pie": true, "quadrantChart": true,
+	"requirementDiagram": true, "gitGraph": true, "C4Context": true, "C4Container": true,
+	"C4Component": true, "C4Dynamic": true, "C4Deployment": true, "mindmap": true,
+	"timeline": true, "zenuml": true, "sankey-beta": true, "xychart-beta": true,
+	"block-beta": true, "packet-beta": true, "kanban": true, "architecture-beta": true,
+	"radar-beta": true,
+}
+
 // Store extracted Mermaid blocks until after Goldmark processing
-var (
-	mermaidBlocks     = make(map[string]string)
-	mermaidBlockCount = 0
-	mermaidMutex      sync.Mutex
-)
+var mermaidBlocks placeholderStore
 
 // MermaidPreprocessor extracts mermaid blocks and replaces them with placeholders
 // that Goldmark won't process. The blocks will be restored after Goldmark rendering.
 func MermaidPreprocessor(markdown string, _ string) string {
-	mermaidMutex.Lock()
-	defer mermaidMutex.Unlock()
-
-	// Reset the storage on each new document
-	mermaidBlocks = make(map[string]string)
-	mermaidBlockCount = 0
-
 	// Process line by line to safely extract mermaid blocks
 	lines := strings.Split(markdown, "\n")
 	var result []string
@@ -42,12 +49,8 @@ func MermaidPreprocessor(markdown string, _ string) string {
 			continue
 		} else if trimmed == "```" && inMermaidBacktick {
 			inMermaidBacktick = false
-			// Generate a placeholder that Goldmark won't touch
-			blockID := fmt.Sprintf("MERMAID_BLOCK_%d", mermaidBlockCount)
-			mermaidBlockCount++
-			// Store the actual mermaid div
-			mermaidDiv := "<div class=\"mermaid\">" + strings.Join(mermaidContent, "\n") + "</div>"
-			mermaidBlocks[blockID] = mermaidDiv
+			// Store the actual mermaid div behind a placeholder that Goldmark won't touch
+			blockID := "MERMAID_BLOCK_" + mermaidBlocks.put(renderMermaidBlock(mermaidContent))
 			// Add placeholder to output - this will pass through Goldmark untouched
 			result = append(result, "<!-- "+blockID+" -->")
 			continue
@@ -57,12 +60,8 @@ func MermaidPreprocessor(markdown string, _ string) string {
 			continue
 		} else if trimmed == "~~~" && inMermaidTilde {
 			inMermaidTilde = false
-			// Generate a placeholder that Goldmark won't touch
-			blockID := fmt.Sprintf("MERMAID_BLOCK_%d", mermaidBlockCount)
-			mermaidBlockCount++
-			// Store the actual mermaid div
-			mermaidDiv := "<div class=\"mermaid\">" + strings.Join(mermaidContent, "\n") + "</div>"
-			mermaidBlocks[blockID] = mermaidDiv
+			// Store the actual mermaid div behind a placeholder that Goldmark won't touch
+			blockID := "MERMAID_BLOCK_" + mermaidBlocks.put(renderMermaidBlock(mermaidContent))
 			// Add placeholder to output - this will pass through Goldmark untouched
 			result = append(result, "<!-- "+blockID+" -->")
 			continue
@@ -78,27 +77,71 @@ func MermaidPreprocessor(markdown string, _ string) string {
 
 	// Handle any unclosed blocks (rare, but possible)
 	if inMermaidBacktick || inMermaidTilde {
-		blockID := fmt.Sprintf("MERMAID_BLOCK_%d", mermaidBlockCount)
-		mermaidBlockCount++
-		mermaidDiv := "<div class=\"mermaid\">" + strings.Join(mermaidContent, "\n") + "</div>"
-		mermaidBlocks[blockID] = mermaidDiv
+		blockID := "MERMAID_BLOCK_" + mermaidBlocks.put(renderMermaidBlock(mermaidContent))
 		result = append(result, "<!-- "+blockID+" -->")
 	}
 
 	return strings.Join(result, "\n")
 }
 
+// renderMermaidBlock returns the mermaid div for the lines of a block,
+// or an error block when validation finds it broken
+// The source is escaped: mermaid reads the diagram from the div's text, and raw markup
+// in a fence would otherwise end up in the page even for untrusted documents.
+func renderMermaidBlock(content []string) string {
+	source := strings.Join(content, "\n")
+	if MermaidValidation {
+		if problem := validateMermaid(content); problem != "" {
+			return `<div class="mermaid-error" role="alert"><p>` + html.EscapeString(problem) + `</p><pre><code>` + html.EscapeString(source) + `</code></pre></div>`
+		}
+	}
+	return "<div class=\"mermaid\"" + BlockAttributes("mermaid") + ">" + html.EscapeString(source) + "</div>"
+}
+
+// validateMermaid returns what is wrong with the header of a mermaid block, or ""
+// Blank lines, %% comments and directives and a leading --- config block are skipped.
+func validateMermaid(content []string) string {
+	inConfig := false
+	for i, line := range content {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "---" && (i == 0 || inConfig) {
+			inConfig = !inConfig
+			continue
+		}
+		if inConfig || trimmed == "" || strings.HasPrefix(trimmed, "%%") {
+			continue
+		}
+
+		diagramType := strings.Fields(trimmed)[0]
+		if !mermaidDiagramTypes[diagramType] {
+			return fmt.Sprintf("Unknown mermaid diagram type %q", diagramType)
+		}
+		return ""
+	}
+	return "Empty mermaid diagram"
+}
+
+// mermaidPlaceholderRegex matches the placeholders left by MermaidPreprocessor
+var mermaidPlaceholderRegex = regexp.MustCompile(`<!-- MERMAID_BLOCK_([0-9a-f]+) -->`)
+
 // RestoreMermaidBlocks replaces placeholders with actual mermaid diagrams
-// This must be called after Goldmark processing
+// This must be called after Goldmark processing. Restored blocks are removed, so the
+// output of a rendering is restored once; PostProcessWriter restores it in parts.
 func RestoreMermaidBlocks(html string) string {
-	mermaidMutex.Lock()
-	defer mermaidMutex.Unlock()
+	return restoreMermaidBlocks(html, restoredBlocks{})
+}
 
-	result := html
-	for id, block := range mermaidBlocks {
-		placeholder := fmt.Sprintf("<!-- %s -->", id)
-		result = strings.Replace(result, placeholder, block, 1)
+// restoreMermaidBlocks replaces placeholders with mermaid diagrams in part of an output
+func restoreMermaidBlocks(html string, restored restoredBlocks) string {
+	if !strings.Contains(html, "<!-- MERMAID_BLOCK_") {
+		return html
 	}
 
-	return result
+	return mermaidPlaceholderRegex.ReplaceAllStringFunc(html, func(placeholder string) string {
+		id := mermaidPlaceholderRegex.FindStringSubmatch(placeholder)[1]
+		if block, ok := mermaidBlocks.restore(placeholder, id, restored); ok {
+			return block
+		}
+		return placeholder
+	})
 }
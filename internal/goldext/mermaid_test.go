@@ -0,0 +1,77 @@
+package goldext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMermaidValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Known diagram type",
+			input:    "```mermaid\ngraph TD\n  A --> B\n```",
+			expected: "<div class=\"mermaid\">graph TD\n  A --&gt; B</div>",
+		},
+		{
+			name:     "Comments, directives and config are skipped",
+			input:    "```mermaid\n---\ntitle: Flow\n---\n%%{init: {'theme': 'dark'}}%%\n\n%% a comment\nsequenceDiagram\n```",
+			expected: "<div class=\"mermaid\">",
+		},
+		{
+			name:     "Unknown diagram type",
+			input:    "~~~mermaid\ngrpah TD\n  A --> <b>B</b>\n~~~",
+			expected: "<div class=\"mermaid-error\" role=\"alert\"><p>Unknown mermaid diagram type &#34;grpah&#34;</p><pre><code>grpah TD\n  A --&gt; &lt;b&gt;B&lt;/b&gt;</code></pre></div>",
+		},
+		{
+			name:     "Empty diagram",
+			input:    "```mermaid\n\n%% nothing yet\n```",
+			expected: "<div class=\"mermaid-error\" role=\"alert\"><p>Empty mermaid diagram</p>",
+		},
+		{
+			name:     "Unclosed block",
+			input:    "```mermaid\npie title Pets",
+			expected: "<div class=\"mermaid\">pie title Pets</div>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RestoreMermaidBlocks(MermaidPreprocessor(tt.input, ""))
+			if !strings.Contains(result, tt.expected) {
+				t.Errorf("Expected %q in %q", tt.expected, result)
+			}
+		})
+	}
+
+	MermaidValidation = false
+	defer func() { MermaidValidation = true }()
+	if result := RestoreMermaidBlocks(MermaidPreprocessor("```mermaid\ngrpah TD\n```", "")); result != "<div class=\"mermaid\">grpah TD</div>" {
+		t.Errorf("Expected unvalidated diagram, got %q", result)
+	}
+}
+
+func TestMermaidPlaceholders(t *testing.T) {
+	placeholder := strings.TrimSpace(MermaidPreprocessor("```mermaid\ngraph TD\n```", ""))
+	if !mermaidPlaceholderRegex.MatchString(placeholder) {
+		t.Fatalf("Expected a placeholder, got %q", placeholder)
+	}
+
+	// Placeholders written by a document don't pull in other blocks
+	for _, guess := range []string{"<!-- MERMAID_BLOCK_0 -->", "<!-- MERMAID_BLOCK_1 -->"} {
+		if result := RestoreMermaidBlocks(guess); result != guess {
+			t.Errorf("Expected %q to stay a placeholder, got %q", guess, result)
+		}
+	}
+
+	// A block is restored once and then removed
+	if result := RestoreMermaidBlocks(placeholder); result != `<div class="mermaid">graph TD</div>` {
+		t.Errorf("Expected the diagram, got %q", result)
+	}
+	if result := RestoreMermaidBlocks(placeholder); result != placeholder {
+		t.Errorf("Expected a restored block to be removed, got %q", result)
+	}
+}
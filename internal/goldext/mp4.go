@@ -8,13 +8,13 @@ import (
 )
 
 // TransformMP4Path transforms a local video file path to a proper API URL
-// It prepends "/api/files/" to the document path and filename
+// It prepends "/api/files/" to the document path and filename, and BasePath to root-relative paths
 func TransformMP4Path(videoPath string, docPath string) string {
 	// Skip transformation if it already looks like a URL
 	if strings.HasPrefix(videoPath, "http://") ||
 		strings.HasPrefix(videoPath, "https://") ||
 		strings.HasPrefix(videoPath, "/") {
-		return videoPath
+		return JoinBasePath(BasePath, videoPath)
 	}
 
 	// URL encode the filename to handle spaces and special characters
@@ -23,11 +23,11 @@ func TransformMP4Path(videoPath string, docPath string) string {
 	// Handle the homepage special case
 	if docPath == "" || docPath == "/" {
 		// Homepage files are stored in "pages/home"
-		return "/api/files/pages/home/" + escapedPath
+		return JoinBasePath(BasePath, "/api/files/pages/home/"+escapedPath)
 	}
 
 	// Regular document files
-	return "/api/files/" + docPath + "/" + escapedPath
+	return JoinBasePath(BasePath, "/api/files/"+docPath+"/"+escapedPath)
 }
 
 // MP4Preprocessor transforms MP4 code blocks into HTML video elements
@@ -0,0 +1,83 @@
+package goldext
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ListContinueMarker is the line that makes the following ordered list
+// continue numbering from the previous ordered list
+var ListContinueMarker = "{continue}"
+
+// OrderedListContinuePreprocessor renumbers an ordered list that follows a
+// {continue} marker so it resumes from the last number of the previous list.
+// Goldmark derives <ol start> from the first item, so only that item is rewritten.
+func OrderedListContinuePreprocessor(markdown string, _ string) string {
+	if !strings.Contains(markdown, ListContinueMarker) {
+		return markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+
+	// Top-level ordered list item: up to 3 spaces of indentation, number, . or )
+	itemRegex := regexp.MustCompile(`^( {0,3})(\d{1,9})([.)])(\s+.*)?$`)
+
+	inCodeBlock := false
+	inList := false
+	pending := false
+	listStart := 0
+	listCount := 0
+	lastNumber := 0
+
+	for i, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			inList = false
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock {
+			continue
+		}
+
+		// Replace the marker with a blank line so the list can start after a paragraph
+		if trimmedLine == ListContinueMarker {
+			lines[i] = ""
+			pending = true
+			inList = false
+			continue
+		}
+
+		if m := itemRegex.FindStringSubmatch(line); m != nil {
+			if !inList {
+				// A new ordered list starts here
+				listStart, _ = strconv.Atoi(m[2])
+				if pending && lastNumber > 0 {
+					listStart = lastNumber + 1
+					lines[i] = m[1] + strconv.Itoa(listStart) + m[3] + m[4]
+				}
+				pending = false
+				listCount = 0
+				inList = true
+			}
+			listCount++
+			lastNumber = listStart + listCount - 1
+			continue
+		}
+
+		// Blank lines and indented continuation lines keep the list open
+		if trimmedLine == "" || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+
+		// Any other line ends the current list
+		inList = false
+	}
+
+	return strings.Join(lines, "\n")
+}
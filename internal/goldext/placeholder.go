@@ -0,0 +1,80 @@
+package goldext
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// placeholderLifetime is how long a stored block waits for its placeholder to be restored
+// Renderings take far less; the limit only drops blocks of output that is never restored,
+// such as sections left out of a RenderSection.
+const placeholderLifetime = 10 * time.Minute
+
+// placeholderStore keeps the blocks preprocessors replace with placeholders until the
+// rendered output is restored. IDs are random, so documents rendered at the same time, or
+// rendered from within another rendering, never see each other's blocks, and a document
+// can't write the placeholder of another document's block. Restoring removes a block.
+type placeholderStore struct {
+	mu      sync.Mutex
+	entries map[string]placeholderEntry
+	sweptAt time.Time
+}
+
+// placeholderEntry is a stored block and when it was stored
+type placeholderEntry struct {
+	value  string
+	stored time.Time
+}
+
+// put stores a block and returns the ID of its placeholder
+func (s *placeholderStore) put(value string) string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	id := hex.EncodeToString(b[:])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.entries == nil {
+		s.entries = make(map[string]placeholderEntry)
+	}
+
+	// Drop expired blocks at most once a minute
+	if now.Sub(s.sweptAt) > time.Minute {
+		for id, entry := range s.entries {
+			if now.Sub(entry.stored) > placeholderLifetime {
+				delete(s.entries, id)
+			}
+		}
+		s.sweptAt = now
+	}
+
+	s.entries[id] = placeholderEntry{value: value, stored: now}
+	return id
+}
+
+// restoredBlocks are the blocks one rendering restored, by placeholder
+// The same placeholder can appear twice in an output, like math in a heading and its
+// table of contents entry, and the block is gone from its store after the first time.
+type restoredBlocks map[string]string
+
+// restore returns the block of a placeholder and its ID and removes it from the store,
+// looking up the blocks the rendering restored before first
+func (s *placeholderStore) restore(placeholder, id string, restored restoredBlocks) (string, bool) {
+	if block, ok := restored[placeholder]; ok {
+		return block, true
+	}
+
+	s.mu.Lock()
+	entry, ok := s.entries[id]
+	delete(s.entries, id)
+	s.mu.Unlock()
+
+	if ok {
+		restored[placeholder] = entry.value
+	}
+	return entry.value, ok
+}
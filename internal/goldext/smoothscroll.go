@@ -0,0 +1,32 @@
+package goldext
+
+import (
+	"regexp"
+)
+
+// SmoothScrollAnchors marks same-page anchor links (href="#section") with
+// data-smooth-scroll so the client scrolls to the target and updates the URL
+// without a navigation. Disabled by default.
+var SmoothScrollAnchors = false
+
+var (
+	// anchorLinkRegex matches the opening tag of a link whose href is a non-empty fragment
+	anchorLinkRegex       = regexp.MustCompile(`<a\s(?:[^>]*\s)?href="#[^"]+"[^>]*>`)
+	smoothScrollAttrRegex = regexp.MustCompile(`\sdata-smooth-scroll=`)
+)
+
+// AddSmoothScrollHooks adds data-smooth-scroll="true" to every same-page anchor link
+// This covers markdown links as well as generated TOC, heading and footnote links.
+// This must be called after Goldmark rendering
+func AddSmoothScrollHooks(htmlContent string) string {
+	if !SmoothScrollAnchors {
+		return htmlContent
+	}
+
+	return anchorLinkRegex.ReplaceAllStringFunc(htmlContent, func(tag string) string {
+		if smoothScrollAttrRegex.MatchString(tag) {
+			return tag
+		}
+		return tag[:len(tag)-1] + ` data-smooth-scroll="true">`
+	})
+}
@@ -0,0 +1,68 @@
+package goldext
+
+import (
+	"testing"
+)
+
+func TestAddSmoothScrollHooks(t *testing.T) {
+	SmoothScrollAnchors = true
+	defer func() { SmoothScrollAnchors = false }()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Same-page anchor",
+			input:    `<a href="#setup">Setup</a>`,
+			expected: `<a href="#setup" data-smooth-scroll="true">Setup</a>`,
+		},
+		{
+			name:     "Generated heading anchor",
+			input:    `<a class="heading-anchor" href="#intro" aria-label="Permalink">¶</a>`,
+			expected: `<a class="heading-anchor" href="#intro" aria-label="Permalink" data-smooth-scroll="true">¶</a>`,
+		},
+		{
+			name:     "Document link with fragment",
+			input:    `<a href="/docs/guide#setup">Guide</a>`,
+			expected: `<a href="/docs/guide#setup">Guide</a>`,
+		},
+		{
+			name:     "External link",
+			input:    `<a href="https://example.com/#top" target="_blank">Example</a>`,
+			expected: `<a href="https://example.com/#top" target="_blank">Example</a>`,
+		},
+		{
+			name:     "Empty fragment",
+			input:    `<a href="#">Nothing</a>`,
+			expected: `<a href="#">Nothing</a>`,
+		},
+		{
+			name:     "Fragment in another attribute",
+			input:    `<a data-href="#x" href="/page">Page</a>`,
+			expected: `<a data-href="#x" href="/page">Page</a>`,
+		},
+		{
+			name:     "Already marked",
+			input:    `<a href="#a" data-smooth-scroll="false">A</a>`,
+			expected: `<a href="#a" data-smooth-scroll="false">A</a>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := AddSmoothScrollHooks(tt.input)
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestAddSmoothScrollHooksDisabledByDefault(t *testing.T) {
+	input := `<a href="#setup">Setup</a>`
+	if result := AddSmoothScrollHooks(input); result != input {
+		t.Errorf("Expected unchanged output, got: %q", result)
+	}
+}
@@ -0,0 +1,66 @@
+package goldext
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	spoilerRegex        = regexp.MustCompile(`\|\|([^|\s](?:[^|\n]*?[^|\s])?)\|\|`)
+	tableDelimiterRegex = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+)
+
+// SpoilerPreprocessor adds support for ||spoiler|| text, rendered as a span that is
+// revealed on click or keyboard activation. Markdown inside the spoiler is still rendered.
+// Table rows and code are never processed, so empty table cells (||) are left alone.
+func SpoilerPreprocessor(markdown string, _ string) string {
+	if !strings.Contains(markdown, "||") {
+		return markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+	var result []string
+
+	inCodeBlock := false
+	inTable := false
+
+	for i, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			inTable = false
+			result = append(result, line)
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock {
+			result = append(result, line)
+			continue
+		}
+
+		// A table starts at a header row followed by a delimiter row and runs until a blank line
+		if trimmedLine == "" {
+			inTable = false
+		} else if !inTable && strings.Contains(line, "|") && i+1 < len(lines) && strings.Contains(lines[i+1], "|") && tableDelimiterRegex.MatchString(lines[i+1]) {
+			inTable = true
+		}
+
+		if inTable || !strings.Contains(line, "||") {
+			result = append(result, line)
+			continue
+		}
+
+		// Process each segment of the line, preserving inline code
+		segments := strings.Split(line, "`")
+		for j := 0; j < len(segments); j += 2 {
+			segments[j] = spoilerRegex.ReplaceAllString(segments[j],
+				`<span class="spoiler" tabindex="0" title="Reveal spoiler">$1</span>`)
+		}
+		result = append(result, strings.Join(segments, "`"))
+	}
+
+	return strings.Join(result, "\n")
+}
@@ -0,0 +1,65 @@
+package goldext
+
+import (
+	"testing"
+)
+
+func TestSpoilerPreprocessor(t *testing.T) {
+	span := `<span class="spoiler" tabindex="0" title="Reveal spoiler">`
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Simple spoiler",
+			input:    "The butler ||did it||.",
+			expected: "The butler " + span + "did it</span>.",
+		},
+		{
+			name:     "Spoiler with emphasis",
+			input:    "||**bold** reveal||",
+			expected: span + "**bold** reveal</span>",
+		},
+		{
+			name:     "Inline code is preserved",
+			input:    "Use `a || b` or ||this||",
+			expected: "Use `a || b` or " + span + "this</span>",
+		},
+		{
+			name:     "Code blocks are preserved",
+			input:    "```\n||not a spoiler||\n```",
+			expected: "```\n||not a spoiler||\n```",
+		},
+		{
+			name:     "Table rows with empty cells are preserved",
+			input:    "| a | b | c |\n|---|---|---|\n| x || y |\n| ||z|| |\n\nAfter ||table||",
+			expected: "| a | b | c |\n|---|---|---|\n| x || y |\n| ||z|| |\n\nAfter " + span + "table</span>",
+		},
+		{
+			name:     "Table without outer pipes",
+			input:    "a | b\n--|--\nx ||y||",
+			expected: "a | b\n--|--\nx ||y||",
+		},
+		{
+			name:     "Setext heading is not a table",
+			input:    "Title ||x||\n---",
+			expected: "Title " + span + "x</span>\n---",
+		},
+		{
+			name:     "Padded delimiters are not spoilers",
+			input:    "a || b || c",
+			expected: "a || b || c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SpoilerPreprocessor(tt.input, "")
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+}
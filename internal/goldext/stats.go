@@ -161,7 +161,7 @@ func renderRecentEdits(w *strings.Builder, count int) {
 			// Structure with elements on one line
 			w.WriteString("<li>\n")
 			w.WriteString("  <div class=\"doc-info\">\n")
-			w.WriteString(fmt.Sprintf("    <a href=\"%s\">%s</a>\n", folderPath, doc.Title))
+			w.WriteString(fmt.Sprintf("    <a href=\"%s\">%s</a>\n", JoinBasePath(BasePath, folderPath), doc.Title))
 			w.WriteString(fmt.Sprintf("    <span class=\"doc-path\">%s</span>\n", folderPath))
 			w.WriteString("  </div>\n")
 			w.WriteString(fmt.Sprintf("  <span class=\"edit-date\">%s</span>\n", doc.ModTime.Format("2006-01-02 15:04")))
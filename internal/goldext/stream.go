@@ -0,0 +1,91 @@
+package goldext
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// PostProcessWriter applies the post-processors that only need to see one line of the
+// rendered HTML at a time while the output is being written: mermaid, direction block and
+// math restoration, footnote ARIA and smooth-scroll hooks. Only the current line is buffered,
+// plus the opening of a footnote list until its first footnote, which names the list's
+// hidden heading. Close must be called to flush a final line without a trailing newline.
+type PostProcessWriter struct {
+	// FootnotePrefix goes in front of the ID of the hidden footnotes heading,
+	// matching a prefix given to the footnote IDs
+	FootnotePrefix string
+
+	w        io.Writer
+	line     []byte
+	held     string
+	restored restoredBlocks
+}
+
+// NewPostProcessWriter creates a PostProcessWriter writing to w
+// A writer restores the placeholders of one rendering.
+func NewPostProcessWriter(w io.Writer) *PostProcessWriter {
+	return &PostProcessWriter{w: w, restored: restoredBlocks{}}
+}
+
+// Write buffers p and writes every completed line post-processed
+func (p *PostProcessWriter) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			p.line = append(p.line, b...)
+			break
+		}
+
+		p.line = append(p.line, b[:i+1]...)
+		if err := p.flush(); err != nil {
+			return n - len(b), err
+		}
+		b = b[i+1:]
+	}
+	return n, nil
+}
+
+// Close writes the remaining partial line
+func (p *PostProcessWriter) Close() error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	if p.held == "" {
+		return nil
+	}
+	return p.write("")
+}
+
+// flush post-processes and writes the buffered line
+func (p *PostProcessWriter) flush() error {
+	if len(p.line) == 0 {
+		return nil
+	}
+
+	line := string(p.line)
+	p.line = p.line[:0]
+	if FootnoteARIA && (p.held != "" || strings.Contains(line, footnotesOpening)) && !footnoteItemRegex.MatchString(line) {
+		p.held += line
+		return nil
+	}
+	return p.write(line)
+}
+
+// write post-processes and writes the held lines followed by line
+func (p *PostProcessWriter) write(line string) error {
+	line = p.held + line
+	p.held = ""
+
+	line = restoreMathSpans(restoreDirectionBlocks(restoreMermaidBlocks(line, p.restored), p.restored), p.restored)
+	if FootnoteARIA {
+		// A single line can't tell whether the document has footnotes, but footnote
+		// markup only appears when it does
+		line = addFootnoteARIA(line, p.FootnotePrefix+footnotesLabelID)
+	}
+	line = AddSmoothScrollHooks(line)
+
+	_, err := io.WriteString(p.w, line)
+	return err
+}
@@ -0,0 +1,80 @@
+package goldext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPostProcessWriter(t *testing.T) {
+	smoothScroll, footnoteARIA := SmoothScrollAnchors, FootnoteARIA
+	SmoothScrollAnchors = true
+	FootnoteARIA = true
+	defer func() {
+		SmoothScrollAnchors = smoothScroll
+		FootnoteARIA = footnoteARIA
+	}()
+
+	// Restoring removes the blocks, so every rendering gets its own placeholders
+	newInput := func() string {
+		mermaid := strings.TrimSpace(MermaidPreprocessor("```mermaid\ngraph TD\n```", ""))
+		direction := strings.TrimSpace(DirectionPreprocessor("```rtl\nשלום\n```", ""))
+		return mermaid + "\n" +
+			"<p><a href=\"#end\">Jump</a></p>\n" +
+			direction + "\n" +
+			"<p>Note<sup id=\"fnref:1\"><a href=\"#fn:1\" class=\"footnote-ref\" role=\"doc-noteref\">1</a></sup></p>\n" +
+			"<div class=\"footnotes\" role=\"doc-endnotes\">\n" +
+			"<!-- UNKNOWN_BLOCK -->"
+	}
+	expected := AddSmoothScrollHooks(AddFootnoteARIA(RestoreDirectionBlocks(RestoreMermaidBlocks(newInput()))))
+
+	// Write in small chunks so placeholders and tags are split across writes
+	for _, size := range []int{1, 7, len(expected)} {
+		input := newInput()
+		var sb strings.Builder
+		pw := NewPostProcessWriter(&sb)
+		for i := 0; i < len(input); i += size {
+			end := i + size
+			if end > len(input) {
+				end = len(input)
+			}
+			if _, err := pw.Write([]byte(input[i:end])); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := pw.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if sb.String() != expected {
+			t.Errorf("Chunk size %d: expected %q, got: %q", size, expected, sb.String())
+		}
+	}
+
+	if !strings.Contains(expected, `<div class="mermaid">graph TD</div>`) || !strings.Contains(expected, `<div class="rtl">`) {
+		t.Errorf("Expected restored blocks, got: %q", expected)
+	}
+}
+
+func TestPostProcessWriterPlaceholders(t *testing.T) {
+	// A placeholder repeated in one rendering, like math in a heading and its TOC entry,
+	// is restored every time
+	math := MathPreprocessor("$x^2$", "")
+	input := "<h2>" + math + "</h2>\n<li>" + math + "</li>\n"
+
+	var sb strings.Builder
+	pw := NewPostProcessWriter(&sb)
+	if _, err := pw.Write([]byte(input)); err != nil {
+		t.Fatal(err)
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "<h2>$x^2$</h2>\n<li>$x^2$</li>\n"; sb.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, sb.String())
+	}
+
+	// Another rendering can't restore the block again
+	if result := RestoreMathSpans(input); strings.Contains(result, "x^2") {
+		t.Errorf("Expected restored math to be removed, got %q", result)
+	}
+}
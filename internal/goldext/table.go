@@ -0,0 +1,87 @@
+package goldext
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ResponsiveTables wraps every rendered table in a horizontally scrolling container
+// so wide tables don't overflow on small screens. Disabled by default.
+var ResponsiveTables = false
+
+// StickyTableHeaders marks responsive table wrappers for sticky header rows
+var StickyTableHeaders = false
+
+var (
+	tableOpenRegex  = regexp.MustCompile(`(?i)<table[\s>]`)
+	tableCloseRegex = regexp.MustCompile(`(?i)</table>`)
+	tableWrapRegex  = regexp.MustCompile(`<div class="table-responsive"[^>]*>$`)
+)
+
+// tableWrapperOpen returns the opening tag of a responsive table wrapper
+func tableWrapperOpen() string {
+	if StickyTableHeaders {
+		return `<div class="table-responsive" data-sticky-header="true">`
+	}
+	return `<div class="table-responsive">`
+}
+
+// isTableWrapped reports whether the HTML before a table ends with a responsive wrapper
+func isTableWrapped(before string) bool {
+	before = strings.TrimRight(before, " \t\n")
+	if len(before) > 128 {
+		before = before[len(before)-128:]
+	}
+	return tableWrapRegex.MatchString(before)
+}
+
+// WrapResponsiveTables wraps each table in a <div class="table-responsive"> exactly once
+// Tables that are already wrapped are left alone, so running it twice is harmless.
+// This must be called after Goldmark rendering
+func WrapResponsiveTables(htmlContent string) string {
+	if !ResponsiveTables || !tableOpenRegex.MatchString(htmlContent) {
+		return htmlContent
+	}
+
+	var builder strings.Builder
+	var wrapped []bool // Per open table, whether this call added a wrapper
+	lastEnd := 0
+
+	opens := tableOpenRegex.FindAllStringIndex(htmlContent, -1)
+	closes := tableCloseRegex.FindAllStringIndex(htmlContent, -1)
+
+	// Walk the open and close tags in document order
+	for len(opens) > 0 || len(closes) > 0 {
+		if len(opens) > 0 && (len(closes) == 0 || opens[0][0] < closes[0][0]) {
+			start := opens[0][0]
+			opens = opens[1:]
+
+			builder.WriteString(htmlContent[lastEnd:start])
+			lastEnd = start
+
+			// Only outermost tables scroll; nested tables and already wrapped ones are left alone
+			wrap := len(wrapped) == 0 && !isTableWrapped(htmlContent[:start])
+			if wrap {
+				builder.WriteString(tableWrapperOpen())
+			}
+			wrapped = append(wrapped, wrap)
+			continue
+		}
+
+		end := closes[0][1]
+		closes = closes[1:]
+
+		builder.WriteString(htmlContent[lastEnd:end])
+		lastEnd = end
+
+		if len(wrapped) > 0 {
+			if wrapped[len(wrapped)-1] {
+				builder.WriteString(`</div>`)
+			}
+			wrapped = wrapped[:len(wrapped)-1]
+		}
+	}
+
+	builder.WriteString(htmlContent[lastEnd:])
+	return builder.String()
+}
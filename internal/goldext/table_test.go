@@ -0,0 +1,66 @@
+package goldext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrapResponsiveTables(t *testing.T) {
+	ResponsiveTables = true
+	defer func() { ResponsiveTables = false }()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Single table",
+			input:    "<table>\n<tr><td>a</td></tr>\n</table>\n",
+			expected: "<div class=\"table-responsive\"><table>\n<tr><td>a</td></tr>\n</table></div>\n",
+		},
+		{
+			name:     "Multiple tables",
+			input:    "<table><tr><td>a</td></tr></table><p>x</p><table class=\"csv\"><tr><td>b</td></tr></table>",
+			expected: "<div class=\"table-responsive\"><table><tr><td>a</td></tr></table></div><p>x</p><div class=\"table-responsive\"><table class=\"csv\"><tr><td>b</td></tr></table></div>",
+		},
+		{
+			name:     "Nested table is not wrapped again",
+			input:    "<table><tr><td><table><tr><td>n</td></tr></table></td></tr></table>",
+			expected: "<div class=\"table-responsive\"><table><tr><td><table><tr><td>n</td></tr></table></td></tr></table></div>",
+		},
+		{
+			name:     "Already wrapped table",
+			input:    "<div class=\"table-responsive\">\n<table></table></div>",
+			expected: "<div class=\"table-responsive\">\n<table></table></div>",
+		},
+		{
+			name:     "No tables",
+			input:    "<p>tablet</p>",
+			expected: "<p>tablet</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := WrapResponsiveTables(tt.input)
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+			// Wrapping is idempotent
+			if again := WrapResponsiveTables(result); again != result {
+				t.Errorf("Expected a second pass to change nothing, got: %q", again)
+			}
+		})
+	}
+}
+
+func TestWrapResponsiveTablesStickyHeaders(t *testing.T) {
+	ResponsiveTables, StickyTableHeaders = true, true
+	defer func() { ResponsiveTables, StickyTableHeaders = false, false }()
+
+	result := WrapResponsiveTables("<table></table><table></table>")
+	if strings.Count(result, `<div class="table-responsive" data-sticky-header="true"><table>`) != 2 {
+		t.Errorf("Expected both tables wrapped with sticky headers, got: %q", result)
+	}
+}
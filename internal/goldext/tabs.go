@@ -0,0 +1,184 @@
+package goldext
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	tabsOpenRegex      = regexp.MustCompile(`^:::\s*tabs\s*$`)
+	tabOpenRegex       = regexp.MustCompile(`^:::\s*tab\s+"([^"]*)"\s*(\{active\})?\s*$`)
+	containerOpenRegex = regexp.MustCompile(`^:::\s*[A-Za-z]`)
+)
+
+// tab is a single tab parsed from a ::: tabs group
+type tab struct {
+	title  string
+	active bool
+	body   []string
+}
+
+// TabsPreprocessor adds support for tab groups with markdown bodies:
+//
+//	::: tabs
+//	::: tab "First"
+//	content
+//	:::
+//	::: tab "Second" {active}
+//	content
+//	:::
+//	:::
+//
+// The first tab is active unless another one is marked {active}.
+// Tab groups can be nested inside tab bodies.
+func TabsPreprocessor(markdown string, _ string) string {
+	if !strings.Contains(markdown, ":::") {
+		return markdown
+	}
+
+	// Group IDs are counted per document so they are unique within the page
+	groupCount := 0
+	return strings.Join(processTabLines(strings.Split(markdown, "\n"), &groupCount), "\n")
+}
+
+// processTabLines replaces every complete tab group in lines with its HTML
+func processTabLines(lines []string, groupCount *int) []string {
+	var result []string
+	inCodeBlock := false
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			result = append(result, line)
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock {
+			result = append(result, line)
+			continue
+		}
+
+		if tabsOpenRegex.MatchString(trimmedLine) {
+			tabs, end, ok := parseTabGroup(lines, i)
+			if ok {
+				result = append(result, renderTabGroup(tabs, groupCount)...)
+				i = end
+				continue
+			}
+			// Unbalanced group: leave the markers as plain text
+		}
+
+		result = append(result, line)
+	}
+
+	return result
+}
+
+// parseTabGroup parses the tab group opened at lines[start]
+// It returns the tabs, the index of the closing line and whether the group was balanced
+func parseTabGroup(lines []string, start int) ([]tab, int, bool) {
+	var tabs []tab
+	var current *tab
+
+	depth := 1 // Containers open, starting with the tab group itself
+	inCodeBlock := false
+
+	for i := start + 1; i < len(lines); i++ {
+		line := lines[i]
+		trimmedLine := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+		} else if !inCodeBlock {
+			if trimmedLine == ":::" {
+				depth--
+				if depth == 0 {
+					// The group itself is closed
+					return tabs, i, current == nil && len(tabs) > 0
+				}
+				if depth == 1 && current != nil {
+					// The current tab is closed
+					tabs = append(tabs, *current)
+					current = nil
+					continue
+				}
+			} else if containerOpenRegex.MatchString(trimmedLine) && !strings.HasSuffix(trimmedLine[3:], ":::") {
+				if depth == 1 {
+					m := tabOpenRegex.FindStringSubmatch(trimmedLine)
+					if m == nil {
+						// Only tabs may appear directly inside a tab group
+						return nil, start, false
+					}
+					current = &tab{title: m[1], active: m[2] != ""}
+					depth++
+					continue
+				}
+				depth++
+			}
+		}
+
+		// Collect the tab body; anything between tabs is ignored
+		if current != nil {
+			current.body = append(current.body, line)
+		}
+	}
+
+	return nil, start, false
+}
+
+// renderTabGroup renders a tab group as accessible HTML with markdown tab bodies
+func renderTabGroup(tabs []tab, groupCount *int) []string {
+	*groupCount++
+	groupID := fmt.Sprintf("tabs-%d", *groupCount)
+
+	// The first tab is active unless another one is flagged
+	activeIndex := 0
+	for i, t := range tabs {
+		if t.active {
+			activeIndex = i
+			break
+		}
+	}
+
+	var result []string
+	result = append(result, `<div class="tabs" id="`+groupID+`"`+BlockAttributes("tabs")+`>`)
+	result = append(result, `<div class="tab-list" role="tablist">`)
+	for i, t := range tabs {
+		tabID := fmt.Sprintf("%s-tab-%d", groupID, i+1)
+		panelID := fmt.Sprintf("%s-panel-%d", groupID, i+1)
+		selected, tabIndex := "false", "-1"
+		if i == activeIndex {
+			selected, tabIndex = "true", "0"
+		}
+		result = append(result, `<button type="button" class="tab" role="tab" id="`+tabID+
+			`" aria-controls="`+panelID+`" aria-selected="`+selected+`" tabindex="`+tabIndex+`">`+
+			html.EscapeString(t.title)+`</button>`)
+	}
+	result = append(result, `</div>`)
+
+	for i, t := range tabs {
+		tabID := fmt.Sprintf("%s-tab-%d", groupID, i+1)
+		panelID := fmt.Sprintf("%s-panel-%d", groupID, i+1)
+		hidden := ""
+		if i != activeIndex {
+			hidden = " hidden"
+		}
+		result = append(result, `<div class="tab-panel" role="tabpanel" id="`+panelID+`" aria-labelledby="`+tabID+`"`+hidden+`>`)
+
+		// Blank lines around the body let Goldmark render it as markdown
+		result = append(result, "")
+		result = append(result, processTabLines(t.body, groupCount)...)
+		result = append(result, "")
+		result = append(result, `</div>`)
+	}
+
+	result = append(result, `</div>`)
+	return result
+}
@@ -0,0 +1,67 @@
+package goldext
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// TaskExternalIDPattern matches the external IDs accepted in task items, e.g. JIRA-123
+var TaskExternalIDPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]*-\d+$`)
+
+// TaskExternalURLTemplate is the URL of an external task, with {id} replaced by the task ID
+// (e.g. "https://jira.example.com/browse/{id}"). When empty, IDs are rendered without a link.
+var TaskExternalURLTemplate = ""
+
+var taskExternalIDRegex = regexp.MustCompile(`^(\s*(?:[-*+]|\d+[.)])\s+\[[ xX]\]\s+.*?)\s*\{#([^}\s]+)\}\s*$`)
+
+// TaskExternalIDPreprocessor turns a trailing {#ID} on task list items into a link to the
+// external task system, annotated with data attributes for syncing:
+//
+//	- [ ] Fix login redirect {#JIRA-123}
+func TaskExternalIDPreprocessor(markdown string, _ string) string {
+	if !strings.Contains(markdown, "{#") {
+		return markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+	inCodeBlock := false
+
+	for i, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock {
+			continue
+		}
+
+		m := taskExternalIDRegex.FindStringSubmatch(line)
+		if m == nil || !TaskExternalIDPattern.MatchString(m[2]) {
+			continue
+		}
+
+		lines[i] = m[1] + " " + renderTaskExternalID(m[2])
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// renderTaskExternalID renders the external ID of a task item
+func renderTaskExternalID(id string) string {
+	escapedID := html.EscapeString(id)
+	attrs := ` class="task-external-id" data-task-id="` + escapedID + `"`
+
+	if TaskExternalURLTemplate == "" {
+		return `<span` + attrs + `>` + escapedID + `</span>`
+	}
+
+	href := strings.ReplaceAll(TaskExternalURLTemplate, "{id}", url.PathEscape(id))
+	return `<a href="` + html.EscapeString(href) + `"` + attrs + ` target="_blank" rel="noopener">` + escapedID + `</a>`
+}
@@ -0,0 +1,66 @@
+package goldext
+
+import (
+	"testing"
+)
+
+func TestTaskExternalIDPreprocessor(t *testing.T) {
+	TaskExternalURLTemplate = "https://jira.example.com/browse/{id}"
+	defer func() { TaskExternalURLTemplate = "" }()
+
+	link := `<a href="https://jira.example.com/browse/JIRA-123" class="task-external-id" data-task-id="JIRA-123" target="_blank" rel="noopener">JIRA-123</a>`
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Open task with ID",
+			input:    "- [ ] Fix login {#JIRA-123}",
+			expected: "- [ ] Fix login " + link,
+		},
+		{
+			name:     "Done nested task with ID",
+			input:    "  * [x] Fix login {#JIRA-123} ",
+			expected: "  * [x] Fix login " + link,
+		},
+		{
+			name:     "Task without ID",
+			input:    "- [ ] Plain task",
+			expected: "- [ ] Plain task",
+		},
+		{
+			name:     "ID not matching the pattern",
+			input:    "- [ ] Task {#not-an-id}",
+			expected: "- [ ] Task {#not-an-id}",
+		},
+		{
+			name:     "Regular list item",
+			input:    "- Item {#JIRA-123}",
+			expected: "- Item {#JIRA-123}",
+		},
+		{
+			name:     "Code block",
+			input:    "```\n- [ ] Task {#JIRA-123}\n```",
+			expected: "```\n- [ ] Task {#JIRA-123}\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := TaskExternalIDPreprocessor(tt.input, "")
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestTaskExternalIDWithoutURLTemplate(t *testing.T) {
+	result := TaskExternalIDPreprocessor("- [ ] Task {#OPS-7}", "")
+	expected := `- [ ] Task <span class="task-external-id" data-task-id="OPS-7">OPS-7</span>`
+	if result != expected {
+		t.Errorf("Expected: %q, got: %q", expected, result)
+	}
+}
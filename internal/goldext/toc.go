@@ -3,9 +3,54 @@ package goldext
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
+// TocMaxEntries caps the number of entries in a generated table of contents
+// Remaining headings are summarized with an "…and N more" note. 0 means unlimited.
+var TocMaxEntries = 0
+
+// TocMinLevel and TocMaxLevel limit the heading levels listed in a table of contents
+// The default minimum of 2 leaves out the # document title.
+var (
+	TocMinLevel = 2
+	TocMaxLevel = 6
+)
+
+// tocMarkerRegex matches a line holding a [toc] marker, optionally with the deepest
+// heading level to list, as in [toc:3]; headingLineRegex matches an ATX heading line
+var (
+	tocMarkerRegex   = regexp.MustCompile(`^\s*\[toc(?::([1-6]))?\]\s*$`)
+	headingLineRegex = regexp.MustCompile(`^#{1,6}(?:\s|$)`)
+)
+
+// headingRegex matches an ATX heading with an optional trailing attribute list of
+// #id, .class and key=value entries, as Goldmark's attribute parser reads them, e.g.
+// ## Setup {#install .wide}; headingIDRegex finds the #id in such a list
+var (
+	headingRegex   = regexp.MustCompile(`^(#{1,6})\s+(.+?)(?:\s+\{\s*((?:(?:#[^\s{}]+|\.[^\s{}]+|[A-Za-z_:][\w:.-]*=(?:"[^"]*"|'[^']*'|[^\s"'{}]+))\s*)+)\})?$`)
+	headingIDRegex = regexp.MustCompile(`(?:^|\s)#([^\s{}]+)`)
+)
+
+// headingLinkRegex matches the markdown links, broken wikilinks and the <a> tags of
+// rendered links in heading text, which leave only their text in IDs and table of
+// contents entries
+var headingLinkRegex = regexp.MustCompile(`\[([^\]]+)\]\([^)]+\)|<span class="wikilink-broken"[^>]*>([^<]*)</span>|</?a(?:\s[^>]*)?>`)
+
+// headingLinkText replaces the links of heading text with their text
+func headingLinkText(text string) string {
+	return headingLinkRegex.ReplaceAllString(text, "$1$2")
+}
+
+// headingExplicitID returns the #id of a heading's attribute list, if it has one
+func headingExplicitID(attributes string) string {
+	if m := headingIDRegex.FindStringSubmatch(attributes); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
 // TocPreprocessor adds support for [toc] markers
 // This generates the complete table of contents during markdown processing
 // by scanning for headings in the document and building the TOC HTML structure
@@ -15,8 +60,6 @@ func TocPreprocessor(markdown string, _ string) string {
 	var result []string
 
 	inCodeBlock := false
-	tocMarker := regexp.MustCompile(`^\s*\[toc\]\s*$`)
-	headingRegex := regexp.MustCompile(`^(#{1,6})\s+(.+?)(?:\s+\{#([a-zA-Z0-9-]+)\})?$`)
 
 	// First pass: collect all headings and their levels
 	var headings []struct {
@@ -26,8 +69,22 @@ func TocPreprocessor(markdown string, _ string) string {
 		Line  string // Store the original line
 	}
 
-	// Track used IDs to avoid duplicates
+	// Track used IDs to avoid duplicates, starting with the IDs authors set themselves
+	// so generated IDs never take them
 	usedIDs := make(map[string]bool)
+	for _, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if matches := headingRegex.FindStringSubmatch(trimmedLine); !inCodeBlock && matches != nil {
+			if id := headingExplicitID(matches[3]); id != "" {
+				usedIDs[id] = true
+			}
+		}
+	}
+	inCodeBlock = false
 
 	for i, line := range lines {
 		// Check if this line starts or ends a code block
@@ -47,53 +104,54 @@ func TocPreprocessor(markdown string, _ string) string {
 		if matches != nil {
 			level := len(matches[1]) // Count the number of # characters
 			text := strings.TrimSpace(matches[2])
-			existingID := ""
+			attributes := strings.TrimSpace(matches[3])
 
-			// Check if the heading already has an ID
-			if len(matches) > 3 && matches[3] != "" {
-				existingID = matches[3]
-			}
+			// An ID the author set is kept as it is, even when it repeats
+			existingID := headingExplicitID(attributes)
+			id := existingID
+			if existingID == "" {
+				id = HeadingSlug(text)
+
+				// Ensure unique IDs
+				baseID := id
+				counter := 1
+				for usedIDs[id] {
+					id = fmt.Sprintf("%s-%d", baseID, counter)
+					counter++
+				}
 
-			// Remove any inline code or formatting from heading text for ID generation
-			idText := text
-			// Remove inline code
-			idText = regexp.MustCompile("`[^`]+`").ReplaceAllString(idText, "")
-			// Remove links
-			idText = regexp.MustCompile(`\[([^\]]+)\]\([^)]+\)`).ReplaceAllString(idText, "$1")
-
-			// Use existing ID or generate a new one
-			var id string
-			if existingID != "" {
-				id = existingID
-			} else {
-				id = makeSlug(idText)
+				// Mark this ID as used
+				usedIDs[id] = true
 			}
 
-			// Ensure unique IDs
-			baseID := id
-			counter := 1
-			for usedIDs[id] {
-				id = fmt.Sprintf("%s-%d", baseID, counter)
-				counter++
+			// Only list headings within the configured levels
+			if level >= TocMinLevel && level <= TocMaxLevel {
+				headings = append(headings, struct {
+					Level int
+					Text  string
+					ID    string
+					Line  string
+				}{Level: level, Text: headingLinkText(text), ID: id, Line: line})
 			}
 
-			// Mark this ID as used
-			usedIDs[id] = true
-
-			headings = append(headings, struct {
-				Level int
-				Text  string
-				ID    string
-				Line  string
-			}{Level: level, Text: text, ID: id, Line: line})
-
-			// If this heading doesn't already have an ID, we need to update it in the original lines
+			// If this heading doesn't already have an ID, we need to update it in the original
+			// lines, keeping the other attributes of the heading
 			if existingID == "" {
-				lines[i] = fmt.Sprintf("%s %s {#%s}", strings.Repeat("#", level), text, id)
+				if attributes != "" {
+					lines[i] = fmt.Sprintf("%s %s {#%s %s}", strings.Repeat("#", level), text, id, attributes)
+				} else {
+					lines[i] = fmt.Sprintf("%s %s {#%s}", strings.Repeat("#", level), text, id)
+				}
 			}
 		}
 	}
 
+	// Headings always get explicit IDs so links to them are stable,
+	// but without a marker there is no TOC to build
+	if !strings.Contains(markdown, "[toc") {
+		return strings.Join(lines, "\n")
+	}
+
 	// Second pass: Replace [toc] markers with generated TOC, but use the updated lines
 	inCodeBlock = false
 	for _, line := range lines {
@@ -113,9 +171,21 @@ func TocPreprocessor(markdown string, _ string) string {
 		}
 
 		// Process [toc] markers outside of code blocks
-		if tocMarker.MatchString(trimmedLine) {
+		if marker := tocMarkerRegex.FindStringSubmatch(trimmedLine); marker != nil {
+			// Leave out headings below the marker's level
+			listed := headings
+			if marker[1] != "" {
+				maxLevel, _ := strconv.Atoi(marker[1])
+				listed = headings[:0:0]
+				for _, heading := range headings {
+					if heading.Level <= maxLevel {
+						listed = append(listed, heading)
+					}
+				}
+			}
+
 			// Generate TOC HTML
-			tocHTML := generateTOCHTML(headings)
+			tocHTML := generateTOCHTML(listed)
 			result = append(result, tocHTML)
 		} else {
 			// Check for inline code sections and preserve them
@@ -147,6 +217,81 @@ func TocPreprocessor(markdown string, _ string) string {
 	return strings.Join(result, "\n")
 }
 
+// FrontmatterTOC applies the toc settings of a document's frontmatter to its markdown
+// With insert, a [toc] marker is put after the first heading, or at the top of documents
+// without one, unless the document has a marker of its own. A maxLevel from 1 to 6 limits
+// the headings listed by the document's marker lines that don't set a level themselves.
+func FrontmatterTOC(markdown string, insert bool, maxLevel int) string {
+	marker := "[toc]"
+	if maxLevel >= 1 && maxLevel <= 6 {
+		marker = "[toc:" + strconv.Itoa(maxLevel) + "]"
+	}
+
+	lines := strings.Split(markdown, "\n")
+	inCodeBlock := false
+	hasMarker := false
+	firstHeading := -1
+
+	for i, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock || strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+
+		if found := tocMarkerRegex.FindStringSubmatch(trimmedLine); found != nil {
+			hasMarker = true
+			if found[1] == "" {
+				lines[i] = strings.Replace(line, "[toc]", marker, 1)
+			}
+			continue
+		}
+
+		// Markers inside a line count too, outside inline code
+		for j, segment := range strings.Split(line, "`") {
+			if j%2 == 0 && strings.Contains(segment, "[toc]") {
+				hasMarker = true
+			}
+		}
+
+		if firstHeading < 0 && strings.HasPrefix(trimmedLine, "#") && headingLineRegex.MatchString(trimmedLine) {
+			firstHeading = i
+		}
+	}
+
+	if !insert || hasMarker {
+		return strings.Join(lines, "\n")
+	}
+
+	// Put the marker in a paragraph of its own after the first heading
+	at := firstHeading + 1
+	inserted := append([]string{}, lines[:at]...)
+	inserted = append(inserted, "", marker, "")
+	inserted = append(inserted, lines[at:]...)
+	return strings.Join(inserted, "\n")
+}
+
+// HeadingSlug returns the ID TocPreprocessor gives a heading with the given text,
+// before duplicates are numbered. Links, wikilinks included, count with the text they
+// display; wikilinks still written as [[target]] are resolved from the documents root.
+func HeadingSlug(text string) string {
+	// Remove any inline code or formatting from heading text for ID generation
+	idText := WikilinkText(strings.TrimSpace(text), "")
+	// Remove inline code
+	idText = regexp.MustCompile("`[^`]+`").ReplaceAllString(idText, "")
+	// Remove links
+	idText = headingLinkText(idText)
+
+	return makeSlug(idText)
+}
+
 // makeSlug creates a URL-friendly slug from text
 func makeSlug(text string) string {
 	// Convert to lowercase
@@ -192,16 +337,33 @@ func generateTOCHTML(headings []struct {
 		return `<div class="wiki-toc"><p class="toc-empty">No headings found in this document.</p></div>`
 	}
 
+	// Apply the entry cap
+	hiddenCount := 0
+	if TocMaxEntries > 0 && len(headings) > TocMaxEntries {
+		hiddenCount = len(headings) - TocMaxEntries
+		headings = headings[:TocMaxEntries]
+	}
+
 	// Start building the TOC HTML
 	var tocBuilder strings.Builder
 	tocBuilder.WriteString(`<nav class="wiki-toc table-of-contents" aria-label="Table of Contents">`)
 	tocBuilder.WriteString(`<div class="toc-title">Table of Contents</div>`)
 
+	// Nest relative to the highest listed heading, so a TOC of ## headings starts at the top list
+	topLevel := headings[0].Level
+	for _, heading := range headings {
+		if heading.Level < topLevel {
+			topLevel = heading.Level
+		}
+	}
+
 	// Track the current list level
 	currentLevel := 0
 
 	// Start the list
 	for i, heading := range headings {
+		heading.Level = heading.Level - topLevel + 1
+
 		// Handle level changes
 		if i == 0 {
 			// First heading - open lists up to this level
@@ -220,11 +382,11 @@ func generateTOCHTML(headings []struct {
 			}
 			currentLevel = heading.Level
 		} else if heading.Level < currentLevel {
-			// Going up - close list(s)
+			// Going up - close the previous item and the list(s) around it
+			tocBuilder.WriteString(`</li>`)
 			for j := currentLevel; j > heading.Level; j-- {
 				tocBuilder.WriteString(`</ul></li>`)
 			}
-			tocBuilder.WriteString(`</li>`)
 			currentLevel = heading.Level
 		} else {
 			// Same level - close previous item
@@ -244,6 +406,10 @@ func generateTOCHTML(headings []struct {
 		}
 	}
 
+	if hiddenCount > 0 {
+		tocBuilder.WriteString(fmt.Sprintf(`<div class="toc-more">…and %d more</div>`, hiddenCount))
+	}
+
 	tocBuilder.WriteString(`</nav>`)
 	return tocBuilder.String()
 }
@@ -0,0 +1,145 @@
+package goldext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTocMaxEntries(t *testing.T) {
+	TocMaxEntries = 3
+	defer func() { TocMaxEntries = 0 }()
+
+	result := TocPreprocessor("[toc]\n\n## One\n## Two\n## Three\n## Four\n## Five\n", "")
+
+	for _, id := range []string{"#one", "#two", "#three"} {
+		if !strings.Contains(result, `href="`+id+`"`) {
+			t.Errorf("Expected TOC entry %q, got: %q", id, result)
+		}
+	}
+	for _, id := range []string{"#four", "#five"} {
+		if strings.Contains(result, `href="`+id+`"`) {
+			t.Errorf("Expected TOC entry %q to be truncated, got: %q", id, result)
+		}
+	}
+	if !strings.Contains(result, `<div class="toc-more">…and 2 more</div></nav>`) {
+		t.Errorf("Expected truncation note, got: %q", result)
+	}
+}
+
+func TestTocMaxEntriesNotExceeded(t *testing.T) {
+	TocMaxEntries = 5
+	defer func() { TocMaxEntries = 0 }()
+
+	result := TocPreprocessor("[toc]\n\n# One\n## Two\n", "")
+	if strings.Contains(result, "toc-more") {
+		t.Errorf("Expected no truncation note, got: %q", result)
+	}
+}
+
+func TestTocLevels(t *testing.T) {
+	md := "# Title\n\n[toc]\n\n## Setup\n### Install\n#### Details\n## Usage\n"
+
+	result := TocPreprocessor(md, "")
+	expected := `<ul class="toc-list"><li><a href="#setup">Setup</a><ul><li><a href="#install">Install</a><ul><li><a href="#details">Details</a></li></ul></li></ul></li><li><a href="#usage">Usage</a></li></ul>`
+	if !strings.Contains(result, expected) {
+		t.Errorf("Expected nested TOC %q, got: %q", expected, result)
+	}
+	if strings.Contains(result, `href="#title"`) {
+		t.Errorf("Expected the title to be left out, got: %q", result)
+	}
+	if !strings.Contains(result, "## Setup {#setup}") {
+		t.Errorf("Expected headings to get matching IDs, got: %q", result)
+	}
+
+	TocMaxLevel = 3
+	defer func() { TocMaxLevel = 6 }()
+	if result := TocPreprocessor(md, ""); strings.Contains(result, `href="#details"`) {
+		t.Errorf("Expected headings below the maximum level to be left out, got: %q", result)
+	}
+}
+
+func TestTocWithoutMarker(t *testing.T) {
+	result := TocPreprocessor("# Title\n\n## Setup\n", "")
+	if expected := "# Title {#title}\n\n## Setup {#setup}\n"; result != expected {
+		t.Errorf("Expected %q, got: %q", expected, result)
+	}
+}
+
+func TestFrontmatterTOC(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		insert   bool
+		maxLevel int
+		expected string
+	}{
+		{"Inserted after the first heading", "# Title\nIntro\n\n## Setup\n", true, 0, "# Title\n\n[toc]\n\nIntro\n\n## Setup\n"},
+		{"Inserted with a level", "# Title\n\n## Setup\n", true, 2, "# Title\n\n[toc:2]\n\n\n## Setup\n"},
+		{"Top of documents without headings", "Just text\n", true, 0, "\n[toc]\n\nJust text\n"},
+		{"Explicit marker wins", "# Title\n\n## Setup\n\n[toc]\n", true, 0, "# Title\n\n## Setup\n\n[toc]\n"},
+		{"Explicit marker gets the level", "# Title\n\n[toc]\n", true, 3, "# Title\n\n[toc:3]\n"},
+		{"Explicit level kept", "# Title\n\n[toc:4]\n", false, 2, "# Title\n\n[toc:4]\n"},
+		{"Inline marker wins", "# Title\n\nSee [toc] here\n", true, 0, "# Title\n\nSee [toc] here\n"},
+		{"Markers in code don't count", "```\n[toc]\n# Not a heading\n```\n# Title\n", true, 0, "```\n[toc]\n# Not a heading\n```\n# Title\n\n[toc]\n\n"},
+		{"Level only", "# Title\n\n## Setup\n", false, 2, "# Title\n\n## Setup\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := FrontmatterTOC(tt.input, tt.insert, tt.maxLevel); result != tt.expected {
+				t.Errorf("Expected %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+
+	// A marker's level leaves out deeper headings
+	result := TocPreprocessor("# Title\n\n[toc:2]\n\n## Setup\n\n### Details\n", "")
+	if !strings.Contains(result, `href="#setup"`) || strings.Contains(result, `href="#details"`) {
+		t.Errorf("Expected only ## headings in the TOC, got: %q", result)
+	}
+}
+
+func TestTocExplicitIDs(t *testing.T) {
+	input := "[toc]\n\n## Setup {#stable_id}\n\n## Usage {#Api.V2 .wide}\n\n## Notes {.wide}\n\n## Setup\n"
+	result := TocPreprocessor(input, "")
+
+	for _, expected := range []string{
+		"## Setup {#stable_id}\n",
+		"## Usage {#Api.V2 .wide}\n",
+		"## Notes {#notes .wide}\n",
+		"## Setup {#setup}\n",
+		`href="#stable_id"`,
+		`href="#Api.V2"`,
+		`href="#notes"`,
+		`href="#setup"`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected %q in the result, got: %q", expected, result)
+		}
+	}
+	if strings.Contains(result, "stable-id") || strings.Contains(result, "setup-1") {
+		t.Errorf("Expected no generated IDs for headings with an explicit ID, got: %q", result)
+	}
+
+	// Generated IDs don't take an ID set elsewhere in the document
+	result = TocPreprocessor("[toc]\n\n## Usage\n\n## Other {#usage}\n", "")
+	if !strings.Contains(result, "## Usage {#usage-1}\n") || !strings.Contains(result, "## Other {#usage}\n") {
+		t.Errorf("Expected the generated ID to avoid the explicit one, got: %q", result)
+	}
+}
+
+func TestTocLinkedHeadings(t *testing.T) {
+	md := "[toc]\n\n## See [[#top|Intro]]\n\n## Read [the docs](https://example.com)\n\n## Missing [[/nowhere]]"
+	result := TocPreprocessor(WikilinkPreprocessor(md, ""), "")
+
+	for _, expected := range []string{
+		`<li><a href="#see-intro">See Intro</a></li>`,
+		`<li><a href="#read-the-docs">Read the docs</a></li>`,
+		`<li><a href="#missing-nowhere">Missing nowhere</a></li>`,
+		`class="wikilink">Intro</a> {#see-intro}`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected %q, got: %q", expected, result)
+		}
+	}
+}
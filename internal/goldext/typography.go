@@ -13,17 +13,17 @@ func TypographyPreprocessor(markdown string, _ string) string {
 
 	inCodeBlock := false
 
-	// Define replacements mapping
-	replacements := map[string]string{
-		"(c)":  "©", // Copyright symbol
-		"(r)":  "®", // Registered trademark symbol
-		"(tm)": "™", // Trademark symbol
-		"(p)":  "¶", // Paragraph symbol
-		"+-":   "±", // Plus-minus symbol
-		"...":  "…", // Ellipsis
-		"(1/2)":  "½", // One-half
-		"(1/4)":  "¼", // One-quarter
-		"(3/4)":  "¾", // Three-quarters
+	// Define replacements, applied in this order so the output doesn't depend on map iteration
+	replacements := []struct{ shortcut, symbol string }{
+		{"(c)", "©"},   // Copyright symbol
+		{"(r)", "®"},   // Registered trademark symbol
+		{"(tm)", "™"},  // Trademark symbol
+		{"(p)", "¶"},   // Paragraph symbol
+		{"+-", "±"},    // Plus-minus symbol
+		{"...", "…"},   // Ellipsis
+		{"(1/2)", "½"}, // One-half
+		{"(1/4)", "¼"}, // One-quarter
+		{"(3/4)", "¾"}, // Three-quarters
 	}
 
 	for _, line := range lines {
@@ -49,8 +49,8 @@ func TypographyPreprocessor(markdown string, _ string) string {
 			// Even segments (0, 2, 4...) are outside inline code
 			if i%2 == 0 {
 				// Apply all replacements to non-code segments
-				for shortcut, symbol := range replacements {
-					segment = strings.ReplaceAll(segment, shortcut, symbol)
+				for _, replacement := range replacements {
+					segment = strings.ReplaceAll(segment, replacement.shortcut, replacement.symbol)
 				}
 				processedLine += segment
 			} else {
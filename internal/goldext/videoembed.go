@@ -0,0 +1,65 @@
+package goldext
+
+import (
+	"regexp"
+	"strings"
+)
+
+// VideoAutoEmbed turns YouTube and Vimeo URLs standing alone in a paragraph into embedded
+// players. Links inside a paragraph stay links. Embeds load the video site's scripts, so
+// deployments that don't want third-party requests can disable it. Enabled by default.
+var VideoAutoEmbed = true
+
+// Watch URLs of YouTube (youtube.com/watch?v= and youtu.be/ short links) and Vimeo
+var (
+	youTubeWatchURLRegex = regexp.MustCompile(`^https?://(?:(?:www\.|m\.)?youtube\.com/watch\?(?:[^#\s]*&)?v=|youtu\.be/)([A-Za-z0-9_-]{11})(?:[?&#]\S*)?$`)
+	vimeoWatchURLRegex   = regexp.MustCompile(`^https?://(?:www\.)?vimeo\.com/(\d+)(?:[/?#]\S*)?$`)
+)
+
+// VideoEmbedPreprocessor replaces YouTube and Vimeo watch URLs on a line of their own,
+// between blank lines, with the players the ```youtube and ```vimeo blocks render.
+func VideoEmbedPreprocessor(markdown string, _ string) string {
+	if !VideoAutoEmbed || (!strings.Contains(markdown, "youtu") && !strings.Contains(markdown, "vimeo.com")) {
+		return markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+	inCodeBlock := false
+
+	for i, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock || strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+
+		// Only a paragraph of its own is embedded
+		if (i > 0 && strings.TrimSpace(lines[i-1]) != "") || (i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "") {
+			continue
+		}
+
+		if embed, ok := videoEmbedHTML(strings.TrimSuffix(strings.TrimPrefix(trimmedLine, "<"), ">")); ok {
+			lines[i] = embed
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// videoEmbedHTML returns the player of a YouTube or Vimeo watch URL
+func videoEmbedHTML(videoURL string) (string, bool) {
+	if m := youTubeWatchURLRegex.FindStringSubmatch(videoURL); m != nil {
+		return youTubeEmbedHTML(m[1]), true
+	}
+	if m := vimeoWatchURLRegex.FindStringSubmatch(videoURL); m != nil {
+		return vimeoEmbedHTML(m[1]), true
+	}
+	return "", false
+}
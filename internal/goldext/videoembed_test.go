@@ -0,0 +1,51 @@
+package goldext
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVideoEmbedPreprocessor(t *testing.T) {
+	youTube := youTubeEmbedHTML("dQw4w9WgXcQ")
+	vimeo := vimeoEmbedHTML("76979871")
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Watch URL", "Intro\n\nhttps://www.youtube.com/watch?v=dQw4w9WgXcQ\n\nMore", "Intro\n\n" + youTube + "\n\nMore"},
+		{"Watch URL with parameters", "https://youtube.com/watch?feature=share&v=dQw4w9WgXcQ&t=42s", youTube},
+		{"Short link", "  https://youtu.be/dQw4w9WgXcQ?t=10", youTube},
+		{"Angle brackets", "<https://youtu.be/dQw4w9WgXcQ>", youTube},
+		{"Vimeo", "https://vimeo.com/76979871", vimeo},
+		{"Inline link", "Watch https://youtu.be/dQw4w9WgXcQ today", "Watch https://youtu.be/dQw4w9WgXcQ today"},
+		{"Inside a paragraph", "Watch this:\nhttps://youtu.be/dQw4w9WgXcQ", "Watch this:\nhttps://youtu.be/dQw4w9WgXcQ"},
+		{"Markdown link", "[Video](https://youtu.be/dQw4w9WgXcQ)", "[Video](https://youtu.be/dQw4w9WgXcQ)"},
+		{"Other pages", "https://www.youtube.com/channel/UC123\n\nhttps://vimeo.com/about", "https://www.youtube.com/channel/UC123\n\nhttps://vimeo.com/about"},
+		{"Code block", "```\nhttps://youtu.be/dQw4w9WgXcQ\n```", "```\nhttps://youtu.be/dQw4w9WgXcQ\n```"},
+		{"Indented code", "    https://youtu.be/dQw4w9WgXcQ", "    https://youtu.be/dQw4w9WgXcQ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := VideoEmbedPreprocessor(tt.input, ""); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	if !strings.Contains(youTube, `src="https://www.youtube.com/embed/dQw4w9WgXcQ"`) || !strings.Contains(vimeo, `src="https://player.vimeo.com/video/76979871"`) {
+		t.Errorf("Unexpected players: %q %q", youTube, vimeo)
+	}
+}
+
+func TestVideoAutoEmbedDisabled(t *testing.T) {
+	VideoAutoEmbed = false
+	defer func() { VideoAutoEmbed = true }()
+
+	input := "https://youtu.be/dQw4w9WgXcQ"
+	if result := VideoEmbedPreprocessor(input, ""); result != input {
+		t.Errorf("Expected no embed when disabled, got %q", result)
+	}
+}
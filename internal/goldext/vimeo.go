@@ -80,17 +80,7 @@ func VimeoPreprocessor(markdown string, _ string) string {
 
 					if videoID != "" {
 						// Create replacement HTML
-						videoURL := "https://vimeo.com/" + videoID
-						replacement := `<div class="video-container">
-<iframe src="https://player.vimeo.com/video/` + videoID + `"
-width="560" height="315" frameborder="0"
-allow="autoplay; fullscreen; picture-in-picture" allowfullscreen></iframe>
-</div>
-<div class="video-print-placeholder">
-<p><strong>Vimeo Video</strong></p>
-<p>This embedded video is not available in print. You can view it online at:</p>
-<p><a href="` + videoURL + `">` + videoURL + `</a></p>
-</div>`
+						replacement := vimeoEmbedHTML(videoID)
 
 						replacements[vimeoStart] = replacement
 					}
@@ -130,17 +120,7 @@ allow="autoplay; fullscreen; picture-in-picture" allowfullscreen></iframe>
 
 					if videoID != "" {
 						// Create replacement HTML
-						videoURL := "https://vimeo.com/" + videoID
-						replacement := `<div class="video-container">
-<iframe src="https://player.vimeo.com/video/` + videoID + `"
-width="560" height="315" frameborder="0"
-allow="autoplay; fullscreen; picture-in-picture" allowfullscreen></iframe>
-</div>
-<div class="video-print-placeholder">
-<p><strong>Vimeo Video</strong></p>
-<p>This embedded video is not available in print. You can view it online at:</p>
-<p><a href="` + videoURL + `">` + videoURL + `</a></p>
-</div>`
+						replacement := vimeoEmbedHTML(videoID)
 
 						replacements[vimeoStart] = replacement
 					}
@@ -171,17 +151,7 @@ allow="autoplay; fullscreen; picture-in-picture" allowfullscreen></iframe>
 
 		if videoID != "" {
 			// Create replacement HTML
-			videoURL := "https://vimeo.com/" + videoID
-			replacement := `<div class="video-container">
-<iframe src="https://player.vimeo.com/video/` + videoID + `"
-width="560" height="315" frameborder="0"
-allow="autoplay; fullscreen; picture-in-picture" allowfullscreen></iframe>
-</div>
-<div class="video-print-placeholder">
-<p><strong>Vimeo Video</strong></p>
-<p>This embedded video is not available in print. You can view it online at:</p>
-<p><a href="` + videoURL + `">` + videoURL + `</a></p>
-</div>`
+			replacement := vimeoEmbedHTML(videoID)
 
 			replacements[vimeoStart] = replacement
 		}
@@ -203,3 +173,18 @@ allow="autoplay; fullscreen; picture-in-picture" allowfullscreen></iframe>
 
 	return strings.Join(result, "\n")
 }
+
+// vimeoEmbedHTML returns the embedded player of a Vimeo video, with a link for print
+func vimeoEmbedHTML(videoID string) string {
+	videoURL := "https://vimeo.com/" + videoID
+	return `<div class="video-container">
+<iframe src="https://player.vimeo.com/video/` + videoID + `"
+width="560" height="315" frameborder="0"
+allow="autoplay; fullscreen; picture-in-picture" allowfullscreen></iframe>
+</div>
+<div class="video-print-placeholder">
+<p><strong>Vimeo Video</strong></p>
+<p>This embedded video is not available in print. You can view it online at:</p>
+<p><a href="` + videoURL + `">` + videoURL + `</a></p>
+</div>`
+}
@@ -0,0 +1,320 @@
+package goldext
+
+import (
+	"container/list"
+	"html"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"wiki-go/internal/frontmatter"
+
+	"github.com/gosimple/slug"
+)
+
+// WikilinkRoot is the documents directory [[wikilinks]] are resolved against
+var WikilinkRoot = filepath.Join("data", "documents")
+
+// WikilinkTitles shows [[target]] links without display text as the title of the linked
+// document: its frontmatter title, else its first heading. Enabled by default.
+var WikilinkTitles = true
+
+// WikilinkTitleCacheMaxEntries is the number of document titles wikilinks keep in memory
+// The least recently used title is dropped first; 0 disables the cache.
+var WikilinkTitleCacheMaxEntries = 1024
+
+// wikilinkRegex matches [[target]] and [[target|text]]; the pipe may be escaped as \| inside tables
+var wikilinkRegex = regexp.MustCompile(`\[\[([^\[\]|\\]+?)(?:\\?\|([^\[\]]+?))?\]\]`)
+
+// WikilinkPreprocessor turns [[Page Name]] and [[path/page|display text]] into links to
+// the matching documents. Relative targets are looked up next to the current document,
+// then below it and then from the documents root; absolute targets (/path) only from the root.
+// Targets that can't be resolved render as <span class="wikilink-broken">.
+func WikilinkPreprocessor(markdown string, docPath string) string {
+	return WikilinkPreprocessorWithFiles(nil)(markdown, docPath)
+}
+
+// WikilinkPreprocessorWithFiles returns a WikilinkPreprocessor looking up the linked
+// documents in files, or in the operating system's filesystem when files is nil
+func WikilinkPreprocessorWithFiles(files FileSystem) Preprocessor {
+	files = filesOrOS(files)
+	return func(markdown string, docPath string) string {
+		return replaceWikilinks(markdown, docPath, func(target, text, docPath string) string {
+			return renderWikilink(files, target, text, docPath)
+		})
+	}
+}
+
+// WikilinkText replaces the [[wikilinks]] of markdown with the text they display, so
+// heading IDs and tables of contents can be derived from what readers see
+func WikilinkText(markdown string, docPath string) string {
+	return replaceWikilinks(markdown, docPath, func(target, text, docPath string) string {
+		displayText, _, _ := wikilinkDisplay(osFiles{}, target, text, docPath)
+		return displayText
+	})
+}
+
+// replaceWikilinks replaces the wikilinks of markdown outside code with the result of
+// replace for their trimmed target and text
+func replaceWikilinks(markdown string, docPath string, replace func(target, text, docPath string) string) string {
+	if !strings.Contains(markdown, "[[") {
+		return markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+	inCodeBlock := false
+
+	for i, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock || !strings.Contains(line, "[[") {
+			continue
+		}
+
+		// Skip inline code
+		parts := strings.Split(line, "`")
+		for j := 0; j < len(parts); j += 2 {
+			parts[j] = wikilinkRegex.ReplaceAllStringFunc(parts[j], func(match string) string {
+				m := wikilinkRegex.FindStringSubmatch(match)
+				return replace(strings.TrimSpace(m[1]), strings.TrimSpace(m[2]), docPath)
+			})
+		}
+		lines[i] = strings.Join(parts, "`")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// renderWikilink renders a single wikilink as a link or as a broken-link marker
+func renderWikilink(files FileSystem, target, text, docPath string) string {
+	displayText, href, ok := wikilinkDisplay(files, target, text, docPath)
+	if !ok {
+		if i := strings.Index(target, "#"); i >= 0 {
+			target = strings.TrimSpace(target[:i])
+		}
+		return `<span class="wikilink-broken" title="Page not found: ` + html.EscapeString(target) + `">` + html.EscapeString(displayText) + `</span>`
+	}
+	return `<a href="` + html.EscapeString(href) + `" class="wikilink">` + html.EscapeString(displayText) + `</a>`
+}
+
+// wikilinkDisplay resolves a wikilink and returns the text it displays and its URL
+func wikilinkDisplay(files FileSystem, target, text, docPath string) (string, string, bool) {
+	fragment := ""
+	if i := strings.Index(target, "#"); i >= 0 {
+		target, fragment = strings.TrimSpace(target[:i]), target[i:]
+	}
+
+	displayText := text
+	if displayText == "" {
+		displayText = path.Base(strings.Trim(target, "/"))
+		if displayText == "." || displayText == "" {
+			displayText = strings.TrimPrefix(fragment, "#")
+		}
+	}
+
+	resolved, ok := resolveWikilink(files, target, docPath)
+	if !ok {
+		return displayText, "", false
+	}
+
+	// Links to other documents show their title, or else their humanized name
+	if text == "" && target != "" && WikilinkTitles {
+		if title := wikilinkTitle(files, resolved); title != "" {
+			displayText = title
+		} else {
+			displayText = humanizeWikilink(displayText)
+		}
+	}
+
+	return displayText, JoinBasePath(BasePath, (&url.URL{Path: "/" + resolved}).EscapedPath()+fragment), true
+}
+
+// ResolveWikilink returns the document path a wikilink target points to
+// An empty target refers to the current document, which allows [[#section]] links.
+func ResolveWikilink(target, docPath string) (string, bool) {
+	return resolveWikilink(osFiles{}, target, docPath)
+}
+
+// resolveWikilink resolves a wikilink target against the documents in files
+func resolveWikilink(files FileSystem, target, docPath string) (string, bool) {
+	docPath = strings.Trim(docPath, "/")
+	if target == "" {
+		return docPath, true
+	}
+
+	var bases []string
+	if strings.HasPrefix(target, "/") {
+		bases = []string{""}
+	} else {
+		parent := path.Dir(docPath)
+		if parent == "." {
+			parent = ""
+		}
+		bases = []string{parent, docPath, ""}
+	}
+
+	segments := strings.Split(strings.Trim(target, "/"), "/")
+	for _, segment := range segments {
+		if segment == ".." || segment == "." || segment == "" {
+			return "", false
+		}
+	}
+
+	for _, base := range bases {
+		if resolved, ok := resolveWikilinkSegments(files, base, segments); ok {
+			return resolved, true
+		}
+	}
+	return "", false
+}
+
+// resolveWikilinkSegments looks up target segments below the base document path
+// Directories must match exactly except for the final segment, which matches
+// case-insensitively or by slug, e.g. "Page Name" finds page-name
+func resolveWikilinkSegments(files FileSystem, base string, segments []string) (string, bool) {
+	dir := path.Join(append([]string{base}, segments[:len(segments)-1]...)...)
+	final := segments[len(segments)-1]
+
+	entries, err := files.ReadDir(filepath.Join(WikilinkRoot, filepath.FromSlash(dir)))
+	if err != nil {
+		return "", false
+	}
+
+	finalSlug := slug.Make(final)
+	match := ""
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		name := entry.Name()
+		if name == final {
+			match = name
+			break
+		}
+		if match == "" && (strings.EqualFold(name, final) || (finalSlug != "" && slug.Make(name) == finalSlug)) {
+			match = name
+		}
+	}
+
+	if match == "" {
+		return "", false
+	}
+	return path.Join(dir, match), true
+}
+
+// wikilinkTitleEntry is the title of a document at a given modification time and size
+type wikilinkTitleEntry struct {
+	path    string
+	modTime time.Time
+	size    int64
+	title   string
+}
+
+// Titles of linked documents by file path, most recently used at the front of the list
+var (
+	wikilinkTitleMutex   sync.Mutex
+	wikilinkTitleList    = list.New()
+	wikilinkTitleEntries = make(map[string]*list.Element)
+)
+
+// wikilinkTitle returns the title of the document at a resolved wikilink path, empty when
+// the document can't be read or has no title. Only titles read from the operating
+// system's filesystem are cached, since other filesystems can hold the same paths.
+func wikilinkTitle(files FileSystem, resolved string) string {
+	filePath := filepath.Join(WikilinkRoot, filepath.FromSlash(resolved), "document.md")
+	if _, ok := files.(osFiles); !ok {
+		content, err := files.ReadFile(filePath)
+		if err != nil {
+			return ""
+		}
+		return documentTitle(string(content))
+	}
+
+	info, err := files.Stat(filePath)
+	if err != nil {
+		return ""
+	}
+
+	wikilinkTitleMutex.Lock()
+	if element, ok := wikilinkTitleEntries[filePath]; ok {
+		entry := element.Value.(*wikilinkTitleEntry)
+		if entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+			wikilinkTitleList.MoveToFront(element)
+			wikilinkTitleMutex.Unlock()
+			return entry.title
+		}
+	}
+	wikilinkTitleMutex.Unlock()
+
+	content, err := files.ReadFile(filePath)
+	if err != nil {
+		return ""
+	}
+	title := documentTitle(string(content))
+
+	wikilinkTitleMutex.Lock()
+	defer wikilinkTitleMutex.Unlock()
+	if WikilinkTitleCacheMaxEntries <= 0 {
+		return title
+	}
+	entry := &wikilinkTitleEntry{path: filePath, modTime: info.ModTime(), size: info.Size(), title: title}
+	if element, ok := wikilinkTitleEntries[filePath]; ok {
+		element.Value = entry
+		wikilinkTitleList.MoveToFront(element)
+	} else {
+		wikilinkTitleEntries[filePath] = wikilinkTitleList.PushFront(entry)
+	}
+	for wikilinkTitleList.Len() > WikilinkTitleCacheMaxEntries {
+		oldest := wikilinkTitleList.Back()
+		wikilinkTitleList.Remove(oldest)
+		delete(wikilinkTitleEntries, oldest.Value.(*wikilinkTitleEntry).path)
+	}
+	return title
+}
+
+// documentTitle returns the frontmatter title of a document, else the text of its first heading
+func documentTitle(content string) string {
+	metadata, body, _ := frontmatter.Parse(content)
+	if title := strings.TrimSpace(metadata.Title); title != "" {
+		return title
+	}
+
+	inCodeBlock := false
+	for _, line := range strings.Split(body, "\n") {
+		trimmedLine := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if inCodeBlock {
+			continue
+		}
+		if matches := headingRegex.FindStringSubmatch(trimmedLine); matches != nil {
+			return strings.TrimSpace(strings.TrimRight(matches[2], "#"))
+		}
+	}
+	return ""
+}
+
+// humanizeWikilink turns a page name such as getting-started into Getting started
+func humanizeWikilink(name string) string {
+	name = strings.Join(strings.Fields(strings.NewReplacer("-", " ", "_", " ").Replace(name)), " ")
+	first, size := utf8.DecodeRuneInString(name)
+	if first == utf8.RuneError {
+		return name
+	}
+	return string(unicode.ToUpper(first)) + name[size:]
+}
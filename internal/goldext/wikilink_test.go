@@ -0,0 +1,146 @@
+package goldext
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWikilinkPreprocessor(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"guides/getting-started", "guides/Install", "guides/start/child", "reference/api", "my docs/notes"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	WikilinkRoot = root
+	defer func() { WikilinkRoot = filepath.Join("data", "documents") }()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Sibling by title slug",
+			input:    "See [[Getting Started]].",
+			expected: `See <a href="/guides/getting-started" class="wikilink">Getting Started</a>.`,
+		},
+		{
+			name:     "Case-insensitive final segment",
+			input:    "[[install|Installation]]",
+			expected: `<a href="/guides/Install" class="wikilink">Installation</a>`,
+		},
+		{
+			name:     "Child of the current document",
+			input:    "[[child]]",
+			expected: `<a href="/guides/start/child" class="wikilink">Child</a>`,
+		},
+		{
+			name:     "Path from the root with fragment",
+			input:    "[[reference/API#errors|API errors]]",
+			expected: `<a href="/reference/api#errors" class="wikilink">API errors</a>`,
+		},
+		{
+			name:     "Absolute path with spaces",
+			input:    "[[/my docs/Notes]]",
+			expected: `<a href="/my%20docs/notes" class="wikilink">Notes</a>`,
+		},
+		{
+			name:     "Same-page fragment",
+			input:    "[[#usage|Usage]]",
+			expected: `<a href="/guides/start#usage" class="wikilink">Usage</a>`,
+		},
+		{
+			name:     "Escaped pipe inside a table",
+			input:    `| [[install\|Install]] | x |`,
+			expected: `| <a href="/guides/Install" class="wikilink">Install</a> | x |`,
+		},
+		{
+			name:     "Broken link",
+			input:    "[[Missing <Page>]]",
+			expected: `<span class="wikilink-broken" title="Page not found: Missing &lt;Page&gt;">Missing &lt;Page&gt;</span>`,
+		},
+		{
+			name:     "Intermediate directories are case-sensitive",
+			input:    "[[Reference/api]]",
+			expected: `<span class="wikilink-broken" title="Page not found: Reference/api">api</span>`,
+		},
+		{
+			name:     "Path traversal",
+			input:    "[[../guides]]",
+			expected: `<span class="wikilink-broken" title="Page not found: ../guides">guides</span>`,
+		},
+		{
+			name:     "Code is left alone",
+			input:    "`[[install]]`\n```\n[[install]]\n```",
+			expected: "`[[install]]`\n```\n[[install]]\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := WikilinkPreprocessor(tt.input, "guides/start")
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestWikilinkTitles(t *testing.T) {
+	root := t.TempDir()
+	documents := map[string]string{
+		"architecture/overview":  "---\ntitle: Architecture Overview\n---\n# Ignored heading\n",
+		"architecture/data":      "```\n# Not a heading\n```\n\nIntro\n\n## Data Model {#model}\n",
+		"architecture/notes":     "No headings here.\n",
+		"architecture/new-ideas": "",
+	}
+	for dir, content := range documents {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if content != "" {
+			if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(dir), "document.md"), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	WikilinkRoot = root
+	defer func() { WikilinkRoot = filepath.Join("data", "documents") }()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[[architecture/overview]]", `<a href="/architecture/overview" class="wikilink">Architecture Overview</a>`},
+		{"[[overview#goals]]", `<a href="/architecture/overview#goals" class="wikilink">Architecture Overview</a>`},
+		{"[[overview|the overview]]", `<a href="/architecture/overview" class="wikilink">the overview</a>`},
+		{"[[data]]", `<a href="/architecture/data" class="wikilink">Data Model</a>`},
+		{"[[notes]]", `<a href="/architecture/notes" class="wikilink">Notes</a>`},
+		{"[[new-ideas]]", `<a href="/architecture/new-ideas" class="wikilink">New ideas</a>`},
+		{"[[#usage]]", `<a href="/architecture/index#usage" class="wikilink">usage</a>`},
+	}
+	for _, tt := range tests {
+		if result := WikilinkPreprocessor(tt.input, "architecture/index"); result != tt.expected {
+			t.Errorf("Expected: %q, got: %q", tt.expected, result)
+		}
+	}
+
+	// A changed document is read again
+	file := filepath.Join(root, "architecture", "overview", "document.md")
+	if err := os.WriteFile(file, []byte("---\ntitle: System Overview, Revised\n---\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if result := WikilinkPreprocessor("[[overview]]", "architecture/index"); !strings.Contains(result, ">System Overview, Revised<") {
+		t.Errorf("Expected the new title, got: %q", result)
+	}
+
+	// Without titles the target is shown as written
+	WikilinkTitles = false
+	defer func() { WikilinkTitles = true }()
+	if result := WikilinkPreprocessor("[[overview]]", "architecture/index"); !strings.Contains(result, ">overview<") {
+		t.Errorf("Expected the raw target, got: %q", result)
+	}
+}
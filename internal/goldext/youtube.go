@@ -80,17 +80,7 @@ func YouTubePreprocessor(markdown string, _ string) string {
 
 					if videoID != "" {
 						// Create replacement HTML
-						videoURL := "https://www.youtube.com/watch?v=" + videoID
-						replacement := `<div class="video-container">
-<iframe width="560" height="315" src="https://www.youtube.com/embed/` + videoID + `"
-frameborder="0" allow="accelerometer; autoplay; clipboard-write; encrypted-media; gyroscope; picture-in-picture"
-allowfullscreen></iframe>
-</div>
-<div class="video-print-placeholder">
-<p><strong>YouTube Video</strong></p>
-<p>This embedded video is not available in print. You can view it online at:</p>
-<p><a href="` + videoURL + `">` + videoURL + `</a></p>
-</div>`
+						replacement := youTubeEmbedHTML(videoID)
 
 						replacements[youtubeStart] = replacement
 					}
@@ -130,17 +120,7 @@ allowfullscreen></iframe>
 
 					if videoID != "" {
 						// Create replacement HTML
-						videoURL := "https://www.youtube.com/watch?v=" + videoID
-						replacement := `<div class="video-container">
-<iframe width="560" height="315" src="https://www.youtube.com/embed/` + videoID + `"
-frameborder="0" allow="accelerometer; autoplay; clipboard-write; encrypted-media; gyroscope; picture-in-picture"
-allowfullscreen></iframe>
-</div>
-<div class="video-print-placeholder">
-<p><strong>YouTube Video</strong></p>
-<p>This embedded video is not available in print. You can view it online at:</p>
-<p><a href="` + videoURL + `">` + videoURL + `</a></p>
-</div>`
+						replacement := youTubeEmbedHTML(videoID)
 
 						replacements[youtubeStart] = replacement
 					}
@@ -171,17 +151,7 @@ allowfullscreen></iframe>
 
 		if videoID != "" {
 			// Create replacement HTML
-			videoURL := "https://www.youtube.com/watch?v=" + videoID
-			replacement := `<div class="video-container">
-<iframe width="560" height="315" src="https://www.youtube.com/embed/` + videoID + `"
-frameborder="0" allow="accelerometer; autoplay; clipboard-write; encrypted-media; gyroscope; picture-in-picture"
-allowfullscreen></iframe>
-</div>
-<div class="video-print-placeholder">
-<p><strong>YouTube Video</strong></p>
-<p>This embedded video is not available in print. You can view it online at:</p>
-<p><a href="` + videoURL + `">` + videoURL + `</a></p>
-</div>`
+			replacement := youTubeEmbedHTML(videoID)
 
 			replacements[youtubeStart] = replacement
 		}
@@ -203,3 +173,18 @@ allowfullscreen></iframe>
 
 	return strings.Join(result, "\n")
 }
+
+// youTubeEmbedHTML returns the embedded player of a YouTube video, with a link for print
+func youTubeEmbedHTML(videoID string) string {
+	videoURL := "https://www.youtube.com/watch?v=" + videoID
+	return `<div class="video-container">
+<iframe width="560" height="315" src="https://www.youtube.com/embed/` + videoID + `"
+frameborder="0" allow="accelerometer; autoplay; clipboard-write; encrypted-media; gyroscope; picture-in-picture"
+allowfullscreen></iframe>
+</div>
+<div class="video-print-placeholder">
+<p><strong>YouTube Video</strong></p>
+<p>This embedded video is not available in print. You can view it online at:</p>
+<p><a href="` + videoURL + `">` + videoURL + `</a></p>
+</div>`
+}
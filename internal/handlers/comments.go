@@ -161,7 +161,7 @@ func GetCommentsHandler(w http.ResponseWriter, r *http.Request) {
 	// Process comments for rendering
 	for i := range commentsList {
 		// Render markdown content with template.HTML
-		commentsList[i].RenderedHTML = template.HTML(utils.RenderMarkdown(commentsList[i].Content))
+		commentsList[i].RenderedHTML = template.HTML(utils.RenderComment(commentsList[i].Content))
 		// Format timestamp
 		commentsList[i].FormattedTime = comments.FormatCommentTime(commentsList[i].Timestamp)
 	}
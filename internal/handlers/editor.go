@@ -221,6 +221,9 @@ func SaveHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Pages including or linking the document render differently now
+	utils.ClearRenderCache()
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -344,6 +347,9 @@ func CreateDocumentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Pages including or linking the document render differently now
+	utils.ClearRenderCache()
+
 	// Return success
 	w.Header().Set("Content-Type", "application/json")
 	response := map[string]interface{}{
@@ -469,6 +475,9 @@ func DeleteDocumentHandler(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Deleted file: %s", fullPath)
 	}
 
+	// Pages including or linking the document render differently now
+	utils.ClearRenderCache()
+
 	// Also delete the corresponding versions directory
 	var versionsPath string
 	if docPath == "pages/home" {
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wiki-go/internal/auth"
+	"wiki-go/internal/config"
+	"wiki-go/internal/roles"
+	"wiki-go/internal/utils"
+)
+
+func TestSaveHandlerRefreshesIncludingPages(t *testing.T) {
+	t.Chdir(t.TempDir())
+	pageCfg, err := config.LoadConfig("config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	InitHandlers(pageCfg)
+	defer utils.ClearRenderCache()
+
+	for docPath, content := range map[string]string{
+		"guide":  "# Guide\n\n{{include: /shared}}\n",
+		"shared": "Old text\n",
+	} {
+		dir := filepath.Join("data", "documents", docPath)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "document.md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	export := func() string {
+		rec := httptest.NewRecorder()
+		ExportHandler(rec, httptest.NewRequest("GET", "/api/export/guide", nil), pageCfg)
+		return rec.Body.String()
+	}
+	if !strings.Contains(export(), "Old text") {
+		t.Fatalf("Expected the included text, got: %q", export())
+	}
+
+	login := httptest.NewRecorder()
+	if err := auth.CreateSession(login, "editor", roles.RoleEditor, false, pageCfg); err != nil {
+		t.Fatal(err)
+	}
+	save := httptest.NewRequest("POST", "/api/save/shared", strings.NewReader("New text\n"))
+	for _, cookie := range login.Result().Cookies() {
+		save.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	SaveHandler(rec, save)
+	if rec.Code != 200 {
+		t.Fatalf("Expected the document to be saved, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The exported page isn't served from the render cache with the old text
+	if result := export(); !strings.Contains(result, "New text") {
+		t.Errorf("Expected the saved text in the exported page, got: %q", result)
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"wiki-go/internal/auth"
+	"wiki-go/internal/config"
+	"wiki-go/internal/utils"
+)
+
+// ExportHandler handles GET /api/export/{path} requests
+// It sends the document as a standalone HTML page to download
+func ExportHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	if r.Method != http.MethodGet {
+		sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed, "")
+		return
+	}
+
+	// Private wikis require an authenticated session
+	if !auth.RequireAuth(r, cfg) {
+		sendJSONError(w, "Authentication required", http.StatusUnauthorized, "")
+		return
+	}
+
+	// Get document path from URL
+	docPath := strings.TrimPrefix(r.URL.Path, "/api/export/")
+	decodedPath, err := url.QueryUnescape(docPath)
+	if err != nil {
+		sendJSONError(w, "Invalid document path", http.StatusBadRequest, err.Error())
+		return
+	}
+	decodedPath = strings.Trim(decodedPath, "/")
+
+	// Reject any path traversal attempts
+	if strings.Contains(decodedPath, "..") {
+		sendJSONError(w, "Invalid document path", http.StatusBadRequest, "")
+		return
+	}
+
+	// The homepage lives outside the documents directory
+	filePath, name := getDocumentPath(decodedPath), path.Base(decodedPath)
+	if decodedPath == "" {
+		filePath, name = filepath.Join(cfg.Wiki.RootDir, "pages", "home", "document.md"), "home"
+	}
+	if _, err := os.Stat(filePath); err != nil {
+		sendJSONError(w, "Document not found", http.StatusNotFound, err.Error())
+		return
+	}
+
+	page, err := utils.RenderStandaloneHTML(filePath)
+	if err != nil {
+		sendJSONError(w, "Failed to export document", http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	name = strings.NewReplacer(`"`, "", `\`, "").Replace(name)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.html"`)
+	w.Write(page)
+}
@@ -15,6 +15,7 @@ import (
 	"wiki-go/internal/auth"
 	"wiki-go/internal/config"
 	"wiki-go/internal/i18n"
+	"wiki-go/internal/utils"
 )
 
 // FileResponse represents the response for file operations
@@ -245,6 +246,9 @@ func UploadFileHandler(w http.ResponseWriter, r *http.Request, cfg *config.Confi
 			return
 		}
 
+		// Pages showing or linking the file render differently now
+		utils.ClearRenderCache()
+
 		// Create URL path for the file
 		urlPath := filepath.Join("/api/files", docPath, filename)
 		// Replace backslashes with forward slashes for URLs
@@ -286,6 +290,9 @@ func UploadFileHandler(w http.ResponseWriter, r *http.Request, cfg *config.Confi
 		return
 	}
 
+	// Pages showing or linking the file render differently now
+	utils.ClearRenderCache()
+
 	// Create URL path for the file
 	urlPath := filepath.Join("/api/files", docPath, filename)
 	// Replace backslashes with forward slashes for URLs
@@ -522,6 +529,9 @@ func DeleteFileHandler(w http.ResponseWriter, r *http.Request, cfg *config.Confi
 		return
 	}
 
+	// Pages showing or linking the file render differently now
+	utils.ClearRenderCache()
+
 	// Return success response
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(FileResponse{
@@ -1237,6 +1247,9 @@ func RenameFileHandler(w http.ResponseWriter, r *http.Request, cfg *config.Confi
 		return
 	}
 
+	// Pages showing or linking the file render differently now
+	utils.ClearRenderCache()
+
 	// Create URL for the renamed file
 	urlPath := filepath.Join("/api/files", newPath)
 	// Replace backslashes with forward slashes for URLs
@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"log"
+	"path/filepath"
 	"wiki-go/internal/config"
 	"wiki-go/internal/i18n"
+	"wiki-go/internal/utils"
 )
 
 var cfg *config.Config
@@ -20,6 +22,12 @@ func InitHandlers(config *config.Config) {
 	// Initialise IP-based ban list for login attempts
 	InitLoginBan(cfg)
 
+	// Resolve rendered document paths against the configured documents directory
+	utils.SetDocumentsRoot(filepath.Join(cfg.Wiki.RootDir, cfg.Wiki.DocumentsDir))
+
+	// Put rendered links below the path the wiki is served under
+	utils.SetBasePath(cfg.Server.BasePath)
+
 	// Routes are now managed in the routes package
 }
 
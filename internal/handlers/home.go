@@ -442,6 +442,8 @@ You can force a specific direction for a section of text by adding the direction
 Force RTL text.
 ` + "```" + `
 
+Add a language code after the direction, as in ` + "`" + "```rtl ar" + "`" + `, to also set the language of the section.
+
 ### Shortcodes
 
 LeoMoon Wiki-Go supports special shortcodes for dynamic content:
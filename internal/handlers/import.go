@@ -15,6 +15,7 @@ import (
 	"time"
 	"wiki-go/internal/auth"
 	"wiki-go/internal/config"
+	"wiki-go/internal/utils"
 )
 
 // ImportResponse represents the response for the import API
@@ -309,6 +310,9 @@ func processMarkdownFile(file *zip.File, jobID string, cfg *config.Config) error
 	if err != nil {
 		return fmt.Errorf("failed to write file: %v", err)
 	}
+
+	// Pages including or linking the document render differently now
+	utils.ClearRenderCache()
 	
 	// Explicitly set permissions to ensure it's readable and writable
 	err = os.Chmod(docPath, 0644)
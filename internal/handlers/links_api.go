@@ -429,16 +429,10 @@ func generateLinksMarkdown(linksData *frontmatter.LinksData, originalContent str
 	var title string
 	contentStartIndex := 0
 	
-	// Handle frontmatter
-	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
-		frontmatterLines = append(frontmatterLines, lines[0])
-		for i := 1; i < len(lines); i++ {
-			frontmatterLines = append(frontmatterLines, lines[i])
-			if strings.TrimSpace(lines[i]) == "---" {
-				contentStartIndex = i + 1
-				break
-			}
-		}
+	// Handle frontmatter, in whichever format it is written
+	if block, _ := frontmatter.Block(originalContent); block != "" {
+		frontmatterLines = strings.Split(block, "\n")
+		contentStartIndex = len(frontmatterLines)
 	}
 	
 	// Find title (first # heading)
@@ -471,7 +465,8 @@ func generateLinksMarkdown(linksData *frontmatter.LinksData, originalContent str
 	}
 	
 	// Add categories and links
-	for category, links := range linksData.Categories {
+	for _, group := range linksData.Groups() {
+		category, links := group.Name, group.Links
 		if len(links) == 0 {
 			continue
 		}
@@ -545,5 +540,8 @@ func saveDocumentWithVersioning(docPath, relativePath string, content []byte) er
 		return fmt.Errorf("failed to save document: %v", err)
 	}
 
+	// Pages including or linking the document render differently now
+	utils.ClearRenderCache()
+
 	return nil
 }
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"wiki-go/internal/auth"
+	"wiki-go/internal/config"
+	"wiki-go/internal/utils"
+)
+
+// The link graph is shared by all requests and refreshed incrementally on each one
+var (
+	linkGraph     *utils.LinkGraph
+	linkGraphOnce sync.Once
+)
+
+// LinkStatsResponse lists the link counts of every document and the orphaned documents
+type LinkStatsResponse struct {
+	Documents map[string]utils.LinkCounts `json:"documents"`
+	Orphans   []string                    `json:"orphans"`
+}
+
+// LinkStatsHandler handles GET /api/link-stats/{path} requests
+// Without a path it returns the counts of all documents and the orphan pages report
+func LinkStatsHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	if r.Method != http.MethodGet {
+		sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed, "")
+		return
+	}
+
+	// Private wikis require an authenticated session
+	if !auth.RequireAuth(r, cfg) {
+		sendJSONError(w, "Authentication required", http.StatusUnauthorized, "")
+		return
+	}
+
+	// Get document path from URL
+	path := strings.TrimPrefix(r.URL.Path, "/api/link-stats/")
+	decodedPath, err := url.QueryUnescape(path)
+	if err != nil {
+		sendJSONError(w, "Invalid document path", http.StatusBadRequest, err.Error())
+		return
+	}
+	decodedPath = strings.Trim(decodedPath, "/")
+
+	// Reject any path traversal attempts
+	if strings.Contains(decodedPath, "..") {
+		sendJSONError(w, "Invalid document path", http.StatusBadRequest, "")
+		return
+	}
+
+	linkGraphOnce.Do(func() {
+		linkGraph = utils.NewLinkGraph(filepath.Join(cfg.Wiki.RootDir, cfg.Wiki.DocumentsDir))
+	})
+	if err := linkGraph.Refresh(); err != nil {
+		sendJSONError(w, "Failed to read documents", http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if decodedPath == "" {
+		json.NewEncoder(w).Encode(LinkStatsResponse{
+			Documents: linkGraph.Counts(),
+			Orphans:   linkGraph.Orphans(),
+		})
+		return
+	}
+
+	counts, ok := linkGraph.Counts()[decodedPath]
+	if !ok {
+		sendJSONError(w, "Document not found", http.StatusNotFound, "")
+		return
+	}
+	json.NewEncoder(w).Encode(counts)
+}
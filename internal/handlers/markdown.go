@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"io"
+	"log"
 	"net/http"
+	"os"
+	"strings"
 	"wiki-go/internal/auth"
 	"wiki-go/internal/utils"
 )
@@ -39,17 +42,32 @@ func RenderMarkdownHandler(w http.ResponseWriter, r *http.Request) {
 	// Get the document path from the query parameter
 	docPath := r.URL.Query().Get("path")
 
-	// Use the utility function to render markdown to HTML with the document path
-	var html []byte
-	if docPath != "" {
-		html = utils.RenderMarkdownWithPath(string(markdown), docPath)
-	} else {
-		html = utils.RenderMarkdown(string(markdown))
-	}
-
 	// Set content type to HTML
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
-	// Write the rendered HTML to the response
-	w.Write(html)
+	if r.URL.Query().Get("check_links") == "true" {
+		// Editor preview: mark internal links by whether their target exists
+		w.Write(utils.RenderMarkdownWithLinkCheck(string(markdown), docPath, utils.CachedLinkChecker(documentExists)))
+		return
+	}
+
+	// Stream the rendered HTML to the response
+	if err := utils.RenderMarkdownTo(w, string(markdown), docPath, utils.WithSiteURL(getBaseURL(r, cfg))); err != nil {
+		log.Printf("Error rendering markdown for %q: %v", docPath, err)
+	}
+}
+
+// documentExists reports whether a wiki document exists at the given path
+func documentExists(target string) bool {
+	// The homepage always exists
+	if target == "" {
+		return true
+	}
+
+	if strings.Contains(target, "..") {
+		return false
+	}
+
+	_, err := os.Stat(getDocumentPath(target))
+	return err == nil
 }
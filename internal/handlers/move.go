@@ -9,6 +9,7 @@ import (
 	"strings"
 	"wiki-go/internal/auth"
 	"wiki-go/internal/config"
+	"wiki-go/internal/utils"
 )
 
 // MoveRequest represents the request to move or rename a document or category
@@ -223,6 +224,9 @@ func MoveDocumentHandler(w http.ResponseWriter, r *http.Request, cfg *config.Con
 		return
 	}
 
+	// Pages including or linking the moved documents render differently now
+	utils.ClearRenderCache()
+
 	// Handle versions directory
 	var versionsSourcePath, versionsTargetPath string
 
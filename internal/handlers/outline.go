@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"wiki-go/internal/auth"
+	"wiki-go/internal/config"
+	"wiki-go/internal/utils"
+)
+
+// OutlineHandler handles GET /api/outline/{path} requests
+// It returns the document's heading structure as versioned JSON for external tools
+func OutlineHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	if r.Method != http.MethodGet {
+		sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed, "")
+		return
+	}
+
+	// Private wikis require an authenticated session
+	if !auth.RequireAuth(r, cfg) {
+		sendJSONError(w, "Authentication required", http.StatusUnauthorized, "")
+		return
+	}
+
+	// Get document path from URL
+	path := strings.TrimPrefix(r.URL.Path, "/api/outline/")
+	decodedPath, err := url.QueryUnescape(path)
+	if err != nil {
+		sendJSONError(w, "Invalid document path", http.StatusBadRequest, err.Error())
+		return
+	}
+	decodedPath = strings.Trim(decodedPath, "/")
+
+	// Reject any path traversal attempts
+	if strings.Contains(decodedPath, "..") {
+		sendJSONError(w, "Invalid document path", http.StatusBadRequest, "")
+		return
+	}
+
+	content, err := os.ReadFile(getDocumentPath(decodedPath))
+	if err != nil {
+		sendJSONError(w, "Document not found", http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(utils.BuildOutline(string(content), decodedPath))
+}
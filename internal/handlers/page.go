@@ -13,7 +13,6 @@ import (
 	"wiki-go/internal/auth"
 	"wiki-go/internal/comments"
 	"wiki-go/internal/config"
-	"wiki-go/internal/frontmatter"
 	"wiki-go/internal/i18n"
 	"wiki-go/internal/types"
 	"wiki-go/internal/utils"
@@ -144,16 +143,15 @@ func PageHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
 				rawContent = string(mdContent)
 			}
 
-			// Parse frontmatter to get document layout
-			metadata, _, hasFrontmatter := frontmatter.Parse(string(mdContent))
+			// Use the document path for rendering to handle local file references;
+			// the parsed frontmatter gives the document layout
+			rendered, metadata, hasFrontmatter := utils.RenderMarkdownWithMetadata(string(mdContent), decodedPath, utils.WithSiteURL(getBaseURL(r, cfg)))
+			content = template.HTML(rendered)
 			documentLayout := ""
 			if hasFrontmatter {
 				documentLayout = metadata.Layout
 			}
 
-			// Use the document path for rendering to handle local file references
-			content = template.HTML(utils.RenderMarkdownWithPath(string(mdContent), decodedPath))
-
 			// If content is empty but document exists, ensure we have something truthy for template conditions
 			if strings.TrimSpace(string(content)) == "" {
 				content = template.HTML(" ") // Single space to make it truthy but effectively empty
@@ -238,7 +236,7 @@ func PageHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
 					// Process comments (render markdown, format timestamps)
 					for i := range commentsList {
 						// Use template.HTML to properly render the HTML without escaping
-						commentsList[i].RenderedHTML = template.HTML(utils.RenderMarkdown(commentsList[i].Content))
+						commentsList[i].RenderedHTML = template.HTML(utils.RenderComment(commentsList[i].Content))
 						commentsList[i].FormattedTime = comments.FormatCommentTime(commentsList[i].Timestamp)
 					}
 				}
@@ -246,13 +244,18 @@ func PageHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
 		}
 	} else {
 		content = " " //To satisfy template conditions
-		docInfo, err := os.Stat(filepath.Join(cfg.Wiki.RootDir, cfg.Wiki.DocumentsDir, r.URL.Path, pdfFile))
+		// The file is looked up in the folder of the requested page
+		if pdfFile == "" || strings.ContainsAny(pdfFile, "/\\") || strings.Contains(pdfFile, "..") {
+			http.Error(w, "PDF file not found", http.StatusNotFound)
+			return
+		}
+		docInfo, err := os.Stat(filepath.Join(fsPath, pdfFile))
 		if err != nil {
 			http.Error(w, "PDF file not found", http.StatusNotFound)
 			return
 		}
 		lastModified = docInfo.ModTime()
-		pdfFile = "/api/files/documents/" + pdfFile
+		pdfFile = (&url.URL{Path: "/api/files/" + strings.TrimPrefix(decodedPath, "/") + "/" + pdfFile}).EscapedPath()
 	}
 
 	// Prepare template data
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wiki-go/internal/config"
+	"wiki-go/internal/utils"
+)
+
+func TestPageHandlerComments(t *testing.T) {
+	// Comments and the wiki data are found relative to the working directory
+	t.Chdir(t.TempDir())
+	pageCfg, err := config.LoadConfig("config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	InitHandlers(pageCfg)
+
+	docDir := filepath.Join("data", "documents", "guide")
+	commentDir := filepath.Join("data", "comments", "guide")
+	for _, dir := range []string{docDir, commentDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(docDir, "document.md"), []byte("# Guide\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hostile := "<script>alert('comment')</script>\n\n" +
+		"<img src=x onerror=alert(1)> [Click](javascript:alert(2)) ![Tracker](https://evil.example/pixel.png)\n\n" +
+		"{{include: guide}}\n"
+	if err := os.WriteFile(filepath.Join(commentDir, "20260101120000_mallory.md"), []byte(hostile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	PageHandler(rec, httptest.NewRequest("GET", "/guide", nil), pageCfg)
+	page := rec.Body.String()
+	if rec.Code != 200 || !strings.Contains(page, `<a href="" rel="nofollow noopener">Click</a>`) {
+		t.Fatalf("Expected the page with the rendered comment, got %d: %q", rec.Code, page)
+	}
+	for _, unwanted := range []string{"alert('comment')", "onerror", "javascript:", `<img src="https://evil.example`} {
+		if strings.Contains(page, unwanted) {
+			t.Errorf("Expected the comment to be rendered safely, found %q", unwanted)
+		}
+	}
+	for _, expected := range []string{
+		`<a href="https://evil.example/pixel.png" target="_blank" rel="nofollow noopener">Tracker</a>`,
+		"{{include: guide}}",
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected the comment to contain %q, got: %q", expected, page)
+		}
+	}
+}
+
+func TestPageHandlerBasePath(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if _, err := config.LoadConfig("config.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The generated configuration has the setting to fill in
+	data, err := os.ReadFile("config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `base_path: ""`) {
+		t.Fatalf("Expected an empty base_path in the generated config, got:\n%s", data)
+	}
+	if err := os.WriteFile("config.yaml", []byte(strings.Replace(string(data), `base_path: ""`, `base_path: "/wiki"`, 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pageCfg, err := config.LoadConfig("config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	InitHandlers(pageCfg)
+	defer utils.SetBasePath("")
+
+	docDir := filepath.Join("data", "documents", "guide")
+	if err := os.MkdirAll(docDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(docDir, "document.md"), []byte("# Guide\n\n![Shot](shot.png)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	PageHandler(rec, httptest.NewRequest("GET", "/guide", nil), pageCfg)
+	if body := rec.Body.String(); !strings.Contains(body, `src="/wiki/api/files/guide/shot.png"`) {
+		t.Errorf("Expected the image below the configured base path, got: %q", body)
+	}
+}
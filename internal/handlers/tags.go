@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"wiki-go/internal/auth"
+	"wiki-go/internal/config"
+	"wiki-go/internal/utils"
+)
+
+// TagNavigationHandler handles GET /api/tag-navigation/{path} requests
+// It returns the previous and next documents sharing the document's primary tag
+func TagNavigationHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	if r.Method != http.MethodGet {
+		sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed, "")
+		return
+	}
+
+	// Private wikis require an authenticated session
+	if !auth.RequireAuth(r, cfg) {
+		sendJSONError(w, "Authentication required", http.StatusUnauthorized, "")
+		return
+	}
+
+	// Get document path from URL
+	path := strings.TrimPrefix(r.URL.Path, "/api/tag-navigation/")
+	decodedPath, err := url.QueryUnescape(path)
+	if err != nil {
+		sendJSONError(w, "Invalid document path", http.StatusBadRequest, err.Error())
+		return
+	}
+	decodedPath = strings.Trim(decodedPath, "/")
+
+	// Reject any path traversal attempts
+	if strings.Contains(decodedPath, "..") {
+		sendJSONError(w, "Invalid document path", http.StatusBadRequest, "")
+		return
+	}
+
+	docs, err := utils.CollectTagDocuments(filepath.Join(cfg.Wiki.RootDir, cfg.Wiki.DocumentsDir))
+	if err != nil {
+		sendJSONError(w, "Failed to read documents", http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	nav := utils.TagNeighbors(docs, decodedPath)
+	if nav == nil {
+		sendJSONError(w, "Document not found or not tagged", http.StatusNotFound, "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(nav)
+}
+
+// TagIndexHandler handles GET /api/tags requests
+// It returns the document paths of every tag, or of a single tag given as ?tag=
+func TagIndexHandler(w http.ResponseWriter, r *http.Request, cfg *config.Config) {
+	if r.Method != http.MethodGet {
+		sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed, "")
+		return
+	}
+
+	// Private wikis require an authenticated session
+	if !auth.RequireAuth(r, cfg) {
+		sendJSONError(w, "Authentication required", http.StatusUnauthorized, "")
+		return
+	}
+
+	index, err := utils.BuildTagIndex(filepath.Join(cfg.Wiki.RootDir, cfg.Wiki.DocumentsDir))
+	if err != nil {
+		sendJSONError(w, "Failed to read documents", http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		tag = utils.NormalizeTag(tag)
+		paths, ok := index[tag]
+		if !ok {
+			sendJSONError(w, "Tag not found", http.StatusNotFound, "")
+			return
+		}
+		index = map[string][]string{tag: paths}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(index)
+}
@@ -323,6 +323,9 @@ func handleVersionRestore(w http.ResponseWriter, r *http.Request, cfg *config.Co
 		return
 	}
 
+	// Pages including or linking the document render differently now
+	utils.ClearRenderCache()
+
 	// Force update the file's modification time to ensure cache invalidation
 	now := time.Now()
 	if err := os.Chtimes(documentPath, now, now); err != nil {
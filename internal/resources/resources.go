@@ -28,6 +28,11 @@ func GetFileSystem() http.FileSystem {
 	return http.FS(fsys)
 }
 
+// ReadStaticFile returns an embedded static file, e.g. css/theme.css
+func ReadStaticFile(name string) ([]byte, error) {
+	return fs.ReadFile(staticFiles, "static/"+name)
+}
+
 // LoadTemplates loads and parses the embedded HTML templates
 func LoadTemplates(funcMap template.FuncMap) (*template.Template, error) {
 	// Parse base template with function map
@@ -102,10 +102,10 @@ async function updatePreview(content) {
         const path = isHomepage ? '/' : window.location.pathname;
 
         // Check for frontmatter to add special styling if needed
-        const hasFrontmatter = content.startsWith('---\n');
+        const hasFrontmatter = /^(---|\+\+\+|\{)\n/.test(content);
 
         // Call the server-side renderer
-        const response = await fetch(`/api/render-markdown?path=${encodeURIComponent(path)}`, {
+        const response = await fetch(`/api/render-markdown?path=${encodeURIComponent(path)}&check_links=true`, {
             method: 'POST',
             headers: {
                 'Content-Type': 'text/plain',
@@ -50,15 +50,25 @@ class KanbanPersistenceManager {
     const lines = markdown.split(/\r?\n/);
 
     // Find frontmatter end
-    const frontmatterEndIndex = lines.findIndex((line, index) =>
-      index > 0 && line.trim() === '---' && lines[0].trim() === '---'
-    );
+    const frontmatterEndIndex = this.findFrontmatterEnd(lines);
 
     if (frontmatterEndIndex > 0) {
       this.collectOriginalFormatting(lines, frontmatterEndIndex, this.originalBoardHeaders, this.originalSectionHeaders, this.originalTaskFormatting);
     }
   }
 
+  /**
+   * Find the line closing the frontmatter: YAML between --- lines, TOML between
+   * +++ lines or a JSON object from a { line to a } line. Returns -1 without one.
+   */
+  findFrontmatterEnd(lines) {
+    const closing = { '---': '---', '+++': '+++', '{': '}' }[lines[0].trim()];
+    if (!closing) {
+      return -1;
+    }
+    return lines.findIndex((line, index) => index > 0 && line.trim() === closing);
+  }
+
   /**
    * Save kanban changes to the server
    */
@@ -163,9 +173,7 @@ class KanbanPersistenceManager {
     const lines = originalMarkdown.split(/\r?\n/);
 
     // Extract frontmatter and content before the first kanban section
-    const frontmatterEndIndex = lines.findIndex((line, index) =>
-      index > 0 && line.trim() === '---' && lines[0].trim() === '---'
-    );
+    const frontmatterEndIndex = this.findFrontmatterEnd(lines);
 
     // Reset processed boards for this save operation
     this.processedBoards.clear();
@@ -286,8 +294,9 @@ class KanbanPersistenceManager {
       const isH5Header = line.match(/^#####\s+/);
       const isTaskLine = line.match(/^\s*[-*+]\s+\[([ xX])\]\s+/);
       const isEmpty = line.trim() === '';
+      const isCardBody = /^\s/.test(line); // Indented lines are card bodies
 
-      if (!isH5Header && !isTaskLine && !isEmpty) {
+      if (!isH5Header && !isTaskLine && !isEmpty && !isCardBody) {
         // This is regular content after the kanban section, don't skip it
         break;
       }
@@ -524,6 +533,14 @@ class KanbanPersistenceManager {
       }
     }
 
+    // Write the card body back below the task, indented under it
+    const bodyMarkdown = task.getAttribute('data-body-markdown');
+    if (bodyMarkdown) {
+      const bodyLines = bodyMarkdown.replace(/\s+$/, '').split('\n')
+        .map(line => line.trim() === '' ? '' : `${indent}  ${line}`);
+      taskLine += '\n' + bodyLines.join('\n');
+    }
+
     return taskLine;
   }
 
@@ -30,4 +30,102 @@ document.addEventListener('DOMContentLoaded', function() {
             });
         });
     }
-});
\ No newline at end of file
+});
+
+// Copy absolute image URLs from the image copy-link control
+document.addEventListener('click', function(event) {
+    const button = event.target.closest('.copy-image-url');
+    if (!button) {
+        return;
+    }
+
+    const url = new URL(button.dataset.imageUrl, window.location.origin).href;
+    navigator.clipboard.writeText(url).catch(err => {
+        console.warn('Failed to copy image URL:', err);
+    });
+});
+
+// Tab groups (::: tabs): switch panels on click and with arrow keys
+(function() {
+    function activateTab(tab) {
+        const tabList = tab.closest('[role="tablist"]');
+        const group = tabList.parentElement;
+
+        tabList.querySelectorAll('[role="tab"]').forEach(other => {
+            const selected = other === tab;
+            other.setAttribute('aria-selected', selected ? 'true' : 'false');
+            other.setAttribute('tabindex', selected ? '0' : '-1');
+
+            const panel = document.getElementById(other.getAttribute('aria-controls'));
+            if (panel && panel.parentElement === group) {
+                panel.hidden = !selected;
+            }
+        });
+    }
+
+    document.addEventListener('click', function(event) {
+        const tab = event.target.closest('.tabs [role="tab"]');
+        if (tab) {
+            activateTab(tab);
+        }
+    });
+
+    document.addEventListener('keydown', function(event) {
+        const tab = event.target.closest('.tabs [role="tab"]');
+        if (!tab || (event.key !== 'ArrowRight' && event.key !== 'ArrowLeft')) {
+            return;
+        }
+
+        const tabs = Array.from(tab.parentElement.querySelectorAll('[role="tab"]'));
+        const offset = event.key === 'ArrowRight' ? 1 : -1;
+        const next = tabs[(tabs.indexOf(tab) + offset + tabs.length) % tabs.length];
+        activateTab(next);
+        next.focus();
+        event.preventDefault();
+    });
+})();
+
+// Spoilers (||text||): reveal on click, Enter or Space
+(function() {
+    function reveal(spoiler) {
+        spoiler.classList.add('revealed');
+        spoiler.removeAttribute('title');
+    }
+
+    document.addEventListener('click', function(event) {
+        const spoiler = event.target.closest('.spoiler:not(.revealed)');
+        if (spoiler) {
+            reveal(spoiler);
+            event.preventDefault();
+        }
+    });
+
+    document.addEventListener('keydown', function(event) {
+        const spoiler = event.target.closest('.spoiler:not(.revealed)');
+        if (spoiler && (event.key === 'Enter' || event.key === ' ')) {
+            reveal(spoiler);
+            event.preventDefault();
+        }
+    });
+})();
+
+// Smooth scrolling for same-page anchor links marked with data-smooth-scroll
+(function() {
+    document.addEventListener('click', function(event) {
+        const link = event.target.closest('a[data-smooth-scroll]');
+        if (!link || event.defaultPrevented || event.button !== 0 || event.metaKey || event.ctrlKey || event.shiftKey) {
+            return;
+        }
+
+        const id = decodeURIComponent(link.getAttribute('href').slice(1));
+        const target = document.getElementById(id);
+        if (!target) {
+            return;
+        }
+
+        const reducedMotion = window.matchMedia('(prefers-reduced-motion: reduce)').matches;
+        target.scrollIntoView({ behavior: reducedMotion ? 'auto' : 'smooth' });
+        history.pushState(null, '', '#' + encodeURIComponent(id));
+        event.preventDefault();
+    });
+})();
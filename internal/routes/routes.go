@@ -352,6 +352,31 @@ func SetupRoutes(cfg *config.Config) {
 		handlers.MoveDocumentHandler(w, r, cfg)
 	}))
 
+	// Document outline API - structure as JSON for external tools
+	mux.HandleFunc("/api/outline/", func(w http.ResponseWriter, r *http.Request) {
+		handlers.OutlineHandler(w, r, cfg)
+	})
+
+	// Document export API - standalone HTML page to download
+	mux.HandleFunc("/api/export/", func(w http.ResponseWriter, r *http.Request) {
+		handlers.ExportHandler(w, r, cfg)
+	})
+
+	// Tag navigation API - previous/next documents sharing a tag
+	mux.HandleFunc("/api/tag-navigation/", func(w http.ResponseWriter, r *http.Request) {
+		handlers.TagNavigationHandler(w, r, cfg)
+	})
+
+	// Tag index API - document paths by tag
+	mux.HandleFunc("/api/tags", func(w http.ResponseWriter, r *http.Request) {
+		handlers.TagIndexHandler(w, r, cfg)
+	})
+
+	// Link stats API - inbound/outbound link counts and orphan pages
+	mux.HandleFunc("/api/link-stats/", func(w http.ResponseWriter, r *http.Request) {
+		handlers.LinkStatsHandler(w, r, cfg)
+	})
+
 	// Markdown rendering API - No auth required
 	mux.HandleFunc("/api/render-markdown", handlers.RenderMarkdownHandler)
 
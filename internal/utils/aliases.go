@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"wiki-go/internal/frontmatter"
+)
+
+// CollectAliases walks the documents root and maps every alias path to the document
+// declaring it in its aliases: frontmatter, for the HTTP layer to redirect from.
+// Documents are visited in lexical path order. When two documents claim the same alias
+// the last one wins and a warning is logged; aliases naming an existing document are
+// ignored with a warning, since the document itself takes precedence.
+func CollectAliases(documentsRoot string) (map[string]string, error) {
+	aliases := make(map[string]string)
+
+	err := filepath.Walk(documentsRoot, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			// Skip hidden directories and the external image cache
+			if path != documentsRoot && (strings.HasPrefix(info.Name(), ".") || info.Name() == ImageCacheDirName) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.Name() != "document.md" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		metadata, _, _ := frontmatter.Parse(string(content))
+		relPath, err := filepath.Rel(documentsRoot, filepath.Dir(path))
+		if err != nil {
+			return nil
+		}
+		docPath := filepath.ToSlash(relPath)
+
+		for _, alias := range frontmatter.Aliases(metadata) {
+			if alias == docPath {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(documentsRoot, filepath.FromSlash(alias), "document.md")); err == nil {
+				log.Printf("Warning: alias %q of %q is an existing document, ignoring it", alias, docPath)
+				continue
+			}
+			if previous, ok := aliases[alias]; ok && previous != docPath {
+				log.Printf("Warning: alias %q is claimed by %q and %q, using %q", alias, previous, docPath, docPath)
+			}
+			aliases[alias] = docPath
+		}
+
+		return nil
+	})
+
+	return aliases, err
+}
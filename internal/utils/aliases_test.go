@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollectAliases(t *testing.T) {
+	root := t.TempDir()
+	writeTestDocument(t, root, "a/new", "---\naliases: [old, shared]\n---\n# New")
+	writeTestDocument(t, root, "b/other", "---\naliases:\n  - shared\n  - a/new\n---\n# Other")
+	writeTestDocument(t, root, "plain", "# Plain")
+
+	aliases, err := CollectAliases(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"old":    "a/new",
+		"shared": "b/other", // last writer wins
+	}
+	if !reflect.DeepEqual(aliases, expected) {
+		t.Errorf("Expected %v, got %v", expected, aliases)
+	}
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// AMPOutput transforms rendered HTML into an AMP-compatible variant: images and iframes
+// become amp-img and amp-iframe, inline scripts are dropped and disallowed attributes
+// are stripped. Meant for mobile experiments. Disabled by default.
+var AMPOutput = false
+
+// Default sizes for elements that AMP requires dimensions on
+const (
+	ampImageFallbackHeight = "300"
+	ampIframeWidth         = "560"
+	ampIframeHeight        = "315"
+)
+
+var (
+	ampScriptRegex    = regexp.MustCompile(`(?is)<script\b[^>]*>.*?</script>`)
+	ampJSONLDRegex    = regexp.MustCompile(`(?i)type\s*=\s*["']application/ld\+json["']`)
+	ampTagRegex       = regexp.MustCompile(`<([a-zA-Z][\w-]*)(\s[^<>]*?)?\s*(/?)>`)
+	ampAttributeRegex = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*("[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?`)
+	ampIframeEndRegex = regexp.MustCompile(`(?i)</iframe>`)
+)
+
+// htmlAttribute is a single parsed attribute of an HTML tag
+type htmlAttribute struct {
+	name  string
+	value string // Including quotes, empty for boolean attributes
+}
+
+// ToAMP converts standard rendered HTML into its AMP variant
+func ToAMP(htmlContent string) string {
+	// Inline scripts are not allowed; structured data is
+	result := ampScriptRegex.ReplaceAllStringFunc(htmlContent, func(script string) string {
+		openTag := script[:strings.Index(script, ">")]
+		if ampJSONLDRegex.MatchString(openTag) {
+			return script
+		}
+		return ""
+	})
+
+	result = ampTagRegex.ReplaceAllStringFunc(result, func(tag string) string {
+		parts := ampTagRegex.FindStringSubmatch(tag)
+		name := strings.ToLower(parts[1])
+		attrs := ampAllowedAttributes(parts[2])
+
+		switch name {
+		case "img":
+			return "<amp-img" + renderAMPAttributes(withAMPLayout(attrs, "")) + "></amp-img>"
+		case "iframe":
+			attrs = withAMPLayout(attrs, ampIframeWidth)
+			if !hasAttribute(attrs, "sandbox") {
+				attrs = append(attrs, htmlAttribute{name: "sandbox", value: `"allow-scripts allow-same-origin allow-popups"`})
+			}
+			return "<amp-iframe" + renderAMPAttributes(attrs) + ">"
+		}
+
+		if parts[2] == "" {
+			return tag
+		}
+		selfClosing := ""
+		if parts[3] == "/" {
+			selfClosing = " /"
+		}
+		return "<" + parts[1] + renderAMPAttributes(attrs) + selfClosing + ">"
+	})
+
+	return ampIframeEndRegex.ReplaceAllString(result, "</amp-iframe>")
+}
+
+// ampAllowedAttributes parses tag attributes, dropping event handlers and inline styles
+func ampAllowedAttributes(raw string) []htmlAttribute {
+	var attrs []htmlAttribute
+	for _, m := range ampAttributeRegex.FindAllStringSubmatch(raw, -1) {
+		name := strings.ToLower(m[1])
+		if strings.HasPrefix(name, "on") || name == "style" {
+			continue
+		}
+		attrs = append(attrs, htmlAttribute{name: m[1], value: m[2]})
+	}
+	return attrs
+}
+
+// withAMPLayout adds the dimensions and layout AMP requires on replaced elements
+// Elements with a width and height scale responsively, others get a fixed height
+func withAMPLayout(attrs []htmlAttribute, defaultWidth string) []htmlAttribute {
+	if !hasAttribute(attrs, "width") && !hasAttribute(attrs, "height") && defaultWidth != "" {
+		attrs = append(attrs,
+			htmlAttribute{name: "width", value: `"` + defaultWidth + `"`},
+			htmlAttribute{name: "height", value: `"` + ampIframeHeight + `"`})
+	}
+	if hasAttribute(attrs, "layout") {
+		return attrs
+	}
+
+	if hasAttribute(attrs, "width") && hasAttribute(attrs, "height") {
+		return append(attrs, htmlAttribute{name: "layout", value: `"responsive"`})
+	}
+	if !hasAttribute(attrs, "height") {
+		attrs = append(attrs, htmlAttribute{name: "height", value: `"` + ampImageFallbackHeight + `"`})
+	}
+	return append(attrs, htmlAttribute{name: "layout", value: `"fixed-height"`})
+}
+
+// hasAttribute reports whether attrs contains an attribute with the given name
+func hasAttribute(attrs []htmlAttribute, name string) bool {
+	for _, attr := range attrs {
+		if strings.EqualFold(attr.name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderAMPAttributes renders attributes back into tag form with a leading space
+func renderAMPAttributes(attrs []htmlAttribute) string {
+	var sb strings.Builder
+	for _, attr := range attrs {
+		sb.WriteString(" " + attr.name)
+		if attr.value != "" {
+			sb.WriteString("=" + attr.value)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"html"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"wiki-go/internal/frontmatter"
+)
+
+// ChangelogShortcode is replaced with the document's frontmatter changelog
+const ChangelogShortcode = "{{changelog}}"
+
+// ChangelogDateFormat is the Go time layout used to display changelog dates
+var ChangelogDateFormat = "January 2, 2006"
+
+// changelogDateLayouts are the accepted date formats for changelog entries
+var changelogDateLayouts = []string{"2006-01-02", time.RFC3339, "2006-01-02 15:04"}
+
+// changelogItem is a changelog entry with its parsed date
+type changelogItem struct {
+	date        time.Time
+	description string
+}
+
+// ExpandChangelog replaces {{changelog}} lines with the changelog from the metadata,
+// most recent entry first. Malformed entries are skipped with a warning.
+func ExpandChangelog(md string, metadata frontmatter.Metadata, docPath string) string {
+	if !strings.Contains(md, ChangelogShortcode) {
+		return md
+	}
+
+	lines := strings.Split(md, "\n")
+	var result []string
+	var rendered []string
+	inCodeBlock := false
+
+	for _, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			result = append(result, line)
+			continue
+		}
+
+		if inCodeBlock || trimmedLine != ChangelogShortcode {
+			result = append(result, line)
+			continue
+		}
+
+		// Render once and reuse for repeated shortcodes
+		if rendered == nil {
+			rendered = renderChangelog(metadata.Changelog, docPath)
+		}
+		result = append(result, rendered...)
+	}
+
+	return strings.Join(result, "\n")
+}
+
+// renderChangelog renders changelog entries as a dated markdown list
+func renderChangelog(entries []frontmatter.ChangelogEntry, docPath string) []string {
+	var items []changelogItem
+	for i, entry := range entries {
+		date, ok := parseChangelogDate(entry.Date)
+		description := strings.TrimSpace(entry.Description)
+		if !ok || description == "" {
+			log.Printf("Warning: skipping malformed changelog entry %d in %s", i+1, docPath)
+			continue
+		}
+		items = append(items, changelogItem{date: date, description: description})
+	}
+
+	// Most recent first; entries on the same date keep their frontmatter order
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].date.After(items[j].date)
+	})
+
+	if len(items) == 0 {
+		return []string{`<div class="changelog changelog-empty"></div>`}
+	}
+
+	// Blank lines around the list let Goldmark render descriptions as markdown
+	result := []string{`<div class="changelog">`, ""}
+	for _, item := range items {
+		result = append(result, `- <time datetime="`+item.date.Format("2006-01-02")+`">`+
+			html.EscapeString(item.date.Format(ChangelogDateFormat))+`</time> — `+item.description)
+	}
+	result = append(result, "", `</div>`)
+
+	return result
+}
+
+// parseChangelogDate parses a changelog date in any of the accepted layouts
+func parseChangelogDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range changelogDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"strings"
+
+	"wiki-go/internal/goldext"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// CodeBlockHandler renders a fenced code block of a registered language as HTML
+// info is the complete info string after the opening fence, e.g. "csv delimiter=;"
+type CodeBlockHandler func(info string, content string) string
+
+// codeBlockHandlers maps fenced block languages to their handlers
+var codeBlockHandlers = map[string]CodeBlockHandler{
+	"csv": renderCSVBlock,
+	"tsv": renderCSVBlock,
+}
+
+// RegisterCodeBlockHandler renders fenced code blocks of the language with handler
+// instead of as code. It must be called before rendering starts.
+func RegisterCodeBlockHandler(language string, handler CodeBlockHandler) {
+	codeBlockHandlers[language] = handler
+}
+
+// CodeHighlighter returns the highlighted HTML of a code block's content for the given
+// language, or false when it doesn't know the language
+type CodeHighlighter func(language string, code string) (string, bool)
+
+// ServerHighlighter highlights fenced code blocks with a language while rendering instead
+// of Chroma; see ServerHighlighting. Nil, the default, leaves highlighting to Prism in the
+// browser unless ServerHighlighting is enabled; blocks it declines render as plain code.
+var ServerHighlighter CodeHighlighter
+
+// Custom HTML renderer for fenced code blocks: registered languages are rendered by
+// their handler, everything else as code carrying block data attributes
+type codeBlockRenderer struct {
+	html.Config
+}
+
+// NewCodeBlockRenderer creates a new code block renderer
+func NewCodeBlockRenderer(opts ...html.Option) renderer.NodeRenderer {
+	r := &codeBlockRenderer{
+		Config: html.NewConfig(),
+	}
+	for _, opt := range opts {
+		opt.SetHTMLOption(&r.Config)
+	}
+	return r
+}
+
+// RegisterFuncs implements NodeRenderer.RegisterFuncs
+func (r *codeBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderFencedCodeBlock)
+}
+
+// Custom render function for fenced code blocks, matching Goldmark's output
+// apart from the data attributes on the <pre> element
+func (r *codeBlockRenderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.FencedCodeBlock)
+	language := n.Language(source)
+
+	if handler, ok := codeBlockHandlers[string(language)]; ok {
+		if entering {
+			var info string
+			if n.Info != nil {
+				info = string(n.Info.Segment.Value(source))
+			}
+			var content strings.Builder
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				line := lines.At(i)
+				content.Write(line.Value(source))
+			}
+			_, _ = w.WriteString(handler(info, content.String()))
+		}
+		return ast.WalkSkipChildren, nil
+	}
+
+	if (ServerHighlighter != nil && language != nil || ServerHighlighter == nil && ServerHighlighting) && entering {
+		var content strings.Builder
+		lines := n.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			content.Write(line.Value(source))
+		}
+
+		var highlighted, preStyle string
+		var ok bool
+		if ServerHighlighter != nil {
+			highlighted, ok = ServerHighlighter(string(language), content.String())
+		} else {
+			highlighted, preStyle, ok = chromaHighlight(string(language), content.String())
+		}
+		if ok {
+			// No language- class, so Prism leaves the block alone
+			_, _ = w.WriteString(`<pre class="highlight"`)
+			if preStyle != "" {
+				_, _ = w.WriteString(` style="`)
+				_, _ = w.Write(util.EscapeHTML([]byte(preStyle)))
+				_ = w.WriteByte('"')
+			}
+			_, _ = w.WriteString(goldext.BlockAttributes("code", "language", string(language)))
+			_, _ = w.WriteString(`><code`)
+			if language != nil {
+				_, _ = w.WriteString(` data-language="`)
+				r.Writer.Write(w, language)
+				_ = w.WriteByte('"')
+			}
+			_ = w.WriteByte('>')
+			_, _ = w.WriteString(highlighted)
+			return ast.WalkSkipChildren, nil
+		}
+	}
+
+	if !entering {
+		_, _ = w.WriteString("</code></pre>\n")
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = w.WriteString("<pre")
+	_, _ = w.WriteString(goldext.BlockAttributes("code", "language", string(language)))
+	_, _ = w.WriteString("><code")
+	if language != nil {
+		_, _ = w.WriteString(` class="language-`)
+		r.Writer.Write(w, language)
+		_ = w.WriteByte('"')
+	}
+	_ = w.WriteByte('>')
+
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		r.Writer.RawWrite(w, line.Value(source))
+	}
+
+	return ast.WalkContinue, nil
+}
+
+// codeBlockExtension is a goldmark.Extender
+type codeBlockExtension struct{}
+
+// Extend implements goldmark.Extender
+func (e *codeBlockExtension) Extend(m goldmark.Markdown) {
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(NewCodeBlockRenderer(), 100),
+	))
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"bytes"
+	"wiki-go/internal/goldext"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// commentMarkdown is the Goldmark instance of RenderComment: CommonMark with tables,
+// strikethrough and autolinks, raw HTML omitted and no heading IDs
+var commentMarkdown = goldmark.New(
+	goldmark.WithExtensions(
+		extension.Table,
+		extension.Strikethrough,
+		extension.Linkify,
+		&commentLinkExtension{},
+	),
+	goldmark.WithRendererOptions(
+		html.WithHardWraps(),
+	),
+)
+
+// RenderComment renders a comment or another user-written snippet with a fixed, minimal
+// feature set: emphasis, lists, quotes, tables, code and links. Unlike RenderMarkdown no
+// preprocessors run, so includes, embeds and shortcodes stay text, raw HTML is omitted,
+// images become links and every link gets rel="nofollow noopener".
+func RenderComment(md string) []byte {
+	var buf bytes.Buffer
+	if err := commentMarkdown.Convert([]byte(md), &buf); err != nil {
+		return []byte("Error rendering comment: " + err.Error())
+	}
+	return buf.Bytes()
+}
+
+// commentLinkRenderer renders the links, autolinks and images of comments
+type commentLinkRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer
+func (r *commentLinkRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindLink, r.renderLink)
+	reg.Register(ast.KindAutoLink, r.renderAutoLink)
+	reg.Register(ast.KindImage, r.renderImage)
+}
+
+// Custom render function for links, whose text keeps its formatting
+func (r *commentLinkRenderer) renderLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = w.WriteString("</a>")
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.Link)
+	writeCommentLinkStart(w, n.Destination, n.Title)
+	return ast.WalkContinue, nil
+}
+
+// Custom render function for autolinks and linkified URLs
+func (r *commentLinkRenderer) renderAutoLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.AutoLink)
+	destination := n.URL(source)
+	if n.AutoLinkType == ast.AutoLinkEmail && !bytes.HasPrefix(bytes.ToLower(destination), []byte("mailto:")) {
+		destination = append([]byte("mailto:"), destination...)
+	}
+	writeCommentLinkStart(w, destination, nil)
+	_, _ = w.Write(util.EscapeHTML(n.Label(source)))
+	_, _ = w.WriteString("</a>")
+	return ast.WalkContinue, nil
+}
+
+// Custom render function for images, linked by their alt text instead of loaded
+func (r *commentLinkRenderer) renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.Image)
+	writeCommentLinkStart(w, n.Destination, n.Title)
+	alt := n.Text(source)
+	if len(alt) == 0 {
+		alt = n.Destination
+	}
+	_, _ = w.Write(util.EscapeHTML(alt))
+	_, _ = w.WriteString("</a>")
+	return ast.WalkSkipChildren, nil
+}
+
+// writeCommentLinkStart writes the opening tag of a comment link
+// Script URLs are dropped; external links open in a new tab like in documents.
+func writeCommentLinkStart(w util.BufWriter, destination, title []byte) {
+	if html.IsDangerousURL(destination) {
+		destination = nil
+	}
+	href := goldext.JoinBasePath(goldext.BasePath, string(destination))
+
+	_, _ = w.WriteString(`<a href="`)
+	_, _ = w.Write(util.EscapeHTML(util.URLEscape([]byte(href), true)))
+	_, _ = w.WriteString(`"`)
+	if len(title) > 0 {
+		_, _ = w.WriteString(` title="`)
+		_, _ = w.Write(util.EscapeHTML(title))
+		_, _ = w.WriteString(`"`)
+	}
+	if ExternalLinksNewTab && isSiteExternalURL(href) {
+		_, _ = w.WriteString(` target="_blank"`)
+	}
+	_, _ = w.WriteString(` rel="nofollow noopener">`)
+}
+
+// commentLinkExtension is a goldmark.Extender
+type commentLinkExtension struct{}
+
+// Extend implements goldmark.Extender
+func (e *commentLinkExtension) Extend(m goldmark.Markdown) {
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&commentLinkRenderer{}, 100),
+	))
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// csvDelimiterNames are the named delimiters accepted by delimiter= in the info string
+var csvDelimiterNames = map[string]rune{
+	"comma":     ',',
+	"semicolon": ';',
+	"tab":       '\t',
+	"pipe":      '|',
+}
+
+// renderCSVBlock renders a ```csv or ```tsv block as a table:
+//
+//	```csv delimiter=; header=false
+//	a;"b; c"
+//	```
+//
+// The first row is the header unless header=false is given. Rows with a different
+// number of fields than the first one render a visible error instead of a table.
+func renderCSVBlock(info string, content string) string {
+	fields := strings.Fields(info)
+
+	delimiter := ','
+	if len(fields) > 0 && fields[0] == "tsv" {
+		delimiter = '\t'
+	}
+	header := true
+
+	for _, option := range fields[1:] {
+		name, value, _ := strings.Cut(option, "=")
+		switch name {
+		case "delimiter":
+			if named, ok := csvDelimiterNames[value]; ok {
+				delimiter = named
+			} else if utf8.RuneCountInString(value) == 1 {
+				delimiter, _ = utf8.DecodeRuneInString(value)
+			} else {
+				return renderCSVError(fmt.Sprintf("invalid delimiter %q", value), content)
+			}
+		case "header":
+			header = value != "false" && value != "no"
+		}
+	}
+
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.Comma = delimiter
+	reader.TrimLeadingSpace = delimiter != '\t'
+
+	var rows [][]string
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return renderCSVError(err.Error(), content)
+		}
+		rows = append(rows, record)
+	}
+
+	if len(rows) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<table class=\"csv-table\">\n")
+	if header {
+		sb.WriteString("<thead>\n")
+		writeCSVRow(&sb, rows[0], "th")
+		sb.WriteString("</thead>\n")
+		rows = rows[1:]
+	}
+	if len(rows) > 0 {
+		sb.WriteString("<tbody>\n")
+		for _, row := range rows {
+			writeCSVRow(&sb, row, "td")
+		}
+		sb.WriteString("</tbody>\n")
+	}
+	sb.WriteString("</table>\n")
+	return sb.String()
+}
+
+// writeCSVRow writes a single table row with escaped cells
+func writeCSVRow(sb *strings.Builder, row []string, cell string) {
+	sb.WriteString("<tr>\n")
+	for _, value := range row {
+		sb.WriteString("<" + cell + ">" + html.EscapeString(value) + "</" + cell + ">\n")
+	}
+	sb.WriteString("</tr>\n")
+}
+
+// renderCSVError renders a parse error followed by the unparsed block content
+func renderCSVError(message string, content string) string {
+	return `<div class="csv-error" role="alert">Invalid CSV: ` + html.EscapeString(message) + "</div>\n" +
+		"<pre><code>" + html.EscapeString(content) + "</code></pre>\n"
+}
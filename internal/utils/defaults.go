@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DirectoryDefaultsName is the file of frontmatter defaults for the document of its
+// directory and every document below it, e.g. layout: kanban or tags for a whole section.
+// It holds YAML frontmatter keys without delimiters; see frontmatter.MergeMetadata for
+// the precedence.
+const DirectoryDefaultsName = "_defaults.yaml"
+
+// directoryDefaults returns the directory defaults of the document at docPath read through
+// files, from the documents root down to the document's own directory
+func directoryDefaults(docPath string, files FileProvider) []string {
+	var defaults []string
+	for _, path := range directoryDefaultsPaths(docPath) {
+		if content, err := files.ReadFile(path); err == nil {
+			defaults = append(defaults, string(content))
+		}
+	}
+	return defaults
+}
+
+// directoryDefaultsStamps returns the modification time and size of every directory
+// defaults file the document at docPath could have, so a cached rendering can tell
+// whether one was created, edited or deleted without reading them
+func directoryDefaultsStamps(docPath string) []fileStamp {
+	paths := directoryDefaultsPaths(docPath)
+	stamps := make([]fileStamp, len(paths))
+	for i, path := range paths {
+		if info, err := OSFileProvider.Stat(path); err == nil {
+			stamps[i] = fileStamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+		}
+	}
+	return stamps
+}
+
+// directoryDefaultsPaths returns the paths of the directory defaults files of the
+// document at docPath, from the documents root down to the document's own directory
+func directoryDefaultsPaths(docPath string) []string {
+	docPath = path.Clean("/" + strings.ReplaceAll(docPath, "\\", "/"))
+
+	paths := []string{filepath.Join(DocumentsRoot, DirectoryDefaultsName)}
+	dir := DocumentsRoot
+	for _, part := range strings.Split(strings.Trim(docPath, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		paths = append(paths, filepath.Join(dir, DirectoryDefaultsName))
+	}
+	return paths
+}
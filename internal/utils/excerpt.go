@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+
+	"wiki-go/internal/frontmatter"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+)
+
+var (
+	excerptWikilinkRegex  = regexp.MustCompile(`\[\[([^\[\]|\\]+?)(?:\\?\|([^\[\]]+?))?\]\]`)
+	excerptShortcodeRegex = regexp.MustCompile(`\{\{[^{}]*\}\}|\[toc\]`)
+	excerptTaskRegex      = regexp.MustCompile(`^\s*\[[ xX]\]\s*`)
+	kanbanBoardRegex      = regexp.MustCompile(`^#{4}\s+`)
+)
+
+// ExtractExcerpt returns the plain text of a document for search snippets and previews
+// Frontmatter, the # title, code blocks (including mermaid), raw HTML, images, shortcodes and
+// kanban boards contribute nothing; the remaining text is collapsed to single spaces and cut on
+// a word boundary after at most maxRunes runes, ending with an ellipsis when shortened.
+// A maxRunes of 0 or less returns the whole text.
+func ExtractExcerpt(md string, maxRunes int) string {
+	return truncateAtWord(documentPlainText(md, true), maxRunes)
+}
+
+// documentPlainText returns the prose of a document collapsed to single spaces,
+// optionally without its # title
+func documentPlainText(md string, skipTitle bool) string {
+	metadata, body, _ := frontmatter.Parse(md)
+	if metadata.Layout == "kanban" {
+		body = kanbanIntro(body)
+	}
+
+	// Wikilinks read as their display text
+	body = excerptWikilinkRegex.ReplaceAllStringFunc(body, func(match string) string {
+		m := excerptWikilinkRegex.FindStringSubmatch(match)
+		if m[2] != "" {
+			return m[2]
+		}
+		return m[1]
+	})
+
+	source := []byte(body)
+	doc := goldmark.New(goldmark.WithExtensions(extension.GFM, extension.DefinitionList)).Parser().Parse(text.NewReader(source))
+
+	// Image alt texts aren't part of the prose
+	var images []ast.Node
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && node.Kind() == ast.KindImage {
+			images = append(images, node)
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, image := range images {
+		image.Parent().RemoveChild(image.Parent(), image)
+	}
+
+	var parts []string
+	titleSkipped := !skipTitle
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch n := node.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock:
+			return ast.WalkSkipChildren, nil
+		case *ast.Heading:
+			if n.Level == 1 && !titleSkipped {
+				titleSkipped = true
+				return ast.WalkSkipChildren, nil
+			}
+			parts = append(parts, inlineText(n, source))
+			return ast.WalkSkipChildren, nil
+		case *ast.Paragraph, *ast.TextBlock:
+			parts = append(parts, excerptTaskRegex.ReplaceAllString(inlineText(n, source), ""))
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	plain := excerptShortcodeRegex.ReplaceAllString(strings.Join(parts, " "), " ")
+	return strings.Join(strings.Fields(plain), " ")
+}
+
+// kanbanIntro returns the part of a kanban document before its first board
+func kanbanIntro(body string) string {
+	lines := strings.Split(body, "\n")
+	inCodeBlock := false
+	for i, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+		if !inCodeBlock && kanbanBoardRegex.MatchString(trimmedLine) {
+			return strings.Join(lines[:i], "\n")
+		}
+	}
+	return body
+}
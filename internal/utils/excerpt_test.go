@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestExtractExcerpt(t *testing.T) {
+	md := "---\ntitle: Guide\nauthor: someone\n---\n# Guide\n\n" +
+		"Intro with **bold**, a [link](/docs) and [[Other Page|another page]].\n\n" +
+		"```mermaid\ngraph TD\n  A-->B\n```\n\n" +
+		"```go\nfunc main() {}\n```\n\n" +
+		"<div class=\"note\">hidden html</div>\n\n" +
+		"## Steps\n\n- [ ] first `step`\n- second <em>step</em>\n\n[toc]\n"
+
+	got := ExtractExcerpt(md, 0)
+	want := "Intro with bold, a link and another page. Steps first step second step"
+	if got != want {
+		t.Errorf("ExtractExcerpt() = %q, want %q", got, want)
+	}
+
+	for _, unwanted := range []string{"Guide", "graph", "func", "hidden", "author", "[toc]"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("excerpt contains %q: %q", unwanted, got)
+		}
+	}
+}
+
+func TestExtractExcerptTruncation(t *testing.T) {
+	md := "Größere Übungen für äußerst müde Köpfe"
+
+	got := ExtractExcerpt(md, 20)
+	if got != "Größere Übungen für…" {
+		t.Errorf("ExtractExcerpt() = %q", got)
+	}
+	if !utf8.ValidString(got) {
+		t.Errorf("excerpt is not valid UTF-8: %q", got)
+	}
+
+	if got := ExtractExcerpt(md, 100); got != md {
+		t.Errorf("short text should be returned unchanged, got %q", got)
+	}
+}
+
+func TestExtractExcerptKanban(t *testing.T) {
+	md := "---\nlayout: kanban\n---\n# Board\n\nSprint planning.\n\n#### Sprint\n\n##### Todo\n- [ ] task one\n"
+
+	if got := ExtractExcerpt(md, 0); got != "Sprint planning." {
+		t.Errorf("ExtractExcerpt() = %q, want %q", got, "Sprint planning.")
+	}
+}
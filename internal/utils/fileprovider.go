@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileProvider is the file access used while rendering documents
+// It lets documents be rendered from an in-memory or embedded filesystem, including the
+// documents they include or link to and their images. It satisfies goldext.FileSystem.
+type FileProvider interface {
+	ReadFile(name string) ([]byte, error)
+	Stat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+}
+
+// OSFileProvider reads files from the operating system's filesystem
+var OSFileProvider FileProvider = osFileProvider{}
+
+// osFileProvider implements FileProvider on top of the os package
+type osFileProvider struct{}
+
+// ReadFile implements FileProvider.ReadFile
+func (osFileProvider) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+// Stat implements FileProvider.Stat
+func (osFileProvider) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// ReadDir implements FileProvider.ReadDir
+func (osFileProvider) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+// fsFileProvider implements FileProvider on top of an fs.FS
+type fsFileProvider struct {
+	fsys fs.FS
+}
+
+// NewFSFileProvider returns a FileProvider reading from fsys, e.g. an embed.FS or fstest.MapFS
+// OS-style paths are converted to slash-separated fs.FS paths
+func NewFSFileProvider(fsys fs.FS) FileProvider {
+	return fsFileProvider{fsys: fsys}
+}
+
+// ReadFile implements FileProvider.ReadFile
+func (p fsFileProvider) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(p.fsys, fsPath(name))
+}
+
+// Stat implements FileProvider.Stat
+func (p fsFileProvider) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(p.fsys, fsPath(name))
+}
+
+// ReadDir implements FileProvider.ReadDir
+func (p fsFileProvider) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(p.fsys, fsPath(name))
+}
+
+// fsPath converts an OS path to a path valid for fs.FS
+func fsPath(name string) string {
+	name = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(name)), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
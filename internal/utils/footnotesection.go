@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"wiki-go/internal/goldext"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// footnotePrefixAttribute holds the section ID prefix on footnote nodes
+// It isn't a data- attribute, so Goldmark never renders it
+var footnotePrefixAttribute = []byte("footnote-prefix")
+
+// footnoteIDPrefix returns the ID prefix of a footnote node set by the section transformer
+func footnoteIDPrefix(node ast.Node) []byte {
+	if prefix, ok := node.AttributeString(string(footnotePrefixAttribute)); ok {
+		return prefix.([]byte)
+	}
+	return nil
+}
+
+// footnoteIDPrefixFunc returns the footnote ID prefix function of a rendering
+// A namespace goes in front of the section prefix, e.g. guide-s2-fn:1
+func footnoteIDPrefixFunc(namespace string) func(ast.Node) []byte {
+	if namespace == "" {
+		return footnoteIDPrefix
+	}
+	return func(node ast.Node) []byte {
+		return append([]byte(namespace+"-"), footnoteIDPrefix(node)...)
+	}
+}
+
+// footnoteSectionTransformer moves footnotes from the end of the document to the end
+// of the section referencing them and renumbers them per section.
+// It runs after Goldmark's footnote transformer has built the document footnote list.
+type footnoteSectionTransformer struct{}
+
+// footnotePlacement is the section-local position of a footnote
+type footnotePlacement struct {
+	index  int
+	prefix []byte
+}
+
+// Transform implements parser.ASTTransformer
+func (t *footnoteSectionTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	list, ok := doc.LastChild().(*east.FootnoteList)
+	if !ok {
+		return
+	}
+
+	// Footnotes by their document-wide index
+	footnotes := make(map[int]*east.Footnote)
+	for child := list.FirstChild(); child != nil; child = child.NextSibling() {
+		if fn, ok := child.(*east.Footnote); ok {
+			footnotes[fn.Index] = fn
+		}
+	}
+	doc.RemoveChild(doc, list)
+
+	placements := make(map[int]footnotePlacement)
+	section := 0
+	var sectionList *east.FootnoteList
+	var sectionEnd ast.Node
+
+	// closeSection appends the footnotes of the finished section after its last node
+	closeSection := func() {
+		if sectionList != nil && sectionEnd != nil {
+			doc.InsertAfter(doc, sectionEnd, sectionList)
+		}
+		sectionList = nil
+	}
+
+	for node := doc.FirstChild(); node != nil; node = node.NextSibling() {
+		if heading, ok := node.(*ast.Heading); ok && heading.Level <= goldext.FootnoteSectionLevel {
+			closeSection()
+			section++
+		}
+		sectionEnd = node
+
+		prefix := []byte(goldext.FootnoteSectionPrefix(section))
+		ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			link, ok := n.(*east.FootnoteLink)
+			if !entering || !ok {
+				return ast.WalkContinue, nil
+			}
+
+			placement, seen := placements[link.Index]
+			if !seen {
+				fn := footnotes[link.Index]
+				if fn == nil {
+					return ast.WalkContinue, nil
+				}
+				if sectionList == nil {
+					sectionList = east.NewFootnoteList()
+				}
+				sectionList.Count++
+				placement = footnotePlacement{index: sectionList.Count, prefix: prefix}
+				placements[link.Index] = placement
+
+				// Renumber the footnote and its back-links for this section
+				renumberFootnote(fn, placement)
+				sectionList.AppendChild(sectionList, fn)
+			}
+
+			link.Index = placement.index
+			link.SetAttribute(footnotePrefixAttribute, placement.prefix)
+			return ast.WalkContinue, nil
+		})
+	}
+	closeSection()
+}
+
+// renumberFootnote sets the section-local index and ID prefix on a footnote and its back-links
+func renumberFootnote(fn *east.Footnote, placement footnotePlacement) {
+	fn.Index = placement.index
+	fn.SetAttribute(footnotePrefixAttribute, placement.prefix)
+
+	ast.Walk(fn, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if backlink, ok := n.(*east.FootnoteBacklink); ok && entering {
+			backlink.Index = placement.index
+			backlink.SetAttribute(footnotePrefixAttribute, placement.prefix)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// footnoteSectionExtension is a goldmark.Extender
+type footnoteSectionExtension struct{}
+
+// Extend implements goldmark.Extender
+func (e *footnoteSectionExtension) Extend(m goldmark.Markdown) {
+	// Transformers run in ascending priority, so this runs after Goldmark's footnote transformer (999)
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(&footnoteSectionTransformer{}, 1000),
+	))
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// headingAnchorRenderer renders headings with a ¶ link to their own ID
+// so readers can copy a link to any section
+type headingAnchorRenderer struct {
+	html.Config
+}
+
+// RegisterFuncs implements NodeRenderer.RegisterFuncs
+func (r *headingAnchorRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindHeading, r.renderHeading)
+}
+
+// renderHeading renders a heading like Goldmark does, adding the anchor before the closing tag
+func (r *headingAnchorRenderer) renderHeading(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.Heading)
+	if entering {
+		_, _ = w.WriteString("<h")
+		_ = w.WriteByte("0123456"[n.Level])
+		if n.Attributes() != nil {
+			html.RenderAttributes(w, node, html.HeadingAttributeFilter)
+		}
+		_ = w.WriteByte('>')
+		return ast.WalkContinue, nil
+	}
+
+	if id, ok := n.AttributeString("id"); ok {
+		if idBytes, ok := id.([]byte); ok && len(idBytes) > 0 {
+			escapedID := string(util.EscapeHTML(idBytes))
+			_, _ = w.WriteString(` <a class="heading-anchor" href="#` + escapedID + `" aria-label="Permalink">¶</a>`)
+		}
+	}
+
+	_, _ = w.WriteString("</h")
+	_ = w.WriteByte("0123456"[n.Level])
+	_, _ = w.WriteString(">\n")
+	return ast.WalkContinue, nil
+}
+
+// headingAnchorExtension is a goldmark.Extender
+type headingAnchorExtension struct{}
+
+// Extend implements goldmark.Extender
+func (e *headingAnchorExtension) Extend(m goldmark.Markdown) {
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&headingAnchorRenderer{Config: html.NewConfig()}, 100),
+	))
+}
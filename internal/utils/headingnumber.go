@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// headingNumberTransformer prefixes headings with hierarchical section numbers
+// (1, 1.1, 1.1.1) for documents with numbered_headings: true. The # title isn't
+// numbered, so numbering starts at the shallowest other level; skipped levels
+// don't add a number. Heading IDs are kept, so existing links stay valid.
+type headingNumberTransformer struct{}
+
+// headingCounter is the counter of an open heading level
+type headingCounter struct {
+	level int
+	count int
+}
+
+// Transform implements parser.ASTTransformer
+func (t *headingNumberTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var counters []headingCounter
+
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		heading, ok := node.(*ast.Heading)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		if heading.Level == 1 {
+			return ast.WalkSkipChildren, nil
+		}
+
+		// A shallower heading closes the deeper levels
+		for len(counters) > 0 && counters[len(counters)-1].level > heading.Level {
+			counters = counters[:len(counters)-1]
+		}
+		if len(counters) > 0 && counters[len(counters)-1].level == heading.Level {
+			counters[len(counters)-1].count++
+		} else {
+			counters = append(counters, headingCounter{level: heading.Level, count: 1})
+		}
+
+		numbers := make([]string, len(counters))
+		for i, counter := range counters {
+			numbers[i] = strconv.Itoa(counter.count)
+		}
+
+		number := ast.NewString([]byte(`<span class="heading-number">` + strings.Join(numbers, ".") + `</span> `))
+		number.SetCode(true)
+		heading.InsertBefore(heading, heading.FirstChild(), number)
+
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+// headingNumberExtension is a goldmark.Extender
+type headingNumberExtension struct{}
+
+// Extend implements goldmark.Extender
+func (e *headingNumberExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(&headingNumberTransformer{}, 500),
+	))
+}
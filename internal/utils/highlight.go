@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// ServerHighlighting highlights fenced code blocks with Chroma while rendering, in the
+// colors of HighlightTheme. Disabled by default, which leaves highlighting to Prism in the
+// browser. A ServerHighlighter takes precedence.
+var ServerHighlighting = false
+
+// Chroma styles for HighlightTheme
+const (
+	HighlightThemeDefault = "github"
+	HighlightThemeDark    = "github-dark"
+)
+
+// HighlightTheme is the Chroma style of ServerHighlighting: HighlightThemeDefault,
+// HighlightThemeDark or the name of any other Chroma style. Unknown names get Chroma's
+// fallback style.
+var HighlightTheme = HighlightThemeDefault
+
+// chromaFormatter writes the highlighted tokens with inline colors, leaving the <pre>
+// element to the code block renderer
+var chromaFormatter = chromahtml.New(chromahtml.WithClasses(false), chromahtml.PreventSurroundingPre(true))
+
+// chromaHighlight highlights code with Chroma and returns it with the style of its
+// <pre> element, which carries the theme's text and background colors. The language
+// of blocks without one is guessed from the code; languages Chroma doesn't know and
+// code it can't guess return false, so the block renders as plain code.
+func chromaHighlight(language string, code string) (string, string, bool) {
+	var lexer chroma.Lexer
+	if language == "" {
+		lexer = lexers.Analyse(code)
+	} else {
+		lexer = lexers.Get(language)
+	}
+	if lexer == nil {
+		return "", "", false
+	}
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return "", "", false
+	}
+	style := styles.Get(HighlightTheme)
+	var highlighted strings.Builder
+	if err := chromaFormatter.Format(&highlighted, style, iterator); err != nil {
+		return "", "", false
+	}
+
+	var preStyle []string
+	background := style.Get(chroma.Background)
+	if background.Colour.IsSet() {
+		preStyle = append(preStyle, "color:"+background.Colour.String())
+	}
+	if background.Background.IsSet() {
+		preStyle = append(preStyle, "background-color:"+background.Background.String())
+	}
+	return highlighted.String(), strings.Join(preStyle, ";"), true
+}
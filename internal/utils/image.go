@@ -0,0 +1,323 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"wiki-go/internal/goldext"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// ImageCopyLinks wraps rendered images with a "copy image URL" control, whose URL is made
+// absolute with StandaloneBaseURL or else the address of the rendering; see WithSiteURL.
+// Images that are all their paragraph holds are wrapped in a figure. Disabled by default.
+var ImageCopyLinks = false
+
+// ImageCopyLinksExternal extends the copy control to external images.
+// By default only internal /api/files images get one.
+var ImageCopyLinksExternal = false
+
+// Custom HTML renderer for images
+type imageRenderer struct {
+	html.Config
+	basePath  string       // Put in front of root-relative sources
+	untrusted bool         // Drops javascript: and other unsafe sources
+	files     FileProvider // Reads internal images for their size when set
+}
+
+// NewImageRenderer creates a new image renderer
+func NewImageRenderer(opts ...html.Option) renderer.NodeRenderer {
+	r := &imageRenderer{
+		Config: html.NewConfig(),
+	}
+	for _, opt := range opts {
+		opt.SetHTMLOption(&r.Config)
+	}
+	return r
+}
+
+// RegisterFuncs implements NodeRenderer.RegisterFuncs
+func (r *imageRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindImage, r.renderImage)
+	reg.Register(kindImageFigure, r.renderImageFigure)
+}
+
+// Custom render function for images
+func (r *imageRenderer) renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*ast.Image)
+	destination := imageDestination(n, r.basePath, r.untrusted)
+
+	// Serve external images from the local cache once they have been downloaded
+	src := destination
+	if CacheExternalImages && isExternalURL(destination) {
+		if cached, ok := cachedImageURL(destination); ok {
+			src = cached
+		}
+	}
+	src = goldext.JoinBasePath(r.basePath, src)
+
+	// Images sit inline inside paragraphs, so the wrapper is a span rather than a <figure>
+	// Images that are all their paragraph holds are in an imageFigure already.
+	withCopyLink := hasCopyLink(destination)
+	inFigure := n.Parent() != nil && n.Parent().Kind() == kindImageFigure
+	if withCopyLink && !inFigure {
+		_, _ = w.WriteString(`<span class="image-copy-link">`)
+	}
+
+	_, _ = w.WriteString(`<img src="`)
+	_, _ = w.Write(util.EscapeHTML(util.URLEscape([]byte(src), true)))
+	_, _ = w.WriteString(`" alt="`)
+	_, _ = w.Write(util.EscapeHTML(n.Text(source)))
+	_ = w.WriteByte('"')
+	if n.Title != nil {
+		_, _ = w.WriteString(` title="`)
+		_, _ = w.Write(util.EscapeHTML(n.Title))
+		_ = w.WriteByte('"')
+	}
+	if n.Attributes() != nil {
+		html.RenderAttributes(w, n, html.ImageAttributeFilter)
+	}
+
+	// Reserve space for internal images unless the author set a size
+	// AMP output requires dimensions, so it always looks them up
+	if ImageDimensions || AMPOutput {
+		_, hasWidth := n.AttributeString("width")
+		_, hasHeight := n.AttributeString("height")
+		if !hasWidth && !hasHeight {
+			if width, height, ok := internalImageSize(destination, r.files); ok {
+				_, _ = w.WriteString(fmt.Sprintf(` width="%d" height="%d"`, width, height))
+			}
+		}
+	}
+	if r.XHTML {
+		_, _ = w.WriteString(" />")
+	} else {
+		_ = w.WriteByte('>')
+	}
+
+	if withCopyLink {
+		siteURL := ""
+		if doc := n.OwnerDocument(); doc != nil {
+			if value, ok := doc.AttributeString(siteURLAttribute); ok {
+				siteURL, _ = value.(string)
+			}
+		}
+		_, _ = w.WriteString(`<button type="button" class="copy-image-url" data-image-url="`)
+		_, _ = w.Write(util.EscapeHTML(util.URLEscape([]byte(absoluteImageURL(r.basePath, destination, siteURL)), true)))
+		_, _ = w.WriteString(`" title="Copy image URL" aria-label="Copy image URL"><i class="fa fa-link"></i></button>`)
+		if !inFigure {
+			_, _ = w.WriteString(`</span>`)
+		}
+	}
+
+	return ast.WalkSkipChildren, nil
+}
+
+// renderImageFigure renders the figure of an image that is all its paragraph holds
+func (r *imageRenderer) renderImageFigure(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(`<figure class="image-copy-link">`)
+	} else {
+		_, _ = w.WriteString("</figure>\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+// imageDestination returns the source of an image without the base path, empty for
+// javascript: and other unsafe sources of untrusted documents
+func imageDestination(n *ast.Image, basePath string, untrusted bool) string {
+	destination := goldext.TrimBasePath(basePath, string(n.Destination))
+
+	// Untrusted documents can't load scripts as images
+	if untrusted && html.IsDangerousURL([]byte(destination)) {
+		return ""
+	}
+	return destination
+}
+
+// hasCopyLink reports whether an image with the given destination gets a copy control
+func hasCopyLink(destination string) bool {
+	return ImageCopyLinks && (ImageCopyLinksExternal || strings.HasPrefix(destination, "/api/files/"))
+}
+
+// absoluteImageURL returns the address of an image for copying: root-relative sources
+// are put below the base path and prefixed with StandaloneBaseURL or, when it isn't set,
+// the address the rendering is served from
+func absoluteImageURL(basePath, destination, siteURL string) string {
+	imageURL := goldext.JoinBasePath(basePath, destination)
+	base := strings.TrimRight(StandaloneBaseURL, "/")
+	if base == "" {
+		base = strings.TrimRight(siteURL, "/")
+	}
+	if base != "" && strings.HasPrefix(imageURL, "/") && !strings.HasPrefix(imageURL, "//") {
+		imageURL = base + imageURL
+	}
+	return imageURL
+}
+
+// siteURLContextKey holds the address of a rendering given with WithSiteURL
+var siteURLContextKey = parser.NewContextKey()
+
+// siteURLAttribute carries the address of a rendering on its document to the image renderer
+const siteURLAttribute = "site-url"
+
+// kindImageFigure is the NodeKind of imageFigure
+var kindImageFigure = ast.NewNodeKind("ImageFigure")
+
+// imageFigure replaces a paragraph holding nothing but an image with a copy link
+// A <figure> is a block element, so it can't be written inside the paragraph's <p>.
+type imageFigure struct {
+	ast.BaseBlock
+}
+
+// Kind implements ast.Node.Kind
+func (n *imageFigure) Kind() ast.NodeKind {
+	return kindImageFigure
+}
+
+// Dump implements ast.Node.Dump
+func (n *imageFigure) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// imageCopyLinkTransformer moves images that are all their paragraph holds and get a
+// copy link into an imageFigure, and puts the address of the rendering on the document
+type imageCopyLinkTransformer struct {
+	basePath  string
+	untrusted bool
+}
+
+// Transform implements parser.ASTTransformer
+func (t *imageCopyLinkTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	if !ImageCopyLinks {
+		return
+	}
+	if siteURL, ok := pc.Get(siteURLContextKey).(string); ok {
+		doc.SetAttributeString(siteURLAttribute, siteURL)
+	}
+
+	var paragraphs []*ast.Paragraph
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		paragraph, ok := node.(*ast.Paragraph)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		if image, ok := paragraph.FirstChild().(*ast.Image); ok && paragraph.ChildCount() == 1 && hasCopyLink(imageDestination(image, t.basePath, t.untrusted)) {
+			paragraphs = append(paragraphs, paragraph)
+		}
+		return ast.WalkSkipChildren, nil
+	})
+
+	for _, paragraph := range paragraphs {
+		image := paragraph.FirstChild()
+		paragraph.RemoveChild(paragraph, image)
+		figure := &imageFigure{}
+		figure.AppendChild(figure, image)
+		paragraph.Parent().ReplaceChild(paragraph.Parent(), paragraph, figure)
+	}
+}
+
+// imageExtension is a goldmark.Extender
+type imageExtension struct {
+	basePath  string
+	untrusted bool
+	files     FileProvider
+}
+
+// Extend implements goldmark.Extender
+func (e *imageExtension) Extend(m goldmark.Markdown) {
+	r := NewImageRenderer().(*imageRenderer)
+	r.basePath = e.basePath
+	r.untrusted = e.untrusted
+	r.files = e.files
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(r, 100),
+	))
+
+	// After image sizes, which drop the {width=... height=...} text following an image
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(&imageCopyLinkTransformer{basePath: e.basePath, untrusted: e.untrusted}, 600),
+	))
+}
+
+// imageSizeAttributesRegex matches a {width=300 height=50%} list right after an image
+// Sizes are pixels or percentages, optionally quoted.
+var (
+	imageSizeAttributesRegex = regexp.MustCompile(`^\{\s*(?:(?:width|height)=(?:\d+%?|"\d+%?"|'\d+%?')\s*)+\}`)
+	imageSizeAttributeRegex  = regexp.MustCompile(`(width|height)=["']?(\d+%?)`)
+)
+
+// imageSizeTransformer moves a {width=... height=...} list following an image onto
+// the image as width and height attributes. Goldmark only reads attribute lists of
+// headings, so the list would otherwise be rendered as text. Lists with anything but
+// a width and height are left alone.
+type imageSizeTransformer struct{}
+
+// Transform implements parser.ASTTransformer
+func (t *imageSizeTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		image, ok := node.(*ast.Image)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+
+		next, ok := image.NextSibling().(*ast.Text)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		start := next.Segment.Start
+		list := imageSizeAttributesRegex.Find(source[start:])
+		if list == nil {
+			return ast.WalkContinue, nil
+		}
+
+		for _, attribute := range imageSizeAttributeRegex.FindAllSubmatch(list, -1) {
+			image.SetAttributeString(string(attribute[1]), attribute[2])
+		}
+
+		// Drop the list from the text that follows, which may be split into several nodes
+		end := start + len(list)
+		for node := ast.Node(next); node != nil; {
+			textNode, ok := node.(*ast.Text)
+			if !ok || textNode.Segment.Start >= end {
+				break
+			}
+			following := node.NextSibling()
+			if textNode.Segment.Stop <= end {
+				if textNode.SoftLineBreak() || textNode.HardLineBreak() {
+					textNode.Segment = textNode.Segment.WithStart(textNode.Segment.Stop)
+				} else {
+					node.Parent().RemoveChild(node.Parent(), node)
+				}
+			} else {
+				textNode.Segment = textNode.Segment.WithStart(end)
+			}
+			node = following
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+// imageSizeExtension is a goldmark.Extender
+type imageSizeExtension struct{}
+
+// Extend implements goldmark.Extender
+func (e *imageSizeExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(&imageSizeTransformer{}, 500),
+	))
+}
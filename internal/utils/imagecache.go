@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// CacheExternalImages stores external images locally on first render and
+// rewrites their src to the cached copy once available. Disabled by default.
+var CacheExternalImages = false
+
+// ImageCacheDirName is the directory inside the documents root holding cached images
+// It is served at /api/files/_cache/ by the regular file handler
+const ImageCacheDirName = "_cache"
+
+// ImageCacheRoot is the filesystem directory cached images are written to
+var ImageCacheRoot = filepath.Join("data", "documents", ImageCacheDirName)
+
+// ImageCacheMaxBytes is the largest external image that will be cached
+var ImageCacheMaxBytes int64 = 5 << 20
+
+// imageCacheTypes maps the accepted content types to file extensions
+// SVG is deliberately excluded since it can carry scripts when served same-origin
+var imageCacheTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// imageFetcher downloads an external image and returns its body and content type
+// It is a variable so tests can replace the network fetch
+var imageFetcher = fetchExternalImage
+
+// ImageFetchWorkers is the number of external images downloaded at the same time
+var ImageFetchWorkers = 2
+
+// ImageFetchQueueSize is the number of external images waiting to be downloaded
+// Images found while the queue is full are queued by a later render instead.
+var ImageFetchQueueSize = 64
+
+// ImageFetchRetryAfter is how long an image whose download failed isn't tried again
+var ImageFetchRetryAfter = 15 * time.Minute
+
+// imageFetchJob is a queued download of an external image
+type imageFetchJob struct {
+	destination string
+	key         string
+}
+
+// Queued and running downloads, so each URL is only fetched once at a time, and the
+// time of failed downloads
+var (
+	imageFetchMutex   sync.Mutex
+	imageFetchPending = make(map[string]bool)
+	imageFetchQueue   []imageFetchJob
+	imageFetchWorkers int
+	imageFetchFailed  = make(map[string]time.Time)
+	imageFetchGroup   sync.WaitGroup
+)
+
+// cachedImageURL returns the /api/files URL of a cached external image
+// If the image isn't cached yet, a background download is queued and ok is false
+func cachedImageURL(destination string) (string, bool) {
+	key := imageCacheKey(destination)
+
+	for _, ext := range imageCacheTypes {
+		if _, err := os.Stat(filepath.Join(ImageCacheRoot, key+ext)); err == nil {
+			return "/api/files/" + ImageCacheDirName + "/" + key + ext, true
+		}
+	}
+
+	queueImageFetch(destination, key)
+	return "", false
+}
+
+// imageCacheKey derives the cache file name for an image URL
+func imageCacheKey(destination string) string {
+	sum := sha1.Sum([]byte(destination))
+	return hex.EncodeToString(sum[:])
+}
+
+// queueImageFetch queues the download of an external image, unless it is already queued,
+// failed recently or the queue is full
+func queueImageFetch(destination, key string) {
+	imageFetchMutex.Lock()
+	defer imageFetchMutex.Unlock()
+
+	if imageFetchPending[destination] || len(imageFetchQueue) >= ImageFetchQueueSize {
+		return
+	}
+	if failed, ok := imageFetchFailed[destination]; ok {
+		if time.Since(failed) < ImageFetchRetryAfter {
+			return
+		}
+		delete(imageFetchFailed, destination)
+	}
+
+	imageFetchPending[destination] = true
+	imageFetchQueue = append(imageFetchQueue, imageFetchJob{destination: destination, key: key})
+	imageFetchGroup.Add(1)
+
+	if imageFetchWorkers < max(ImageFetchWorkers, 1) {
+		imageFetchWorkers++
+		go runImageFetches()
+	}
+}
+
+// runImageFetches downloads queued images until the queue is empty
+func runImageFetches() {
+	for {
+		imageFetchMutex.Lock()
+		if len(imageFetchQueue) == 0 {
+			imageFetchWorkers--
+			imageFetchMutex.Unlock()
+			return
+		}
+		job := imageFetchQueue[0]
+		imageFetchQueue = imageFetchQueue[1:]
+		imageFetchMutex.Unlock()
+
+		err := storeExternalImage(job.destination, job.key)
+		if err != nil {
+			log.Printf("Error caching external image %s: %v", job.destination, err)
+		}
+
+		imageFetchMutex.Lock()
+		delete(imageFetchPending, job.destination)
+		if err != nil {
+			// Forget failures old enough to be retried anyway
+			now := time.Now()
+			for destination, failed := range imageFetchFailed {
+				if now.Sub(failed) >= ImageFetchRetryAfter {
+					delete(imageFetchFailed, destination)
+				}
+			}
+			imageFetchFailed[job.destination] = now
+		}
+		imageFetchMutex.Unlock()
+
+		// Cached renderings still point at the original URL
+		if err == nil {
+			ClearRenderCache()
+		}
+		imageFetchGroup.Done()
+	}
+}
+
+// storeExternalImage fetches an image and writes it to the cache directory
+func storeExternalImage(destination, key string) error {
+	data, contentType, err := imageFetcher(destination)
+	if err != nil {
+		return err
+	}
+
+	if int64(len(data)) > ImageCacheMaxBytes {
+		return fmt.Errorf("image exceeds %d bytes", ImageCacheMaxBytes)
+	}
+
+	// Trust the detected type over the server's header
+	detected := http.DetectContentType(data)
+	ext, ok := imageCacheTypes[detected]
+	if !ok {
+		return fmt.Errorf("unsupported image type %q (server sent %q)", detected, contentType)
+	}
+
+	if err := os.MkdirAll(ImageCacheRoot, 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial image
+	tmpFile, err := os.CreateTemp(ImageCacheRoot, key+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(ImageCacheRoot, key+ext))
+}
+
+// imageFetchClient is the HTTP client of external image downloads
+// It never connects to the server's own network, so documents can't make the server
+// request internal services; the check runs on every connection, including redirects.
+var imageFetchClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 5 * time.Second, Control: denyInternalAddress}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which net/netip doesn't count as private
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// denyInternalAddress rejects connections to loopback, private, link-local and other
+// addresses that aren't reachable on the public internet
+func denyInternalAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("address %s is not public", ip)
+	}
+	return nil
+}
+
+// fetchExternalImage downloads an image over HTTP with a timeout and size limit
+func fetchExternalImage(destination string) ([]byte, string, error) {
+	resp, err := imageFetchClient.Get(destination)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", fmt.Errorf("not an image: %q", contentType)
+	}
+
+	// Read one byte past the limit so oversized images can be detected
+	data, err := io.ReadAll(io.LimitReader(resp.Body, ImageCacheMaxBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, contentType, nil
+}
+
+// waitForImageFetches blocks until all background image downloads have finished
+func waitForImageFetches() {
+	imageFetchGroup.Wait()
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"  // Register GIF header decoding
+	_ "image/jpeg" // Register JPEG header decoding
+	_ "image/png"  // Register PNG header decoding
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ImageDimensions adds width and height attributes to internal /api/files images
+// so browsers can reserve space before the image loads. Disabled by default.
+var ImageDimensions = false
+
+// ImageFilesRoot is the filesystem directory /api/files/ image paths are resolved against
+var ImageFilesRoot = filepath.Join("data", "documents")
+
+// imageSize is a cached image size, valid while the file's modification time is unchanged
+type imageSize struct {
+	width, height int
+	modTime       time.Time
+}
+
+var (
+	imageSizeMutex sync.Mutex
+	imageSizeCache = make(map[string]imageSize)
+)
+
+// internalImageSize returns the intrinsic size of an internal /api/files image, read
+// through files or from the operating system's filesystem when files is nil
+// Only the image header is decoded; sizes read from the filesystem are cached by path
+// and modification time.
+func internalImageSize(destination string, files FileProvider) (int, int, bool) {
+	if !strings.HasPrefix(destination, "/api/files/") {
+		return 0, 0, false
+	}
+
+	// Drop any query string or fragment
+	if i := strings.IndexAny(destination, "?#"); i >= 0 {
+		destination = destination[:i]
+	}
+
+	relPath := strings.TrimPrefix(destination, "/api/files/")
+	if relPath == "" || strings.Contains(relPath, "..") {
+		return 0, 0, false
+	}
+	filePath := filepath.Join(ImageFilesRoot, filepath.FromSlash(relPath))
+
+	if files != nil {
+		content, err := files.ReadFile(filePath)
+		if err != nil {
+			return 0, 0, false
+		}
+		config, _, err := image.DecodeConfig(bytes.NewReader(content))
+		if err != nil {
+			return 0, 0, false
+		}
+		return config.Width, config.Height, true
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil || info.IsDir() {
+		return 0, 0, false
+	}
+
+	imageSizeMutex.Lock()
+	cached, ok := imageSizeCache[filePath]
+	imageSizeMutex.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.width, cached.height, true
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, 0, false
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	imageSizeMutex.Lock()
+	imageSizeCache[filePath] = imageSize{width: config.Width, height: config.Height, modTime: info.ModTime()}
+	imageSizeMutex.Unlock()
+
+	return config.Width, config.Height, true
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"wiki-go/internal/frontmatter"
+)
+
+// ArticleStructuredData wraps rendered documents in an <article> carrying schema.org
+// Article JSON-LD built from the frontmatter. Disabled by default.
+var ArticleStructuredData = false
+
+// articleAuthor is the schema.org Person of an article
+type articleAuthor struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+// articleJSONLD is the schema.org Article describing a document
+type articleJSONLD struct {
+	Context       string         `json:"@context"`
+	Type          string         `json:"@type"`
+	Headline      string         `json:"headline,omitempty"`
+	Description   string         `json:"description,omitempty"`
+	Image         string         `json:"image,omitempty"`
+	Author        *articleAuthor `json:"author,omitempty"`
+	DatePublished string         `json:"datePublished,omitempty"`
+	DateModified  string         `json:"dateModified,omitempty"`
+}
+
+// BuildArticleJSONLD returns the schema.org Article JSON-LD of a document
+// Frontmatter title, description, author, date and modified win; otherwise the headline
+// and description are derived like the social card ones and the dates fall back to modTime.
+// A zero modTime leaves dates without a frontmatter value out.
+func BuildArticleJSONLD(md string, docPath string, modTime time.Time) (string, error) {
+	metadata, _, _ := frontmatter.Parse(md)
+	hints := BuildOGCardHints(md, docPath)
+
+	article := articleJSONLD{
+		Context:       "https://schema.org",
+		Type:          "Article",
+		Headline:      hints.Title,
+		Description:   hints.Subtitle,
+		DatePublished: articleDate(metadata.Date, modTime),
+		DateModified:  articleDate(metadata.Modified, modTime),
+	}
+	if !hints.Generated {
+		article.Image = hints.ImageURL
+	}
+	if author := strings.TrimSpace(metadata.Author); author != "" {
+		article.Author = &articleAuthor{Type: "Person", Name: author}
+	}
+
+	// json.Marshal escapes <, > and &, so the data can't close the script element
+	data, err := json.Marshal(article)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// articleDate returns a frontmatter date if it is valid, otherwise the file time
+func articleDate(value string, modTime time.Time) string {
+	value = strings.TrimSpace(value)
+	if _, err := time.Parse("2006-01-02", value); err == nil {
+		return value
+	}
+	if date, err := frontmatter.ParseDate(value); err == nil {
+		return date.Format(time.RFC3339)
+	}
+	if modTime.IsZero() {
+		return ""
+	}
+	return modTime.UTC().Format(time.RFC3339)
+}
+
+// documentModTime returns the modification time of a document's markdown file
+// Documents live below the same root /api/files/ paths are resolved against.
+func documentModTime(docPath string) time.Time {
+	docPath = strings.Trim(docPath, "/")
+	if strings.Contains(docPath, "..") {
+		return time.Time{}
+	}
+
+	info, err := os.Stat(filepath.Join(ImageFilesRoot, filepath.FromSlash(docPath), "document.md"))
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// articleClosing closes the element opened by articleOpening
+const articleClosing = "</article>\n"
+
+// articleOpening returns the opening <article> with the document's JSON-LD
+func articleOpening(md string, docPath string) (string, bool) {
+	data, err := BuildArticleJSONLD(md, docPath, documentModTime(docPath))
+	if err != nil {
+		return "", false
+	}
+	return `<article class="document-article">` + "\n" +
+		`<script type="application/ld+json">` + data + "</script>\n", true
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"wiki-go/internal/frontmatter"
+)
+
+// UseGitLastEditor enables looking up the last commit author of a document
+// when its frontmatter does not name a last editor. Disabled by default.
+var UseGitLastEditor = false
+
+// LastUpdatedBy returns who last edited a document
+// Frontmatter last_editor wins, then the git history (if enabled), then author
+func LastUpdatedBy(filePath string, metadata frontmatter.Metadata) string {
+	if editor := strings.TrimSpace(metadata.LastEditor); editor != "" {
+		return editor
+	}
+
+	if UseGitLastEditor {
+		if editor, err := GitLastAuthor(filePath); err == nil && editor != "" {
+			return editor
+		}
+	}
+
+	return strings.TrimSpace(metadata.Author)
+}
+
+// GitLastAuthor returns the author name of the last commit that touched the file
+// An error is returned when git is not installed or the file is not in a git repository
+func GitLastAuthor(filePath string) (string, error) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command(gitPath, "log", "-1", "--format=%an", "--", filepath.Base(filePath))
+	cmd.Dir = filepath.Dir(filePath)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
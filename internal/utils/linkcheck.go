@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+
+	"wiki-go/internal/goldext"
+)
+
+// LinkChecker reports whether the document an internal link points to exists
+// The target is the unescaped document path without leading or trailing slashes,
+// so the homepage is the empty string
+type LinkChecker func(target string) bool
+
+// RenderMarkdownWithLinkCheck converts markdown text to HTML like RenderMarkdownWithPath,
+// additionally marking internal links with an "exists" or "broken" class.
+// This is meant for the editor preview; normal rendering never checks links.
+func RenderMarkdownWithLinkCheck(md string, docPath string, checker LinkChecker) []byte {
+	return renderMarkdown(md, docPath, renderOptions{linkChecker: checker})
+}
+
+// CachedLinkChecker wraps a checker so each target is only checked once
+// The returned checker is not safe for concurrent use and should live for a single render
+func CachedLinkChecker(checker LinkChecker) LinkChecker {
+	seen := make(map[string]bool)
+	return func(target string) bool {
+		exists, ok := seen[target]
+		if !ok {
+			exists = checker(target)
+			seen[target] = exists
+		}
+		return exists
+	}
+}
+
+// internalLinkTarget returns the document path of an internal link
+// Only absolute wiki paths count; external, anchor, API and static links are ignored.
+// Paths may start with the base path.
+func internalLinkTarget(destination string) (string, bool) {
+	destination = goldext.TrimBasePath(goldext.BasePath, destination)
+	if !strings.HasPrefix(destination, "/") || strings.HasPrefix(destination, "//") {
+		return "", false
+	}
+
+	// Drop the fragment and query string
+	if i := strings.IndexAny(destination, "#?"); i >= 0 {
+		destination = destination[:i]
+	}
+
+	if strings.HasPrefix(destination, "/api/") || strings.HasPrefix(destination, "/static/") {
+		return "", false
+	}
+
+	target, err := url.PathUnescape(destination)
+	if err != nil {
+		return "", false
+	}
+
+	return strings.Trim(target, "/"), true
+}
@@ -0,0 +1,268 @@
+package utils
+
+import (
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"wiki-go/internal/frontmatter"
+	"wiki-go/internal/goldext"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+)
+
+// LinkKind is the category of a link found in a document
+type LinkKind string
+
+// Link kinds
+const (
+	LinkInternal LinkKind = "internal" // A wiki document
+	LinkExternal LinkKind = "external" // An http(s) URL
+	LinkFile     LinkKind = "file"     // An uploaded file or static asset
+	LinkOther    LinkKind = "other"    // Any other destination, e.g. mailto:
+	LinkImage    LinkKind = "image"    // An image source
+)
+
+// Link is a link or image of a document
+type Link struct {
+	Kind   LinkKind `json:"kind"`
+	URL    string   `json:"url"`              // The destination as rendered, relative paths resolved
+	Target string   `json:"target,omitempty"` // Document path of internal links, without leading or trailing slashes
+}
+
+// DocumentLinks are the distinct link targets of a document
+type DocumentLinks struct {
+	Internal []string // Document paths without leading or trailing slashes
+	External []string // Absolute http(s) URLs
+}
+
+// LinkCounts are the link statistics of a single document
+type LinkCounts struct {
+	Inbound          int `json:"inbound"`
+	OutboundInternal int `json:"outbound_internal"`
+	OutboundExternal int `json:"outbound_external"`
+}
+
+// wikilinkAnchorRegex matches the opening tag of a resolved wikilink
+var wikilinkAnchorRegex = regexp.MustCompile(`^<a href="([^"]*)" class="wikilink">`)
+
+// ExtractLinks returns every link and image of a document in document order
+// Destinations are resolved like they are for rendering: relative paths against docPath
+// and [[wikilinks]] to the documents they find. Links in code and broken wikilinks are
+// skipped; #fragment links point to the document itself.
+func ExtractLinks(md string, docPath string) []Link {
+	_, body, _ := frontmatter.Parse(md)
+	docPath = strings.Trim(docPath, "/")
+	source := []byte(goldext.LinkPreprocessor(goldext.WikilinkPreprocessor(body, docPath), docPath))
+
+	doc := goldmark.New(goldmark.WithExtensions(extension.GFM)).Parser().Parse(text.NewReader(source))
+
+	var links []Link
+	addLink := func(destination string) {
+		link := Link{URL: destination}
+		if target, ok := internalLinkTarget(destination); ok {
+			link.Kind, link.Target = LinkInternal, target
+		} else if strings.HasPrefix(destination, "/") && !strings.HasPrefix(destination, "//") {
+			link.Kind = LinkFile
+		} else if strings.HasPrefix(destination, "#") {
+			link.Kind, link.Target = LinkInternal, docPath
+		} else if isExternalURL(destination) {
+			link.Kind = LinkExternal
+		} else {
+			link.Kind = LinkOther
+		}
+		links = append(links, link)
+	}
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch link := n.(type) {
+		case *ast.Link:
+			addLink(string(link.Destination))
+		case *ast.AutoLink:
+			if link.AutoLinkType == ast.AutoLinkURL {
+				addLink(string(link.URL(source)))
+			}
+		case *ast.Image:
+			links = append(links, Link{Kind: LinkImage, URL: string(link.Destination)})
+		case *ast.RawHTML:
+			if m := wikilinkAnchorRegex.FindStringSubmatch(string(link.Segments.Value(source))); m != nil {
+				addLink(html.UnescapeString(m[1]))
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return links
+}
+
+// documentLinks returns the internal documents and external URLs a document links to
+// Self-links and file links are ignored.
+func documentLinks(md string, docPath string) DocumentLinks {
+	docPath = strings.Trim(docPath, "/")
+
+	var links DocumentLinks
+	seen := make(map[string]bool)
+	for _, link := range ExtractLinks(md, docPath) {
+		switch link.Kind {
+		case LinkInternal:
+			if link.Target != docPath && !seen["internal:"+link.Target] {
+				seen["internal:"+link.Target] = true
+				links.Internal = append(links.Internal, link.Target)
+			}
+		case LinkExternal:
+			if !seen["external:"+link.URL] {
+				seen["external:"+link.URL] = true
+				links.External = append(links.External, link.URL)
+			}
+		}
+	}
+	return links
+}
+
+// linkGraphEntry is the cached link extraction of a single document
+type linkGraphEntry struct {
+	modTime time.Time
+	links   DocumentLinks
+}
+
+// LinkGraph caches the links of every document below a documents root
+// Documents are only re-read when their modification time changes.
+type LinkGraph struct {
+	root    string
+	mu      sync.Mutex
+	entries map[string]linkGraphEntry
+}
+
+// NewLinkGraph creates an empty link graph for the documents root
+func NewLinkGraph(documentsRoot string) *LinkGraph {
+	return &LinkGraph{
+		root:    documentsRoot,
+		entries: make(map[string]linkGraphEntry),
+	}
+}
+
+// Refresh brings the graph up to date with the documents on disk
+// Changed documents are re-read and deleted documents are dropped.
+func (g *LinkGraph) Refresh() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	present := make(map[string]bool)
+	err := filepath.Walk(g.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			// Skip hidden directories and the external image cache
+			if path != g.root && (strings.HasPrefix(info.Name(), ".") || info.Name() == ImageCacheDirName) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.Name() != "document.md" {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(g.root, filepath.Dir(path))
+		if err != nil {
+			return nil
+		}
+		docPath := filepath.ToSlash(relPath)
+		if docPath == "." {
+			docPath = ""
+		}
+		present[docPath] = true
+
+		if entry, ok := g.entries[docPath]; ok && entry.modTime.Equal(info.ModTime()) {
+			return nil
+		}
+		g.updateLocked(docPath, path, info.ModTime())
+		return nil
+	})
+
+	for docPath := range g.entries {
+		if !present[docPath] {
+			delete(g.entries, docPath)
+		}
+	}
+
+	return err
+}
+
+// Update re-reads a single document, e.g. right after it was saved
+func (g *LinkGraph) Update(docPath string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	docPath = strings.Trim(docPath, "/")
+	filePath := filepath.Join(g.root, filepath.FromSlash(docPath), "document.md")
+	info, err := os.Stat(filePath)
+	if err != nil {
+		delete(g.entries, docPath)
+		return
+	}
+	g.updateLocked(docPath, filePath, info.ModTime())
+}
+
+// updateLocked extracts the links of a document; g.mu must be held
+func (g *LinkGraph) updateLocked(docPath, filePath string, modTime time.Time) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		delete(g.entries, docPath)
+		return
+	}
+	g.entries[docPath] = linkGraphEntry{
+		modTime: modTime,
+		links:   documentLinks(string(content), docPath),
+	}
+}
+
+// Counts returns the link counts of every document in the graph
+// Inbound links are counted once per linking document.
+func (g *LinkGraph) Counts() map[string]LinkCounts {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	counts := make(map[string]LinkCounts, len(g.entries))
+	for docPath, entry := range g.entries {
+		c := counts[docPath]
+		c.OutboundInternal = len(entry.links.Internal)
+		c.OutboundExternal = len(entry.links.External)
+		counts[docPath] = c
+	}
+	for _, entry := range g.entries {
+		for _, target := range entry.links.Internal {
+			if c, ok := counts[target]; ok {
+				c.Inbound++
+				counts[target] = c
+			}
+		}
+	}
+
+	return counts
+}
+
+// Orphans returns the documents no other document links to, sorted by path
+func (g *LinkGraph) Orphans() []string {
+	var orphans []string
+	for docPath, c := range g.Counts() {
+		if c.Inbound == 0 {
+			orphans = append(orphans, docPath)
+		}
+	}
+	sort.Strings(orphans)
+	return orphans
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"wiki-go/internal/goldext"
+)
+
+func TestDocumentLinks(t *testing.T) {
+	md := "---\ntitle: Doc\n---\n# Doc\n\nSee [B](/guides/b), [B again](/guides/b#setup), [self](/guides/a) and [top](#doc).\n\n" +
+		"Visit [Example](https://example.com/x) or https://go.dev directly. [File](/api/files/guides/a/f.pdf)\n\n```\n[Code](/guides/c)\n```\n"
+
+	links := documentLinks(md, "guides/a")
+
+	if expected := []string{"guides/b"}; !reflect.DeepEqual(links.Internal, expected) {
+		t.Errorf("Expected internal links %v, got %v", expected, links.Internal)
+	}
+	if expected := []string{"https://example.com/x", "https://go.dev"}; !reflect.DeepEqual(links.External, expected) {
+		t.Errorf("Expected external links %v, got %v", expected, links.External)
+	}
+}
+
+func TestExtractLinks(t *testing.T) {
+	root := t.TempDir()
+	writeTestDocument(t, root, "guides/b", "# B\n")
+	defer func(previous string) { goldext.WikilinkRoot = previous }(goldext.WikilinkRoot)
+	goldext.WikilinkRoot = root
+
+	md := "---\ntitle: Doc\n---\n# Doc\n\n[Setup](/guides/b#setup) [[b|B]] [[Missing]] [top](#doc)\n\n" +
+		"![Diagram](my diagram.png) [Notes](notes.pdf) <https://go.dev> [Mail](mailto:team@example.com)\n\n" +
+		"| Link |\n|---|\n| [Example](https://example.com/x) |\n\n`[Code](/guides/c)`\n"
+
+	expected := []Link{
+		{Kind: LinkInternal, URL: "/guides/b#setup", Target: "guides/b"},
+		{Kind: LinkInternal, URL: "/guides/b", Target: "guides/b"},
+		{Kind: LinkInternal, URL: "#doc", Target: "guides/a"},
+		{Kind: LinkImage, URL: "/api/files/guides/a/my%20diagram.png"},
+		{Kind: LinkFile, URL: "/api/files/guides/a/notes.pdf"},
+		{Kind: LinkExternal, URL: "https://go.dev"},
+		{Kind: LinkOther, URL: "mailto:team@example.com"},
+		{Kind: LinkExternal, URL: "https://example.com/x"},
+	}
+	if links := ExtractLinks(md, "/guides/a/"); !reflect.DeepEqual(links, expected) {
+		t.Errorf("Expected links %+v, got %+v", expected, links)
+	}
+}
+
+func TestLinkGraphCounts(t *testing.T) {
+	root := t.TempDir()
+	writeTestDocument(t, root, "a", "# A\n\n[B](/b) [C](/c) [Missing](/nowhere) [Ext](https://example.com)\n")
+	writeTestDocument(t, root, "b", "# B\n\n[C](/c) [A](/a) [A twice](/a)\n")
+	writeTestDocument(t, root, "c", "# C\n\nNo internal links, only https://example.org and https://example.net\n")
+	writeTestDocument(t, root, "orphan", "# Orphan\n\n[A](/a)\n")
+
+	graph := NewLinkGraph(root)
+	if err := graph.Refresh(); err != nil {
+		t.Fatalf("Expected graph to refresh, got error: %v", err)
+	}
+
+	expected := map[string]LinkCounts{
+		"a":      {Inbound: 2, OutboundInternal: 3, OutboundExternal: 1},
+		"b":      {Inbound: 1, OutboundInternal: 2, OutboundExternal: 0},
+		"c":      {Inbound: 2, OutboundInternal: 0, OutboundExternal: 2},
+		"orphan": {Inbound: 0, OutboundInternal: 1, OutboundExternal: 0},
+	}
+	if counts := graph.Counts(); !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Expected counts %v, got %v", expected, counts)
+	}
+	if orphans := graph.Orphans(); !reflect.DeepEqual(orphans, []string{"orphan"}) {
+		t.Errorf("Expected orphans [orphan], got %v", orphans)
+	}
+
+	// Editing a document only changes the counts it affects
+	writeTestDocument(t, root, "b", "# B\n\n[Orphan](/orphan)\n")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(root, "b", "document.md"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := graph.Refresh(); err != nil {
+		t.Fatalf("Expected graph to refresh, got error: %v", err)
+	}
+	counts := graph.Counts()
+	if counts["a"].Inbound != 1 || counts["c"].Inbound != 1 || counts["orphan"].Inbound != 1 || counts["b"].OutboundInternal != 1 {
+		t.Errorf("Expected counts to follow the edit, got %v", counts)
+	}
+	if orphans := graph.Orphans(); len(orphans) != 0 {
+		t.Errorf("Expected no orphans after the edit, got %v", orphans)
+	}
+
+	// Deleted documents drop out of the graph, an explicit update picks up new ones
+	if err := os.RemoveAll(filepath.Join(root, "orphan")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestDocument(t, root, "d", "# D\n\n[C](/c)\n")
+	graph.Update("d")
+	counts = graph.Counts()
+	if counts["c"].Inbound != 2 {
+		t.Errorf("Expected the updated document to count, got %v", counts)
+	}
+	if err := graph.Refresh(); err != nil {
+		t.Fatalf("Expected graph to refresh, got error: %v", err)
+	}
+	if _, ok := graph.Counts()["orphan"]; ok {
+		t.Errorf("Expected deleted document to be dropped, got %v", graph.Counts())
+	}
+}
@@ -2,12 +2,18 @@ package utils
 
 import (
 	"bytes"
-	"os"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 	"wiki-go/internal/frontmatter"
 	"wiki-go/internal/goldext"
 
+	"github.com/gosimple/slug"
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
@@ -17,22 +23,84 @@ import (
 	"github.com/yuin/goldmark/util"
 )
 
+// ExternalLinksNewTab opens links to external sites in a new tab with rel="noopener noreferrer".
+// Internal links always open in the same tab. Enabled by default.
+var ExternalLinksNewTab = true
+
+// HardWraps renders single newlines in paragraphs as line breaks. Documents can turn it
+// off with hard_wraps: false for prose wrapped at a fixed width, and renderings with
+// WithHardWraps. Enabled by default.
+var HardWraps = true
+
+// SiteHosts are the host names of the wiki itself; absolute links to them count as internal
+var SiteHosts []string
+
 // Custom HTML renderer for links
 type pdfLinkRenderer struct {
 	html.Config
+	linkChecker    LinkChecker // Marks internal links as existing or broken when set
+	externalNewTab bool        // Opens external links in a new tab
+	untrusted      bool        // Drops javascript: and other unsafe destinations
+	basePath       string      // Put in front of root-relative destinations
+}
+
+// LinkRendererOption configures the link renderer
+type LinkRendererOption func(*pdfLinkRenderer)
+
+// WithExternalLinksNewTab sets whether external links open in a new tab
+func WithExternalLinksNewTab(enabled bool) LinkRendererOption {
+	return func(r *pdfLinkRenderer) {
+		r.externalNewTab = enabled
+	}
+}
+
+// WithBasePath serves root-relative links below basePath, e.g. /wiki
+// Links that already start with it aren't prefixed again.
+func WithBasePath(basePath string) LinkRendererOption {
+	return func(r *pdfLinkRenderer) {
+		r.basePath = goldext.CleanBasePath(basePath)
+	}
+}
+
+// WithLinkHTMLOptions applies Goldmark HTML renderer options to the link renderer
+func WithLinkHTMLOptions(opts ...html.Option) LinkRendererOption {
+	return func(r *pdfLinkRenderer) {
+		for _, opt := range opts {
+			opt.SetHTMLOption(&r.Config)
+		}
+	}
 }
 
 // NewPDFLinkRenderer creates a new renderer
-func NewLinkRenderer(opts ...html.Option) renderer.NodeRenderer {
+// External links open in a new tab unless disabled with WithExternalLinksNewTab(false).
+func NewLinkRenderer(opts ...LinkRendererOption) renderer.NodeRenderer {
 	r := &pdfLinkRenderer{
-		Config: html.NewConfig(),
+		Config:         html.NewConfig(),
+		externalNewTab: true,
 	}
 	for _, opt := range opts {
-		opt.SetHTMLOption(&r.Config)
+		opt(r)
 	}
 	return r
 }
 
+// isSiteExternalURL reports whether a link leaves the wiki
+func isSiteExternalURL(destination string) bool {
+	if !isExternalURL(destination) {
+		return false
+	}
+	u, err := url.Parse(destination)
+	if err != nil {
+		return false
+	}
+	for _, host := range SiteHosts {
+		if strings.EqualFold(u.Hostname(), host) {
+			return false
+		}
+	}
+	return true
+}
+
 // RegisterFuncs implements NodeRenderer.RegisterFuncs
 func (r *pdfLinkRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
 	// Register the default HTML renderer for all nodes
@@ -43,67 +111,253 @@ func (r *pdfLinkRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer)
 func (r *pdfLinkRenderer) renderLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	var err error
 	if !entering {
-		_, err = w.WriteString("</a>")
-		if err != nil {
-			return ast.WalkStop, err
-		}
+		// The whole link, closing tag included, was written when entering
 		return ast.WalkContinue, nil
 	}
 
-	destination := string(node.(*ast.Link).Destination)
+	// Links written with the base path are handled like root-relative ones
+	destination := goldext.TrimBasePath(r.basePath, string(node.(*ast.Link).Destination))
 	text := string(node.Text(source))
 
-	destinationLower := strings.ToLower(destination)
-	if strings.HasPrefix(destinationLower, "/api/files/") && strings.HasSuffix(destinationLower, ".pdf") {
-		destination = strings.TrimPrefix(destination, "/api/files")
-		//Render as link to PDF viewer
-		_, err = w.WriteString(`<a href="` + string(util.EscapeHTML([]byte(filepath.Dir(destination)))) + `?mode=pdf&file=` + filepath.Base(destination) + `">` + string(text) + `</a>`)
-		if err != nil {
-			return ast.WalkStop, err
+	// Uploaded files of known types get their viewer or player
+	if strings.HasPrefix(strings.ToLower(destination), "/api/files/") {
+		if renderFile, ok := FileLinkRenderers[fileLinkExtension(destination)]; ok {
+			_, err = w.WriteString(renderFile(goldext.JoinBasePath(r.basePath, destination), text))
+			if err != nil {
+				return ast.WalkStop, err
+			}
+			return ast.WalkSkipChildren, err
+		}
+	}
+
+	if StripTrackingParams {
+		destination = stripTrackingParams(destination)
+	}
+
+	// Untrusted documents can't link to scripts
+	if r.untrusted && html.IsDangerousURL([]byte(destination)) {
+		destination = ""
+	}
+
+	// Mark internal links by whether their target exists (editor preview only)
+	class := ""
+	if r.linkChecker != nil {
+		if target, ok := internalLinkTarget(destination); ok {
+			if r.linkChecker(target) {
+				class = ` class="exists"`
+			} else {
+				class = ` class="broken"`
+			}
 		}
-		return ast.WalkSkipChildren, err
 	}
 
-	_, err = w.WriteString(`<a href="` + string(util.EscapeHTML([]byte(destination))) + `" target="_blank">` + string(text) + `</a>`)
+	// Only external links open in a new tab, without access to this page
+	target := ""
+	if r.externalNewTab && isSiteExternalURL(destination) {
+		target = ` target="_blank" rel="noopener noreferrer"`
+	}
+
+	destination = goldext.JoinBasePath(r.basePath, destination)
+	_, err = w.WriteString(`<a href="` + string(util.EscapeHTML([]byte(destination))) + `"` + class + target + `>` + string(text) + `</a>`)
 	if err != nil {
 		return ast.WalkStop, err
 	}
 	return ast.WalkSkipChildren, nil
 }
 
+// FileLinkRenderers render links to uploaded files under /api/files/ by their lower-case
+// extension. Each gets the link destination, below the base path, and the rendered link text
+// and must HTML-escape the destination itself; links to other files are rendered as usual.
+var FileLinkRenderers = map[string]func(destination, text string) string{
+	".pdf":  renderPDFViewerLink,
+	".mp3":  renderAudioLink,
+	".ogg":  renderAudioLink,
+	".wav":  renderAudioLink,
+	".mp4":  renderVideoLink,
+	".webm": renderVideoLink,
+}
+
+// fileLinkExtension returns the lower-case extension of a link's path
+func fileLinkExtension(destination string) string {
+	filePath, _, _ := strings.Cut(destination, "#")
+	filePath, _, _ = strings.Cut(filePath, "?")
+	return strings.ToLower(path.Ext(filePath))
+}
+
+// renderPDFViewerLink renders a link opening a PDF in the viewer
+func renderPDFViewerLink(destination, text string) string {
+	// The query is already escaped
+	viewerPath, viewerQuery, _ := strings.Cut(pdfViewerURL(destination), "?")
+	return `<a href="` + string(util.EscapeHTML([]byte(viewerPath))) + `?` + viewerQuery + `">` + text + `</a>`
+}
+
+// renderAudioLink renders an audio player, falling back to the link
+func renderAudioLink(destination, text string) string {
+	src := string(util.EscapeHTML([]byte(destination)))
+	return `<audio class="file-audio" controls preload="metadata" src="` + src + `"><a href="` + src + `">` + text + `</a></audio>`
+}
+
+// renderVideoLink renders a video player, falling back to the link
+func renderVideoLink(destination, text string) string {
+	src := string(util.EscapeHTML([]byte(destination)))
+	return `<video class="file-video" controls preload="metadata" src="` + src + `"><a href="` + src + `">` + text + `</a></video>`
+}
+
+// pdfViewerURL returns the PDF viewer link of an uploaded PDF
+// The viewer is opened on the folder holding the file, so
+// /api/files/docs/reports/q3%20summary.pdf becomes /docs/reports?mode=pdf&file=q3+summary.pdf
+// A #fragment, such as #page=2, is kept on the viewer link; a ?query is dropped.
+// The viewer link keeps the base path the file is served under.
+func pdfViewerURL(destination string) string {
+	destination, fragment, _ := strings.Cut(destination, "#")
+	destination, _, _ = strings.Cut(destination, "?")
+
+	basePath := ""
+	if i := strings.Index(destination, "/api/files/"); i > 0 {
+		basePath, destination = destination[:i], destination[i:]
+	}
+
+	// Split the escaped path, so an escaped slash stays part of its segment
+	rawDir, file := path.Split(strings.TrimPrefix(destination, "/api/files"))
+	if unescaped, err := url.PathUnescape(file); err == nil {
+		file = unescaped
+	}
+	rawDir = strings.TrimSuffix(rawDir, "/")
+	if rawDir == "" {
+		rawDir = "/"
+	}
+	dir := rawDir
+	if unescaped, err := url.PathUnescape(rawDir); err == nil {
+		dir = unescaped
+	}
+
+	// The folder and fragment keep their escaping when it is valid
+	viewerURL := basePath + (&url.URL{Path: dir, RawPath: rawDir}).EscapedPath() + "?mode=pdf&file=" + url.QueryEscape(file)
+	if fragment != "" {
+		if u, err := url.Parse("#" + fragment); err == nil {
+			fragment = u.EscapedFragment()
+		}
+		viewerURL += "#" + fragment
+	}
+	return viewerURL
+}
+
 // linkExtension is a goldmark.Extender
-type pdfLinkExtension struct{}
+type pdfLinkExtension struct {
+	linkChecker    LinkChecker
+	untrusted      bool
+	externalNewTab bool
+	basePath       string
+}
 
 // Extend implements goldmark.Extender
 func (e *pdfLinkExtension) Extend(m goldmark.Markdown) {
+	r := NewLinkRenderer(WithExternalLinksNewTab(e.externalNewTab), WithBasePath(e.basePath)).(*pdfLinkRenderer)
+	r.linkChecker = e.linkChecker
+	r.untrusted = e.untrusted
 	m.Renderer().AddOptions(renderer.WithNodeRenderers(
-		util.Prioritized(NewLinkRenderer(), 100),
+		util.Prioritized(r, 100),
 	))
 }
 
-// RenderMarkdownFile reads a markdown file and returns its HTML representation
-func RenderMarkdownFile(filePath string) ([]byte, error) {
-	// Read the markdown file
-	mdContent, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, err
-	}
+// DocumentsRoot is the directory holding the documents; file paths below it map to
+// document paths in URLs. Set it with SetDocumentsRoot at startup.
+var DocumentsRoot = filepath.Join("data", "documents")
+
+// SetDocumentsRoot sets the documents directory for rendering, including the roots
+// wikilinks, includes and images are resolved against. It must be called before rendering starts.
+func SetDocumentsRoot(root string) {
+	DocumentsRoot = root
+	ImageFilesRoot = root
+	ImageCacheRoot = filepath.Join(root, ImageCacheDirName)
+	goldext.WikilinkRoot = root
+	goldext.IncludeRoot = root
+}
 
+// SetBasePath sets the path the wiki is served under, e.g. /wiki behind a reverse proxy
+// Root-relative links, images and file URLs in rendered documents are put below it.
+// It must be called before rendering starts.
+func SetBasePath(basePath string) {
+	goldext.BasePath = goldext.CleanBasePath(basePath)
+}
+
+// documentPathOf returns the document path of the directory holding a markdown file
+func documentPathOf(filePath string) string {
 	// Get the directory path for the document
 	docDir := filepath.Dir(filePath)
 
 	// Convert to a relative path for URL construction
-	relPath, err := filepath.Rel(filepath.Join("data", "documents"), docDir)
+	relPath, err := filepath.Rel(DocumentsRoot, docDir)
 	if err != nil {
 		// If we can't get a relative path, just use the directory name
 		relPath = filepath.Base(docDir)
 	}
 
 	// Replace backslashes with forward slashes for URLs
-	relPath = strings.ReplaceAll(relPath, "\\", "/")
+	return strings.ReplaceAll(relPath, "\\", "/")
+}
+
+// RenderMarkdownFile reads a markdown file and returns its HTML representation
+// Renderings are cached by path, modification time and size; see ClearRenderCache.
+func RenderMarkdownFile(filePath string) ([]byte, error) {
+	html, _, err := RenderMarkdownFileWithMetadata(filePath)
+	return html, err
+}
+
+// RenderMarkdownFileWithMetadata reads a markdown file once and returns its HTML
+// representation together with its parsed frontmatter
+// The metadata is empty when the file has none, except for LastEditor, which holds who
+// last edited the file as LastUpdatedBy resolves it. Renderings are cached like
+// RenderMarkdownFile's.
+func RenderMarkdownFileWithMetadata(filePath string) ([]byte, *frontmatter.Metadata, error) {
+	html, metadata, err := renderMarkdownFileCached(filePath, documentPathOf(filePath))
+	if err != nil {
+		return nil, nil, err
+	}
+	return html, &metadata, nil
+}
+
+// renderMarkdownFileCached renders a markdown file as the document at docPath through the render cache
+func renderMarkdownFileCached(filePath string, docPath string) ([]byte, frontmatter.Metadata, error) {
+	info, err := OSFileProvider.Stat(filePath)
+	if err != nil {
+		return nil, frontmatter.Metadata{}, err
+	}
+
+	// Renderings for another document path than the file's own are cached separately
+	key := renderCacheKey(filePath)
+	if docPath != documentPathOf(filePath) {
+		key += "#" + docPath
+	}
+	// Editing the directory defaults changes the rendering just like editing the file
+	stamps := directoryDefaultsStamps(docPath)
+	if html, metadata, ok := cachedRendering(key, info, stamps); ok {
+		return html, metadata, nil
+	}
+
+	mdContent, err := OSFileProvider.ReadFile(filePath)
+	if err != nil {
+		return nil, frontmatter.Metadata{}, err
+	}
+
+	defaults := directoryDefaults(docPath, OSFileProvider)
+	html, metadata, _ := renderMarkdownWithMetadata(string(mdContent), docPath, renderOptions{defaults: defaults, defaultsRead: true})
+	metadata.LastEditor = LastUpdatedBy(filePath, metadata)
+	storeRendering(key, info, stamps, html, metadata)
+	return html, metadata, nil
+}
+
+// RenderMarkdownFileWithProvider reads a markdown file through provider and returns its HTML representation
+// Its directory defaults, included documents, wikilink targets and image sizes are read through provider too.
+func RenderMarkdownFileWithProvider(filePath string, provider FileProvider) ([]byte, error) {
+	// Read the markdown file
+	mdContent, err := provider.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
 
 	// Use the path-aware rendering function
-	return RenderMarkdownWithPath(string(mdContent), relPath), nil
+	return renderMarkdown(string(mdContent), documentPathOf(filePath), renderOptions{files: provider}), nil
 }
 
 // RenderMarkdown converts markdown text to HTML
@@ -111,10 +365,152 @@ func RenderMarkdown(md string) []byte {
 	return RenderMarkdownWithPath(md, "")
 }
 
+// RenderOption configures a single rendering
+type RenderOption func(*renderOptions)
+
+// renderOptions are the per-rendering settings
+type renderOptions struct {
+	linkChecker       LinkChecker      // Marks internal links as existing or broken when set
+	footnoteNamespace string           // Prefix of footnote IDs, already anchor-safe
+	untrusted         bool             // Sanitizes raw HTML and unsafe link URLs
+	hardWraps         *bool            // Overrides HardWraps when set
+	section           *sectionSelector // Renders only this section when set
+	defaults          []string         // Directory defaults of the document when defaultsRead
+	defaultsRead      bool             // Whether defaults were read already, else they are read for docPath
+	files             FileProvider     // Reads the other files of the rendering when set, else OSFileProvider
+	siteURL           string           // Address the rendering is served from, for copied image URLs
+}
+
+// WithUntrustedHTML renders the document as untrusted content: raw HTML is reduced to
+// an allowlist of formatting elements and attributes and javascript: links are dropped.
+// Documents can also opt in with trusted: false in their frontmatter.
+func WithUntrustedHTML() RenderOption {
+	return func(o *renderOptions) {
+		o.untrusted = true
+	}
+}
+
+// WithFootnoteNamespace prefixes the footnote and footnote reference IDs with namespace,
+// e.g. fn:1 becomes guide-fn:1, so several rendered documents can share a page.
+// The namespace is reduced to lowercase letters, digits and dashes.
+func WithFootnoteNamespace(namespace string) RenderOption {
+	return func(o *renderOptions) {
+		o.footnoteNamespace = slug.Make(namespace)
+	}
+}
+
+// WithHardWraps turns rendering single newlines as line breaks on or off for the
+// rendering instead of following HardWraps. A hard_wraps setting in the document's
+// frontmatter still takes precedence.
+func WithHardWraps(enabled bool) RenderOption {
+	return func(o *renderOptions) {
+		o.hardWraps = &enabled
+	}
+}
+
+// WithSiteURL gives the address the rendering is served from, e.g. https://wiki.example.com
+// built from the request, so copied image URLs are absolute when StandaloneBaseURL is empty
+func WithSiteURL(siteURL string) RenderOption {
+	return func(o *renderOptions) {
+		o.siteURL = siteURL
+	}
+}
+
 // RenderMarkdownWithPath converts markdown text to HTML with the current document path
-func RenderMarkdownWithPath(md string, docPath string) []byte {
-	// Check for frontmatter
-	metadata, contentWithoutFrontmatter, hasFrontmatter := frontmatter.Parse(md)
+func RenderMarkdownWithPath(md string, docPath string, opts ...RenderOption) []byte {
+	var options renderOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return renderMarkdown(md, docPath, options)
+}
+
+// RenderMarkdownWithMetadata converts markdown text to HTML with the current document path
+// and also returns the parsed frontmatter and whether the document has any, so callers
+// needing the layout, title or tags don't parse the document a second time
+func RenderMarkdownWithMetadata(md string, docPath string, opts ...RenderOption) ([]byte, *frontmatter.Metadata, bool) {
+	var options renderOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	html, metadata, hasFrontmatter := renderMarkdownWithMetadata(md, docPath, options)
+	return html, &metadata, hasFrontmatter
+}
+
+// RenderMarkdownTo converts markdown text to HTML with the current document path and
+// writes it to w while it is rendered instead of building the whole document in memory.
+// The print, AMP and responsive table options need the complete HTML, so the rendered
+// document is buffered when one of them is enabled. Output already written when an error
+// is returned is left in w.
+func RenderMarkdownTo(w io.Writer, md string, docPath string, opts ...RenderOption) error {
+	var options renderOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return renderMarkdownTo(w, md, docPath, options)
+}
+
+// renderMarkdown converts markdown text to HTML with the given options
+func renderMarkdown(md string, docPath string, opts renderOptions) []byte {
+	html, _, _ := renderMarkdownWithMetadata(md, docPath, opts)
+	return html
+}
+
+// renderMarkdownWithMetadata converts markdown text to HTML and returns the parsed frontmatter with it
+func renderMarkdownWithMetadata(md string, docPath string, opts renderOptions) ([]byte, frontmatter.Metadata, bool) {
+	metadata, contentWithoutFrontmatter, hasFrontmatter := parseDocument(md, docPath, opts)
+
+	var buf bytes.Buffer
+	if err := renderDocumentTo(&buf, md, metadata, contentWithoutFrontmatter, hasFrontmatter, docPath, opts); err != nil {
+		// If there's an error, return an error message
+		return []byte("<p>Error rendering markdown with Goldmark: " + err.Error() + "</p>"), metadata, hasFrontmatter
+	}
+	return buf.Bytes(), metadata, hasFrontmatter
+}
+
+// renderMarkdownTo writes the HTML of markdown text to w with the given options
+func renderMarkdownTo(w io.Writer, md string, docPath string, opts renderOptions) error {
+	metadata, contentWithoutFrontmatter, hasFrontmatter := parseDocument(md, docPath, opts)
+	return renderDocumentTo(w, md, metadata, contentWithoutFrontmatter, hasFrontmatter, docPath, opts)
+}
+
+// parseDocument parses the frontmatter of a document merged with its directory defaults
+func parseDocument(md string, docPath string, opts renderOptions) (frontmatter.Metadata, string, bool) {
+	defaults := opts.defaults
+	if !opts.defaultsRead {
+		files := opts.files
+		if files == nil {
+			files = OSFileProvider
+		}
+		defaults = directoryDefaults(docPath, files)
+	}
+	return frontmatter.MergeMetadata(md, defaults...)
+}
+
+// renderDocumentTo writes the HTML of a document whose frontmatter is already parsed to w
+func renderDocumentTo(w io.Writer, md string, metadata frontmatter.Metadata, contentWithoutFrontmatter string, hasFrontmatter bool, docPath string, opts renderOptions) error {
+	// Keep the complete document for the structured data
+	document := md
+
+	// Frontmatter can only lower the trust a caller gives a document
+	if metadata.Trusted != nil && !*metadata.Trusted {
+		opts.untrusted = true
+	}
+
+	// Wrap the output of every layout in the document's frontmatter classes,
+	// starting with the banner of drafts, which sections leave out
+	classesOpen, classesClose := classesWrapper(metadata)
+	if opts.section == nil {
+		classesOpen += draftBannerHTML(metadata)
+	}
+
+	// Show why invalid frontmatter was ignored instead of rendering the raw YAML
+	if !hasFrontmatter {
+		if _, body, found, err := frontmatter.ParseStrict(md); found && err != nil {
+			classesOpen += frontmatterErrorHTML(err)
+			md = body
+		}
+	}
 
 	// If this has kanban layout, render as kanban with full goldext support
 	if hasFrontmatter && metadata.Layout == "kanban" {
@@ -122,28 +518,27 @@ func RenderMarkdownWithPath(md string, docPath string) []byte {
 		var preprocessors []frontmatter.PreprocessorFunc
 		var postProcessors []frontmatter.PostProcessorFunc
 
-		// Add all goldext preprocessors (frontmatter will be a no-op since it's already processed)
-		for _, preprocessor := range goldext.RegisteredPreprocessors {
-			if preprocessor != nil {
-				// Create a closure that captures the docPath for kanban rendering
-				capturedPreprocessor := preprocessor
-				capturedDocPath := docPath
-				wrappedPreprocessor := func(md string, _ string) string {
-					return capturedPreprocessor(md, capturedDocPath)
-				}
-				preprocessors = append(preprocessors, wrappedPreprocessor)
-			}
-		}
+		// Add all goldext preprocessors (frontmatter will be a no-op since it's already processed),
+		// with a closure that captures the docPath and files for kanban rendering
+		preprocessors = append(preprocessors, func(md string, _ string) string {
+			return goldext.ProcessMarkdownWithFiles(md, docPath, opts.files)
+		})
 
-		// Add post-processors for mermaid and direction blocks
+		// Add post-processors for mermaid and direction blocks and math
 		postProcessors = append(postProcessors, func(html string) string {
 			result := goldext.RestoreMermaidBlocks(html)
 			result = goldext.RestoreDirectionBlocks(result)
+			result = goldext.RestoreMathSpans(result)
 			return result
 		})
 
+		if opts.untrusted {
+			contentWithoutFrontmatter = goldext.UntrustedIncludePreprocessorWithFiles(opts.files)(goldext.SanitizeUntrustedMarkdown(contentWithoutFrontmatter, docPath), docPath)
+		}
+
 		kanbanHTML := frontmatter.RenderKanbanWithProcessors(contentWithoutFrontmatter, preprocessors, postProcessors)
-		return []byte(kanbanHTML)
+		_, err := io.WriteString(w, classesOpen+kanbanHTML+classesClose)
+		return err
 	}
 
 	// If this has links layout, render as links document
@@ -153,61 +548,315 @@ func RenderMarkdownWithPath(md string, docPath string) []byte {
 			// If links rendering fails, fall back to regular markdown
 			md = contentWithoutFrontmatter
 		} else {
-			return []byte(linksHTML)
+			_, err := io.WriteString(w, classesOpen+linksHTML+classesClose)
+			return err
 		}
 	}
 
+	// If this has gallery layout, render the images as a thumbnail grid
+	if hasFrontmatter && metadata.Layout == "gallery" {
+		// Resolve image paths against the document first
+		galleryHTML, err := frontmatter.RenderGallery(goldext.LinkPreprocessor(contentWithoutFrontmatter, docPath))
+		if err != nil {
+			// If gallery rendering fails, fall back to regular markdown
+			md = contentWithoutFrontmatter
+		} else {
+			_, err := io.WriteString(w, classesOpen+galleryHTML+classesClose)
+			return err
+		}
+	}
+
+	// Time the phases of rendering when observed
+	var start time.Time
+	if renderObserver != nil {
+		start = time.Now()
+	}
+
 	// If there's frontmatter but not kanban layout, use content without frontmatter
 	if hasFrontmatter {
 		md = contentWithoutFrontmatter
 	}
 
+	// Render the placeholder for documents without content
+	md = emptyDocumentMarkdown(md, metadata)
+
+	// Substitute {{name}} document variables from the frontmatter
+	md = ExpandVariables(md, metadata.Vars)
+
+	// Expand the {{changelog}} shortcode from the frontmatter changelog
+	md = ExpandChangelog(md, metadata, docPath)
+
+	// Insert the table of contents asked for by the frontmatter
+	if metadata.TOC || metadata.TOCMaxLevel > 0 {
+		md = goldext.FrontmatterTOC(md, bool(metadata.TOC), metadata.TOCMaxLevel)
+	}
+
+	// Wrap all or only the first occurrence of abbreviations as the frontmatter asks
+	if metadata.Abbreviations != "" {
+		md = goldext.FrontmatterAbbreviations(md, metadata.Abbreviations)
+	}
+
+	// Reduce the author's raw HTML to safe formatting before preprocessors add their own,
+	// also in included documents
+	if opts.untrusted {
+		md = goldext.UntrustedIncludePreprocessorWithFiles(opts.files)(goldext.SanitizeUntrustedMarkdown(md, docPath), docPath)
+	}
+
 	// Apply any custom extensions via pre-processing
-	md = goldext.ProcessMarkdown(md, docPath)
+	md = goldext.ProcessMarkdownWithFiles(md, docPath, opts.files)
+	if renderObserver != nil {
+		observeRender(docPath, RenderPhasePreprocess, start)
+	}
+
+	// Reuse the Goldmark instance configured for these options
+	markdown := markdownFor(opts, metadata)
+
+	// Wrap the document in an <article> with JSON-LD when enabled
+	articleClose := ""
+	if ArticleStructuredData && opts.section == nil {
+		if articleOpen, ok := articleOpening(document, docPath); ok {
+			if _, err := io.WriteString(w, articleOpen); err != nil {
+				return err
+			}
+			articleClose = articleClosing
+		}
+	}
+
+	if classesOpen != "" {
+		if _, err := io.WriteString(w, classesOpen); err != nil {
+			return err
+		}
+	}
+	articleClose = classesClose + articleClose
+
+	// Flow the body of columns layout documents into CSS columns
+	if hasFrontmatter && metadata.Layout == "columns" {
+		columnsOpen := fmt.Sprintf(`<div class="doc-columns" style="column-count:%d">`+"\n", frontmatter.ColumnCount(metadata))
+		if _, err := io.WriteString(w, columnsOpen); err != nil {
+			return err
+		}
+		articleClose = "</div>\n" + articleClose
+	}
+
+	// Options rewriting the whole document need the complete HTML
+	if goldext.ResponsiveTables || PrintOutput || AMPOutput {
+		var buf bytes.Buffer
+		if err := convertPostProcessed(markdown, md, &buf, docPath, opts); err != nil {
+			return err
+		}
+		if renderObserver != nil {
+			start = time.Now()
+		}
+
+		// Post-process: Wrap tables in scroll containers when enabled
+		htmlResult := goldext.WrapResponsiveTables(buf.String())
+
+		// Post-process: Convert to the print variant when enabled
+		if PrintOutput {
+			htmlResult = ToPrint(htmlResult)
+		}
+
+		// Post-process: Convert to the AMP variant when enabled
+		if AMPOutput {
+			htmlResult = ToAMP(htmlResult)
+		}
+		if renderObserver != nil {
+			observeRender(docPath, RenderPhaseRewrite, start)
+		}
+
+		_, err := io.WriteString(w, htmlResult+articleClose)
+		return err
+	}
+
+	// Stream the HTML through the line-based post-processors
+	if err := convertPostProcessed(markdown, md, w, docPath, opts); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, articleClose)
+	return err
+}
+
+// markdownConfig is everything the Goldmark instance of a rendering depends on
+type markdownConfig struct {
+	footnoteNamespace   string
+	untrusted           bool
+	anchors             bool
+	numberedHeadings    bool
+	paragraphPermalinks bool
+	images              bool
+	footnotesPerSection bool
+	externalNewTab      bool
+	basePath            string
+	hardWraps           bool
+	extensions          int // Number of goldext.Extensions, so registering one builds new instances
+}
+
+// Goldmark instances by configuration, built on first use
+// Parsing and rendering keep their state per call, so instances are shared by concurrent renderings.
+var (
+	markdownInstancesMutex sync.Mutex
+	markdownInstances      = make(map[markdownConfig]goldmark.Markdown)
+)
+
+// markdownFor returns the Goldmark instance for a rendering with the given options
+// The options read from package variables are looked up on every call, so changing
+// them still takes effect. Link-checked, namespaced, section and file provider renderings
+// get a fresh instance, which keeps the cache small.
+func markdownFor(opts renderOptions, metadata frontmatter.Metadata) goldmark.Markdown {
+	config := markdownConfig{
+		footnoteNamespace:   opts.footnoteNamespace,
+		untrusted:           opts.untrusted,
+		anchors:             metadata.Anchors == nil || *metadata.Anchors,
+		numberedHeadings:    bool(metadata.NumberedHeadings),
+		paragraphPermalinks: ParagraphPermalinks,
+		images:              ImageCopyLinks || CacheExternalImages || ImageDimensions || AMPOutput || goldext.BasePath != "" || opts.untrusted,
+		footnotesPerSection: goldext.FootnotesPerSection,
+		externalNewTab:      ExternalLinksNewTab,
+		basePath:            goldext.BasePath,
+		hardWraps:           hardWrapsFor(opts, metadata),
+		extensions:          len(goldext.Extensions()),
+	}
+	if opts.linkChecker != nil || opts.footnoteNamespace != "" || opts.section != nil || opts.files != nil {
+		return newMarkdown(config, opts.linkChecker, opts.section, opts.files)
+	}
+
+	markdownInstancesMutex.Lock()
+	defer markdownInstancesMutex.Unlock()
+
+	markdown, ok := markdownInstances[config]
+	if !ok {
+		markdown = newMarkdown(config, nil, nil, nil)
+		markdownInstances[config] = markdown
+	}
+	return markdown
+}
+
+// hardWrapsFor reports whether single newlines break lines in a rendering: the document's
+// hard_wraps wins over the rendering's WithHardWraps, which wins over HardWraps
+func hardWrapsFor(opts renderOptions, metadata frontmatter.Metadata) bool {
+	if metadata.HardWraps != nil {
+		return *metadata.HardWraps
+	}
+	if opts.hardWraps != nil {
+		return *opts.hardWraps
+	}
+	return HardWraps
+}
+
+// newMarkdown builds a Goldmark instance with the extensions of a configuration
+// Image sizes are looked up through files, or OSFileProvider when it is nil.
+func newMarkdown(config markdownConfig, linkChecker LinkChecker, section *sectionSelector, files FileProvider) goldmark.Markdown {
+	// Collect the extensions used for rendering
+	extensions := []goldmark.Extender{
+		extension.Table,         // Enable tables
+		extension.Strikethrough, // Enable ~~strikethrough~~
+		extension.Linkify,       // Auto-link URLs
+		// extension.TaskList,    // Disabled - we use our own task list processor
+		extension.NewFootnote( // Enable footnotes
+			extension.WithFootnoteIDPrefixFunction(footnoteIDPrefixFunc(config.footnoteNamespace)),
+		),
+		extension.DefinitionList, // Enable definition lists
+		extension.GFM,            // GitHub Flavored Markdown
+		// MathJax is now handled via client-side JavaScript
+		&pdfLinkExtension{linkChecker: linkChecker, untrusted: config.untrusted, externalNewTab: config.externalNewTab, basePath: config.basePath},
+		&codeBlockExtension{}, // Registered fenced languages (csv, tsv) and block data attributes
+		&taskIndexExtension{}, // data-task-index on task list checkboxes
+		&imageSizeExtension{}, // {width=... height=...} after images
+	}
+
+	// Heading ¶ anchors, unless the document opts out with anchors: false
+	if config.anchors {
+		extensions = append(extensions, &headingAnchorExtension{})
+	}
+
+	// Section numbers for documents with numbered_headings: true
+	if config.numberedHeadings {
+		extensions = append(extensions, &headingNumberExtension{})
+	}
+
+	// Optional extensions
+	if config.paragraphPermalinks {
+		extensions = append(extensions, &paragraphPermalinkExtension{})
+	}
+	if config.images {
+		extensions = append(extensions, &imageExtension{basePath: config.basePath, untrusted: config.untrusted, files: files})
+	}
+	if config.footnotesPerSection {
+		extensions = append(extensions, &footnoteSectionExtension{})
+	}
+	if section != nil {
+		extensions = append(extensions, &sectionExtension{section: section})
+	}
+
+	// Extensions registered with goldext.RegisterExtension
+	extensions = append(extensions, goldext.Extensions()...)
+
+	// Renderer options
+	rendererOptions := []renderer.Option{
+		html.WithUnsafe(), // Allow raw HTML in the markdown
+	}
+	if config.hardWraps {
+		rendererOptions = append(rendererOptions, html.WithHardWraps())
+	}
 
 	// Configure Goldmark with all needed extensions
-	markdown := goldmark.New(
+	return goldmark.New(
 		// Enable common extensions
-		goldmark.WithExtensions(
-			extension.Table,         // Enable tables
-			extension.Strikethrough, // Enable ~~strikethrough~~
-			extension.Linkify,       // Auto-link URLs
-			// extension.TaskList,    // Disabled - we use our own task list processor
-			extension.Footnote,       // Enable footnotes
-			extension.DefinitionList, // Enable definition lists
-			extension.GFM,            // GitHub Flavored Markdown
-			// MathJax is now handled via client-side JavaScript
-			&pdfLinkExtension{},
-		),
+		goldmark.WithExtensions(extensions...),
 		// Parser options
 		goldmark.WithParserOptions(
 			parser.WithAutoHeadingID(), // Enable auto heading IDs
 			parser.WithAttribute(),     // Enable attributes
 		),
-		// Renderer options
-		goldmark.WithRendererOptions(
-			html.WithUnsafe(), // Allow raw HTML in the markdown
-			html.WithHardWraps(),
-		),
+		goldmark.WithRendererOptions(rendererOptions...),
 	)
+}
 
-	// Create a buffer to store the rendered HTML
-	var buf bytes.Buffer
+// classesWrapper returns the opening and closing tags of the element carrying the
+// frontmatter classes of a document, or empty strings when it has none
+func classesWrapper(metadata frontmatter.Metadata) (string, string) {
+	classes := frontmatter.Classes(metadata)
+	if len(classes) == 0 {
+		return "", ""
+	}
+	return `<div class="` + strings.Join(classes, " ") + `">` + "\n", "</div>\n"
+}
 
-	// Convert markdown to HTML
-	if err := markdown.Convert([]byte(md), &buf); err != nil {
-		// If there's an error, return an error message
-		errMsg := []byte("<p>Error rendering markdown with Goldmark: " + err.Error() + "</p>")
-		return errMsg
+// convertPostProcessed renders markdown to w, restoring mermaid and direction blocks and
+// adding footnote ARIA and smooth-scroll hooks on the way
+func convertPostProcessed(markdown goldmark.Markdown, md string, w io.Writer, docPath string, opts renderOptions) error {
+	pw := goldext.NewPostProcessWriter(w)
+	if opts.footnoteNamespace != "" {
+		pw.FootnotePrefix = opts.footnoteNamespace + "-"
 	}
 
-	// Post-process: Restore Mermaid blocks that were replaced with placeholders
-	htmlResult := goldext.RestoreMermaidBlocks(buf.String())
+	// The image renderer is shared by renderings served from different addresses
+	var parseOptions []parser.ParseOption
+	if opts.siteURL != "" {
+		pc := parser.NewContext()
+		pc.Set(siteURLContextKey, opts.siteURL)
+		parseOptions = append(parseOptions, parser.WithContext(pc))
+	}
 
-	// Post-process: Restore Direction blocks that were replaced with placeholders
-	// This ensures RTL/LTR content is properly rendered with Markdown formatting
-	htmlResult = goldext.RestoreDirectionBlocks(htmlResult)
+	// Render into a buffer first when observed, so converting and restoring are timed apart
+	if renderObserver != nil {
+		start := time.Now()
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(md), &buf, parseOptions...); err != nil {
+			return err
+		}
+		observeRender(docPath, RenderPhaseConvert, start)
 
-	// Return the post-processed HTML
-	return []byte(htmlResult)
+		start = time.Now()
+		if _, err := pw.Write(buf.Bytes()); err != nil {
+			return err
+		}
+		err := pw.Close()
+		observeRender(docPath, RenderPhaseRestore, start)
+		return err
+	}
+	if err := markdown.Convert([]byte(md), pw, parseOptions...); err != nil {
+		return err
+	}
+	return pw.Close()
 }
@@ -0,0 +1,2853 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"wiki-go/internal/frontmatter"
+	"wiki-go/internal/goldext"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+func TestRenderMarkdownDefinitionLists(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "One term with many definitions",
+			input:    "Apple\n:   Pomaceous fruit\n:   A technology company\n",
+			expected: "<dl>\n<dt>Apple</dt>\n<dd>Pomaceous fruit</dd>\n<dd>A technology company</dd>\n</dl>\n",
+		},
+		{
+			name:     "Many terms sharing one definition",
+			input:    "Color\nColour\n:   The property of reflecting light\n",
+			expected: "<dl>\n<dt>Color</dt>\n<dt>Colour</dt>\n<dd>The property of reflecting light</dd>\n</dl>\n",
+		},
+		{
+			name:     "Nested markdown inside definitions",
+			input:    "Go\n:   A *compiled* language\n:   Example:\n\n    ```go\n    x := 1\n    ```\n",
+			expected: "<dl>\n<dt>Go</dt>\n<dd>A <em>compiled</em> language</dd>\n<dd>Example:\n<pre><code class=\"language-go\">x := 1\n</code></pre>\n</dd>\n</dl>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := string(RenderMarkdown(tt.input))
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownDefinitionListGrouping(t *testing.T) {
+	result := string(RenderMarkdown("Term A\n:   First\n\nTerm B\n:   Second\n:   Third\n"))
+
+	if strings.Count(result, "<dl>") != 1 {
+		t.Errorf("Expected consecutive terms to share one <dl>, got: %q", result)
+	}
+	if strings.Count(result, "<dt>") != 2 || strings.Count(result, "<dd>") != 3 {
+		t.Errorf("Expected 2 <dt> and 3 <dd> elements, got: %q", result)
+	}
+}
+
+func TestLastUpdatedByFrontmatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Last editor takes precedence",
+			input:    "---\nauthor: Alice\nlast_editor: Bob\n---\n# Page\n",
+			expected: "Bob",
+		},
+		{
+			name:     "Falls back to author",
+			input:    "---\nauthor: Alice\n---\n# Page\n",
+			expected: "Alice",
+		},
+		{
+			name:     "No frontmatter",
+			input:    "# Page\n",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, _, _ := frontmatter.Parse(tt.input)
+			result := LastUpdatedBy("document.md", metadata)
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParagraphPermalinks(t *testing.T) {
+	ParagraphPermalinks = true
+	defer func() { ParagraphPermalinks = false }()
+
+	first := string(RenderMarkdown("First paragraph.\n\nSecond paragraph.\n"))
+	again := string(RenderMarkdown("First paragraph.\n\nSecond paragraph.\n"))
+	changed := string(RenderMarkdown("First paragraph.\n\nSecond paragraph, edited.\n"))
+
+	firstID := paragraphID([]byte("First paragraph."))
+	secondID := paragraphID([]byte("Second paragraph."))
+
+	if first != again {
+		t.Errorf("Expected identical output for unchanged content, got: %q and %q", first, again)
+	}
+	if !strings.Contains(first, `<p id="`+firstID+`">`) || !strings.Contains(first, `href="#`+firstID+`"`) {
+		t.Errorf("Expected paragraph ID %q with permalink, got: %q", firstID, first)
+	}
+	if !strings.Contains(changed, `<p id="`+firstID+`">`) {
+		t.Errorf("Expected unchanged paragraph to keep ID %q, got: %q", firstID, changed)
+	}
+	if strings.Contains(changed, secondID) {
+		t.Errorf("Expected edited paragraph to get a new ID, got: %q", changed)
+	}
+}
+
+func TestParagraphPermalinksDisabledByDefault(t *testing.T) {
+	result := string(RenderMarkdown("Plain paragraph.\n"))
+	if result != "<p>Plain paragraph.</p>\n" {
+		t.Errorf("Expected no permalink markup, got: %q", result)
+	}
+}
+
+func TestImageCopyLinks(t *testing.T) {
+	ImageCopyLinks = true
+	defer func() { ImageCopyLinks = false }()
+
+	result := string(RenderMarkdownWithPath("![Diagram](flow chart.png)\n\n![Logo](https://example.com/logo.png)\n", "docs/guide"))
+
+	expected := `<figure class="image-copy-link"><img src="/api/files/docs/guide/flow%20chart.png" alt="Diagram"><button type="button" class="copy-image-url" data-image-url="/api/files/docs/guide/flow%20chart.png"`
+	if !strings.Contains(result, expected) {
+		t.Errorf("Expected internal image with copy link %q, got: %q", expected, result)
+	}
+	if !strings.Contains(result, `<i class="fa fa-link"></i></button></figure>`) {
+		t.Errorf("Expected the figure to be closed after the control, got: %q", result)
+	}
+	if strings.Count(result, "copy-image-url") != 1 {
+		t.Errorf("Expected external image without copy link, got: %q", result)
+	}
+	if strings.Contains(result, "<p><figure") {
+		t.Errorf("Expected the figure in place of the paragraph, got: %q", result)
+	}
+
+	// Images inside text keep an inline wrapper
+	result = string(RenderMarkdownWithPath("See ![Diagram](flow.png) here\n", "docs/guide"))
+	expected = `<p>See <span class="image-copy-link"><img src="/api/files/docs/guide/flow.png" alt="Diagram"><button type="button" class="copy-image-url" data-image-url="/api/files/docs/guide/flow.png" title="Copy image URL" aria-label="Copy image URL"><i class="fa fa-link"></i></button></span> here</p>`
+	if !strings.Contains(result, expected) {
+		t.Errorf("Expected inline image with copy link %q, got: %q", expected, result)
+	}
+
+	// Without a base URL the copied URL is absolute with the address of the rendering
+	result = string(RenderMarkdownWithPath("![Diagram](flow.png)\n", "docs/guide", WithSiteURL("http://localhost:8080")))
+	if !strings.Contains(result, `data-image-url="http://localhost:8080/api/files/docs/guide/flow.png"`) {
+		t.Errorf("Expected the copied URL below the site URL, got: %q", result)
+	}
+
+	// With a base URL the copied URL is absolute, below the base path; external images keep theirs
+	StandaloneBaseURL = "https://wiki.example.com/"
+	goldext.BasePath = "/wiki"
+	ImageCopyLinksExternal = true
+	defer func() {
+		StandaloneBaseURL = ""
+		goldext.BasePath = ""
+		ImageCopyLinksExternal = false
+	}()
+
+	result = string(RenderMarkdownWithPath("![Diagram](flow chart.png)\n\n![Logo](https://example.com/logo.png)\n", "docs/guide"))
+	for _, want := range []string{
+		`<img src="/wiki/api/files/docs/guide/flow%20chart.png" alt="Diagram"><button type="button" class="copy-image-url" data-image-url="https://wiki.example.com/wiki/api/files/docs/guide/flow%20chart.png"`,
+		`data-image-url="https://example.com/logo.png"`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, result)
+		}
+	}
+}
+
+func TestStripTrackingParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Mixed tracked and untracked params",
+			input:    "https://example.com/page?id=42&utm_source=news&utm_medium=email&ref=home#top",
+			expected: "https://example.com/page?id=42&ref=home#top",
+		},
+		{
+			name:     "Only tracked params",
+			input:    "https://example.com/?fbclid=abc&gclid=def",
+			expected: "https://example.com/",
+		},
+		{
+			name:     "Encoded values are preserved",
+			input:    "https://example.com/search?q=a%20b&UTM_Campaign=x",
+			expected: "https://example.com/search?q=a%20b",
+		},
+		{
+			name:     "Escaped path and fragment are preserved",
+			input:    "https://example.com/a%2Fb/my%20file?utm_source=x&id=1#see%20also",
+			expected: "https://example.com/a%2Fb/my%20file?id=1#see%20also",
+		},
+		{
+			name:     "Path is kept as written",
+			input:    "https://example.com/wiki/Café?utm_source=x#top",
+			expected: "https://example.com/wiki/Café#top",
+		},
+		{
+			name:     "Internal links are untouched",
+			input:    "/docs/page?utm_source=news",
+			expected: "/docs/page?utm_source=news",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := stripTrackingParams(tt.input)
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestBuildOutlineJSON(t *testing.T) {
+	md := "---\nlayout: default\n---\n# Guide\n\n## Install\n\n### Linux\n\n### macOS\n\n## Usage\n\n```\n# not a heading\n```\n"
+
+	data, err := json.Marshal(BuildOutline(md, "docs/guide"))
+	if err != nil {
+		t.Fatalf("Failed to marshal outline: %v", err)
+	}
+
+	expected := `{"schema_version":1,"path":"docs/guide","title":"Guide","metadata":{"layout":"default"},"headings":[` +
+		`{"level":1,"text":"Guide","id":"guide","child_count":2,"children":[` +
+		`{"level":2,"text":"Install","id":"install","child_count":2,"children":[` +
+		`{"level":3,"text":"Linux","id":"linux","child_count":0,"children":[]},` +
+		`{"level":3,"text":"macOS","id":"macos","child_count":0,"children":[]}]},` +
+		`{"level":2,"text":"Usage","id":"usage","child_count":0,"children":[]}]}]}`
+	if string(data) != expected {
+		t.Errorf("Expected: %s, got: %s", expected, data)
+	}
+}
+
+func TestOrderedListContinue(t *testing.T) {
+	md := "1. First\n2. Second\n\nA note between the steps.\n\n{continue}\n1. Third\n1. Fourth\n\nAnother note.\n\n{continue}\n1. Fifth\n"
+	result := string(RenderMarkdown(md))
+
+	if !strings.Contains(result, "<ol>\n<li>First</li>") {
+		t.Errorf("Expected first list to start at 1, got: %q", result)
+	}
+	if !strings.Contains(result, `<ol start="3">`+"\n<li>Third</li>") {
+		t.Errorf("Expected resumed list to start at 3, got: %q", result)
+	}
+	if !strings.Contains(result, `<ol start="5">`+"\n<li>Fifth</li>") {
+		t.Errorf("Expected second resumed list to start at 5, got: %q", result)
+	}
+	if strings.Contains(result, "{continue}") {
+		t.Errorf("Expected marker to be removed, got: %q", result)
+	}
+}
+
+func TestFootnoteARIA(t *testing.T) {
+	result := string(RenderMarkdown("Text[^1] and more[^note].\n\n[^1]: First.\n[^note]: Second.\n"))
+
+	expected := []string{
+		`<a href="#fn:1" class="footnote-ref" role="doc-noteref" aria-describedby="footnotes-label" aria-label="Footnote 1">1</a>`,
+		`<a href="#fn:2" class="footnote-ref" role="doc-noteref" aria-describedby="footnotes-label" aria-label="Footnote 2">2</a>`,
+		`<h2 id="footnotes-label" class="sr-only">Footnotes</h2>`,
+		`<li id="fn:1" tabindex="-1">`,
+		`<a href="#fnref:2" class="footnote-backref" role="doc-backlink" aria-label="Back to reference 2">`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(result, e) {
+			t.Errorf("Expected %q in output, got: %q", e, result)
+		}
+	}
+}
+
+func TestTabsRendering(t *testing.T) {
+	md := "::: tabs\n::: tab \"Go\"\n```go\nfmt.Println(\"hi\")\n```\n:::\n::: tab \"Steps\" {active}\n1. **Install**\n2. Run\n:::\n:::\n"
+	result := string(RenderMarkdown(md))
+
+	expected := []string{
+		`<div class="tabs" id="tabs-1">`,
+		`<button type="button" class="tab" role="tab" id="tabs-1-tab-1" aria-controls="tabs-1-panel-1" aria-selected="false" tabindex="-1">Go</button>`,
+		`<button type="button" class="tab" role="tab" id="tabs-1-tab-2" aria-controls="tabs-1-panel-2" aria-selected="true" tabindex="0">Steps</button>`,
+		`<div class="tab-panel" role="tabpanel" id="tabs-1-panel-1" aria-labelledby="tabs-1-tab-1" hidden>`,
+		`<pre><code class="language-go">fmt.Println(&quot;hi&quot;)`,
+		`<div class="tab-panel" role="tabpanel" id="tabs-1-panel-2" aria-labelledby="tabs-1-tab-2">`,
+		`<li><strong>Install</strong></li>`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(result, e) {
+			t.Errorf("Expected %q in output, got: %q", e, result)
+		}
+	}
+	if strings.Contains(result, ":::") {
+		t.Errorf("Expected tab markers to be consumed, got: %q", result)
+	}
+}
+
+func TestTabsNestedAndUnbalanced(t *testing.T) {
+	nested := string(RenderMarkdown("::: tabs\n::: tab \"Outer\"\n::: tabs\n::: tab \"Inner\"\nInner body\n:::\n:::\n:::\n:::\n"))
+	if !strings.Contains(nested, `id="tabs-1"`) || !strings.Contains(nested, `id="tabs-2"`) || !strings.Contains(nested, "<p>Inner body</p>") {
+		t.Errorf("Expected nested tab groups with unique IDs, got: %q", nested)
+	}
+
+	unbalanced := string(RenderMarkdown("::: tabs\n::: tab \"Open\"\nNever closed\n"))
+	if strings.Contains(unbalanced, `class="tabs"`) {
+		t.Errorf("Expected unbalanced group to be left as text, got: %q", unbalanced)
+	}
+}
+
+func TestCacheExternalImages(t *testing.T) {
+	CacheExternalImages = true
+	ImageCacheRoot = t.TempDir()
+	defer func() {
+		CacheExternalImages = false
+		ImageCacheRoot = filepath.Join("data", "documents", ImageCacheDirName)
+		imageFetcher = fetchExternalImage
+	}()
+
+	// A minimal PNG header is enough for content type detection
+	pngData := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	fetches := 0
+	imageFetcher = func(destination string) ([]byte, string, error) {
+		fetches++
+		return pngData, "image/png", nil
+	}
+
+	md := "![Remote](https://example.com/pic.png) ![Local](/api/files/docs/local.png)\n"
+
+	// The first render keeps the original URL while the image downloads
+	first := string(RenderMarkdown(md))
+	if !strings.Contains(first, `src="https://example.com/pic.png"`) {
+		t.Errorf("Expected original src before caching, got: %q", first)
+	}
+
+	waitForImageFetches()
+
+	cachedURL := "/api/files/_cache/" + imageCacheKey("https://example.com/pic.png") + ".png"
+	second := string(RenderMarkdown(md))
+	if !strings.Contains(second, `src="`+cachedURL+`"`) {
+		t.Errorf("Expected cached src %q, got: %q", cachedURL, second)
+	}
+	if !strings.Contains(second, `src="/api/files/docs/local.png"`) {
+		t.Errorf("Expected internal image to be untouched, got: %q", second)
+	}
+	if fetches != 1 {
+		t.Errorf("Expected exactly one fetch, got %d", fetches)
+	}
+}
+
+func TestCacheExternalImagesRejectsNonImages(t *testing.T) {
+	ImageCacheRoot = t.TempDir()
+	defer func() {
+		ImageCacheRoot = filepath.Join("data", "documents", ImageCacheDirName)
+		imageFetcher = fetchExternalImage
+	}()
+
+	imageFetcher = func(destination string) ([]byte, string, error) {
+		return []byte("<html><script>alert(1)</script></html>"), "image/png", nil
+	}
+
+	if err := storeExternalImage("https://example.com/fake.png", "fake"); err == nil {
+		t.Errorf("Expected HTML disguised as an image to be rejected")
+	}
+}
+
+func TestCacheExternalImagesBackoff(t *testing.T) {
+	CacheExternalImages = true
+	ImageCacheRoot = t.TempDir()
+	defer func() {
+		CacheExternalImages = false
+		ImageCacheRoot = filepath.Join("data", "documents", ImageCacheDirName)
+		imageFetcher = fetchExternalImage
+		imageFetchMutex.Lock()
+		imageFetchFailed = make(map[string]time.Time)
+		imageFetchMutex.Unlock()
+	}()
+
+	fetches := 0
+	imageFetcher = func(destination string) ([]byte, string, error) {
+		fetches++
+		return nil, "", errors.New("connection refused")
+	}
+
+	md := "![Remote](https://example.com/down.png)\n"
+	for range 3 {
+		RenderMarkdown(md)
+		waitForImageFetches()
+	}
+	if fetches != 1 {
+		t.Errorf("Expected a failed image to be fetched once, got %d fetches", fetches)
+	}
+
+	// Once the backoff has passed the image is tried again
+	retryAfter := ImageFetchRetryAfter
+	ImageFetchRetryAfter = 0
+	defer func() { ImageFetchRetryAfter = retryAfter }()
+	RenderMarkdown(md)
+	waitForImageFetches()
+	if fetches != 2 {
+		t.Errorf("Expected a retry after the backoff, got %d fetches", fetches)
+	}
+}
+
+func TestImageFetchQueue(t *testing.T) {
+	ImageCacheRoot = t.TempDir()
+	workers, queueSize := ImageFetchWorkers, ImageFetchQueueSize
+	ImageFetchWorkers, ImageFetchQueueSize = 1, 2
+	defer func() {
+		ImageCacheRoot = filepath.Join("data", "documents", ImageCacheDirName)
+		ImageFetchWorkers, ImageFetchQueueSize = workers, queueSize
+		imageFetcher = fetchExternalImage
+	}()
+
+	started := make(chan string)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var fetched []string
+	imageFetcher = func(destination string) ([]byte, string, error) {
+		started <- destination
+		<-release
+		mu.Lock()
+		fetched = append(fetched, destination)
+		mu.Unlock()
+		return []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png", nil
+	}
+
+	queueImageFetch("https://example.com/a.png", "a")
+	<-started
+
+	// One download runs, two wait and the rest is dropped until a later render
+	for _, name := range []string{"b", "c", "d", "e"} {
+		queueImageFetch("https://example.com/"+name+".png", name)
+	}
+	imageFetchMutex.Lock()
+	queued, running := len(imageFetchQueue), imageFetchWorkers
+	imageFetchMutex.Unlock()
+	if queued != 2 || running != 1 {
+		t.Errorf("Expected 2 queued downloads and 1 worker, got %d and %d", queued, running)
+	}
+
+	go func() {
+		for range started {
+		}
+	}()
+	close(release)
+	waitForImageFetches()
+	close(started)
+
+	expected := []string{"https://example.com/a.png", "https://example.com/b.png", "https://example.com/c.png"}
+	if !reflect.DeepEqual(fetched, expected) {
+		t.Errorf("Expected downloads %v, got %v", expected, fetched)
+	}
+}
+
+func TestFetchExternalImageDeniesInternalAddresses(t *testing.T) {
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG\r\n\x1a\n"))
+	}))
+	defer server.Close()
+
+	if _, _, err := fetchExternalImage(server.URL + "/pic.png"); err == nil || !strings.Contains(err.Error(), "not public") {
+		t.Errorf("Expected a loopback image to be refused, got: %v", err)
+	}
+	if requested {
+		t.Errorf("Expected no request to reach the loopback server")
+	}
+
+	for _, address := range []string{"10.0.0.1:80", "172.16.5.4:443", "192.168.1.1:80", "169.254.169.254:80", "100.100.100.200:80", "[::1]:80", "[fe80::1]:80", "[::ffff:127.0.0.1]:80", "0.0.0.0:80"} {
+		if err := denyInternalAddress("tcp", address, nil); err == nil {
+			t.Errorf("Expected %s to be denied", address)
+		}
+	}
+	for _, address := range []string{"93.184.215.14:443", "[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:443"} {
+		if err := denyInternalAddress("tcp", address, nil); err != nil {
+			t.Errorf("Expected %s to be allowed, got: %v", address, err)
+		}
+	}
+}
+
+func TestCacheExternalImagesRefreshesRenderCache(t *testing.T) {
+	CacheExternalImages = true
+	ImageCacheRoot = t.TempDir()
+	ClearRenderCache()
+	defer func() {
+		CacheExternalImages = false
+		ImageCacheRoot = filepath.Join("data", "documents", ImageCacheDirName)
+		imageFetcher = fetchExternalImage
+		ClearRenderCache()
+	}()
+
+	imageFetcher = func(destination string) ([]byte, string, error) {
+		return []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png", nil
+	}
+
+	filePath := filepath.Join(t.TempDir(), "document.md")
+	if err := os.WriteFile(filePath, []byte("![Remote](https://example.com/fresh.png)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := RenderMarkdownFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(first), `src="https://example.com/fresh.png"`) {
+		t.Errorf("Expected original src before caching, got: %q", first)
+	}
+	waitForImageFetches()
+
+	// The rendering cached with the original URL is dropped once the image is stored
+	cachedURL := "/api/files/_cache/" + imageCacheKey("https://example.com/fresh.png") + ".png"
+	second, err := RenderMarkdownFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(second), `src="`+cachedURL+`"`) {
+		t.Errorf("Expected cached src %q, got: %q", cachedURL, second)
+	}
+}
+
+func TestBlockquoteAttribution(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Quote with em-dash attribution",
+			input:    "> Simplicity is prerequisite for reliability.\n> — Edsger W. Dijkstra\n",
+			expected: "<blockquote>\n<p>Simplicity is prerequisite for reliability.</p>\n<footer class=\"blockquote-attribution\">\n<p>— <cite>Edsger W. Dijkstra</cite></p>\n</footer>\n</blockquote>\n",
+		},
+		{
+			name:     "Quote with double-hyphen attribution and markdown",
+			input:    "> Stay hungry.\n> -- Steve Jobs, *Stanford*\n",
+			expected: "<blockquote>\n<p>Stay hungry.</p>\n<footer class=\"blockquote-attribution\">\n<p>— <cite>Steve Jobs, <em>Stanford</em></cite></p>\n</footer>\n</blockquote>\n",
+		},
+		{
+			name:     "Quote without attribution",
+			input:    "> Just a quote.\n> Second line.\n",
+			expected: "<blockquote>\n<p>Just a quote.<br>\nSecond line.</p>\n</blockquote>\n",
+		},
+		{
+			name:     "Dash line alone is not an attribution",
+			input:    "> — Not an attribution\n",
+			expected: "<blockquote>\n<p>— Not an attribution</p>\n</blockquote>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := string(RenderMarkdown(tt.input))
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestBlockDataAttributes(t *testing.T) {
+	goldext.BlockDataAttributes = true
+	defer func() { goldext.BlockDataAttributes = false }()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "Fenced code block",
+			input:    "```go\nfmt.Println(\"hi\")\n```\n",
+			expected: []string{`<pre data-block="code" data-language="go"><code class="language-go">fmt.Println(&quot;hi&quot;)`},
+		},
+		{
+			name:     "Fenced code block without language",
+			input:    "```\nplain\n```\n",
+			expected: []string{"<pre data-block=\"code\"><code>plain\n</code></pre>"},
+		},
+		{
+			name:     "Details container",
+			input:    "```details Title\nBody\n```\n",
+			expected: []string{`<details class="markdown-details" data-block="details">`},
+		},
+		{
+			name:     "Tabs container",
+			input:    "::: tabs\n::: tab \"One\"\nBody\n:::\n:::\n",
+			expected: []string{`<div class="tabs" id="tabs-1" data-block="tabs">`},
+		},
+		{
+			name:     "Callout container",
+			input:    "::: warning\nBody\n:::\n",
+			expected: []string{`<div class="callout callout-warning" data-block="callout" data-callout-type="warning">`},
+		},
+		{
+			name:     "Direction container",
+			input:    "```rtl\nمرحبا\n```\n",
+			expected: []string{`<div class="rtl" data-block="direction" data-direction="rtl">`},
+		},
+		{
+			name:     "Mermaid diagram",
+			input:    "```mermaid\ngraph TD\n```\n",
+			expected: []string{`<div class="mermaid" data-block="mermaid">`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := string(RenderMarkdown(tt.input))
+			for _, want := range tt.expected {
+				if !strings.Contains(result, want) {
+					t.Errorf("Expected %q in output, got: %q", want, result)
+				}
+			}
+		})
+	}
+}
+
+func TestBlockDataAttributesDisabledByDefault(t *testing.T) {
+	result := string(RenderMarkdown("```go\nx\n```\n\n```details\nBody\n```\n"))
+	if strings.Contains(result, "data-block") {
+		t.Errorf("Expected no data attributes by default, got: %q", result)
+	}
+}
+
+func TestChangelogShortcode(t *testing.T) {
+	input := "---\nchangelog:\n  - date: 2024-01-15\n    description: First draft\n  - date: 2024-03-02\n    description: Added **examples**\n  - date: \"2024-02-10\"\n    description: Fixed typos\n---\n# Doc\n\n{{changelog}}\n"
+	result := string(RenderMarkdown(input))
+
+	expected := "<div class=\"changelog\">\n<ul>\n" +
+		"<li><time datetime=\"2024-03-02\">March 2, 2024</time> — Added <strong>examples</strong></li>\n" +
+		"<li><time datetime=\"2024-02-10\">February 10, 2024</time> — Fixed typos</li>\n" +
+		"<li><time datetime=\"2024-01-15\">January 15, 2024</time> — First draft</li>\n" +
+		"</ul>\n</div>"
+	if !strings.Contains(result, expected) {
+		t.Errorf("Expected sorted changelog %q, got: %q", expected, result)
+	}
+}
+
+func TestChangelogMalformedEntries(t *testing.T) {
+	input := "---\nauthor: jane\nchangelog:\n  - date: not-a-date\n    description: Bad date\n  - just a string\n  - date: 2024-05-01\n  - date: 2024-04-01\n    description: Kept\n---\n{{changelog}}\n\n```\n{{changelog}}\n```\n"
+
+	metadata, _, ok := frontmatter.Parse(input)
+	if !ok || metadata.Author != "jane" {
+		t.Fatalf("Expected malformed changelog entries not to break frontmatter parsing, got: %+v", metadata)
+	}
+
+	result := string(RenderMarkdown(input))
+	if strings.Count(result, "<li>") != 1 || !strings.Contains(result, "— Kept</li>") {
+		t.Errorf("Expected only the valid entry to be rendered, got: %q", result)
+	}
+	if !strings.Contains(result, "<code>{{changelog}}") {
+		t.Errorf("Expected shortcode inside code blocks to be left alone, got: %q", result)
+	}
+}
+
+func TestRenderMarkdownWithLinkCheck(t *testing.T) {
+	existing := map[string]bool{"": true, "guides/setup": true}
+	calls := 0
+	checker := CachedLinkChecker(func(target string) bool {
+		calls++
+		return existing[target]
+	})
+
+	input := "[Setup](/guides/setup/#install) [Missing](/guides/missing) [Again](/guides/missing?x=1) " +
+		"[Home](/) [External](https://example.com/) [File](/api/files/a/b.png) [Anchor](#top)\n"
+	result := string(RenderMarkdownWithLinkCheck(input, "", checker))
+
+	tests := []string{
+		`<a href="/guides/setup/#install" class="exists">Setup</a>`,
+		`<a href="/guides/missing" class="broken">Missing</a>`,
+		`<a href="/guides/missing?x=1" class="broken">Again</a>`,
+		`<a href="/" class="exists">Home</a>`,
+		`<a href="https://example.com/" target="_blank" rel="noopener noreferrer">External</a>`,
+		`<a href="/api/files/a/b.png">File</a>`,
+		`<a href="#top">Anchor</a>`,
+	}
+	for _, want := range tests {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in output, got: %q", want, result)
+		}
+	}
+
+	if calls != 3 {
+		t.Errorf("Expected each target to be checked once (3 calls), got %d", calls)
+	}
+
+	// Normal rendering never checks links
+	if strings.Contains(string(RenderMarkdown(input)), "broken") {
+		t.Errorf("Expected no link classes in normal rendering")
+	}
+}
+
+func TestFootnotesPerSection(t *testing.T) {
+	goldext.FootnotesPerSection = true
+	defer func() { goldext.FootnotesPerSection = false }()
+
+	input := "# Title\n\n## First\n\nAlpha[^1] and beta[^note].\n\n[^1]: First one.\n[^note]: First note.\n\n" +
+		"## Second\n\nGamma[^1].\n\n[^1]: Second one.\n"
+	result := string(RenderMarkdown(input))
+
+	first := strings.Index(result, `<h2 id="first"`)
+	second := strings.Index(result, `<h2 id="second"`)
+	if first < 0 || second < 0 {
+		t.Fatalf("Expected both section headings, got: %q", result)
+	}
+	firstSection, secondSection := result[first:second], result[second:]
+
+	expectations := []struct {
+		section string
+		want    string
+	}{
+		{firstSection, `<sup id="s2-fnref:1"><a href="#s2-fn:1" class="footnote-ref" role="doc-noteref" aria-describedby="s2-footnotes-label"`},
+		{firstSection, `<sup id="s2-fnref:2"><a href="#s2-fn:2"`},
+		{firstSection, `<h2 id="s2-footnotes-label" class="sr-only">Footnotes</h2>`},
+		{firstSection, `<li id="s2-fn:1" tabindex="-1">` + "\n<p>First one.&#160;<a href=\"#s2-fnref:1\""},
+		{firstSection, `<li id="s2-fn:2" tabindex="-1">` + "\n<p>First note."},
+		{secondSection, `<sup id="s3-fnref:1"><a href="#s3-fn:1" class="footnote-ref" role="doc-noteref" aria-describedby="s3-footnotes-label"`},
+		{secondSection, `<h2 id="s3-footnotes-label" class="sr-only">Footnotes</h2>`},
+		{secondSection, `<li id="s3-fn:1" tabindex="-1">` + "\n<p>Second one."},
+	}
+	for _, e := range expectations {
+		if !strings.Contains(e.section, e.want) {
+			t.Errorf("Expected %q in section, got: %q", e.want, e.section)
+		}
+	}
+
+	// Each section's footnotes come before the next heading
+	if strings.Count(firstSection, `class="footnotes"`) != 1 || strings.Count(secondSection, `class="footnotes"`) != 1 {
+		t.Errorf("Expected one footnote list per section, got: %q", result)
+	}
+	if strings.Contains(result, `id="footnotes-label"`) {
+		t.Errorf("Expected no shared footnotes heading ID, got: %q", result)
+	}
+
+	// Streaming names the headings the same way
+	var buf bytes.Buffer
+	if err := RenderMarkdownTo(&buf, input, ""); err != nil {
+		t.Fatal(err)
+	}
+	if streamed := buf.String(); streamed != result {
+		t.Errorf("Expected streamed output %q to match %q", streamed, result)
+	}
+}
+
+func TestFootnotesPerSectionDefinedAtEnd(t *testing.T) {
+	goldext.FootnotesPerSection = true
+	defer func() { goldext.FootnotesPerSection = false }()
+
+	input := "# Title\n\n## First\n\nAlpha[^1] and beta[^long].\n\n## Second\n\nGamma[^1].\n\n" +
+		"## Notes\n\n[^1]: Shared note.\n[^long]: Long note\n    continued.\n"
+	result := string(RenderMarkdown(input))
+
+	first := strings.Index(result, `<h2 id="first"`)
+	second := strings.Index(result, `<h2 id="second"`)
+	notes := strings.Index(result, `<h2 id="notes"`)
+	if first < 0 || second < 0 || notes < 0 {
+		t.Fatalf("Expected all section headings, got: %q", result)
+	}
+	firstSection, secondSection := result[first:second], result[second:notes]
+
+	for _, e := range []struct {
+		section string
+		want    string
+	}{
+		{firstSection, `<sup id="s2-fnref:1"><a href="#s2-fn:1"`},
+		{firstSection, `<li id="s2-fn:1" tabindex="-1">` + "\n<p>Shared note."},
+		{firstSection, `<li id="s2-fn:2" tabindex="-1">` + "\n<p>Long note<br>\ncontinued."},
+		{secondSection, `<sup id="s3-fnref:1"><a href="#s3-fn:1"`},
+		{secondSection, `<li id="s3-fn:1" tabindex="-1">` + "\n<p>Shared note."},
+	} {
+		if !strings.Contains(e.section, e.want) {
+			t.Errorf("Expected %q in section, got: %q", e.want, e.section)
+		}
+	}
+	if strings.Contains(result, "[^") || strings.Count(result, `class="footnotes"`) != 2 {
+		t.Errorf("Expected every reference resolved and no footnotes under the notes heading, got: %q", result)
+	}
+}
+
+func TestSpoilerRendering(t *testing.T) {
+	result := string(RenderMarkdown("Ending: ||the *hero* wins||\n\n| a | b |\n|---|---|\n| x || y |\n"))
+
+	if !strings.Contains(result, `<span class="spoiler" tabindex="0" title="Reveal spoiler">the <em>hero</em> wins</span>`) {
+		t.Errorf("Expected spoiler with rendered emphasis, got: %q", result)
+	}
+	if !strings.Contains(result, "<td>x</td>\n<td></td>") {
+		t.Errorf("Expected empty table cell to be preserved, got: %q", result)
+	}
+}
+
+func TestDocumentVariables(t *testing.T) {
+	vars := map[string]string{
+		"product": "Acme X",
+		"full":    "{{product}} {{version}}",
+		"version": "2.0",
+		"loop":    "{{loop}}!",
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Defined variable",
+			input:    "Welcome to {{product}} and {{ product }}.",
+			expected: "Welcome to Acme X and Acme X.",
+		},
+		{
+			name:     "Undefined variable stays literal",
+			input:    "Unknown {{missing}} here.",
+			expected: "Unknown {{missing}} here.",
+		},
+		{
+			name:     "Nested variables",
+			input:    "Release: {{full}}",
+			expected: "Release: Acme X 2.0",
+		},
+		{
+			name:     "Self-reference is bounded",
+			input:    "{{loop}}",
+			expected: "{{loop}}!!!!!!",
+		},
+		{
+			name:     "Code is protected",
+			input:    "Use `{{product}}` here.\n```\n{{product}}\n```\n{{product}}",
+			expected: "Use `{{product}}` here.\n```\n{{product}}\n```\nAcme X",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ExpandVariables(tt.input, vars)
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+
+	// Variables come from the document frontmatter when rendering
+	result := string(RenderMarkdown("---\nvars:\n  product: Acme X\n---\nTry {{product}}\n"))
+	if result != "<p>Try Acme X</p>\n" {
+		t.Errorf("Expected frontmatter variables to be substituted, got: %q", result)
+	}
+}
+
+func TestRenderMarkdownFileWithProvider(t *testing.T) {
+	provider := NewFSFileProvider(fstest.MapFS{
+		"data/documents/guides/setup/document.md": {Data: []byte("# Setup\n\n[Manual](/api/files/guides/setup/manual.pdf)\n")},
+	})
+
+	result, err := RenderMarkdownFileWithProvider(filepath.Join("data", "documents", "guides", "setup", "document.md"), provider)
+	if err != nil {
+		t.Fatalf("Expected in-memory document to render, got error: %v", err)
+	}
+	if !strings.Contains(string(result), `<a href="/guides/setup?mode=pdf&file=manual.pdf">Manual</a>`) {
+		t.Errorf("Expected PDF link relative to the document, got: %q", result)
+	}
+
+	if _, err := provider.Stat("/data/documents/guides/setup/document.md"); err != nil {
+		t.Errorf("Expected Stat to accept rooted paths, got error: %v", err)
+	}
+	if _, err := RenderMarkdownFileWithProvider("data/documents/missing/document.md", provider); err == nil {
+		t.Errorf("Expected an error for a missing document")
+	}
+}
+
+func TestRenderMarkdownFileWithProviderReadsLinkedFiles(t *testing.T) {
+	ImageDimensions = true
+	defer func() { ImageDimensions = false }()
+
+	var shot bytes.Buffer
+	if err := png.Encode(&shot, image.NewRGBA(image.Rect(0, 0, 40, 30))); err != nil {
+		t.Fatal(err)
+	}
+	provider := NewFSFileProvider(fstest.MapFS{
+		"data/documents/guides/setup/document.md":   {Data: []byte("# Setup\n\n{{include: /shared/notice}}\n\nSee [[install]].\n\n![Shot](shot.png)\n")},
+		"data/documents/guides/setup/shot.png":      {Data: shot.Bytes()},
+		"data/documents/guides/install/document.md": {Data: []byte("# Installing the wiki\n")},
+		"data/documents/shared/notice/document.md":  {Data: []byte("---\ntitle: Notice\n---\nIncluded from memory, see [[/guides/install|the guide]].\n")},
+	})
+
+	result, err := RenderMarkdownFileWithProvider(filepath.Join("data", "documents", "guides", "setup", "document.md"), provider)
+	if err != nil {
+		t.Fatalf("Expected in-memory document to render, got error: %v", err)
+	}
+	html := string(result)
+	for _, expected := range []string{
+		"Included from memory",
+		`<a href="/guides/install" class="wikilink">the guide</a>`,
+		`<a href="/guides/install" class="wikilink">Installing the wiki</a>`,
+		`width="40" height="30"`,
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Expected %q in the rendering, got: %q", expected, html)
+		}
+	}
+	if strings.Contains(html, "include-error") || strings.Contains(html, "wikilink-broken") {
+		t.Errorf("Expected the included and linked documents to be found, got: %q", html)
+	}
+}
+
+func TestDocumentsRoot(t *testing.T) {
+	defer SetDocumentsRoot(filepath.Join("data", "documents"))
+
+	SetDocumentsRoot(filepath.Join("srv", "wiki", "docs"))
+	provider := NewFSFileProvider(fstest.MapFS{
+		"srv/wiki/docs/guides/setup/document.md": {Data: []byte("![Shot](shot.png)\n")},
+	})
+	result, err := RenderMarkdownFileWithProvider(filepath.Join("srv", "wiki", "docs", "guides", "setup", "document.md"), provider)
+	if err != nil {
+		t.Fatalf("Expected the document to render, got error: %v", err)
+	}
+	if !strings.Contains(string(result), `src="/api/files/guides/setup/shot.png"`) {
+		t.Errorf("Expected image resolved below the configured root, got: %q", result)
+	}
+
+	tests := []struct {
+		name     string
+		root     string
+		file     string
+		expected string
+		windows  bool
+	}{
+		{"Unix relative root", "srv/wiki/docs", "srv/wiki/docs/a/b/document.md", "a/b", false},
+		{"Unix absolute root", "/var/lib/wiki/documents", "/var/lib/wiki/documents/guides/document.md", "guides", false},
+		{"Rel fails, base name is used", "/var/lib/wiki/documents", "elsewhere/notes/document.md", "notes", false},
+		{"Windows root", `C:\wiki\docs`, `C:\wiki\docs\guides\setup\document.md`, "guides/setup", true},
+		{"Windows root with forward slashes", `C:/wiki/docs`, `C:\wiki\docs\a\document.md`, "a", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.windows != (runtime.GOOS == "windows") {
+				t.Skip("path style of another platform")
+			}
+			SetDocumentsRoot(filepath.FromSlash(tt.root))
+			if result := documentPathOf(filepath.FromSlash(tt.file)); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestImageDimensions(t *testing.T) {
+	root := t.TempDir()
+	ImageDimensions = true
+	ImageFilesRoot = root
+	defer func() {
+		ImageDimensions = false
+		ImageFilesRoot = filepath.Join("data", "documents")
+	}()
+
+	writeImage := func(name string, width, height int, modTime time.Time) {
+		var buf bytes.Buffer
+		img := image.NewRGBA(image.Rect(0, 0, width, height))
+		var err error
+		if strings.HasSuffix(name, ".png") {
+			err = png.Encode(&buf, img)
+		} else {
+			err = jpeg.Encode(&buf, img, nil)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(root, "docs", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	modTime := time.Now().Add(-time.Hour)
+	writeImage("chart.png", 3, 2, modTime)
+	writeImage("photo.jpg", 5, 4, modTime)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"PNG", "![c](/api/files/docs/chart.png)", `<img src="/api/files/docs/chart.png" alt="c" width="3" height="2">`},
+		{"JPEG", "![p](/api/files/docs/photo.jpg)", `<img src="/api/files/docs/photo.jpg" alt="p" width="5" height="4">`},
+		{"Missing file", "![m](/api/files/docs/missing.png)", `<img src="/api/files/docs/missing.png" alt="m">`},
+		{"External image", "![e](https://example.com/chart.png)", `<img src="https://example.com/chart.png" alt="e">`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := string(RenderMarkdown(tt.input))
+			if !strings.Contains(result, tt.expected) {
+				t.Errorf("Expected %q in output, got: %q", tt.expected, result)
+			}
+		})
+	}
+
+	// A modified image is decoded again
+	writeImage("chart.png", 8, 6, modTime.Add(time.Minute))
+	if result := string(RenderMarkdown("![c](/api/files/docs/chart.png)")); !strings.Contains(result, `width="8" height="6"`) {
+		t.Errorf("Expected updated dimensions after the image changed, got: %q", result)
+	}
+}
+
+func TestToAMP(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Image with dimensions",
+			input:    `<p><img src="/a.png" alt="a" width="3" height="2"></p>`,
+			expected: `<p><amp-img src="/a.png" alt="a" width="3" height="2" layout="responsive"></amp-img></p>`,
+		},
+		{
+			name:     "Image without dimensions",
+			input:    `<img src="https://example.com/b.png" alt="b" />`,
+			expected: `<amp-img src="https://example.com/b.png" alt="b" height="300" layout="fixed-height"></amp-img>`,
+		},
+		{
+			name:     "Iframe with dimensions",
+			input:    "<iframe width=\"560\" height=\"315\" src=\"https://www.youtube.com/embed/x\"\nallowfullscreen></iframe>",
+			expected: `<amp-iframe width="560" height="315" src="https://www.youtube.com/embed/x" allowfullscreen layout="responsive" sandbox="allow-scripts allow-same-origin allow-popups"></amp-iframe>`,
+		},
+		{
+			name:     "Iframe without dimensions",
+			input:    `<iframe src="https://player.vimeo.com/video/1"></iframe>`,
+			expected: `<amp-iframe src="https://player.vimeo.com/video/1" width="560" height="315" layout="responsive" sandbox="allow-scripts allow-same-origin allow-popups"></amp-iframe>`,
+		},
+		{
+			name:     "Inline scripts and disallowed attributes",
+			input:    `<div style="color:red" onclick="x()" class="c">Hi</div><script>alert(1)</script><script type="application/ld+json">{}</script>`,
+			expected: `<div class="c">Hi</div><script type="application/ld+json">{}</script>`,
+		},
+		{
+			name:     "Escaped code is untouched",
+			input:    `<pre><code>&lt;img src="x"&gt;</code></pre>`,
+			expected: `<pre><code>&lt;img src="x"&gt;</code></pre>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ToAMP(tt.input)
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestAMPOutputDisabledByDefault(t *testing.T) {
+	if result := string(RenderMarkdown("![a](/a.png)")); !strings.Contains(result, "<img ") {
+		t.Errorf("Expected a standard <img> by default, got: %q", result)
+	}
+}
+
+func TestBuildOGCardHints(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		docPath  string
+		expected OGCardHints
+	}{
+		{
+			name:    "Derived from content",
+			input:   "# Getting Started\n\nInstall the *wiki* and\nrun it.\n\nSecond paragraph.\n",
+			docPath: "guides/getting-started",
+			expected: OGCardHints{
+				Title:      "Getting Started",
+				Subtitle:   "Install the wiki and run it.",
+				ThemeColor: OGDefaultThemeColor,
+				ImageURL:   "/api/og-image/guides/getting-started",
+				Generated:  true,
+			},
+		},
+		{
+			name:    "Frontmatter wins",
+			input:   "---\ntitle: Custom Title\ndescription: Custom subtitle\ntheme_color: \"#ff8800\"\nimage: /api/files/cover.png\n---\n# Heading\n\nBody text.\n",
+			docPath: "a",
+			expected: OGCardHints{
+				Title:      "Custom Title",
+				Subtitle:   "Custom subtitle",
+				ThemeColor: "#ff8800",
+				ImageURL:   "/api/files/cover.png",
+			},
+		},
+		{
+			name:    "Invalid theme color and document image",
+			input:   "---\ntheme_color: red;background:url(x)\n---\nIntro text.\n\n![Diagram](diagram.png)\n",
+			docPath: "my docs/flow",
+			expected: OGCardHints{
+				Title:      "Flow",
+				Subtitle:   "Intro text.",
+				ThemeColor: OGDefaultThemeColor,
+				ImageURL:   "/api/files/my%20docs/flow/diagram.png",
+			},
+		},
+		{
+			name:    "Long subtitle is cut at a word",
+			input:   "# T\n\n" + strings.Repeat("word ", 40) + "\n",
+			docPath: "long",
+			expected: OGCardHints{
+				Title:      "T",
+				Subtitle:   strings.TrimSpace(strings.Repeat("word ", 32)) + "…",
+				ThemeColor: OGDefaultThemeColor,
+				ImageURL:   "/api/og-image/long",
+				Generated:  true,
+			},
+		},
+		{
+			name:    "Path is escaped for the generated image",
+			input:   "Text\n",
+			docPath: "my docs/é",
+			expected: OGCardHints{
+				Title:      "É",
+				Subtitle:   "Text",
+				ThemeColor: OGDefaultThemeColor,
+				ImageURL:   "/api/og-image/my%20docs/%C3%A9",
+				Generated:  true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hints := BuildOGCardHints(tt.input, tt.docPath)
+			if hints != tt.expected {
+				t.Errorf("Expected: %+v, got: %+v", tt.expected, hints)
+			}
+		})
+	}
+}
+
+func TestBuildOpenGraph(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		docPath  string
+		expected OpenGraph
+	}{
+		{
+			name:    "Derived from content",
+			input:   "# Getting Started\n\n```sh\nmake\n```\n\nInstall the *wiki*.\n\n![Shot](shot.png)\n",
+			docPath: "guides/start",
+			expected: OpenGraph{
+				Title:       "Getting Started",
+				Description: "Install the wiki.",
+				Image:       "/api/files/guides/start/shot.png",
+			},
+		},
+		{
+			name:    "Frontmatter wins",
+			input:   "---\ntitle: Custom\ndescription: Summary\nimage: cover.png\n---\n# Heading\n\nBody.\n",
+			docPath: "a",
+			expected: OpenGraph{
+				Title:       "Custom",
+				Description: "Summary",
+				Image:       "/api/files/a/cover.png",
+			},
+		},
+		{
+			name:     "No image",
+			input:    "Just text.\n",
+			docPath:  "notes",
+			expected: OpenGraph{Title: "Notes", Description: "Just text."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if og := BuildOpenGraph(tt.input, tt.docPath); og != tt.expected {
+				t.Errorf("Expected: %+v, got: %+v", tt.expected, og)
+			}
+		})
+	}
+}
+
+func TestNumberedFigureRendering(t *testing.T) {
+	goldext.NumberedFigures = true
+	defer func() { goldext.NumberedFigures = false }()
+
+	md := "See {{ref:fig-flow}} and {{ref:tbl-results}}.\n\n![Flow](flow.png){#fig-flow}\n\nTable: Results {#tbl-results}\n| A | B |\n|---|---|\n| 1 | 2 |\n"
+	result := string(RenderMarkdownWithPath(md, "docs/guide"))
+
+	for _, want := range []string{
+		`See <a href="#fig-flow" class="figure-ref">Figure 1</a> and <a href="#tbl-results" class="figure-ref">Table 1</a>.`,
+		"<figure id=\"fig-flow\" class=\"numbered-figure\">\n<p><img src=\"/api/files/docs/guide/flow.png\" alt=\"Flow\"></p>\n<figcaption><span class=\"figure-number\">Figure 1:</span> Flow</figcaption>\n</figure>",
+		"<figure id=\"tbl-results\" class=\"numbered-table\">\n<figcaption><span class=\"figure-number\">Table 1:</span> Results</figcaption>\n<table>",
+		"</table>\n</figure>",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, result)
+		}
+	}
+}
+
+func TestNestedCallouts(t *testing.T) {
+	md := "::: note Before you start\nRead the *setup* guide.\n\n::: warning\nBack up **first**.\n:::\n\nThen continue.\n:::\n"
+	result := string(RenderMarkdown(md))
+
+	expected := "<div class=\"callout callout-note\">\n<p class=\"callout-title\">Before you start</p>\n<div class=\"callout-content\">\n<p>Read the <em>setup</em> guide.</p>\n" +
+		"<div class=\"callout callout-warning\">\n<p class=\"callout-title\">Warning</p>\n<div class=\"callout-content\">\n<p>Back up <strong>first</strong>.</p>\n</div>\n</div>\n" +
+		"<p>Then continue.</p>\n</div>\n</div>"
+	if !strings.Contains(result, expected) {
+		t.Errorf("Expected nested callouts with rendered bodies, got: %q", result)
+	}
+}
+
+func TestDetailsContainers(t *testing.T) {
+	md := "::: details How do I <reset>?\nOpen *Settings*.\n\n::: details Still stuck?\nAsk an admin.\n:::\n\n::: tip\nInside\n:::\n:::\n\nAfter.\n"
+	result := string(RenderMarkdown(md))
+
+	expected := "<details class=\"markdown-details\">\n<summary>How do I &lt;reset&gt;?</summary>\n<div class=\"details-content\">\n<p>Open <em>Settings</em>.</p>\n" +
+		"<details class=\"markdown-details\">\n<summary>Still stuck?</summary>\n<div class=\"details-content\">\n<p>Ask an admin.</p>\n</div>\n</details>\n" +
+		"<div class=\"callout callout-tip\">"
+	if !strings.Contains(result, expected) || !strings.Contains(result, "</div>\n</details>\n<p>After.</p>") {
+		t.Errorf("Expected nested details with rendered bodies, got: %q", result)
+	}
+
+	unmatched := string(RenderMarkdown("::: details Never closed\nText\n\nMore text\n"))
+	if strings.Contains(unmatched, "<details") || !strings.Contains(unmatched, "<p>More text</p>") {
+		t.Errorf("Expected an unclosed details container to stay text, got: %q", unmatched)
+	}
+}
+
+func TestCalloutNestingGuards(t *testing.T) {
+	// The only ::: closes the innermost callout, leaving the outer one unbalanced
+	unbalanced := string(RenderMarkdown("::: note\nOuter\n::: tip\nInner\n:::\n"))
+	if strings.Count(unbalanced, `class="callout `) != 1 || !strings.Contains(unbalanced, `class="callout callout-tip"`) || !strings.Contains(unbalanced, "::: note") {
+		t.Errorf("Expected only the inner callout to render, got: %q", unbalanced)
+	}
+
+	open := string(RenderMarkdown("::: warning\nNever closed\n"))
+	if strings.Contains(open, `class="callout`) {
+		t.Errorf("Expected unclosed callout to be left as text, got: %q", open)
+	}
+
+	goldext.CalloutMaxDepth = 1
+	defer func() { goldext.CalloutMaxDepth = 3 }()
+	limited := string(RenderMarkdown("::: note\n::: tip\nDeep\n:::\n:::\n"))
+	if strings.Count(limited, `class="callout `) != 1 || !strings.Contains(limited, "::: tip") {
+		t.Errorf("Expected callouts beyond the depth limit to stay text, got: %q", limited)
+	}
+}
+
+func TestCalloutInsideTabs(t *testing.T) {
+	result := string(RenderMarkdown("::: tabs\n::: tab \"One\"\n::: tip\nInside a tab\n:::\n:::\n:::\n"))
+	if !strings.Contains(result, `class="tab-panel"`) || !strings.Contains(result, "<div class=\"callout callout-tip\">") || !strings.Contains(result, "<p>Inside a tab</p>") {
+		t.Errorf("Expected a callout inside the tab panel, got: %q", result)
+	}
+}
+
+func TestAlertCallouts(t *testing.T) {
+	result := string(RenderMarkdown("> [!WARNING]\n> Back up **first**.\n>\n> - one\n> - two\n\nAfter the alert.\n"))
+	expected := "<div class=\"callout callout-warning\">\n<p class=\"callout-title\">Warning</p>\n<div class=\"callout-content\">\n<p>Back up <strong>first</strong>.</p>\n<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n</div>\n</div>\n<p>After the alert.</p>"
+	if !strings.Contains(result, expected) {
+		t.Errorf("Expected the alert as a callout, got: %q", result)
+	}
+
+	custom := string(RenderMarkdown("> [!SECURITY] Read this\n> Rotate the keys.\n"))
+	if !strings.Contains(custom, "<div class=\"callout\">\n<p class=\"callout-title\">Read this</p>") || !strings.Contains(custom, "<p>Rotate the keys.</p>") {
+		t.Errorf("Expected unknown alert types as generic callouts, got: %q", custom)
+	}
+
+	plain := string(RenderMarkdown("> Just a quote [!NOTE]\n\n```\n> [!TIP]\n```\n"))
+	if strings.Contains(plain, "callout") || !strings.Contains(plain, "<blockquote>") || !strings.Contains(plain, "&gt; [!TIP]") {
+		t.Errorf("Expected plain quotes and code to be left alone, got: %q", plain)
+	}
+
+	blocks := string(RenderMarkdown("> [!NOTE]\n> ```mermaid\n> graph TD\n> A-->B\n> ```\n>\n> ```rtl\n> שלום\n> ```\n"))
+	if !strings.Contains(blocks, "<div class=\"mermaid\">graph TD\nA--&gt;B</div>") || !strings.Contains(blocks, "<div class=\"rtl\"><p>שלום</p>") || strings.Contains(blocks, "PLACEHOLDER") {
+		t.Errorf("Expected mermaid and direction blocks inside the alert, got: %q", blocks)
+	}
+}
+
+func TestServerHighlighter(t *testing.T) {
+	ServerHighlighter = func(language, code string) (string, bool) {
+		if language != "go" {
+			return "", false
+		}
+		return `<span class="k">` + strings.TrimSpace(code) + `</span>`, true
+	}
+	defer func() { ServerHighlighter = nil }()
+
+	result := string(RenderMarkdown("```go\nfunc\n```\n\n```cobol\nDISPLAY\n```\n\n```\nplain\n```\n"))
+	for _, want := range []string{
+		"<pre class=\"highlight\"><code data-language=\"go\"><span class=\"k\">func</span></code></pre>",
+		"<pre><code class=\"language-cobol\">DISPLAY\n</code></pre>",
+		"<pre><code>plain\n</code></pre>",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, result)
+		}
+	}
+}
+
+func TestServerHighlighting(t *testing.T) {
+	ServerHighlighting = true
+	defer func() {
+		ServerHighlighting = false
+		HighlightTheme = HighlightThemeDefault
+	}()
+
+	md := "```go\nfunc main() {} // </code><script>\n```\n\n```nosuchlanguage\nDISPLAY\n```\n\n```\n#!/bin/sh\necho hi\n```\n\n```\nplain words\n```\n"
+	result := string(RenderMarkdown(md))
+	for _, want := range []string{
+		`<pre class="highlight" style="background-color:#f7f7f7"><code data-language="go"><span style="color:#cf222e">func</span>`,
+		`&lt;/code&gt;&lt;script&gt;`,
+		"<pre><code class=\"language-nosuchlanguage\">DISPLAY\n</code></pre>",
+		`<pre class="highlight" style="background-color:#f7f7f7"><code><span style="color:#57606a">#!/bin/sh`,
+		"<pre><code>plain words\n</code></pre>",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, result)
+		}
+	}
+	if strings.Contains(result, "<script>") {
+		t.Errorf("Expected the highlighted code to be escaped, got: %q", result)
+	}
+
+	// The dark variant has its own colors
+	HighlightTheme = HighlightThemeDark
+	if result := string(RenderMarkdown("```go\nfunc main() {}\n```\n")); !strings.Contains(result, `<pre class="highlight" style="color:#e6edf3;background-color:#0d1117">`) {
+		t.Errorf("Expected the colors of the dark theme, got: %q", result)
+	}
+}
+
+func TestToPrint(t *testing.T) {
+	input := `<h1 id="a">A</h1>
+<details class="markdown-details"><summary>More</summary><div class="details-content">Hidden</div></details>
+<details open><summary>Open</summary></details>
+<p>See <a href="https://example.com/docs">the docs</a>, <a href="/guide">the guide</a> and <a href="https://example.com/docs">again</a>.</p>
+<p><a href="#a">Top</a> <a href="https://example.com">https://example.com</a></p>
+<h1 id="b" class="title">B</h1>
+<h2 id="c">C</h2>`
+
+	result := ToPrint(input)
+
+	for _, want := range []string{
+		`<h1 id="a">A</h1>`,
+		`<details class="markdown-details" open><summary>More</summary>`,
+		`<details open><summary>Open</summary>`,
+		`<a href="https://example.com/docs">the docs</a><sup class="print-link-ref"><a href="#print-link-1">[1]</a></sup>`,
+		`<a href="/guide">the guide</a><sup class="print-link-ref"><a href="#print-link-2">[2]</a></sup>`,
+		`<a href="https://example.com/docs">again</a><sup class="print-link-ref"><a href="#print-link-1">[1]</a></sup>`,
+		`<p><a href="#a">Top</a> <a href="https://example.com">https://example.com</a></p>`,
+		`<h1 id="b" class="title page-break-before">B</h1>`,
+		`<h2 id="c">C</h2>`,
+		"<section class=\"print-link-references\">\n<h2>Links</h2>\n<ol>\n<li id=\"print-link-1\">https://example.com/docs</li>\n<li id=\"print-link-2\">/guide</li>\n</ol>\n</section>",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, result)
+		}
+	}
+}
+
+func TestPrintOutputRendering(t *testing.T) {
+	PrintOutput = true
+	defer func() { PrintOutput = false }()
+
+	result := string(RenderMarkdown("# One\n\n```details Notes\nInside\n```\n\n[Site](https://example.com)\n\n# Two\n"))
+	for _, want := range []string{
+		`<details class="markdown-details" open>`,
+		`<sup class="print-link-ref"><a href="#print-link-1">[1]</a></sup>`,
+		`<li id="print-link-1">https://example.com</li>`,
+		`class="page-break-before"`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, result)
+		}
+	}
+
+	PrintOutput = false
+	if plain := string(RenderMarkdown("[Site](https://example.com)")); strings.Contains(plain, "print-link") {
+		t.Errorf("Expected no print references by default, got: %q", plain)
+	}
+}
+
+func TestCSVCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "CSV with quoted fields",
+			input:    "```csv\nName, Note\n\"Smith, J\", \"said \"\"hi\"\"\"\n<b>, x\n```\n",
+			expected: "<table class=\"csv-table\">\n<thead>\n<tr>\n<th>Name</th>\n<th>Note</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>Smith, J</td>\n<td>said &#34;hi&#34;</td>\n</tr>\n<tr>\n<td>&lt;b&gt;</td>\n<td>x</td>\n</tr>\n</tbody>\n</table>\n",
+		},
+		{
+			name:     "TSV with quoted field",
+			input:    "```tsv\nKey\tValue\nmode\t\"tab\tinside\"\n```\n",
+			expected: "<table class=\"csv-table\">\n<thead>\n<tr>\n<th>Key</th>\n<th>Value</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>mode</td>\n<td>tab\tinside</td>\n</tr>\n</tbody>\n</table>\n",
+		},
+		{
+			name:     "Custom delimiter without header",
+			input:    "```csv delimiter=; header=false\na;\"b;c\"\n```\n",
+			expected: "<table class=\"csv-table\">\n<tbody>\n<tr>\n<td>a</td>\n<td>b;c</td>\n</tr>\n</tbody>\n</table>\n",
+		},
+		{
+			name:     "Malformed row",
+			input:    "```csv\na,b\n1,2,3\n```\n",
+			expected: "<div class=\"csv-error\" role=\"alert\">Invalid CSV: record on line 2: wrong number of fields</div>\n<pre><code>a,b\n1,2,3\n</code></pre>\n",
+		},
+		{
+			name:     "Invalid delimiter",
+			input:    "```csv delimiter=ab\na\n```\n",
+			expected: `<div class="csv-error" role="alert">Invalid CSV: invalid delimiter &#34;ab&#34;</div>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := string(RenderMarkdown(tt.input))
+			if !strings.Contains(result, tt.expected) {
+				t.Errorf("Expected output to contain %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+
+	// Other fenced blocks still render as code
+	if result := string(RenderMarkdown("```go\nx := 1\n```\n")); result != "<pre><code class=\"language-go\">x := 1\n</code></pre>\n" {
+		t.Errorf("Expected regular code block, got: %q", result)
+	}
+}
+
+func TestBuildArticleJSONLD(t *testing.T) {
+	modTime := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		input    string
+		docPath  string
+		modTime  time.Time
+		expected map[string]interface{}
+	}{
+		{
+			name:    "Frontmatter values",
+			input:   "---\ntitle: Release </script> notes\nauthor: Jane \"JD\" Doe\ndate: 2024-01-15\nmodified: 2024-02-01\ndescription: What's new & improved\nimage: /api/files/r/cover.png\n---\n# Heading\n",
+			docPath: "releases/v2",
+			modTime: modTime,
+			expected: map[string]interface{}{
+				"@context":      "https://schema.org",
+				"@type":         "Article",
+				"headline":      "Release </script> notes",
+				"description":   "What's new & improved",
+				"image":         "/api/files/r/cover.png",
+				"author":        map[string]interface{}{"@type": "Person", "name": "Jane \"JD\" Doe"},
+				"datePublished": "2024-01-15",
+				"dateModified":  "2024-02-01",
+			},
+		},
+		{
+			name:    "Computed fallbacks",
+			input:   "# Getting Started\n\nInstall the wiki.\n",
+			docPath: "guides/start",
+			modTime: modTime,
+			expected: map[string]interface{}{
+				"@context":      "https://schema.org",
+				"@type":         "Article",
+				"headline":      "Getting Started",
+				"description":   "Install the wiki.",
+				"datePublished": "2024-05-06T07:08:09Z",
+				"dateModified":  "2024-05-06T07:08:09Z",
+			},
+		},
+		{
+			name:    "Invalid date without file time",
+			input:   "---\ndate: last week\n---\nJust text.\n",
+			docPath: "notes/my-note",
+			expected: map[string]interface{}{
+				"@context":    "https://schema.org",
+				"@type":       "Article",
+				"headline":    "My Note",
+				"description": "Just text.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := BuildArticleJSONLD(tt.input, tt.docPath, tt.modTime)
+			if err != nil {
+				t.Fatalf("Expected JSON-LD, got error: %v", err)
+			}
+			if strings.Contains(data, "</script>") {
+				t.Errorf("Expected script end tags to be escaped, got: %s", data)
+			}
+
+			var decoded map[string]interface{}
+			if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+				t.Fatalf("Expected valid JSON, got error %v for: %s", err, data)
+			}
+			if !reflect.DeepEqual(decoded, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, decoded)
+			}
+		})
+	}
+}
+
+func TestArticleStructuredDataRendering(t *testing.T) {
+	ArticleStructuredData = true
+	ImageFilesRoot = t.TempDir()
+	defer func() {
+		ArticleStructuredData = false
+		ImageFilesRoot = filepath.Join("data", "documents")
+	}()
+
+	dir := filepath.Join(ImageFilesRoot, "guides", "start")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	md := "# Start\n\nBody text.\n"
+	if err := os.WriteFile(filepath.Join(dir, "document.md"), []byte(md), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2023, 3, 4, 5, 6, 7, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dir, "document.md"), modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	result := string(RenderMarkdownWithPath(md, "guides/start"))
+	prefix := `<article class="document-article">` + "\n" + `<script type="application/ld+json">`
+	if !strings.HasPrefix(result, prefix) || !strings.HasSuffix(result, "</article>\n") {
+		t.Fatalf("Expected document wrapped in an article, got: %q", result)
+	}
+	if !strings.Contains(result, `"dateModified":"2023-03-04T05:06:07Z"`) || !strings.Contains(result, "<p>Body text.</p>") {
+		t.Errorf("Expected JSON-LD with the file time and the rendered body, got: %q", result)
+	}
+}
+
+func TestEmptyDocumentPlaceholder(t *testing.T) {
+	EmptyDocumentPlaceholder = "*This page is empty.* {{owner}} will fill it in."
+	EmptyDraftPlaceholder = "This draft has no content yet."
+	defer func() {
+		EmptyDocumentPlaceholder = ""
+		EmptyDraftPlaceholder = ""
+	}()
+
+	page := "<div class=\"empty-document\">\n<p><em>This page is empty.</em> {{owner}} will fill it in.</p>\n</div>\n"
+	banner := "<div class=\"draft-banner\" role=\"note\">This page is a draft and may be incomplete.</div>\n"
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Empty document",
+			input:    "",
+			expected: page,
+		},
+		{
+			name:     "Whitespace only",
+			input:    "  \n\t\n\n",
+			expected: page,
+		},
+		{
+			name:     "Frontmatter only",
+			input:    "---\nvars:\n  owner: Docs team\n---\n",
+			expected: "<div class=\"empty-document\">\n<p><em>This page is empty.</em> Docs team will fill it in.</p>\n</div>\n",
+		},
+		{
+			name:     "Empty draft",
+			input:    "---\ndraft: true\n---\n\n",
+			expected: banner + "<div class=\"empty-document empty-draft\">\n<p>This draft has no content yet.</p>\n</div>\n",
+		},
+		{
+			name:     "Document with content",
+			input:    "---\ndraft: true\n---\nHello",
+			expected: banner + "<p>Hello</p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := string(RenderMarkdown(tt.input))
+			if result != tt.expected {
+				t.Errorf("Expected: %q, got: %q", tt.expected, result)
+			}
+		})
+	}
+
+	// Drafts fall back to the regular placeholder
+	EmptyDraftPlaceholder = ""
+	if result := string(RenderMarkdown("---\ndraft: true\n---\n")); !strings.Contains(result, `<div class="empty-document empty-draft">`) || !strings.Contains(result, "This page is empty.") {
+		t.Errorf("Expected the regular placeholder for drafts, got: %q", result)
+	}
+}
+
+func TestEmptyDocumentPlaceholderDisabledByDefault(t *testing.T) {
+	if result := string(RenderMarkdown("---\ntitle: Stub\n---\n")); result != "" {
+		t.Errorf("Expected no output by default, got: %q", result)
+	}
+}
+
+func TestSmoothScrollRendering(t *testing.T) {
+	goldext.SmoothScrollAnchors = true
+	defer func() { goldext.SmoothScrollAnchors = false }()
+
+	result := string(RenderMarkdown("## Setup\n\n[Jump](#setup), [guide](/docs/guide#setup), [site](https://example.com) and a note[^1].\n\n[^1]: Footnote.\n"))
+
+	if strings.Count(result, "data-smooth-scroll") != 4 {
+		t.Errorf("Expected hooks on the heading, jump, footnote and back links only, got: %q", result)
+	}
+	for _, want := range []string{
+		`<a href="#setup" data-smooth-scroll="true">Jump</a>`,
+		`<a href="/docs/guide#setup">guide</a>`,
+		`<a href="https://example.com" target="_blank" rel="noopener noreferrer">site</a>`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, result)
+		}
+	}
+}
+
+func TestBackToTopRendering(t *testing.T) {
+	goldext.BackToTopLinks = true
+	defer func() { goldext.BackToTopLinks = false }()
+
+	result := string(RenderMarkdown("## One\n\nText\n\n## Two\n\nMore\n"))
+	if !strings.HasPrefix(result, `<div id="page-top"></div>`) {
+		t.Errorf("Expected the page-top anchor first, got: %q", result)
+	}
+	if !strings.Contains(result, "<p>Text</p>\n<p class=\"back-to-top\"><a href=\"#page-top\">Back to top</a></p>\n<h2 id=\"two\">") {
+		t.Errorf("Expected a back-to-top link before the second section, got: %q", result)
+	}
+}
+
+func TestWikilinkRendering(t *testing.T) {
+	goldext.WikilinkRoot = t.TempDir()
+	defer func() { goldext.WikilinkRoot = filepath.Join("data", "documents") }()
+	if err := os.MkdirAll(filepath.Join(goldext.WikilinkRoot, "guides", "page-name"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	result := string(RenderMarkdownWithPath("Read [[Page Name]] and [[Nowhere]].", "guides/intro"))
+	expected := `<p>Read <a href="/guides/page-name" class="wikilink">Page Name</a> and <span class="wikilink-broken" title="Page not found: Nowhere">Nowhere</span>.</p>`
+	if !strings.Contains(result, expected) {
+		t.Errorf("Expected %q, got: %q", expected, result)
+	}
+}
+
+func TestFootnoteNamespace(t *testing.T) {
+	md := "Text[^1]\n\n[^1]: Note\n"
+
+	result := string(RenderMarkdownWithPath(md, "", WithFootnoteNamespace("Guide Part/1")))
+	for _, want := range []string{
+		`<sup id="guide-part-1-fnref:1"><a href="#guide-part-1-fn:1" class="footnote-ref" role="doc-noteref" aria-describedby="guide-part-1-footnotes-label"`,
+		`<h2 id="guide-part-1-footnotes-label" class="sr-only">`,
+		`<li id="guide-part-1-fn:1" tabindex="-1">`,
+		`<a href="#guide-part-1-fnref:1" class="footnote-backref" role="doc-backlink"`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, result)
+		}
+	}
+
+	// Without a namespace the IDs are unchanged
+	if plain := string(RenderMarkdownWithPath(md, "")); !strings.Contains(plain, `<li id="fn:1" tabindex="-1">`) || !strings.Contains(plain, `<h2 id="footnotes-label"`) {
+		t.Errorf("Expected unprefixed footnote IDs, got: %q", plain)
+	}
+}
+
+func TestRenderMarkdownWithMetadata(t *testing.T) {
+	md := "---\ntitle: Guide\nlayout: custom\ntags: [setup, docs]\n---\n# Guide\n\nHello *world*\n"
+
+	html, metadata, hasFrontmatter := RenderMarkdownWithMetadata(md, "docs/guide")
+	if !hasFrontmatter || metadata == nil {
+		t.Fatalf("Expected parsed frontmatter, got %v %v", metadata, hasFrontmatter)
+	}
+	if metadata.Title != "Guide" || metadata.Layout != "custom" || !reflect.DeepEqual(metadata.Tags, frontmatter.TagList{"setup", "docs"}) {
+		t.Errorf("Unexpected metadata: %+v", *metadata)
+	}
+	if want := RenderMarkdownWithPath(md, "docs/guide"); !bytes.Equal(html, want) {
+		t.Errorf("Expected %q, got %q", want, html)
+	}
+
+	if _, metadata, hasFrontmatter := RenderMarkdownWithMetadata("Plain text\n", ""); hasFrontmatter || metadata == nil {
+		t.Errorf("Expected empty metadata without frontmatter, got %v %v", metadata, hasFrontmatter)
+	}
+}
+
+func TestRenderMarkdownFileWithMetadata(t *testing.T) {
+	ClearRenderCache()
+	defer ClearRenderCache()
+	defer SetDocumentsRoot(filepath.Join("data", "documents"))
+
+	root := t.TempDir()
+	SetDocumentsRoot(root)
+	filePath := filepath.Join(root, "guides", "setup", "document.md")
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	md := "---\ntitle: Setup\nlayout: custom\n---\n# Setup\n\n![Shot](shot.png)\n"
+	if err := os.WriteFile(filePath, []byte(md), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	html, metadata, err := RenderMarkdownFileWithMetadata(filePath)
+	if err != nil {
+		t.Fatalf("Expected the document to render, got error: %v", err)
+	}
+	if metadata == nil || metadata.Title != "Setup" || metadata.Layout != "custom" {
+		t.Fatalf("Unexpected metadata: %+v", metadata)
+	}
+	if !strings.Contains(string(html), `src="/api/files/guides/setup/shot.png"`) {
+		t.Errorf("Expected image resolved against the document path, got: %q", html)
+	}
+
+	// The cached rendering keeps its metadata
+	cached, cachedMetadata, err := RenderMarkdownFileWithMetadata(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cached, html) || cachedMetadata.Title != "Setup" {
+		t.Errorf("Expected the cached rendering and metadata, got %q %+v", cached, cachedMetadata)
+	}
+	if plain, _ := RenderMarkdownFile(filePath); !bytes.Equal(plain, html) {
+		t.Errorf("Expected RenderMarkdownFile to match, got %q", plain)
+	}
+
+	if _, _, err := RenderMarkdownFileWithMetadata(filepath.Join(root, "missing", "document.md")); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+}
+
+func TestRenderMarkdownFileLastEditor(t *testing.T) {
+	ClearRenderCache()
+	defer ClearRenderCache()
+
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Last editor", "---\nauthor: Alice\nlast_editor: Bob\n---\n# Page\n", "Bob"},
+		{"Author", "---\nauthor: Alice\n---\n# Page\n", "Alice"},
+		{"Neither", "# Page\n", ""},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(dir, strconv.Itoa(i)+".md")
+			if err := os.WriteFile(filePath, []byte(tt.input), 0644); err != nil {
+				t.Fatal(err)
+			}
+			for _, useGit := range []bool{false, true} {
+				// Outside a git repository the git lookup fails quietly
+				UseGitLastEditor = useGit
+				ClearRenderCache()
+				_, metadata, err := RenderMarkdownFileWithMetadata(filePath)
+				UseGitLastEditor = false
+				if err != nil {
+					t.Fatal(err)
+				}
+				if metadata.LastEditor != tt.expected {
+					t.Errorf("Expected the last editor %q with git lookup %v, got %q", tt.expected, useGit, metadata.LastEditor)
+				}
+			}
+		})
+	}
+}
+
+func TestDirectoryDefaults(t *testing.T) {
+	ClearRenderCache()
+	defer ClearRenderCache()
+	defer SetDocumentsRoot(filepath.Join("data", "documents"))
+
+	root := t.TempDir()
+	SetDocumentsRoot(root)
+	writeDefaults := func(dir, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, dir, DirectoryDefaultsName), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeDefaults("", "draft: true\ntags: [wiki]\n")
+	writeDefaults("guides", "toc: true\nnumbered_headings: true\ntags: [guide]\naliases: /old\n")
+
+	// The included document's frontmatter only applies to the included content
+	writeTestDocument(t, root, "shared", "---\ndraft: false\nlayout: kanban\ntags: [shared]\n---\nShared text\n")
+	writeTestDocument(t, root, "guides/setup", "---\ndraft: false\ntoc: false\n---\n# Setup\n\n## Install\n\n{{include: /shared}}\n")
+	writeTestDocument(t, root, "guides/plain", "# Plain\n\n{{include: /shared}}\n")
+
+	html, metadata, err := RenderMarkdownFileWithMetadata(filepath.Join(root, "guides", "setup", "document.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(html)
+	if bool(metadata.Draft || metadata.TOC || !metadata.NumberedHeadings) || !reflect.DeepEqual(metadata.Tags, frontmatter.TagList{"guide"}) || metadata.Layout != "" || len(metadata.Aliases) != 0 {
+		t.Errorf("Expected the document to override the defaults, got %+v", metadata)
+	}
+	if strings.Contains(result, "draft-banner") || strings.Contains(result, "wiki-toc") || !strings.Contains(result, "heading-number") || !strings.Contains(result, "Shared text") {
+		t.Errorf("Expected no banner or table of contents and numbered headings, got: %s", result)
+	}
+
+	html, metadata, err = RenderMarkdownFileWithMetadata(filepath.Join(root, "guides", "plain", "document.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = string(html)
+	if !bool(metadata.Draft && metadata.TOC) || metadata.Layout != "" || !strings.Contains(result, "draft-banner") || !strings.Contains(result, "wiki-toc") {
+		t.Errorf("Expected the directory defaults without frontmatter, got %+v: %s", metadata, result)
+	}
+
+	// Editing the defaults changes cached renderings
+	writeDefaults("", "tags: [wiki]\n")
+	if html, _, _ := RenderMarkdownFileWithMetadata(filepath.Join(root, "guides", "plain", "document.md")); strings.Contains(string(html), "draft-banner") {
+		t.Errorf("Expected the new defaults to apply, got: %s", html)
+	}
+}
+
+func TestSharedMarkdownInstance(t *testing.T) {
+	noAnchors := false
+	if markdownFor(renderOptions{}, frontmatter.Metadata{}) != markdownFor(renderOptions{}, frontmatter.Metadata{Title: "Other"}) {
+		t.Errorf("Expected renderings with the same options to share a Goldmark instance")
+	}
+	if markdownFor(renderOptions{}, frontmatter.Metadata{}) == markdownFor(renderOptions{}, frontmatter.Metadata{Anchors: &noAnchors}) {
+		t.Errorf("Expected documents without anchors to use another instance")
+	}
+	if markdownFor(renderOptions{untrusted: true}, frontmatter.Metadata{}) == markdownFor(renderOptions{}, frontmatter.Metadata{}) {
+		t.Errorf("Expected untrusted renderings to use another instance")
+	}
+
+	// Run with -race: the shared instance is used by concurrent renderings
+	documents := []string{
+		"# Title\n\nSome *text* with a [link](https://example.com) and a footnote[^1].\n\n[^1]: Note\n",
+		"## Table\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n- [ ] Task\n- [x] Done\n",
+		"Term\n: Definition\n\n```go\nfunc main() {}\n```\n\n![Shot](shot.png)\n",
+		"---\nanchors: false\n---\n# No anchors\n\n~~struck~~ https://go.dev\n",
+		// Blocks restored from placeholders must come back to the rendering that stored them
+		"```mermaid\ngraph TD; A-->B\n```\n\nInline $a*b*c$ math.\n",
+		"```rtl\nשלום עולם\n```\n\n```mermaid\nsequenceDiagram\n```\n",
+		"$$\nx_1 * y_2\n$$\n\n```ltr\nLeft *to* right\n```\n",
+	}
+	expected := make([]string, len(documents))
+	for i, md := range documents {
+		expected[i] = string(RenderMarkdownWithPath(md, "docs/page"))
+	}
+
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		for i, md := range documents {
+			wg.Add(1)
+			go func(i int, md string) {
+				defer wg.Done()
+				if result := string(RenderMarkdownWithPath(md, "docs/page")); result != expected[i] {
+					t.Errorf("Expected concurrent rendering %q, got %q", expected[i], result)
+				}
+			}(i, md)
+		}
+	}
+	wg.Wait()
+
+	// Interleave deterministically: another rendering runs between preprocessing and restoring
+	preprocessed := goldext.ProcessMarkdown(documents[5], "docs/page")
+	RenderMarkdownWithPath(documents[4], "docs/page")
+	restored := goldext.RestoreMathSpans(goldext.RestoreDirectionBlocks(goldext.RestoreMermaidBlocks(preprocessed)))
+	if !strings.Contains(restored, "שלום עולם") || !strings.Contains(restored, "sequenceDiagram") || strings.Contains(restored, "graph TD") {
+		t.Errorf("Expected the blocks of the interrupted rendering, got %q", restored)
+	}
+}
+
+func TestRenderStandaloneHTML(t *testing.T) {
+	ClearRenderCache()
+	defer ClearRenderCache()
+	defer SetDocumentsRoot(filepath.Join("data", "documents"))
+
+	root := t.TempDir()
+	SetDocumentsRoot(root)
+	writeTestDocument(t, root, "guides/setup", "---\ntitle: Setup <Guide>\n---\n# Setup\n\n![Shot](shot.png) [Home](/home) [Top](#setup)\n")
+	writeTestDocument(t, root, "guides/flow", "# Flow chart\n\n```mermaid\ngraph TD\n```\n")
+
+	page, err := RenderStandaloneHTML(filepath.Join(root, "guides", "setup", "document.md"))
+	if err != nil {
+		t.Fatalf("Expected the page to render, got error: %v", err)
+	}
+	result := string(page)
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<title>Setup &lt;Guide&gt;</title>",
+		".callout",
+		`<main class="content">`,
+		`src="/api/files/guides/setup/shot.png"`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected page to contain %q", want)
+		}
+	}
+	if strings.Contains(result, "mermaid.initialize") {
+		t.Errorf("Expected no mermaid script without diagrams")
+	}
+
+	// Root-relative links point to the wiki when its address is set
+	StandaloneBaseURL = "https://wiki.example.com/"
+	defer func() { StandaloneBaseURL = "" }()
+	ClearRenderCache()
+	page, err = RenderStandaloneHTML(filepath.Join(root, "guides", "flow", "document.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = string(page)
+	if !strings.Contains(result, "<title>Flow chart</title>") || !strings.Contains(result, "mermaid.initialize") {
+		t.Errorf("Expected the heading title and the mermaid script, got: %.300q", result)
+	}
+	page, _ = RenderStandaloneHTML(filepath.Join(root, "guides", "setup", "document.md"))
+	for _, want := range []string{`src="https://wiki.example.com/api/files/guides/setup/shot.png"`, `href="https://wiki.example.com/home"`, `href="#setup"`} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("Expected page to contain %q", want)
+		}
+	}
+
+	if _, err := RenderStandaloneHTML(filepath.Join(root, "missing", "document.md")); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+}
+
+func TestRenderMarkdownTo(t *testing.T) {
+	footnoteARIA := goldext.FootnoteARIA
+	goldext.FootnoteARIA = true
+	defer func() { goldext.FootnoteARIA = footnoteARIA }()
+
+	md := "# Title\n\n```mermaid\ngraph TD\n```\n\n```rtl\nשלום\n```\n\nText[^1]\n\n[^1]: Note\n"
+
+	var buf bytes.Buffer
+	if err := RenderMarkdownTo(&buf, md, "docs/page"); err != nil {
+		t.Fatal(err)
+	}
+	result := buf.String()
+	for _, want := range []string{
+		`<div class="mermaid">graph TD</div>`,
+		`<div class="rtl">`,
+		`<h2 id="footnotes-label" class="sr-only">`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, result)
+		}
+	}
+
+	// Without tables the buffered rendering used for whole-document options must match
+	goldext.ResponsiveTables = true
+	defer func() { goldext.ResponsiveTables = false }()
+	if buffered := string(RenderMarkdownWithPath(md, "docs/page")); buffered != result {
+		t.Errorf("Expected buffered output %q to match streamed output %q", buffered, result)
+	}
+}
+
+func TestGalleryLayoutRendering(t *testing.T) {
+	md := "---\nlayout: gallery\n---\n# Trip\n\n![Beach](beach.jpg)\n\n## Hiking\n\n![Summit](https://example.com/summit.png) ![]()\n"
+
+	result := string(RenderMarkdownWithPath(md, "photos/trip"))
+	for _, want := range []string{
+		`<h1 class="gallery-document-title">Trip</h1>`,
+		`<a href="/api/files/photos/trip/beach.jpg" class="gallery-link" data-gallery="" data-caption="Beach" target="_blank">`,
+		`<img src="/api/files/photos/trip/beach.jpg" alt="Beach" loading="lazy">`,
+		`<h2 class="gallery-section-title">Hiking</h2>`,
+		`<img src="https://example.com/summit.png" alt="Summit" loading="lazy">`,
+		`<figure class="gallery-item gallery-item-missing">`,
+		`<div class="gallery-placeholder" role="img" aria-label="">Image not found</div>`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, result)
+		}
+	}
+
+	// Without images the document renders as regular markdown
+	result = string(RenderMarkdown("---\nlayout: gallery\n---\nNo images yet"))
+	if !strings.Contains(result, "<p>No images yet</p>") {
+		t.Errorf("Expected the markdown fallback, got: %q", result)
+	}
+}
+
+func TestColumnsLayoutRendering(t *testing.T) {
+	md := "---\nlayout: columns\ncolumn_count: 3\n---\n# Glossary\n\n::: note\nA *term*\n:::\n\n```mermaid\ngraph TD\n```\n"
+	result := string(RenderMarkdownWithPath(md, "docs/glossary"))
+
+	if !strings.HasPrefix(result, "<div class=\"doc-columns\" style=\"column-count:3\">\n<h1") || !strings.HasSuffix(result, "</div>\n") {
+		t.Errorf("Expected the body wrapped in columns, got: %q", result)
+	}
+	for _, want := range []string{`<div class="callout callout-note">`, "<em>term</em>", `<div class="mermaid">graph TD</div>`} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, result)
+		}
+	}
+
+	for input, count := range map[string]string{"": "2", "column_count: 9\n": "4", "column_count: -1\n": "1"} {
+		result := string(RenderMarkdown("---\nlayout: columns\n" + input + "---\nText\n"))
+		if !strings.Contains(result, `style="column-count:`+count+`"`) {
+			t.Errorf("Expected %s columns for %q, got: %q", count, input, result)
+		}
+	}
+}
+
+func TestFrontmatterClasses(t *testing.T) {
+	tests := []struct {
+		name   string
+		md     string
+		prefix string
+	}{
+		{"Default layout", "---\nclasses: policy\n---\n# Policy\n", "<div class=\"policy\">\n<h1"},
+		{"Columns layout", "---\nlayout: columns\nclasses: [tutorial, wide]\n---\nText\n", "<div class=\"tutorial wide\">\n<div class=\"doc-columns\""},
+		{"Gallery layout", "---\nlayout: gallery\nclasses: photos\n---\n![Beach](beach.jpg)\n", "<div class=\"photos\">\n<div class=\"gallery"},
+		{"Unsafe names are dropped", "---\nclasses: ['\"><script>', changelog]\n---\nText\n", "<div class=\"changelog\">\n<p>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := string(RenderMarkdownWithPath(tt.md, "docs/page"))
+			if !strings.HasPrefix(result, tt.prefix) || !strings.HasSuffix(result, "</div>\n") {
+				t.Errorf("Expected output wrapped starting with %q, got: %q", tt.prefix, result)
+			}
+			if strings.Contains(result, "<script>") {
+				t.Errorf("Expected unsafe classes to be dropped, got: %q", result)
+			}
+		})
+	}
+
+	if result := string(RenderMarkdown("---\nclasses: '<b>'\n---\nText\n")); result != "<p>Text</p>\n" {
+		t.Errorf("Expected no wrapper without valid classes, got: %q", result)
+	}
+}
+
+func TestDraftBanner(t *testing.T) {
+	banner := `<div class="draft-banner" role="note">This page is a draft and may be incomplete.</div>` + "\n"
+	body := "# Plan\n\nText\n"
+
+	for _, value := range []string{"true", "yes", "on", "1", "YES"} {
+		html, metadata, _ := RenderMarkdownWithMetadata("---\ndraft: "+value+"\n---\n"+body, "docs/plan")
+		if !bool(metadata.Draft) || !strings.HasPrefix(string(html), banner) {
+			t.Errorf("Expected draft: %s to render the banner, got %v %q", value, metadata.Draft, html)
+		}
+	}
+
+	// Non-drafts render exactly like documents without the field
+	plain := string(RenderMarkdownWithPath(body, "docs/plan"))
+	for _, value := range []string{"false", "no", "off", "0"} {
+		html, metadata, _ := RenderMarkdownWithMetadata("---\ndraft: "+value+"\n---\n"+body, "docs/plan")
+		if bool(metadata.Draft) || string(html) != plain {
+			t.Errorf("Expected draft: %s to render %q, got %v %q", value, plain, metadata.Draft, html)
+		}
+	}
+
+	// The banner opens the wrapper of every layout
+	result := string(RenderMarkdown("---\ndraft: true\nlayout: columns\nclasses: policy\n---\nText\n"))
+	if !strings.HasPrefix(result, "<div class=\"policy\">\n"+banner+"<div class=\"doc-columns\"") {
+		t.Errorf("Expected the banner inside the classes wrapper, got: %q", result)
+	}
+}
+
+func TestFrontmatterError(t *testing.T) {
+	result := string(RenderMarkdown("---\ntitle: [unclosed\ndraft: true\n---\n# Plan\n"))
+	if !strings.HasPrefix(result, `<div class="frontmatter-error" role="alert">invalid frontmatter: `) {
+		t.Errorf("Expected the frontmatter error notice, got: %q", result)
+	}
+	if strings.Contains(result, "unclosed") || strings.Contains(result, "<hr") || !strings.Contains(result, `<h1 id="plan">`) {
+		t.Errorf("Expected the body without the raw YAML, got: %q", result)
+	}
+
+	// The metadata of invalid frontmatter doesn't apply
+	if strings.Contains(result, "draft-banner") {
+		t.Errorf("Expected no draft banner, got: %q", result)
+	}
+}
+
+func TestNumberedHeadings(t *testing.T) {
+	md := "---\nnumbered_headings: true\n---\n# Manual\n\n## Scope\n\n### Terms\n\n### Roles\n\n#### Owner\n\n## Process\n\n#### Skipped level\n\n```\n## Not a heading\n```\n"
+	result := string(RenderMarkdown(md))
+
+	number := func(n string) string { return `<span class="heading-number">` + n + `</span> ` }
+	for _, want := range []string{
+		`<h1 id="manual">Manual`,
+		`<h2 id="scope">` + number("1") + "Scope",
+		`<h3 id="terms">` + number("1.1") + "Terms",
+		`<h3 id="roles">` + number("1.2") + "Roles",
+		`<h4 id="owner">` + number("1.2.1") + "Owner",
+		`<h2 id="process">` + number("2") + "Process",
+		`<h4 id="skipped-level">` + number("2.1") + "Skipped level",
+		"## Not a heading",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, result)
+		}
+	}
+	if strings.Count(result, "heading-number") != 6 {
+		t.Errorf("Expected 6 numbered headings, got: %q", result)
+	}
+
+	if result := string(RenderMarkdown("## Scope\n")); strings.Contains(result, "heading-number") {
+		t.Errorf("Expected no numbers without the flag, got: %q", result)
+	}
+}
+
+func TestMathRendering(t *testing.T) {
+	md := "## Cost of $n$ items\n\nThe cost is $5 and $10, while $a*b*c$ and \\(x_1 + x_2\\) stay math.\n\n$$\n\\frac{a}{b} < c\n$$\n\n| Term | Value |\n|---|---|\n| $|x|$ | 1 |\n"
+	result := string(RenderMarkdown(md))
+
+	dollar := `<span class="tex2jax_ignore">$</span>`
+	for _, want := range []string{
+		`<h2 id="cost-of-items">Cost of $n$ items`,
+		"The cost is " + dollar + "5 and " + dollar + "10, while $a*b*c$ and $x_1 + x_2$ stay math.",
+		"$$\n\\frac{a}{b} &lt; c\n$$",
+		"<td>$|x|$</td>",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, result)
+		}
+	}
+	if strings.Contains(result, "<em>") {
+		t.Errorf("Expected math to be safe from emphasis, got: %q", result)
+	}
+}
+
+func TestUntrustedHTML(t *testing.T) {
+	md := "<b onclick=\"x()\">Bold</b> [link](javascript:alert(1))\n\n<script>alert(1)</script>\n\n::: note\nStill a callout\n:::\n"
+
+	trusted := string(RenderMarkdownWithPath(md, ""))
+	if !strings.Contains(trusted, `<b onclick="x()">`) {
+		t.Errorf("Expected raw HTML to pass through by default, got: %q", trusted)
+	}
+
+	for name, result := range map[string]string{
+		"option":      string(RenderMarkdownWithPath(md, "", WithUntrustedHTML())),
+		"frontmatter": string(RenderMarkdown("---\ntrusted: false\n---\n" + md)),
+	} {
+		if !strings.Contains(result, "<b>Bold</b>") || strings.Contains(result, "onclick") {
+			t.Errorf("%s: Expected formatting without event handlers, got: %q", name, result)
+		}
+		if strings.Contains(result, "<script") || strings.Contains(result, "javascript:") {
+			t.Errorf("%s: Expected scripts to be neutralized, got: %q", name, result)
+		}
+		if !strings.Contains(result, `<div class="callout callout-note">`) {
+			t.Errorf("%s: Expected preprocessor HTML to be kept, got: %q", name, result)
+		}
+	}
+}
+
+func TestUntrustedMath(t *testing.T) {
+	root := t.TempDir()
+	goldext.IncludeRoot = root
+	defer func() { goldext.IncludeRoot = filepath.Join("data", "documents") }()
+	if err := os.MkdirAll(filepath.Join(root, "shared"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "shared", "document.md"), []byte("Included $c<d$"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	md := "Inline $a<b$ and\n\n$$\nx<y\n$$\n\n{{include: /shared}}\n\n<script>alert(1)</script>\n"
+	trusted := string(RenderMarkdownWithPath(md, ""))
+	result := string(RenderMarkdownWithPath(md, "", WithUntrustedHTML()))
+	for _, want := range []string{"$a&lt;b$", "$$\nx&lt;y\n$$", "$c&lt;d$"} {
+		if !strings.Contains(result, want) || !strings.Contains(trusted, want) {
+			t.Errorf("Expected math escaped once as %q, got: %q and trusted %q", want, result, trusted)
+		}
+	}
+	if strings.Contains(result, "&amp;lt;") || strings.Contains(result, "<script") {
+		t.Errorf("Expected no double escaping and a sanitized script, got: %q", result)
+	}
+}
+
+func TestUntrustedImages(t *testing.T) {
+	md := "![X](javascript:alert(1)) ![Y](/api/files/docs/y.png)\n"
+
+	ImageCopyLinks = true
+	ImageCopyLinksExternal = true
+	defer func() {
+		ImageCopyLinks = false
+		ImageCopyLinksExternal = false
+	}()
+
+	result := string(RenderMarkdownWithPath(md, "docs", WithUntrustedHTML()))
+	if strings.Contains(result, "javascript:") {
+		t.Errorf("Expected script image sources to be dropped, got: %q", result)
+	}
+	if !strings.Contains(result, `<img src="" alt="X">`) || !strings.Contains(result, `<img src="/api/files/docs/y.png" alt="Y">`) {
+		t.Errorf("Expected a blank source for the script and safe images kept, got: %q", result)
+	}
+
+	// Without other image options untrusted renderings still check sources
+	ImageCopyLinks = false
+	if result := string(RenderMarkdownWithPath(md, "docs", WithUntrustedHTML())); strings.Contains(result, "javascript:") {
+		t.Errorf("Expected script image sources to be dropped, got: %q", result)
+	}
+}
+
+func TestUntrustedHTMLIncludes(t *testing.T) {
+	root := t.TempDir()
+	goldext.IncludeRoot = root
+	defer func() { goldext.IncludeRoot = filepath.Join("data", "documents") }()
+	for docPath, content := range map[string]string{
+		"shared/snippet": "Shared <img src=x onerror=alert(1)> <kbd>Ctrl</kbd>\n\n{{include: nested}}",
+		"shared/nested":  "<script>alert(2)</script>",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, docPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, docPath, "document.md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	md := "Page\n\n{{include: /shared/snippet}}\n"
+	result := string(RenderMarkdownWithPath(md, "page", WithUntrustedHTML()))
+	if strings.Contains(result, "onerror") || strings.Contains(result, "<script") {
+		t.Errorf("Expected included HTML to be sanitized, got: %q", result)
+	}
+	if !strings.Contains(result, `<img src="x">`) || !strings.Contains(result, "<kbd>Ctrl</kbd>") {
+		t.Errorf("Expected included formatting to be kept, got: %q", result)
+	}
+
+	// Trusted renderings keep the included HTML
+	if trusted := string(RenderMarkdownWithPath(md, "page")); !strings.Contains(trusted, "onerror") {
+		t.Errorf("Expected trusted includes to be unchanged, got: %q", trusted)
+	}
+}
+
+func TestUntrustedHTMLFencedBlocks(t *testing.T) {
+	md := "```mermaid\ngraph TD\n</div><script>alert(2)</script>\n```\n\n```rtl\nשלום <img src=x onerror=alert(1)>\n```\n"
+
+	result := string(RenderMarkdownWithPath(md, "", WithUntrustedHTML()))
+	if !strings.Contains(result, `<div class="mermaid">graph TD`+"\n"+`&lt;/div&gt;&lt;script&gt;alert(2)&lt;/script&gt;</div>`) {
+		t.Errorf("Expected the mermaid source escaped, got: %q", result)
+	}
+	if !strings.Contains(result, `<img src="x">`) {
+		t.Errorf("Expected the rtl block sanitized, got: %q", result)
+	}
+	if strings.Contains(result, "<script") || strings.Contains(result, "onerror") {
+		t.Errorf("Expected no script from fenced blocks, got: %q", result)
+	}
+}
+
+func TestExternalLinkTargets(t *testing.T) {
+	md := "[Docs](/docs/guide) [Site](https://example.com) [Own](https://wiki.example.org/page) [Mail](mailto:a@example.com)"
+
+	SiteHosts = []string{"wiki.example.org"}
+	defer func() { SiteHosts = nil }()
+
+	result := string(RenderMarkdown(md))
+	for _, want := range []string{
+		`<a href="/docs/guide">Docs</a>`,
+		`<a href="https://example.com" target="_blank" rel="noopener noreferrer">Site</a>`,
+		`<a href="https://wiki.example.org/page">Own</a>`,
+		`<a href="mailto:a@example.com">Mail</a>`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, result)
+		}
+	}
+
+	ExternalLinksNewTab = false
+	defer func() { ExternalLinksNewTab = true }()
+	if result := string(RenderMarkdown(md)); strings.Contains(result, "_blank") {
+		t.Errorf("Expected no new-tab links when disabled, got: %q", result)
+	}
+}
+
+func TestFileLinkRenderers(t *testing.T) {
+	tests := []struct {
+		name     string
+		md       string
+		expected string
+	}{
+		{"PDF viewer", "[Manual](/api/files/docs/manual.pdf)", `<a href="/docs?mode=pdf&file=manual.pdf">Manual</a>`},
+		{"Audio", "[Talk](/api/files/docs/talk%201.MP3)", `<p><audio class="file-audio" controls preload="metadata" src="/api/files/docs/talk%201.MP3"><a href="/api/files/docs/talk%201.MP3">Talk</a></audio></p>`},
+		{"Audio with query", "[Clip](/api/files/docs/clip.ogg?v=2)", `<audio class="file-audio" controls preload="metadata" src="/api/files/docs/clip.ogg?v=2">`},
+		{"Video escapes the destination", `[Demo](</api/files/docs/a"b.webm>)`, `<video class="file-video" controls preload="metadata" src="/api/files/docs/a&quot;b.webm"><a href="/api/files/docs/a&quot;b.webm">Demo</a></video></p>`},
+		{"Unknown type stays a link", "[Book](/api/files/docs/book.epub)", `<a href="/api/files/docs/book.epub">Book</a>`},
+		{"Other hosts stay links", "[Song](https://example.com/song.mp3)", `<a href="https://example.com/song.mp3" target="_blank" rel="noopener noreferrer">Song</a>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := string(RenderMarkdown(tt.md))
+			if !strings.Contains(result, tt.expected) {
+				t.Errorf("Expected %q in %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestPDFViewerLinks(t *testing.T) {
+	tests := []struct {
+		name        string
+		destination string
+		expectPath  string
+		expectFile  string
+	}{
+		{"Nested folder with space", "/api/files/docs/reports/q3%20summary.pdf", "/docs/reports", "q3 summary.pdf"},
+		{"Unicode name", "/api/files/docs/r%C3%A9sum%C3%A9.pdf", "/docs", "résumé.pdf"},
+		{"Query characters", "/api/files/docs/a&b=c.pdf", "/docs", "a&b=c.pdf"},
+		{"Homepage files", "/api/files/pages/home/intro.pdf", "/pages/home", "intro.pdf"},
+		{"Escaped slash in the name", "/api/files/docs/q1%2Fq2.pdf", "/docs", "q1/q2.pdf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viewerURL := pdfViewerURL(tt.destination)
+			u, err := url.Parse(viewerURL)
+			if err != nil {
+				t.Fatalf("Invalid viewer URL %q: %v", viewerURL, err)
+			}
+			if u.Path != tt.expectPath || u.Query().Get("mode") != "pdf" || u.Query().Get("file") != tt.expectFile {
+				t.Errorf("Expected %s?mode=pdf&file=%s, got: %q", tt.expectPath, tt.expectFile, viewerURL)
+			}
+		})
+	}
+
+	result := string(RenderMarkdown("[Q3](/api/files/docs/reports/q3%20summary.pdf)"))
+	if !strings.Contains(result, `<a href="/docs/reports?mode=pdf&file=q3+summary.pdf">Q3</a>`) {
+		t.Errorf("Expected a viewer link for the nested PDF, got: %q", result)
+	}
+
+	// Escaped folders and fragments aren't escaped again
+	if viewerURL := pdfViewerURL("/api/files/docs/a%2Fb/my%20notes/c.pdf#name%20d"); viewerURL != "/docs/a%2Fb/my%20notes?mode=pdf&file=c.pdf#name%20d" {
+		t.Errorf("Expected the escaping to be kept, got: %q", viewerURL)
+	}
+
+	// Relative PDFs are resolved against the document before they are detected
+	result = string(RenderMarkdownWithPath("[A](report.pdf) [B](../shared/Spec.PDF#page=3) [C][q3] [D](/api/files/docs/reports/report.pdf?v=2)\n\n[q3]: sub/q3%20summary.pdf\n", "docs/reports"))
+	for _, expected := range []string{
+		`<a href="/docs/reports?mode=pdf&file=report.pdf">A</a>`,
+		`<a href="/docs/shared?mode=pdf&file=Spec.PDF#page=3">B</a>`,
+		`<a href="/docs/reports/sub?mode=pdf&file=q3+summary.pdf">C</a>`,
+		`<a href="/docs/reports?mode=pdf&file=report.pdf">D</a>`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected %q, got: %q", expected, result)
+		}
+	}
+}
+
+func TestHeadingAnchors(t *testing.T) {
+	result := string(RenderMarkdown("## Setup Guide\n\nText"))
+	expected := `<h2 id="setup-guide">Setup Guide <a class="heading-anchor" href="#setup-guide" aria-label="Permalink">¶</a></h2>`
+	if !strings.Contains(result, expected) {
+		t.Errorf("Expected %q, got: %q", expected, result)
+	}
+
+	result = string(RenderMarkdown("---\nanchors: false\n---\n## Setup Guide\n\nText"))
+	if strings.Contains(result, "heading-anchor") {
+		t.Errorf("Expected no anchors with anchors: false, got: %q", result)
+	}
+	if !strings.Contains(result, `<h2 id="setup-guide">Setup Guide</h2>`) {
+		t.Errorf("Expected the heading to keep its ID, got: %q", result)
+	}
+}
+
+func TestIncludeRendering(t *testing.T) {
+	root := t.TempDir()
+	goldext.IncludeRoot = root
+	defer func() { goldext.IncludeRoot = filepath.Join("data", "documents") }()
+	writeTestDocument(t, root, "shared/diagram", "```mermaid\ngraph TD\n```\n\nShared **text**")
+
+	result := string(RenderMarkdownWithPath("# Page\n\n{{include: /shared/diagram}}\n", "guides"))
+	for _, want := range []string{`<div class="mermaid">graph TD</div>`, "<p>Shared <strong>text</strong></p>"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got: %q", want, result)
+		}
+	}
+}
+
+func TestHeadingSlug(t *testing.T) {
+	headings := []string{
+		"Getting Started",
+		"What's new in v2.0?",
+		"C++ & Go: a comparison",
+		"Use `go test` to run tests",
+		"See [the docs](https://example.com/docs) first",
+		"snake_case and kebab-case",
+		"  Extra   spaces  ",
+		"Überblick",
+		"!!!",
+		"Step 1 (optional)",
+	}
+
+	var md strings.Builder
+	for _, heading := range headings {
+		md.WriteString("## " + heading + "\n\nText\n\n")
+	}
+	result := string(RenderMarkdown(md.String()))
+
+	for _, heading := range headings {
+		id := HeadingSlug(heading)
+		if id == "" || !strings.Contains(result, `id="`+id+`"`) {
+			t.Errorf("Expected the rendered document to have an ID %q for %q, got: %s", id, heading, result)
+		}
+	}
+
+	// Only the first of repeated headings gets the bare slug
+	result = string(RenderMarkdown("## Notes\n\n## Notes\n"))
+	if !strings.Contains(result, `id="`+HeadingSlug("Notes")+`"`) || !strings.Contains(result, `id="notes-1"`) {
+		t.Errorf("Expected numbered IDs for repeated headings, got: %s", result)
+	}
+}
+
+func TestTaskIndex(t *testing.T) {
+	md := "- [ ] First\n- Plain item\n- [x] Second\n  - [ ] Nested\n  - Nested plain\n- [X] Third\n\n> - [ ] Quoted\n\n1. [ ] Ordered\n\n```\n- [ ] In code\n```\n"
+	result := string(RenderMarkdown(md))
+
+	for index, item := range []string{"First", "Second", "Nested", "Third", "Quoted", "Ordered"} {
+		checked := ""
+		if item == "Second" || item == "Third" {
+			checked = `checked="" `
+		}
+		expected := `<input ` + checked + `disabled="" type="checkbox" data-task-index="` + strconv.Itoa(index) + `"> ` + item
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected %q in: %s", expected, result)
+		}
+	}
+	if count := strings.Count(result, "data-task-index"); count != 6 {
+		t.Errorf("Expected 6 indexed tasks, got %d: %s", count, result)
+	}
+	if !strings.Contains(result, "<li>Plain item</li>") {
+		t.Errorf("Expected plain items without an index, got: %s", result)
+	}
+}
+
+func TestBasePath(t *testing.T) {
+	SetBasePath("wiki/")
+	defer SetBasePath("")
+
+	md := "![Shot](shot.png) [Report](report.pdf) [Start](/guides/start) [Again](/wiki/guides/start) [Site](https://example.com) [Top](#top)\n"
+	result := string(RenderMarkdownWithPath(md, "docs"))
+	for _, expected := range []string{
+		`<img src="/wiki/api/files/docs/shot.png" alt="Shot">`,
+		`<a href="/wiki/docs?mode=pdf&file=report.pdf">Report</a>`,
+		`<a href="/wiki/guides/start">Start</a>`,
+		`<a href="/wiki/guides/start">Again</a>`,
+		`<a href="https://example.com" target="_blank" rel="noopener noreferrer">Site</a>`,
+		`<a href="#top">Top</a>`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected %q, got: %q", expected, result)
+		}
+	}
+
+	// Link targets are checked without the base path
+	var targets []string
+	RenderMarkdownWithLinkCheck("[a](/guides/start) [b](/wiki/pages/x)", "docs", func(target string) bool {
+		targets = append(targets, target)
+		return true
+	})
+	if !reflect.DeepEqual(targets, []string{"guides/start", "pages/x"}) {
+		t.Errorf("Expected targets without the base path, got %v", targets)
+	}
+
+	// The renderer option works on its own as well
+	markdown := goldmark.New(goldmark.WithRendererOptions(renderer.WithNodeRenderers(util.Prioritized(NewLinkRenderer(WithBasePath("/docs-site")), 100))))
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte("[File](/api/files/a/b.pdf) [Page](/docs-site/a)"), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `<a href="/docs-site/a?mode=pdf&file=b.pdf">File</a> <a href="/docs-site/a">Page</a>`) {
+		t.Errorf("Expected links below the option's base path, got: %q", buf.String())
+	}
+}
+
+func TestKanbanCardBody(t *testing.T) {
+	md := "---\nlayout: kanban\n---\n#### Sprint\n\n##### Todo\n- [ ] Release **prep**\n  Checklist:\n\n  - [x] tag the [release](https://example.com)\n  - [ ] announce\n\n  ```mermaid\n  graph TD; A-->B\n  ```\n- [x] Plain task\n  - [ ] Nested task\n\nAfter the board.\n"
+	result := string(RenderMarkdown(md))
+
+	for _, want := range []string{
+		`<li class="task-list-item-container has-body" style="list-style-type: none;" data-body-markdown="Checklist:`,
+		`<span class="task-text">Release <strong>prep</strong></span>`,
+		`<div class="kanban-card-body"><p>Checklist:</p>`,
+		`<a href="https://example.com">release</a>`,
+		`class="mermaid"`,
+		`<span class="task-text">Nested task</span>`,
+		`<li class="task-list-item-container" style="list-style-type: none;">`,
+		`<li class="task-list-item-container" style="list-style-type: none;" data-indent-level="1">`,
+		`<p>After the board.</p>`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in: %s", want, result)
+		}
+	}
+
+	// Tasks in a body are not cards of their own
+	if strings.Count(result, `class="task-checkbox"`) != 3 {
+		t.Errorf("Expected three cards, got: %s", result)
+	}
+}
+
+// recordingObserver collects the phases it is told about
+type recordingObserver struct {
+	mu     sync.Mutex
+	phases []string
+}
+
+func (o *recordingObserver) ObserveRender(docPath string, phase RenderPhase, duration time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if duration < 0 {
+		phase += "(negative)"
+	}
+	o.phases = append(o.phases, docPath+":"+string(phase))
+}
+
+func TestRenderObserver(t *testing.T) {
+	md := "# Title\n\n```mermaid\ngraph TD; A-->B\n```\n"
+	unobserved := string(RenderMarkdownWithPath(md, "guides/a"))
+
+	observer := &recordingObserver{}
+	SetRenderObserver(observer)
+	defer SetRenderObserver(nil)
+
+	result := string(RenderMarkdownWithPath(md, "guides/a"))
+	if result != unobserved {
+		t.Errorf("Expected observing not to change the output, got %q, want %q", result, unobserved)
+	}
+
+	expected := "guides/a:preprocess guides/a:convert guides/a:restore"
+	if got := strings.Join(observer.phases, " "); got != expected {
+		t.Errorf("Expected phases %q, got %q", expected, got)
+	}
+
+	// Whole-document rewrites are a phase of their own
+	observer.phases = nil
+	PrintOutput = true
+	defer func() { PrintOutput = false }()
+	RenderMarkdownWithPath(md, "guides/a")
+	if got := strings.Join(observer.phases, " "); got != expected+" guides/a:rewrite" {
+		t.Errorf("Expected a rewrite phase, got %q", got)
+	}
+}
+
+func TestFrontmatterTOC(t *testing.T) {
+	result := string(RenderMarkdown("---\ntoc: true\ntoc_max_level: 2\n---\n# Guide\n\n## Setup\n\n### Details\n"))
+	title := strings.Index(result, `<h1 id="guide">`)
+	toc := strings.Index(result, `<nav class="wiki-toc table-of-contents"`)
+	if title < 0 || toc < title || strings.Count(result, "wiki-toc") != 1 {
+		t.Fatalf("Expected one TOC after the title, got: %s", result)
+	}
+	nav := result[toc : toc+strings.Index(result[toc:], "</nav>")]
+	if !strings.Contains(nav, `href="#setup"`) || strings.Contains(nav, `href="#details"`) {
+		t.Errorf("Expected the TOC to stop at level 2, got: %s", nav)
+	}
+
+	// Documents without the flag have no TOC
+	if result := string(RenderMarkdown("# Guide\n\n## Setup\n")); strings.Contains(result, "wiki-toc") {
+		t.Errorf("Expected no TOC, got: %s", result)
+	}
+}
+
+func TestHardWraps(t *testing.T) {
+	md := "First line\nsecond line\n"
+
+	if result := string(RenderMarkdown(md)); !strings.Contains(result, "First line<br>") {
+		t.Errorf("Expected line breaks by default, got: %q", result)
+	}
+	if result := string(RenderMarkdown("---\nhard_wraps: false\n---\n" + md)); result != "<p>First line\nsecond line</p>\n" {
+		t.Errorf("Expected hard_wraps: false to join the lines, got: %q", result)
+	}
+	if result := string(RenderMarkdownWithPath(md, "", WithHardWraps(false))); strings.Contains(result, "<br>") {
+		t.Errorf("Expected WithHardWraps(false) to join the lines, got: %q", result)
+	}
+
+	// The document's setting wins over the rendering's
+	if result := string(RenderMarkdownWithPath("---\nhard_wraps: true\n---\n"+md, "", WithHardWraps(false))); !strings.Contains(result, "First line<br>") {
+		t.Errorf("Expected the frontmatter to win, got: %q", result)
+	}
+}
+
+func TestRenderSection(t *testing.T) {
+	md := "---\nnumbered_headings: true\n---\n# Guide\n\nIntro\n\n## Setup\n\nInstall it[^1].\n\n### Details\n\n- [ ] check\n\n## Usage\n\nRun it.\n\n[^1]: From the site.\n"
+
+	result, err := RenderSection(md, "guides/a", "setup")
+	if err != nil {
+		t.Fatalf("Expected the section, got %v", err)
+	}
+	full := string(RenderMarkdownWithPath(md, "guides/a"))
+	for _, want := range []string{`<h2 id="setup">`, "Install it", `<h3 id="details">`, `data-task-index="0"`, "From the site."} {
+		if !strings.Contains(string(result), want) {
+			t.Errorf("Expected %q in the section, got: %s", want, result)
+		}
+	}
+	for _, unwanted := range []string{"Intro", `<h1`, "Usage", "Run it."} {
+		if strings.Contains(string(result), unwanted) {
+			t.Errorf("Expected %q outside the section, got: %s", unwanted, result)
+		}
+	}
+
+	// The section renders like its part of the whole document
+	heading := `<h3 id="details">` + `<span class="heading-number">1.1</span> Details`
+	if !strings.Contains(full, heading) || !strings.Contains(string(result), heading) {
+		t.Errorf("Expected %q in both renderings, got: %s", heading, result)
+	}
+
+	if _, err := RenderSection(md, "guides/a", "missing"); !errors.Is(err, ErrSectionNotFound) {
+		t.Errorf("Expected ErrSectionNotFound, got %v", err)
+	}
+}
+
+func TestImageSizeAttributes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Pixel width", "![Logo](/logo.png){width=300}\n", `<p><img src="/logo.png" alt="Logo" width="300"></p>` + "\n"},
+		{"Percentage width", "![Logo](/logo.png){width=50%} is wide\n", `<p><img src="/logo.png" alt="Logo" width="50%"> is wide</p>` + "\n"},
+		{"Width and height", `![Logo](/logo.png){width="300" height=200}` + "\n", `<p><img src="/logo.png" alt="Logo" width="300" height="200"></p>` + "\n"},
+		{"Invalid value", "![Logo](/logo.png){width=300px}\n", `<p><img src="/logo.png" alt="Logo">{width=300px}</p>` + "\n"},
+		{"Other attributes", "![Logo](/logo.png){onload=alert(1)}\n", `<p><img src="/logo.png" alt="Logo">{onload=alert(1)}</p>` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := string(RenderMarkdown(tt.input)); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+// extendedHeadingTransformer marks headings reading "Extended heading"
+type extendedHeadingTransformer struct{}
+
+func (t *extendedHeadingTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if heading, ok := node.(*ast.Heading); ok && entering && string(heading.Text(reader.Source())) == "Extended heading" {
+			heading.SetAttributeString("class", []byte("extended"))
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// extendedHeadingExtension is a goldmark.Extender
+type extendedHeadingExtension struct{}
+
+func (e *extendedHeadingExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(&extendedHeadingTransformer{}, 500)))
+}
+
+func TestRegisterExtension(t *testing.T) {
+	md := "## Extended heading\n"
+
+	// Renders before the registration are cached and must not hide the extension
+	if result := string(RenderMarkdown(md)); strings.Contains(result, `class="extended"`) {
+		t.Fatalf("Expected no extension yet, got %q", result)
+	}
+
+	goldext.RegisterExtension(&extendedHeadingExtension{})
+	if result := string(RenderMarkdown(md)); !strings.Contains(result, `class="extended"`) {
+		t.Errorf("Expected the registered transformer to run, got %q", result)
+	}
+}
+
+func TestWikilinkHeadingIDs(t *testing.T) {
+	ClearRenderCache()
+	defer ClearRenderCache()
+	defer SetDocumentsRoot(filepath.Join("data", "documents"))
+
+	root := t.TempDir()
+	SetDocumentsRoot(root)
+	writeTestDocument(t, root, "guides/setup", "# Setup Guide\n")
+	md := "# Intro\n\n[toc]\n\n## Read [[setup]]\n\n## Also [[setup|the setup]]\n\n## Missing [[nowhere]]\n"
+	writeTestDocument(t, root, "guides/intro", md)
+
+	html, err := RenderMarkdownFile(filepath.Join(root, "guides", "intro", "document.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(html)
+
+	// IDs and table of contents entries use the text the wikilinks display
+	expected := []Heading{
+		{Level: 1, Text: "Intro", ID: "intro"},
+		{Level: 2, Text: "Read Setup Guide", ID: "read-setup-guide"},
+		{Level: 2, Text: "Also the setup", ID: "also-the-setup"},
+		{Level: 2, Text: "Missing nowhere", ID: "missing-nowhere"},
+	}
+	for _, heading := range expected {
+		if !strings.Contains(result, `id="`+heading.ID+`"`) {
+			t.Errorf("Expected the heading ID %q, got: %s", heading.ID, result)
+		}
+	}
+	if !strings.Contains(result, `<li><a href="#read-setup-guide">Read Setup Guide</a>`) || !strings.Contains(result, `<a href="/guides/setup" class="wikilink">Setup Guide</a>`) {
+		t.Errorf("Expected plain table of contents entries and linked headings, got: %s", result)
+	}
+	if strings.Contains(result, `<a href="#read-setup-guide">Read <a`) {
+		t.Errorf("Expected no links nested in table of contents links, got: %s", result)
+	}
+
+	// The outline and HeadingSlug agree with the rendering
+	if headings := ExtractHeadingsWithPath(md, "guides/intro"); !reflect.DeepEqual(headings, expected) {
+		t.Errorf("Expected the headings %+v, got %+v", expected, headings)
+	}
+	for i, text := range []string{"Intro", "Read [[setup]]", "Also [[setup|the setup]]", "Missing [[nowhere]]"} {
+		if id := HeadingSlugWithPath(text, "guides/intro"); id != expected[i].ID {
+			t.Errorf("Expected the slug %q for %q, got %q", expected[i].ID, text, id)
+		}
+	}
+	if id := HeadingSlug("Read [[/guides/setup]]"); id != "read-setup-guide" {
+		t.Errorf("Expected root wikilinks to resolve without a document, got %q", id)
+	}
+}
+
+func TestExplicitHeadingIDs(t *testing.T) {
+	result := string(RenderMarkdown("[toc]\n\n## My Heading {#stable-id}\n\n## Other {#stable_id .big}\n"))
+
+	for _, expected := range []string{
+		`id="stable-id"`,
+		`href="#stable-id"`,
+		`id="stable_id"`,
+		`href="#stable_id"`,
+		`class="big"`,
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected %q in the rendered document, got: %s", expected, result)
+		}
+	}
+	if strings.Contains(result, "my-heading") || strings.Contains(result, "other") || strings.Contains(result, "{#") {
+		t.Errorf("Expected no generated IDs or leftover attributes, got: %s", result)
+	}
+}
+
+func TestRenderDeterministic(t *testing.T) {
+	md := "---\ntitle: Release notes\ntags: [release, notes]\n---\n# Release notes\n\n[toc]\n\n" +
+		"## Changes {#changes}\n\nFixed (c) and (tm) handling... +- (1/2) of the time.[^1] See the HTML spec.[^note]\n\n" +
+		"> [!NOTE]\n> Upgrade first.\n\n" +
+		"```rtl ar\nمرحبا\n```\n\n" +
+		"```mermaid\ngraph TD\n  A --> B\n```\n\n" +
+		"## Changes\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n" +
+		"*[HTML]: HyperText Markup Language\n\n" +
+		"[^1]: First footnote.\n[^note]: Second footnote.\n"
+
+	first := RenderMarkdownWithPath(md, "releases/notes")
+	for i := 0; i < 100; i++ {
+		if result := RenderMarkdownWithPath(md, "releases/notes"); !bytes.Equal(result, first) {
+			t.Fatalf("Rendering %d differs from the first:\n%s\n\nfirst:\n%s", i, result, first)
+		}
+	}
+}
+
+func TestFrontmatterFormatsRender(t *testing.T) {
+	body := "\n# Board\n\n#### Sprint\n\n##### Todo\n- [ ] Write docs\n\n##### Done\n- [x] Ship\n"
+	expected := RenderMarkdownWithPath("---\nlayout: kanban\ntitle: Board\n---\n"+body, "boards/sprint")
+	if !strings.Contains(string(expected), "kanban-board") {
+		t.Fatalf("Expected a kanban board, got: %s", expected)
+	}
+
+	for name, front := range map[string]string{
+		"TOML": "+++\nlayout = \"kanban\"\ntitle = \"Board\"\n+++\n",
+		"JSON": "{\n  \"layout\": \"kanban\",\n  \"title\": \"Board\"\n}\n",
+	} {
+		if result := RenderMarkdownWithPath(front+body, "boards/sprint"); !bytes.Equal(result, expected) {
+			t.Errorf("Expected %s frontmatter to render like YAML, got: %s", name, result)
+		}
+	}
+}
+
+func TestAbbreviationElements(t *testing.T) {
+	md := "*[API]: Application Programming Interface\n\n## API reference\n\nCall the API, not `API` in code.\n"
+	for _, opts := range [][]RenderOption{nil, {WithUntrustedHTML()}} {
+		result := string(RenderMarkdownWithPath(md, "", opts...))
+		if strings.Count(result, `<abbr title="Application Programming Interface">API</abbr>`) != 1 {
+			t.Errorf("Expected one <abbr> element, got: %s", result)
+		}
+		if strings.Contains(result, "*[API]") || !strings.Contains(result, `id="api-reference"`) || !strings.Contains(result, "<code>API</code>") {
+			t.Errorf("Expected the definition stripped and the heading and code untouched, got: %s", result)
+		}
+	}
+}
+
+func TestAbbreviationFirstOnly(t *testing.T) {
+	md := "*[API]: Application Programming Interface\n\n## API reference\n\nCall the API.\n\nThe API again.\n"
+	abbr := `<abbr title="Application Programming Interface">API</abbr>`
+
+	goldext.AbbreviationFirstOnly = true
+	defer func() { goldext.AbbreviationFirstOnly = false }()
+	if result := string(RenderMarkdown(md)); strings.Count(result, abbr) != 1 || !strings.Contains(result, "<p>Call the "+abbr+".</p>") {
+		t.Errorf("Expected only the first occurrence wrapped, got: %s", result)
+	}
+	if result := string(RenderMarkdown("---\nabbreviations: all\n---\n" + md)); strings.Count(result, abbr) != 2 || strings.Contains(result, "abbr:") {
+		t.Errorf("Expected abbreviations: all to wrap every occurrence, got: %s", result)
+	}
+
+	goldext.AbbreviationFirstOnly = false
+	if result := string(RenderMarkdown("---\nabbreviations: first\n---\n" + md)); strings.Count(result, abbr) != 1 {
+		t.Errorf("Expected abbreviations: first to wrap the first occurrence, got: %s", result)
+	}
+}
+
+func TestRenderComment(t *testing.T) {
+	md := "**Nice** page, see [the *docs*](https://example.com/docs \"Docs\") and https://example.org.\n" +
+		"Line two\n\n- one\n- two\n\n```go\nfmt.Println(\"<b>\")\n```\n\n" +
+		"<script>alert(1)</script>\n\n<iframe src=\"https://evil.example\"></iframe>\n\n" +
+		"Inline <b>bold</b>, [bad](javascript:alert(1)), ![pixel](https://tracker.example/p.gif) and [home](/home).\n\n" +
+		"{{include: secrets}}\n\n[[Page]]\n\n| a | b |\n|---|---|\n| 1 | 2 |\n"
+	result := string(RenderComment(md))
+
+	for _, expected := range []string{
+		"<strong>Nice</strong>",
+		`<a href="https://example.com/docs" title="Docs" target="_blank" rel="nofollow noopener">the <em>docs</em></a>`,
+		`<a href="https://example.org" target="_blank" rel="nofollow noopener">https://example.org</a>`,
+		"<br>\nLine two",
+		"<li>one</li>",
+		`<pre><code class="language-go">fmt.Println(&quot;&lt;b&gt;&quot;)`,
+		`<a href="" rel="nofollow noopener">bad</a>`,
+		`<a href="https://tracker.example/p.gif" target="_blank" rel="nofollow noopener">pixel</a>`,
+		`<a href="/home" rel="nofollow noopener">home</a>`,
+		"{{include: secrets}}",
+		"[[Page]]",
+		"<td>1</td>",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected %q in the comment, got: %s", expected, result)
+		}
+	}
+	for _, unexpected := range []string{"<script", "<iframe", "<b>", "<img", " id="} {
+		if strings.Contains(result, unexpected) {
+			t.Errorf("Expected no %q in the comment, got: %s", unexpected, result)
+		}
+	}
+}
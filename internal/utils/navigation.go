@@ -98,6 +98,11 @@ func BuildNavigation(rootDir string, documentsDir string) (*types.NavItem, error
 			return nil
 		}
 
+		// Skip the external image cache
+		if filepath.Base(path) == ImageCacheDirName {
+			return filepath.SkipDir
+		}
+
 		// Skip the pages/home directory in navigation
 		if path == filepath.Join(rootDir, "pages", "home") || path == filepath.Join(rootDir, "pages") {
 			return filepath.SkipDir
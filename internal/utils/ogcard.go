@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"wiki-go/internal/frontmatter"
+	"wiki-go/internal/goldext"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// OGDefaultThemeColor is the accent color of generated social cards without a theme_color
+var OGDefaultThemeColor = "#0366d6"
+
+// OGSubtitleMaxLength caps the derived card subtitle, cut at a word boundary
+var OGSubtitleMaxLength = 160
+
+// OGImageEndpoint is the card-generation endpoint; the document path is appended
+var OGImageEndpoint = "/api/og-image/"
+
+var (
+	ogThemeColorRegex = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+	ogImageRegex      = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?([^)>]+?)>?(?:\s+"[^"]*")?\s*\)`)
+)
+
+// OGCardHints are the inputs a card-generation endpoint needs to render a social image
+type OGCardHints struct {
+	Title      string `json:"title"`
+	Subtitle   string `json:"subtitle"`
+	ThemeColor string `json:"theme_color"`
+	ImageURL   string `json:"image_url"`
+	Generated  bool   `json:"generated"` // Whether ImageURL points at the card-generation endpoint
+}
+
+// BuildOGCardHints derives social card inputs for a document
+// Frontmatter title, description, image and theme_color win over derived values.
+func BuildOGCardHints(md string, docPath string) OGCardHints {
+	metadata, body, _ := frontmatter.Parse(md)
+	docPath = strings.Trim(docPath, "/")
+
+	hints := OGCardHints{
+		Title:      strings.TrimSpace(metadata.Title),
+		Subtitle:   strings.TrimSpace(metadata.Description),
+		ThemeColor: OGDefaultThemeColor,
+		ImageURL:   strings.TrimSpace(metadata.Image),
+	}
+
+	if hints.Title == "" {
+		for _, heading := range ExtractHeadingsWithPath(body, docPath) {
+			if heading.Level == 1 {
+				hints.Title = heading.Text
+				break
+			}
+		}
+	}
+	if hints.Title == "" && docPath != "" {
+		hints.Title = FormatDirName(path.Base(docPath))
+	}
+
+	if hints.Subtitle == "" {
+		hints.Subtitle = truncateAtWord(firstParagraphText(body), OGSubtitleMaxLength)
+	}
+
+	if ogThemeColorRegex.MatchString(strings.TrimSpace(metadata.ThemeColor)) {
+		hints.ThemeColor = strings.TrimSpace(metadata.ThemeColor)
+	}
+
+	// Prefer an image from the document itself; otherwise ask for a generated card
+	if hints.ImageURL == "" {
+		if m := ogImageRegex.FindStringSubmatch(goldext.LinkPreprocessor(body, docPath)); m != nil {
+			hints.ImageURL = m[1]
+		}
+	}
+	if hints.ImageURL == "" {
+		hints.ImageURL = OGImageEndpoint + (&url.URL{Path: docPath}).EscapedPath()
+		hints.Generated = true
+	}
+
+	return hints
+}
+
+// OpenGraph is the page preview data for <meta property="og:..."> and description tags
+type OpenGraph struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Image       string `json:"image,omitempty"` // /api/files/ URL of the first image, empty without one
+}
+
+// BuildOpenGraph derives the preview data of a document
+// The title and description come from the frontmatter, otherwise from the first # heading
+// and the document excerpt; the image from the frontmatter or the first image of the body.
+func BuildOpenGraph(md string, docPath string) OpenGraph {
+	metadata, _, _ := frontmatter.Parse(md)
+	hints := BuildOGCardHints(md, docPath)
+
+	og := OpenGraph{
+		Title:       hints.Title,
+		Description: strings.TrimSpace(metadata.Description),
+	}
+	if og.Description == "" {
+		og.Description = ExtractExcerpt(md, OGSubtitleMaxLength)
+	}
+
+	if !hints.Generated {
+		og.Image = hints.ImageURL
+	}
+
+	// Frontmatter images may be relative to the document like body images
+	if og.Image != "" && !strings.HasPrefix(og.Image, "/") && !isExternalURL(og.Image) {
+		if m := ogImageRegex.FindStringSubmatch(goldext.LinkPreprocessor("![]("+og.Image+")", strings.Trim(docPath, "/"))); m != nil {
+			og.Image = m[1]
+		}
+	}
+
+	return og
+}
+
+// firstParagraphText returns the plain text of the first top-level paragraph
+func firstParagraphText(md string) string {
+	source := []byte(md)
+	doc := goldmark.New().Parser().Parse(text.NewReader(source))
+
+	for node := doc.FirstChild(); node != nil; node = node.NextSibling() {
+		if paragraph, ok := node.(*ast.Paragraph); ok {
+			return strings.Join(strings.Fields(inlineText(paragraph, source)), " ")
+		}
+	}
+	return ""
+}
+
+// inlineText returns the text content of a node's inline children without markup
+func inlineText(node ast.Node, source []byte) string {
+	var sb strings.Builder
+	ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := n.(type) {
+		case *ast.Text:
+			sb.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				sb.WriteByte(' ')
+			}
+		case *ast.String:
+			sb.Write(t.Value)
+		case *ast.CodeSpan:
+			for c := t.FirstChild(); c != nil; c = c.NextSibling() {
+				if text, ok := c.(*ast.Text); ok {
+					sb.Write(text.Segment.Value(source))
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return sb.String()
+}
+
+// truncateAtWord shortens text to at most max characters without cutting a word
+func truncateAtWord(text string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(text) <= max {
+		return text
+	}
+
+	runes := []rune(text)
+	cut := string(runes[:max])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:.") + "…"
+}
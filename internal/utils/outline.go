@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"wiki-go/internal/frontmatter"
+	"wiki-go/internal/goldext"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// OutlineSchemaVersion is the version of the outline JSON format
+// Bump it whenever a field is renamed or removed
+const OutlineSchemaVersion = 1
+
+// Heading represents a single heading in a document
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	ID    string `json:"id"`
+}
+
+// OutlineNode is a heading together with the headings nested below it
+type OutlineNode struct {
+	Heading
+	ChildCount int            `json:"child_count"`
+	Children   []*OutlineNode `json:"children"`
+}
+
+// Outline is the structure of a document for consumption by external tools
+type Outline struct {
+	SchemaVersion int                  `json:"schema_version"`
+	Path          string               `json:"path"`
+	Title         string               `json:"title"`
+	Metadata      frontmatter.Metadata `json:"metadata"`
+	Headings      []*OutlineNode       `json:"headings"`
+}
+
+// ExtractHeadings returns the headings of a markdown document in document order
+// The IDs match the anchors emitted when the document is rendered; wikilinks are
+// resolved from the documents root, see ExtractHeadingsWithPath.
+func ExtractHeadings(md string) []Heading {
+	return ExtractHeadingsWithPath(md, "")
+}
+
+// ExtractHeadingsWithPath returns the headings of the markdown document at docPath, with
+// wikilinks in the text and IDs of headings as the rendered document displays them
+func ExtractHeadingsWithPath(md string, docPath string) []Heading {
+	// Parse returns the content unchanged when there is no frontmatter
+	_, content, _ := frontmatter.Parse(md)
+
+	// Assign heading IDs the same way the render pipeline does
+	content = goldext.WikilinkText(content, docPath)
+	content = goldext.TocPreprocessor(content, "")
+
+	source := []byte(content)
+	markdown := goldmark.New(
+		goldmark.WithParserOptions(
+			parser.WithAutoHeadingID(),
+			parser.WithAttribute(),
+		),
+	)
+	doc := markdown.Parser().Parse(text.NewReader(source))
+
+	var headings []Heading
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		heading, ok := node.(*ast.Heading)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+
+		id := ""
+		if value, ok := heading.AttributeString("id"); ok {
+			if idBytes, ok := value.([]byte); ok {
+				id = string(idBytes)
+			}
+		}
+
+		headings = append(headings, Heading{
+			Level: heading.Level,
+			Text:  string(heading.Text(source)),
+			ID:    id,
+		})
+		return ast.WalkSkipChildren, nil
+	})
+
+	return headings
+}
+
+// HeadingSlug returns the anchor ID the renderer gives a heading with the given text,
+// so #anchor links can be built in code. The text is the heading as written, without
+// the leading #s. A few cases can't be predicted from the text alone:
+//   - repeated headings get -1, -2, ... suffixes in document order
+//   - headings with an explicit {#id} keep that ID
+//   - math and other syntax expanded before IDs are assigned may change the ID;
+//     math never contributes to it
+//   - wikilinks count with the text they display, which for relative targets depends on
+//     the document; see HeadingSlugWithPath
+//   - setext (underlined) headings get Goldmark's own IDs
+func HeadingSlug(text string) string {
+	return HeadingSlugWithPath(text, "")
+}
+
+// HeadingSlugWithPath returns the anchor ID of a heading of the document at docPath,
+// whose relative wikilinks are resolved next to the document
+func HeadingSlugWithPath(text string, docPath string) string {
+	return goldext.HeadingSlug(goldext.WikilinkText(text, docPath))
+}
+
+// BuildOutline computes the nested heading outline of a document
+func BuildOutline(md string, docPath string) *Outline {
+	metadata, _, _ := frontmatter.Parse(md)
+
+	outline := &Outline{
+		SchemaVersion: OutlineSchemaVersion,
+		Path:          docPath,
+		Metadata:      metadata,
+		Headings:      []*OutlineNode{},
+	}
+
+	// Stack of currently open headings, shallowest first
+	var stack []*OutlineNode
+
+	for _, heading := range ExtractHeadingsWithPath(md, docPath) {
+		if outline.Title == "" && heading.Level == 1 {
+			outline.Title = heading.Text
+		}
+
+		node := &OutlineNode{Heading: heading, Children: []*OutlineNode{}}
+
+		// Close headings at the same or a deeper level
+		for len(stack) > 0 && stack[len(stack)-1].Level >= heading.Level {
+			stack = stack[:len(stack)-1]
+		}
+
+		if len(stack) == 0 {
+			outline.Headings = append(outline.Headings, node)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+			parent.ChildCount = len(parent.Children)
+		}
+
+		stack = append(stack, node)
+	}
+
+	return outline
+}
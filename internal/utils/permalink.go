@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// ParagraphPermalinks enables IDs and ¶ permalink buttons on paragraphs.
+// Disabled by default.
+var ParagraphPermalinks = false
+
+// paragraphPermalinkTransformer assigns content-hash IDs to paragraphs
+// so a link to a paragraph stays valid as long as its text is unchanged
+type paragraphPermalinkTransformer struct{}
+
+// Transform implements parser.ASTTransformer
+func (t *paragraphPermalinkTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+
+	// Track used IDs per document so identical paragraphs still get unique IDs
+	usedIDs := make(map[string]int)
+
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		paragraph, ok := node.(*ast.Paragraph)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+
+		id := paragraphID(paragraph.Lines().Value(source))
+		usedIDs[id]++
+		if usedIDs[id] > 1 {
+			id = fmt.Sprintf("%s-%d", id, usedIDs[id])
+		}
+
+		paragraph.SetAttributeString("id", []byte(id))
+
+		// Append the permalink button as raw HTML at the end of the paragraph
+		anchor := ast.NewString([]byte(` <a class="paragraph-permalink" href="#` + id + `" aria-label="Permalink">¶</a>`))
+		anchor.SetCode(true)
+		paragraph.AppendChild(paragraph, anchor)
+
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+// paragraphID derives a stable ID from the paragraph's source text
+func paragraphID(content []byte) string {
+	sum := sha1.Sum(content)
+	return "p-" + hex.EncodeToString(sum[:])[:8]
+}
+
+// paragraphPermalinkExtension is a goldmark.Extender
+type paragraphPermalinkExtension struct{}
+
+// Extend implements goldmark.Extender
+func (e *paragraphPermalinkExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(&paragraphPermalinkTransformer{}, 500),
+	))
+}
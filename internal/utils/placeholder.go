@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"html"
+	"strings"
+
+	"wiki-go/internal/frontmatter"
+)
+
+// EmptyDocumentPlaceholder is the markdown rendered for documents without content,
+// such as empty or frontmatter-only files. It may use {{name}} document variables.
+// Disabled (empty documents render nothing) when empty, which is the default.
+var EmptyDocumentPlaceholder = ""
+
+// EmptyDraftPlaceholder replaces EmptyDocumentPlaceholder for documents marked draft: true
+// When empty, drafts use EmptyDocumentPlaceholder as well.
+var EmptyDraftPlaceholder = ""
+
+// DraftBanner is the text of the banner shown above documents marked draft: true
+var DraftBanner = "This page is a draft and may be incomplete."
+
+// draftBannerHTML returns the draft banner of a document, or "" when it isn't a draft
+func draftBannerHTML(metadata frontmatter.Metadata) string {
+	if !metadata.Draft {
+		return ""
+	}
+	return `<div class="draft-banner" role="note">` + html.EscapeString(DraftBanner) + "</div>\n"
+}
+
+// frontmatterErrorHTML returns the notice shown above documents with invalid frontmatter
+func frontmatterErrorHTML(err error) string {
+	return `<div class="frontmatter-error" role="alert">` + html.EscapeString(err.Error()) + "</div>\n"
+}
+
+// emptyDocumentMarkdown returns the placeholder markdown for a content-empty document
+// The placeholder is wrapped in a container so drafts can be told apart by class.
+func emptyDocumentMarkdown(md string, metadata frontmatter.Metadata) string {
+	if strings.TrimSpace(md) != "" || EmptyDocumentPlaceholder == "" {
+		return md
+	}
+
+	placeholder, class := EmptyDocumentPlaceholder, "empty-document"
+	if metadata.Draft {
+		class += " empty-draft"
+		if EmptyDraftPlaceholder != "" {
+			placeholder = EmptyDraftPlaceholder
+		}
+	}
+
+	return `<div class="` + class + `">` + "\n\n" + placeholder + "\n\n</div>\n"
+}
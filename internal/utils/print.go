@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// PrintOutput transforms rendered HTML for printing from the browser: details blocks are
+// expanded, link URLs are listed as numbered references and page breaks are hinted before
+// top-level headings. Disabled by default.
+var PrintOutput = false
+
+// PrintPageBreakLevel is the heading level a new printed page starts at.
+// The first heading of that level never gets a break.
+var PrintPageBreakLevel = 1
+
+var (
+	printDetailsRegex   = regexp.MustCompile(`(?i)<details\b([^>]*)>`)
+	printOpenAttrRegex  = regexp.MustCompile(`(?i)\sopen(\s|=|$)`)
+	printLinkRegex      = regexp.MustCompile(`(?is)<a\b([^>]*)>(.*?)</a>`)
+	printHrefRegex      = regexp.MustCompile(`(?i)\shref="([^"]*)"`)
+	printTagRegex       = regexp.MustCompile(`<[^>]*>`)
+	printHeadingRegex   = regexp.MustCompile(`(?i)<h([1-6])\b([^>]*)>`)
+	printClassAttrRegex = regexp.MustCompile(`(?i)\sclass="([^"]*)"`)
+)
+
+// ToPrint converts standard rendered HTML into its print variant
+func ToPrint(htmlContent string) string {
+	result := expandDetailsForPrint(htmlContent)
+	result = addPrintPageBreaks(result)
+	return addPrintLinkReferences(result)
+}
+
+// expandDetailsForPrint opens every <details> block so its content is printed
+func expandDetailsForPrint(htmlContent string) string {
+	return printDetailsRegex.ReplaceAllStringFunc(htmlContent, func(tag string) string {
+		attrs := printDetailsRegex.FindStringSubmatch(tag)[1]
+		if printOpenAttrRegex.MatchString(attrs) {
+			return tag
+		}
+		return "<details" + attrs + " open>"
+	})
+}
+
+// addPrintPageBreaks marks headings at PrintPageBreakLevel, except the first, to start a new page
+func addPrintPageBreaks(htmlContent string) string {
+	seen := false
+	return printHeadingRegex.ReplaceAllStringFunc(htmlContent, func(tag string) string {
+		parts := printHeadingRegex.FindStringSubmatch(tag)
+		if parts[1] != fmt.Sprint(PrintPageBreakLevel) {
+			return tag
+		}
+		if !seen {
+			seen = true
+			return tag
+		}
+
+		attrs := parts[2]
+		if m := printClassAttrRegex.FindStringSubmatchIndex(attrs); m != nil {
+			attrs = attrs[:m[3]] + " page-break-before" + attrs[m[3]:]
+		} else {
+			attrs += ` class="page-break-before"`
+		}
+		return "<h" + parts[1] + attrs + ">"
+	})
+}
+
+// addPrintLinkReferences numbers the URL of every link and lists them after the content
+// Fragment links and links whose text already is the URL are left alone.
+func addPrintLinkReferences(htmlContent string) string {
+	var urls []string
+	numbers := make(map[string]int)
+
+	result := printLinkRegex.ReplaceAllStringFunc(htmlContent, func(link string) string {
+		parts := printLinkRegex.FindStringSubmatch(link)
+		m := printHrefRegex.FindStringSubmatch(parts[1])
+		if m == nil {
+			return link
+		}
+
+		href := html.UnescapeString(m[1])
+		text := strings.TrimSpace(html.UnescapeString(printTagRegex.ReplaceAllString(parts[2], "")))
+		if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") || text == href {
+			return link
+		}
+
+		number, ok := numbers[href]
+		if !ok {
+			urls = append(urls, href)
+			number = len(urls)
+			numbers[href] = number
+		}
+		return fmt.Sprintf(`%s<sup class="print-link-ref"><a href="#print-link-%d">[%d]</a></sup>`, link, number, number)
+	})
+
+	if len(urls) == 0 {
+		return result
+	}
+
+	var sb strings.Builder
+	sb.WriteString(result)
+	sb.WriteString("\n<section class=\"print-link-references\">\n<h2>Links</h2>\n<ol>\n")
+	for i, u := range urls {
+		sb.WriteString(fmt.Sprintf("<li id=\"print-link-%d\">%s</li>\n", i+1, html.EscapeString(u)))
+	}
+	sb.WriteString("</ol>\n</section>\n")
+	return sb.String()
+}
@@ -0,0 +1,48 @@
+package utils
+
+import "unicode"
+
+// ReadingWordsPerMinute is the reading speed used for reading time estimates
+var ReadingWordsPerMinute = 200
+
+// ReadingStats returns the number of words of a document and its estimated reading time
+// in minutes, rounded up. Frontmatter, code blocks, raw HTML and markup are not counted;
+// CJK text has no spaces between words, so each of its characters counts as a word.
+func ReadingStats(md string) (words int, minutes int) {
+	words = countWords(documentPlainText(md, false))
+	if words == 0 {
+		return 0, 0
+	}
+
+	wpm := ReadingWordsPerMinute
+	if wpm <= 0 {
+		wpm = 200
+	}
+	return words, (words + wpm - 1) / wpm
+}
+
+// countWords counts whitespace separated words and CJK characters
+func countWords(text string) int {
+	count := 0
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			inWord = false
+		case isCJK(r):
+			count++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if !inWord {
+				count++
+				inWord = true
+			}
+		}
+	}
+	return count
+}
+
+// isCJK reports whether r is a Chinese, Japanese or Korean character
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadingStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		words   int
+		minutes int
+	}{
+		{"Empty", "---\ntitle: Nothing\n---\n", 0, 0},
+		{"Markup and code are not counted", "---\nauthor: someone\n---\n# Title here\n\nSome **bold** text, [a link](/x).\n\n```go\nfunc main() { println(1) }\n```\n\n<div>raw html</div>\n", 7, 1},
+		{"CJK characters count as words", "日本語の文章 and English", 8, 1},
+		{"Rounded up", strings.Repeat("word ", 401), 401, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			words, minutes := ReadingStats(tt.input)
+			if words != tt.words || minutes != tt.minutes {
+				t.Errorf("Expected %d words and %d minutes, got %d and %d", tt.words, tt.minutes, words, minutes)
+			}
+		})
+	}
+
+	ReadingWordsPerMinute = 100
+	defer func() { ReadingWordsPerMinute = 200 }()
+	if _, minutes := ReadingStats(strings.Repeat("word ", 250)); minutes != 3 {
+		t.Errorf("Expected 3 minutes at 100 words per minute, got %d", minutes)
+	}
+}
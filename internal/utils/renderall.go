@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// RenderAll renders every markdown file below root, e.g. to warm the render cache or
+// export the site. Results are keyed by the slash-separated path of the file relative
+// to root, such as "guides/setup/document.md"; each file is rendered as the document of
+// its directory. A file that fails doesn't stop the others: its error is returned
+// instead, with errors in path order. At most concurrency files render at once, one
+// per CPU when concurrency is below 1.
+func RenderAll(root string, concurrency int) (map[string][]byte, []error) {
+	var files []string
+	var errs []error
+
+	walkErr := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			if entry != nil && entry.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if entry.IsDir() {
+			// Skip hidden directories and the external image cache
+			if path != root && (strings.HasPrefix(entry.Name(), ".") || entry.Name() == ImageCacheDirName) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if strings.EqualFold(filepath.Ext(entry.Name()), ".md") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if walkErr != nil {
+		errs = append(errs, walkErr)
+	}
+
+	if concurrency < 1 {
+		concurrency = runtime.NumCPU()
+	}
+
+	results := make(map[string][]byte, len(files))
+	fileErrs := make([]error, len(files))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	jobs := make(chan int)
+	for worker := 0; worker < concurrency; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				relPath, err := filepath.Rel(root, files[i])
+				if err != nil {
+					fileErrs[i] = err
+					continue
+				}
+				docPath, _ := filepath.Rel(root, filepath.Dir(files[i]))
+
+				html, _, err := renderMarkdownFileCached(files[i], filepath.ToSlash(docPath))
+				if err != nil {
+					fileErrs[i] = fmt.Errorf("%s: %w", filepath.ToSlash(relPath), err)
+					continue
+				}
+
+				mu.Lock()
+				results[filepath.ToSlash(relPath)] = html
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// WalkDir visits files in lexical order, so errors come out in path order
+	for _, err := range fileErrs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return results, errs
+}
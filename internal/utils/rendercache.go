@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"bytes"
+	"container/list"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"wiki-go/internal/frontmatter"
+)
+
+// RenderCacheMaxEntries is the number of rendered files RenderMarkdownFile keeps in memory
+// The least recently used file is dropped first; 0 disables the cache.
+var RenderCacheMaxEntries = 256
+
+// renderCacheEntry is the rendered HTML and frontmatter of a file at a given modification
+// time and size, and with given directory defaults files
+type renderCacheEntry struct {
+	path     string
+	modTime  time.Time
+	size     int64
+	defaults []fileStamp
+	html     []byte
+	metadata frontmatter.Metadata
+}
+
+// fileStamp is the modification time and size of a file, or zero for a missing file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// equal reports whether two stamps describe the same version of a file
+func (s fileStamp) equal(other fileStamp) bool {
+	return s.exists == other.exists && s.size == other.size && s.modTime.Equal(other.modTime)
+}
+
+// Rendered files by absolute path, most recently used at the front of the list
+var (
+	renderCacheMutex   sync.Mutex
+	renderCacheList    = list.New()
+	renderCacheEntries = make(map[string]*list.Element)
+)
+
+// ClearRenderCache drops every cached rendering, e.g. after documents were edited
+// or rendering options changed. Renderings depend on other documents through includes,
+// wikilinks and their titles, so the handlers writing documents clear the whole cache.
+func ClearRenderCache() {
+	renderCacheMutex.Lock()
+	defer renderCacheMutex.Unlock()
+
+	renderCacheList.Init()
+	renderCacheEntries = make(map[string]*list.Element)
+}
+
+// renderCacheKey returns the absolute path files are cached under
+func renderCacheKey(filePath string) string {
+	if absPath, err := filepath.Abs(filePath); err == nil {
+		return absPath
+	}
+	return filepath.Clean(filePath)
+}
+
+// cachedRendering returns the cached HTML and frontmatter of a file if it is cached for its
+// current modification time, size and directory defaults files
+func cachedRendering(path string, info fs.FileInfo, defaults []fileStamp) ([]byte, frontmatter.Metadata, bool) {
+	renderCacheMutex.Lock()
+	defer renderCacheMutex.Unlock()
+
+	element, ok := renderCacheEntries[path]
+	if !ok || RenderCacheMaxEntries <= 0 {
+		return nil, frontmatter.Metadata{}, false
+	}
+
+	entry := element.Value.(*renderCacheEntry)
+	if !entry.modTime.Equal(info.ModTime()) || entry.size != info.Size() || !slices.EqualFunc(entry.defaults, defaults, fileStamp.equal) {
+		renderCacheList.Remove(element)
+		delete(renderCacheEntries, path)
+		return nil, frontmatter.Metadata{}, false
+	}
+
+	renderCacheList.MoveToFront(element)
+	return bytes.Clone(entry.html), entry.metadata, true
+}
+
+// storeRendering caches the HTML and frontmatter of a file, evicting the least recently
+// used files beyond RenderCacheMaxEntries
+func storeRendering(path string, info fs.FileInfo, defaults []fileStamp, html []byte, metadata frontmatter.Metadata) {
+	renderCacheMutex.Lock()
+	defer renderCacheMutex.Unlock()
+
+	if element, ok := renderCacheEntries[path]; ok {
+		renderCacheList.Remove(element)
+		delete(renderCacheEntries, path)
+	}
+
+	if RenderCacheMaxEntries > 0 {
+		entry := &renderCacheEntry{path: path, modTime: info.ModTime(), size: info.Size(), defaults: defaults, html: bytes.Clone(html), metadata: metadata}
+		renderCacheEntries[path] = renderCacheList.PushFront(entry)
+	}
+
+	for renderCacheList.Len() > RenderCacheMaxEntries {
+		oldest := renderCacheList.Back()
+		renderCacheList.Remove(oldest)
+		delete(renderCacheEntries, oldest.Value.(*renderCacheEntry).path)
+	}
+}
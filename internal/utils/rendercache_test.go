@@ -0,0 +1,244 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderMarkdownFileCache(t *testing.T) {
+	ClearRenderCache()
+	defer ClearRenderCache()
+
+	filePath := filepath.Join(t.TempDir(), "document.md")
+	if err := os.WriteFile(filePath, []byte("# First"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := RenderMarkdownFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(first), "First") {
+		t.Fatalf("Expected the rendered file, got: %q", first)
+	}
+
+	// Same size and modification time: the cached rendering is returned
+	info, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modTime := info.ModTime()
+	if err := os.WriteFile(filePath, []byte("# Cache"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := RenderMarkdownFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(cached), "First") {
+		t.Errorf("Expected the cached rendering, got: %q", cached)
+	}
+
+	// A new modification time renders the file again
+	modTime = modTime.Add(time.Minute)
+	if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := RenderMarkdownFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(fresh), "Cache") {
+		t.Errorf("Expected a fresh rendering, got: %q", fresh)
+	}
+
+	// Modifying the returned HTML doesn't affect the cache
+	fresh[0] = 'X'
+	again, _ := RenderMarkdownFile(filePath)
+	if again[0] == 'X' {
+		t.Errorf("Expected the cached HTML to be copied")
+	}
+}
+
+func TestRenderCacheEviction(t *testing.T) {
+	ClearRenderCache()
+	defer ClearRenderCache()
+
+	maxEntries := RenderCacheMaxEntries
+	RenderCacheMaxEntries = 2
+	defer func() { RenderCacheMaxEntries = maxEntries }()
+
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a", "b", "c"} {
+		filePath := filepath.Join(dir, name+".md")
+		if err := os.WriteFile(filePath, []byte("# "+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, filePath)
+	}
+
+	RenderMarkdownFile(paths[0])
+	RenderMarkdownFile(paths[1])
+	RenderMarkdownFile(paths[0]) // a is now the most recently used
+	RenderMarkdownFile(paths[2])
+
+	if len(renderCacheEntries) != 2 {
+		t.Fatalf("Expected 2 cached files, got %d", len(renderCacheEntries))
+	}
+	if _, ok := renderCacheEntries[renderCacheKey(paths[1])]; ok {
+		t.Errorf("Expected the least recently used file to be evicted")
+	}
+	if _, ok := renderCacheEntries[renderCacheKey(paths[0])]; !ok {
+		t.Errorf("Expected the recently used file to stay cached")
+	}
+
+	RenderCacheMaxEntries = 0
+	RenderMarkdownFile(paths[0])
+	if len(renderCacheEntries) != 0 {
+		t.Errorf("Expected no cached files with the cache disabled, got %d", len(renderCacheEntries))
+	}
+}
+
+func TestRenderAll(t *testing.T) {
+	ClearRenderCache()
+	defer ClearRenderCache()
+	defer SetDocumentsRoot(filepath.Join("data", "documents"))
+
+	root := t.TempDir()
+	SetDocumentsRoot(root)
+	writeTestDocument(t, root, "pages/home", "# Home\n")
+	writeTestDocument(t, root, "guides/setup", "# Setup\n\n![Shot](shot.png)\n")
+	writeTestDocument(t, root, "guides/setup/advanced", "# Advanced\n")
+	writeTestDocument(t, root, ".hidden", "# Hidden\n")
+	if err := os.WriteFile(filepath.Join(root, "guides", "notes.md"), []byte("Notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "missing.md"), filepath.Join(root, "guides", "broken.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	results, errs := RenderAll(root, 4)
+	if len(results) != 4 {
+		t.Errorf("Expected 4 rendered files, got %d", len(results))
+	}
+	if !strings.Contains(string(results["guides/setup/document.md"]), `src="/api/files/guides/setup/shot.png"`) {
+		t.Errorf("Expected links resolved against the document, got: %q", results["guides/setup/document.md"])
+	}
+	if !strings.Contains(string(results["guides/notes.md"]), "Notes") {
+		t.Errorf("Expected other markdown files to render, got: %v", results)
+	}
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "guides/broken.md: ") {
+		t.Errorf("Expected a single error for the broken file, got: %v", errs)
+	}
+
+	// Renderings go through the render cache and don't depend on the concurrency
+	if _, ok := renderCacheEntries[renderCacheKey(filepath.Join(root, "guides", "setup", "document.md"))]; !ok {
+		t.Errorf("Expected the rendering to be cached")
+	}
+	ClearRenderCache()
+	sequential, _ := RenderAll(root, 1)
+	for path, html := range results {
+		if string(sequential[path]) != string(html) {
+			t.Errorf("Expected the same rendering of %s, got %q and %q", path, sequential[path], html)
+		}
+	}
+}
+
+func TestRenderAllPlaceholders(t *testing.T) {
+	ClearRenderCache()
+	defer ClearRenderCache()
+	defer SetDocumentsRoot(filepath.Join("data", "documents"))
+
+	// Mermaid, direction and math blocks are restored from placeholders after rendering;
+	// documents rendered at the same time must each get their own blocks back
+	root := t.TempDir()
+	SetDocumentsRoot(root)
+	const count = 40
+	for i := 0; i < count; i++ {
+		writeTestDocument(t, root, fmt.Sprintf("doc%d", i), fmt.Sprintf(
+			"# Doc %d\n\n```mermaid\ngraph TD; A%d\n```\n\n```rtl\nשלום %d\n```\n\nSum $a*b*_%d$ here.\n", i, i, i, i))
+	}
+
+	results, errs := RenderAll(root, 8)
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors, got: %v", errs)
+	}
+	for i := 0; i < count; i++ {
+		html := string(results[fmt.Sprintf("doc%d/document.md", i)])
+		for _, expected := range []string{
+			fmt.Sprintf(`<div class="mermaid">graph TD; A%d</div>`, i),
+			fmt.Sprintf(`<div class="rtl"><p>שלום %d</p>`, i),
+			fmt.Sprintf(`$a*b*_%d$`, i),
+		} {
+			if !strings.Contains(html, expected) {
+				t.Errorf("Expected doc%d to contain %q, got: %q", i, expected, html)
+			}
+		}
+		if strings.Contains(html, "_BLOCK_") || strings.Count(html, "graph TD") != 1 || strings.Count(html, "שלום") != 1 {
+			t.Errorf("Expected only the blocks of doc%d, got: %q", i, html)
+		}
+	}
+}
+
+// countingFileProvider counts the files read through another provider
+type countingFileProvider struct {
+	FileProvider
+	reads int
+}
+
+func (p *countingFileProvider) ReadFile(name string) ([]byte, error) {
+	p.reads++
+	return p.FileProvider.ReadFile(name)
+}
+
+func TestRenderCacheDirectoryDefaults(t *testing.T) {
+	ClearRenderCache()
+	defer ClearRenderCache()
+	defer SetDocumentsRoot(filepath.Join("data", "documents"))
+
+	root := t.TempDir()
+	SetDocumentsRoot(root)
+	filePath := filepath.Join(root, "guides", "document.md")
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filePath, []byte("# Guides"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, DirectoryDefaultsName), []byte("layout: wide\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &countingFileProvider{FileProvider: OSFileProvider}
+	defer func(previous FileProvider) { OSFileProvider = previous }(OSFileProvider)
+	OSFileProvider = provider
+
+	if _, metadata, err := RenderMarkdownFileWithMetadata(filePath); err != nil || metadata.Layout != "wide" {
+		t.Fatalf("Expected the root defaults, got %+v, %v", metadata, err)
+	}
+
+	// A cache hit reads neither the document nor its defaults
+	provider.reads = 0
+	if _, _, err := RenderMarkdownFileWithMetadata(filePath); err != nil {
+		t.Fatal(err)
+	}
+	if provider.reads != 0 {
+		t.Errorf("Expected a cache hit to read no files, got %d reads", provider.reads)
+	}
+
+	// Creating defaults closer to the document renders it again
+	if err := os.WriteFile(filepath.Join(root, "guides", DirectoryDefaultsName), []byte("layout: kanban\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, metadata, err := RenderMarkdownFileWithMetadata(filePath); err != nil || metadata.Layout != "kanban" {
+		t.Errorf("Expected the new directory defaults, got %+v, %v", metadata, err)
+	}
+}
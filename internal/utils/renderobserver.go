@@ -0,0 +1,39 @@
+package utils
+
+import "time"
+
+// RenderPhase is a step of rendering a document, as reported to a RenderObserver
+type RenderPhase string
+
+// Phases of rendering a markdown document
+const (
+	RenderPhasePreprocess RenderPhase = "preprocess" // Variables, shortcodes, sanitizing and the goldext preprocessors
+	RenderPhaseConvert    RenderPhase = "convert"    // Goldmark parsing and rendering
+	RenderPhaseRestore    RenderPhase = "restore"    // Restoring mermaid, direction and math blocks and the other line post-processors
+	RenderPhaseRewrite    RenderPhase = "rewrite"    // Responsive tables, print and AMP output, only when enabled
+)
+
+// RenderObserver is told how long each phase of rendering a document took, e.g. to
+// export render metrics. Documents render concurrently, so implementations must be
+// safe for concurrent use. Cached documents and the kanban, links and gallery layouts
+// aren't observed.
+type RenderObserver interface {
+	ObserveRender(docPath string, phase RenderPhase, duration time.Duration)
+}
+
+// renderObserver receives render timings, nil when timings aren't wanted
+var renderObserver RenderObserver
+
+// SetRenderObserver sets the observer told the timing of each render phase, or removes
+// it when observer is nil. Without an observer rendering isn't timed at all, and
+// documents are streamed through the post-processors while Goldmark renders them;
+// with one, Goldmark renders into a buffer first so the phases can be timed apart.
+// It must be called before rendering starts.
+func SetRenderObserver(observer RenderObserver) {
+	renderObserver = observer
+}
+
+// observeRender reports the time a phase took since start to the render observer
+func observeRender(docPath string, phase RenderPhase, start time.Time) {
+	renderObserver.ObserveRender(docPath, phase, time.Since(start))
+}
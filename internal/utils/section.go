@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// ErrSectionNotFound is returned by RenderSection when no heading has the anchor ID
+var ErrSectionNotFound = errors.New("section not found")
+
+// RenderSection renders the section of a document under the heading with the given
+// anchor ID: the heading and everything below it up to the next heading of the same
+// or a higher level, e.g. for previews of links to the heading. The section renders
+// through the same preprocessors and extensions as the whole document, so heading
+// numbers and task indexes match the full rendering. Only headings at the top level of
+// the document start a section, and kanban, links and gallery layouts have none.
+func RenderSection(md string, docPath string, anchorID string) ([]byte, error) {
+	section := &sectionSelector{anchorID: strings.TrimPrefix(anchorID, "#")}
+	html, _, _ := renderMarkdownWithMetadata(md, docPath, renderOptions{section: section})
+	if !section.found {
+		return nil, ErrSectionNotFound
+	}
+	return html, nil
+}
+
+// sectionSelector is the section of a document to render, and whether it was found
+type sectionSelector struct {
+	anchorID string
+	found    bool
+}
+
+// sectionTransformer removes everything outside the selected section from the document
+// It runs after the other transformers, so what they number counts the whole document.
+type sectionTransformer struct {
+	section *sectionSelector
+}
+
+// Transform implements parser.ASTTransformer
+func (t *sectionTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var start *ast.Heading
+	for child := doc.FirstChild(); child != nil; child = child.NextSibling() {
+		if heading, ok := child.(*ast.Heading); ok {
+			if value, ok := heading.AttributeString("id"); ok {
+				if id, ok := value.([]byte); ok && string(id) == t.section.anchorID {
+					start = heading
+					break
+				}
+			}
+		}
+	}
+	t.section.found = start != nil
+
+	// Keep the section and the footnote lists its references point to
+	inSection := false
+	for child := doc.FirstChild(); child != nil; {
+		next := child.NextSibling()
+		if heading, ok := child.(*ast.Heading); ok && start != nil {
+			if heading == start {
+				inSection = true
+			} else if heading.Level <= start.Level {
+				inSection = false
+			}
+		}
+		if _, ok := child.(*extast.FootnoteList); !ok && !inSection {
+			doc.RemoveChild(doc, child)
+		}
+		child = next
+	}
+}
+
+// sectionExtension is a goldmark.Extender
+type sectionExtension struct {
+	section *sectionSelector
+}
+
+// Extend implements goldmark.Extender
+func (e *sectionExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(&sectionTransformer{section: e.section}, 1100),
+	))
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"bytes"
+	"html"
+	"os"
+	"regexp"
+	"strings"
+
+	"wiki-go/internal/resources"
+)
+
+// StandaloneBaseURL is the address of the wiki, e.g. https://wiki.example.com, put in front
+// of the root-relative links and images of exported pages so they still load from a saved
+// file, and of the URLs of image copy links. Left empty, the links stay root-relative.
+var StandaloneBaseURL = ""
+
+// standaloneStylesheets are the embedded stylesheets inlined into exported pages
+var standaloneStylesheets = []string{
+	"css/theme.css",
+	"css/typography.css",
+	"css/markdown-extensions.css",
+}
+
+// standaloneMermaidScript is the embedded mermaid library inlined for pages with diagrams
+const standaloneMermaidScript = "libs/mermaid-11.8.1/mermaid.min.js"
+
+// standaloneURLRegex matches root-relative src and href attributes
+var standaloneURLRegex = regexp.MustCompile(`\b(src|href)="/([^/"][^"]*)?"`)
+
+// RenderStandaloneHTML renders a markdown file as a self-contained HTML page for download
+// The page has the document's title, inlined base styles and, when the document has
+// diagrams, the inlined mermaid library, so it also works offline.
+func RenderStandaloneHTML(filePath string) ([]byte, error) {
+	content, metadata, err := RenderMarkdownFileWithMetadata(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fall back to the first heading or the directory name like social cards do
+	title := strings.TrimSpace(metadata.Title)
+	if title == "" {
+		md, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		title = BuildOGCardHints(string(md), documentPathOf(filePath)).Title
+	}
+
+	if base := strings.TrimRight(StandaloneBaseURL, "/"); base != "" {
+		content = standaloneURLRegex.ReplaceAll(content, []byte(`$1="`+base+`/$2"`))
+	}
+
+	var page bytes.Buffer
+	page.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	page.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	page.WriteString("<title>" + html.EscapeString(title) + "</title>\n<style>\n")
+	for _, name := range standaloneStylesheets {
+		css, err := resources.ReadStaticFile(name)
+		if err != nil {
+			return nil, err
+		}
+		page.Write(css)
+		page.WriteString("\n")
+	}
+	page.WriteString("body { max-width: 900px; margin: 0 auto; padding: 2em 1em; }\n</style>\n</head>\n<body>\n")
+
+	page.WriteString("<main class=\"content\">\n")
+	page.Write(content)
+	page.WriteString("</main>\n")
+
+	if bytes.Contains(content, []byte(`class="mermaid"`)) {
+		script, err := resources.ReadStaticFile(standaloneMermaidScript)
+		if err != nil {
+			return nil, err
+		}
+		page.WriteString("<script>\n")
+		page.Write(bytes.ReplaceAll(script, []byte("</script"), []byte(`<\/script`)))
+		page.WriteString("\n</script>\n<script>mermaid.initialize({startOnLoad: true, securityLevel: 'strict'});</script>\n")
+	}
+
+	page.WriteString("</body>\n</html>\n")
+	return page.Bytes(), nil
+}
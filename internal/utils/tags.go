@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"wiki-go/internal/frontmatter"
+	"wiki-go/internal/goldext"
+)
+
+// TagNavigationOrder selects how documents sharing a tag are ordered: "date" or "weight"
+var TagNavigationOrder = "date"
+
+// TagDocument is a document entry of the tag index
+type TagDocument struct {
+	Path       string
+	Title      string
+	Tags       []string
+	PrimaryTag string
+	Date       time.Time
+	Weight     int // Zero means unset
+}
+
+// TagNavLink is a link to a neighbouring document within a tag
+type TagNavLink struct {
+	Path  string `json:"path"`
+	Title string `json:"title"`
+}
+
+// TagNavigation holds the previous and next documents sharing a tag
+type TagNavigation struct {
+	Tag  string      `json:"tag"`
+	Prev *TagNavLink `json:"prev,omitempty"`
+	Next *TagNavLink `json:"next,omitempty"`
+}
+
+// NormalizeTag returns the canonical form of a tag, or "" for empty tags
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// documentTags returns the normalized frontmatter tags and body hashtags of a document
+func documentTags(metadata frontmatter.Metadata, body string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+
+	candidates := append(append([]string{}, metadata.Tags...), goldext.ExtractHashtags(body)...)
+	for _, tag := range candidates {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+// CollectTagDocuments walks the documents root and returns every tagged document
+func CollectTagDocuments(documentsRoot string) ([]TagDocument, error) {
+	var docs []TagDocument
+
+	err := walkTaggedDocuments(documentsRoot, func(dir, relPath string, metadata frontmatter.Metadata, tags []string) {
+		doc := TagDocument{
+			Path:       relPath,
+			Title:      GetDocumentTitle(dir),
+			Tags:       tags,
+			PrimaryTag: NormalizeTag(metadata.PrimaryTag),
+			Weight:     metadata.Weight,
+		}
+		if metadata.ParsedDate != nil {
+			doc.Date = *metadata.ParsedDate
+		}
+		docs = append(docs, doc)
+	})
+
+	return docs, err
+}
+
+// BuildTagIndex walks the documents root and returns the paths of the documents carrying
+// each normalized tag, sorted by path
+func BuildTagIndex(documentsRoot string) (map[string][]string, error) {
+	index := make(map[string][]string)
+
+	err := walkTaggedDocuments(documentsRoot, func(_, relPath string, _ frontmatter.Metadata, tags []string) {
+		for _, tag := range tags {
+			index[tag] = append(index[tag], relPath)
+		}
+	})
+
+	// Walk order differs from path order for names sorting before "/", e.g. "a-b" and "a/c"
+	for _, paths := range index {
+		sort.Strings(paths)
+	}
+
+	return index, err
+}
+
+// walkTaggedDocuments calls fn with the directory, document path, frontmatter and
+// normalized tags of every tagged document below the documents root
+func walkTaggedDocuments(documentsRoot string, fn func(dir, relPath string, metadata frontmatter.Metadata, tags []string)) error {
+	return filepath.WalkDir(documentsRoot, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			// Skip hidden directories and the external image cache
+			if path != documentsRoot && (strings.HasPrefix(entry.Name(), ".") || entry.Name() == ImageCacheDirName) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if entry.Name() != "document.md" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		metadata, body, _ := frontmatter.Parse(string(content))
+		tags := documentTags(metadata, body)
+		if len(tags) == 0 {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(documentsRoot, filepath.Dir(path))
+		if err != nil {
+			return nil
+		}
+
+		fn(filepath.Dir(path), filepath.ToSlash(relPath), metadata, tags)
+		return nil
+	})
+}
+
+// navigationTag picks the tag used for a document's prev/next navigation
+// The primary tag wins when the document actually has it; otherwise the first tag is used
+func (d TagDocument) navigationTag() string {
+	for _, tag := range d.Tags {
+		if tag == d.PrimaryTag {
+			return tag
+		}
+	}
+	if len(d.Tags) > 0 {
+		return d.Tags[0]
+	}
+	return ""
+}
+
+// TagNeighbors returns the previous and next documents sharing the navigation tag of the document at path
+// It returns nil when the document isn't tagged; a tag with a single document has no neighbours
+func TagNeighbors(docs []TagDocument, path string) *TagNavigation {
+	path = strings.Trim(path, "/")
+
+	var current *TagDocument
+	for i := range docs {
+		if docs[i].Path == path {
+			current = &docs[i]
+			break
+		}
+	}
+	if current == nil {
+		return nil
+	}
+
+	tag := current.navigationTag()
+	if tag == "" {
+		return nil
+	}
+
+	var related []TagDocument
+	for _, doc := range docs {
+		for _, t := range doc.Tags {
+			if t == tag {
+				related = append(related, doc)
+				break
+			}
+		}
+	}
+	sortTagDocuments(related)
+
+	nav := &TagNavigation{Tag: tag}
+	for i, doc := range related {
+		if doc.Path != path {
+			continue
+		}
+		if i > 0 {
+			nav.Prev = &TagNavLink{Path: "/" + related[i-1].Path, Title: related[i-1].Title}
+		}
+		if i < len(related)-1 {
+			nav.Next = &TagNavLink{Path: "/" + related[i+1].Path, Title: related[i+1].Title}
+		}
+		break
+	}
+
+	return nav
+}
+
+// sortTagDocuments orders documents by TagNavigationOrder, falling back to the path
+// Documents without a date or weight come after the others
+func sortTagDocuments(docs []TagDocument) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		a, b := docs[i], docs[j]
+		if TagNavigationOrder == "weight" {
+			if a.Weight != b.Weight {
+				if a.Weight == 0 || b.Weight == 0 {
+					return b.Weight == 0
+				}
+				return a.Weight < b.Weight
+			}
+		} else if !a.Date.Equal(b.Date) {
+			if a.Date.IsZero() || b.Date.IsZero() {
+				return b.Date.IsZero()
+			}
+			return a.Date.Before(b.Date)
+		}
+		return a.Path < b.Path
+	})
+}
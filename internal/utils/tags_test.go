@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeTestDocument creates a document.md below root for the tag tests
+func writeTestDocument(t *testing.T, root, path, content string) {
+	t.Helper()
+	dir := filepath.Join(root, filepath.FromSlash(path))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "document.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTagNeighbors(t *testing.T) {
+	root := t.TempDir()
+	writeTestDocument(t, root, "guides/b", "---\ntags: [Guide]\ndate: 2024-02-01\nweight: 1\n---\n# B\n")
+	writeTestDocument(t, root, "guides/a", "---\ntags: [guide, howto]\nprimary_tag: guide\ndate: 2024-01-01\nweight: 3\n---\n# A\n")
+	writeTestDocument(t, root, "guides/c", "---\ndate: 2024-03-01\nweight: 2\n---\n# C\n\nTagged inline as #guide.\n")
+	writeTestDocument(t, root, "guides/undated", "---\ntags: [guide]\n---\n# Undated\n")
+	writeTestDocument(t, root, "solo", "---\ntags: [solo]\n---\n# Solo\n")
+	writeTestDocument(t, root, "untagged", "# Untagged\n")
+
+	docs, err := CollectTagDocuments(root)
+	if err != nil {
+		t.Fatalf("Expected documents to be collected, got error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		order      string
+		path       string
+		prev, next string
+	}{
+		{"First by date", "date", "guides/a", "", "/guides/b"},
+		{"Middle by date", "date", "guides/b", "/guides/a", "/guides/c"},
+		{"Undated last", "date", "guides/undated", "/guides/c", ""},
+		{"First by weight", "weight", "guides/b", "", "/guides/c"},
+		{"Last by weight", "weight", "guides/a", "/guides/c", "/guides/undated"},
+		{"Single-document tag", "date", "solo", "", ""},
+	}
+
+	defer func() { TagNavigationOrder = "date" }()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			TagNavigationOrder = tt.order
+			nav := TagNeighbors(docs, tt.path)
+			if nav == nil {
+				t.Fatalf("Expected navigation for %s", tt.path)
+			}
+
+			prev, next := "", ""
+			if nav.Prev != nil {
+				prev = nav.Prev.Path
+			}
+			if nav.Next != nil {
+				next = nav.Next.Path
+			}
+			if prev != tt.prev || next != tt.next {
+				t.Errorf("Expected prev %q next %q, got prev %q next %q", tt.prev, tt.next, prev, next)
+			}
+		})
+	}
+
+	if nav := TagNeighbors(docs, "untagged"); nav != nil {
+		t.Errorf("Expected no navigation for an untagged document, got %+v", nav)
+	}
+}
+
+func TestBuildTagIndex(t *testing.T) {
+	root := t.TempDir()
+	writeTestDocument(t, root, "a/c", "---\ntags: Guide, Setup\n---\n# C\n")
+	writeTestDocument(t, root, "a-b", "---\ntags:\n  - guide\n  - \" \"\n---\n# A-B\n")
+	writeTestDocument(t, root, "notes", "# Notes\n\nAbout #setup.\n")
+	writeTestDocument(t, root, "untagged", "# Untagged\n")
+	writeTestDocument(t, root, ".hidden/doc", "---\ntags: [guide]\n---\n")
+
+	index, err := BuildTagIndex(root)
+	if err != nil {
+		t.Fatalf("Expected the index to be built, got error: %v", err)
+	}
+
+	expected := map[string][]string{
+		"guide": {"a-b", "a/c"},
+		"setup": {"a/c", "notes"},
+	}
+	if !reflect.DeepEqual(index, expected) {
+		t.Errorf("Expected %v, got %v", expected, index)
+	}
+}
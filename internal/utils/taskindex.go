@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"strconv"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// taskIndexAttribute holds the position of a task item among the document's task items
+const taskIndexAttribute = "data-task-index"
+
+// taskIndexTransformer numbers the task list checkboxes of a document from 0 in
+// document order, nested lists included, so a frontend can tell which item was
+// toggled. List items without a checkbox aren't numbered.
+type taskIndexTransformer struct{}
+
+// Transform implements parser.ASTTransformer
+func (t *taskIndexTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	index := 0
+
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		if checkbox, ok := node.(*extast.TaskCheckBox); ok {
+			checkbox.SetAttributeString(taskIndexAttribute, []byte(strconv.Itoa(index)))
+			index++
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// taskCheckBoxRenderer renders task list checkboxes with their index
+type taskCheckBoxRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer
+func (r *taskCheckBoxRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(extast.KindTaskCheckBox, r.renderTaskCheckBox)
+}
+
+// Custom render function for task checkboxes, matching Goldmark's output
+// apart from the index attribute
+func (r *taskCheckBoxRenderer) renderTaskCheckBox(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*extast.TaskCheckBox)
+
+	if n.IsChecked {
+		_, _ = w.WriteString(`<input checked="" disabled="" type="checkbox"`)
+	} else {
+		_, _ = w.WriteString(`<input disabled="" type="checkbox"`)
+	}
+	if index, ok := n.AttributeString(taskIndexAttribute); ok {
+		_, _ = w.WriteString(` ` + taskIndexAttribute + `="`)
+		_, _ = w.Write(index.([]byte))
+		_ = w.WriteByte('"')
+	}
+	_, _ = w.WriteString("> ")
+	return ast.WalkContinue, nil
+}
+
+// taskIndexExtension is a goldmark.Extender
+type taskIndexExtension struct{}
+
+// Extend implements goldmark.Extender
+func (e *taskIndexExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(&taskIndexTransformer{}, 500),
+	))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&taskCheckBoxRenderer{}, 100),
+	))
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+// StripTrackingParams removes tracking query parameters from external link destinations.
+// Disabled by default.
+var StripTrackingParams = false
+
+// TrackingParams lists the query parameter names removed when StripTrackingParams is enabled
+// A trailing * matches any parameter with that prefix
+var TrackingParams = []string{"utm_*", "fbclid", "gclid", "dclid", "msclkid", "mc_cid", "mc_eid", "igshid", "yclid", "_hsenc", "_hsmi"}
+
+// isTrackingParam reports whether a query parameter name matches TrackingParams
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range TrackingParams {
+		pattern = strings.ToLower(pattern)
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(name, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// isExternalURL reports whether a destination is an absolute http(s) URL
+func isExternalURL(destination string) bool {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// stripTrackingParams removes tracking parameters from an external URL
+// Only the query is rewritten: the order and encoding of the remaining parameters, and
+// the path and fragment as written, are preserved
+func stripTrackingParams(destination string) string {
+	if !isExternalURL(destination) {
+		return destination
+	}
+
+	rest, fragment := destination, ""
+	if i := strings.Index(rest, "#"); i >= 0 {
+		rest, fragment = rest[:i], rest[i:]
+	}
+	prefix, query, ok := strings.Cut(rest, "?")
+	if !ok || query == "" {
+		return destination
+	}
+
+	var kept []string
+	for _, pair := range strings.Split(query, "&") {
+		if pair == "" {
+			continue
+		}
+		name := pair
+		if i := strings.Index(pair, "="); i >= 0 {
+			name = pair[:i]
+		}
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+		if !isTrackingParam(name) {
+			kept = append(kept, pair)
+		}
+	}
+
+	if len(kept) > 0 {
+		prefix += "?" + strings.Join(kept, "&")
+	}
+	return prefix + fragment
+}
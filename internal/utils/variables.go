@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// VariableMaxDepth bounds how deeply variables referencing other variables are resolved
+var VariableMaxDepth = 5
+
+var variableRegex = regexp.MustCompile(`\{\{\s*([A-Za-z_][\w.-]*)\s*\}\}`)
+
+// ExpandVariables replaces {{name}} references with the document variables from the
+// frontmatter vars map. Unknown names are left as written and code is never touched.
+func ExpandVariables(md string, vars map[string]string) string {
+	if len(vars) == 0 || !strings.Contains(md, "{{") {
+		return md
+	}
+
+	lines := strings.Split(md, "\n")
+	inCodeBlock := false
+
+	for i, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Check if this line starts or ends a code block
+		if strings.HasPrefix(trimmedLine, "```") || strings.HasPrefix(trimmedLine, "~~~") {
+			inCodeBlock = !inCodeBlock
+			continue
+		}
+
+		// If we're in a code block, don't process
+		if inCodeBlock || !strings.Contains(line, "{{") {
+			continue
+		}
+
+		// Skip inline code
+		segments := strings.Split(line, "`")
+		for j := 0; j < len(segments); j += 2 {
+			segments[j] = expandVariableRefs(segments[j], vars, VariableMaxDepth)
+		}
+		lines[i] = strings.Join(segments, "`")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// expandVariableRefs substitutes variable references, resolving references inside
+// variable values up to depth levels deep
+func expandVariableRefs(text string, vars map[string]string, depth int) string {
+	return variableRegex.ReplaceAllStringFunc(text, func(match string) string {
+		value, ok := vars[variableRegex.FindStringSubmatch(match)[1]]
+		if !ok {
+			return match
+		}
+		if depth > 0 && strings.Contains(value, "{{") {
+			value = expandVariableRefs(value, vars, depth-1)
+		}
+		return value
+	})
+}